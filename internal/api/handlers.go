@@ -0,0 +1,333 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/httpjson"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+	"github.com/andinianst93/systemd-monitoring/internal/systemd"
+)
+
+// sseHeartbeat keeps idle log streams alive through proxies
+const sseHeartbeat = 15 * time.Second
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	httpjson.Write(w, http.StatusOK, map[string]interface{}{
+		"status":    "ok",
+		"backend":   s.client.Backend(),
+		"read_only": s.opts.ReadOnly,
+	})
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(openAPISpec))
+}
+
+// handleListUnits serves GET /api/v1/units?type=service&status=running
+func (s *Server) handleListUnits(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// 1. Parse filters
+	unitType := models.UnitService
+	if value := query.Get("type"); value != "" {
+		parsed, err := models.ParseUnitType(value)
+		if err != nil {
+			httpjson.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		unitType = parsed
+	}
+
+	var status models.ServiceStatus
+	switch value := query.Get("status"); value {
+	case "", "all":
+	case "running", "failed", "stopped":
+		status = models.ServiceStatus(value)
+	default:
+		httpjson.Error(w, http.StatusBadRequest, fmt.Sprintf("invalid status %q (use running, failed, stopped or all)", value))
+		return
+	}
+
+	// 2. List
+	serviceList, err := s.client.ListUnits(r.Context(), unitType)
+	if err != nil {
+		httpjson.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// 3. Filter, keeping the counters consistent with the result
+	if status != "" {
+		filtered := models.NewServiceList()
+		filtered.Timestamp = serviceList.Timestamp
+		for _, service := range serviceList.GetByStatus(status) {
+			filtered.AddService(service)
+		}
+		serviceList = filtered
+	}
+
+	httpjson.Write(w, http.StatusOK, serviceList)
+}
+
+// handleGetUnit serves GET /api/v1/units/{name}
+func (s *Server) handleGetUnit(w http.ResponseWriter, r *http.Request) {
+	unitName, ok := unitFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	service, err := s.client.GetServiceStatus(r.Context(), unitName)
+	if err != nil {
+		httpjson.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httpjson.Write(w, http.StatusOK, service)
+}
+
+// handleLogs serves GET /api/v1/units/{name}/logs
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	unitName, ok := unitFromRequest(w, r)
+	if !ok {
+		return
+	}
+	opts, ok := logOptionsFromRequest(w, r, 50)
+	if !ok {
+		return
+	}
+
+	entries, err := s.client.GetServiceLogs(r.Context(), unitName, opts)
+	if err != nil {
+		httpjson.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []*models.LogEntry{}
+	}
+
+	httpjson.Write(w, http.StatusOK, entries)
+}
+
+// handleLogStream serves GET /api/v1/units/{name}/logs/stream as
+// Server-Sent Events: one "log" event per entry, an "error" event if
+// following fails
+func (s *Server) handleLogStream(w http.ResponseWriter, r *http.Request) {
+	unitName, ok := unitFromRequest(w, r)
+	if !ok {
+		return
+	}
+	opts, ok := logOptionsFromRequest(w, r, 10)
+	if !ok {
+		return
+	}
+	opts.Follow = true
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpjson.Error(w, http.StatusInternalServerError, "streaming is not supported by this connection")
+		return
+	}
+
+	// 1. Start following; the request context stops journalctl when the
+	// client disconnects
+	logChan, errChan, err := s.client.GetServiceLogsStream(r.Context(), unitName, opts)
+	if err != nil {
+		httpjson.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	// 2. Relay entries until the stream or the client ends; an error is
+	// sent after the entries read before it
+	var streamErr error
+	for {
+		select {
+		case entry, ok := <-logChan:
+			if !ok {
+				if errChan != nil {
+					streamErr = <-errChan
+				}
+				if streamErr != nil {
+					writeEvent(w, "error", httpjson.ErrorBody{Error: streamErr.Error()})
+					flusher.Flush()
+				}
+				return
+			}
+			writeEvent(w, "log", entry)
+
+		case err, ok := <-errChan:
+			if ok && err != nil {
+				streamErr = err
+			}
+			errChan = nil
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes one Server-Sent Event with a JSON payload
+func writeEvent(w http.ResponseWriter, event string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+// actionResult is the body of an action response
+type actionResult struct {
+	Action string              `json:"action"`
+	Unit   *models.ServiceInfo `json:"unit,omitempty"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// handleAction serves POST /api/v1/units/{name}/{action}?timeout=30s
+func (s *Server) handleAction(w http.ResponseWriter, r *http.Request) {
+	if s.opts.ReadOnly {
+		httpjson.Error(w, http.StatusForbidden, "server is read-only")
+		return
+	}
+	// A browser sends neither cross-site without a CORS preflight, so a
+	// page on another origin cannot submit a form here
+	if r.Header.Get("Authorization") == "" && !isJSON(r) {
+		httpjson.Error(w, http.StatusUnsupportedMediaType, "actions need an Authorization header or Content-Type: application/json")
+		return
+	}
+
+	action, err := systemd.ParseControlAction(r.PathValue("action"))
+	if err != nil {
+		httpjson.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+	unitName, ok := unitFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	timeout := s.opts.ActionTimeout
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		timeout, err = time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			httpjson.Error(w, http.StatusBadRequest, fmt.Sprintf("invalid timeout %q", value))
+			return
+		}
+	}
+
+	// A unit that ends up in the wrong state is reported with its status
+	service, err := s.client.ControlUnit(r.Context(), action, unitName, timeout)
+	result := actionResult{Action: string(action), Unit: service}
+	switch {
+	case err == nil:
+		httpjson.Write(w, http.StatusOK, result)
+	case service != nil:
+		result.Error = err.Error()
+		httpjson.Write(w, http.StatusConflict, result)
+	default:
+		result.Error = err.Error()
+		httpjson.Write(w, http.StatusInternalServerError, result)
+	}
+}
+
+// isJSON reports whether the request body is declared as JSON
+func isJSON(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// unitFromRequest validates the {name} path value and adds the suffix
+// from ?type= (default service)
+func unitFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.PathValue("name")
+	if !validUnitName(name) {
+		httpjson.Error(w, http.StatusBadRequest, fmt.Sprintf("invalid unit name %q", name))
+		return "", false
+	}
+
+	unitType := models.UnitService
+	if value := r.URL.Query().Get("type"); value != "" {
+		parsed, err := models.ParseUnitType(value)
+		if err != nil || parsed == models.UnitAll {
+			httpjson.Error(w, http.StatusBadRequest, fmt.Sprintf("invalid unit type %q", value))
+			return "", false
+		}
+		unitType = parsed
+	}
+
+	return models.NormalizeUnitName(name, unitType), true
+}
+
+// validUnitName accepts the characters systemd allows in unit names and
+// rejects anything that could be taken for a command-line option
+func validUnitName(name string) bool {
+	if name == "" || len(name) > 256 || strings.HasPrefix(name, "-") {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune(":-_.\\@", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// logOptionsFromRequest reads lines, since, until, priority and grep
+func logOptionsFromRequest(w http.ResponseWriter, r *http.Request, defaultLines int) (*models.LogOptions, bool) {
+	query := r.URL.Query()
+
+	opts := models.NewLogOptions()
+	opts.Lines = defaultLines
+	opts.Since = query.Get("since")
+	opts.Until = query.Get("until")
+	opts.Priority = query.Get("priority")
+	opts.Grep = query.Get("grep")
+
+	if value := query.Get("lines"); value != "" {
+		lines, err := strconv.Atoi(value)
+		if err != nil || lines < 0 {
+			httpjson.Error(w, http.StatusBadRequest, fmt.Sprintf("invalid lines %q", value))
+			return nil, false
+		}
+		opts.Lines = lines
+	}
+
+	if opts.Priority != "" && !validPriority(opts.Priority) {
+		httpjson.Error(w, http.StatusBadRequest, fmt.Sprintf("invalid priority %q", opts.Priority))
+		return nil, false
+	}
+
+	return opts, true
+}
+
+// validPriority accepts what journalctl -p takes: a name or number, or a
+// range such as "err..warning"
+func validPriority(value string) bool {
+	for _, part := range strings.SplitN(value, "..", 2) {
+		if _, ok := models.ParsePriority(part); !ok {
+			return false
+		}
+	}
+	return true
+}
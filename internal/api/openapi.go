@@ -0,0 +1,245 @@
+package api
+
+// openAPISpec describes the API; served at /api/v1/openapi.json
+const openAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "systemd-monitoring API",
+    "version": "1.0.0",
+    "description": "Query systemd units and their journal logs, and control units unless the server runs read-only, as it does without a token."
+  },
+  "servers": [{"url": "/"}],
+  "security": [{"bearerAuth": []}],
+  "paths": {
+    "/healthz": {
+      "get": {
+        "summary": "Health check (no authentication)",
+        "security": [],
+        "responses": {
+          "200": {"description": "Server is up", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Health"}}}}
+        }
+      }
+    },
+    "/api/v1/openapi.json": {
+      "get": {
+        "summary": "This document (no authentication)",
+        "security": [],
+        "responses": {"200": {"description": "OpenAPI description"}}
+      }
+    },
+    "/api/v1/units": {
+      "get": {
+        "summary": "List units, like the list command",
+        "parameters": [
+          {"name": "type", "in": "query", "schema": {"type": "string", "default": "service"}, "description": "service, timer, socket, mount, path, target, scope, ... or all"},
+          {"name": "status", "in": "query", "schema": {"type": "string", "enum": ["all", "running", "failed", "stopped"], "default": "all"}}
+        ],
+        "responses": {
+          "200": {"description": "Matching units with counters", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ServiceList"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/v1/units/{name}": {
+      "get": {
+        "summary": "Status of one unit",
+        "parameters": [{"$ref": "#/components/parameters/Name"}, {"$ref": "#/components/parameters/Type"}],
+        "responses": {
+          "200": {"description": "Unit status", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ServiceInfo"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/v1/units/{name}/logs": {
+      "get": {
+        "summary": "Journal entries of a unit",
+        "parameters": [
+          {"$ref": "#/components/parameters/Name"},
+          {"$ref": "#/components/parameters/Type"},
+          {"name": "lines", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 50}, "description": "Most recent entries to return; 0 = all"},
+          {"$ref": "#/components/parameters/Since"},
+          {"name": "until", "in": "query", "schema": {"type": "string"}, "description": "journalctl --until value"},
+          {"$ref": "#/components/parameters/Priority"},
+          {"$ref": "#/components/parameters/Grep"}
+        ],
+        "responses": {
+          "200": {"description": "Entries, oldest first", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/LogEntry"}}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/v1/units/{name}/logs/stream": {
+      "get": {
+        "summary": "Follow journal entries as Server-Sent Events",
+        "description": "Sends the last lines entries, then new ones as they are written. Each entry is an event named log whose data is a LogEntry; a failure ends the stream with an error event whose data is an Error.",
+        "parameters": [
+          {"$ref": "#/components/parameters/Name"},
+          {"$ref": "#/components/parameters/Type"},
+          {"name": "lines", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 10}},
+          {"$ref": "#/components/parameters/Since"},
+          {"$ref": "#/components/parameters/Priority"},
+          {"$ref": "#/components/parameters/Grep"}
+        ],
+        "responses": {
+          "200": {"description": "Event stream", "content": {"text/event-stream": {"schema": {"type": "string"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/api/v1/units/{name}/{action}": {
+      "post": {
+        "summary": "Run a systemctl action and wait for its job",
+        "parameters": [
+          {"$ref": "#/components/parameters/Name"},
+          {"$ref": "#/components/parameters/Type"},
+          {"name": "action", "in": "path", "required": true, "schema": {"type": "string", "enum": ["start", "stop", "restart", "reload", "enable", "disable", "mask", "unmask"]}},
+          {"name": "timeout", "in": "query", "schema": {"type": "string", "default": "30s"}, "description": "How long to wait for the job, as a Go duration"}
+        ],
+        "responses": {
+          "200": {"description": "Action done; resulting status", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ActionResult"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"description": "Server is read-only (always without a token)", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+          "404": {"description": "Unknown action", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+          "409": {"description": "Job finished but the unit is not in the expected state", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ActionResult"}}}},
+          "415": {"description": "Neither an Authorization header nor Content-Type: application/json", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+          "500": {"description": "Action failed", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ActionResult"}}}}
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearerAuth": {"type": "http", "scheme": "bearer", "description": "Required when the server was started with a token"}
+    },
+    "parameters": {
+      "Name": {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}, "description": "Unit name; .service is added unless it has a suffix or type is given"},
+      "Type": {"name": "type", "in": "query", "schema": {"type": "string", "default": "service"}, "description": "Unit type for names without a suffix"},
+      "Since": {"name": "since", "in": "query", "schema": {"type": "string"}, "description": "journalctl --since value, e.g. \"1 hour ago\" or \"today\""},
+      "Priority": {"name": "priority", "in": "query", "schema": {"type": "string"}, "description": "emerg, alert, crit, err, warning, notice, info, debug, 0-7 or a range like err..warning"},
+      "Grep": {"name": "grep", "in": "query", "schema": {"type": "string"}, "description": "Case-insensitive substring the message must contain"}
+    },
+    "responses": {
+      "BadRequest": {"description": "Invalid parameter", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "Unauthorized": {"description": "Missing or invalid bearer token", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "Error": {"description": "systemd could not be queried", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+    },
+    "schemas": {
+      "Error": {
+        "type": "object",
+        "properties": {"error": {"type": "string"}}
+      },
+      "Health": {
+        "type": "object",
+        "properties": {"status": {"type": "string"}, "backend": {"type": "string"}, "read_only": {"type": "boolean"}}
+      },
+      "ServiceInfo": {
+        "type": "object",
+        "properties": {
+          "Name": {"type": "string"},
+          "UnitType": {"type": "string"},
+          "Status": {"type": "string", "enum": ["running", "stopped", "failed", "unknown", "degraded"]},
+          "ActiveState": {"type": "string"},
+          "SubState": {"type": "string"},
+          "UnitFileState": {"type": "string"},
+          "Uptime": {"type": "integer", "description": "Nanoseconds since the unit became active"},
+          "PID": {"type": "integer"},
+          "Restarts": {"type": "integer"},
+          "MemoryUsage": {"type": "string"},
+          "MemoryBytes": {"type": "integer"},
+          "Details": {"type": "object", "additionalProperties": {"type": "string"}, "nullable": true},
+          "CheckedAt": {"type": "string", "format": "date-time"},
+          "ControlGroup": {"type": "string"},
+          "CPUUsage": {"type": "integer", "description": "CPU time consumed in nanoseconds"},
+          "CPUPercent": {"type": "number", "description": "CPU use since the previous sample; 100 = one core"},
+          "CPUSampled": {"type": "boolean", "description": "Whether CPUPercent is known"},
+          "MemoryPeak": {"type": "integer"},
+          "SwapBytes": {"type": "integer"},
+          "IOReadBytes": {"type": "integer"},
+          "IOWriteBytes": {"type": "integer"},
+          "Tasks": {"type": "integer"},
+          "Pressure": {"$ref": "#/components/schemas/Pressure"},
+          "Probes": {"type": "array", "items": {"$ref": "#/components/schemas/ProbeResult"}, "nullable": true}
+        }
+      },
+      "ProbeResult": {
+        "type": "object",
+        "description": "Outcome of a health probe run by the monitor",
+        "properties": {
+          "Name": {"type": "string"},
+          "Healthy": {"type": "boolean"},
+          "Latency": {"type": "integer", "description": "Nanoseconds the probe took"},
+          "Error": {"type": "string"},
+          "LastError": {"type": "string"},
+          "LastErrorAt": {"type": "string", "format": "date-time"}
+        }
+      },
+      "Pressure": {
+        "type": "object",
+        "nullable": true,
+        "description": "Pressure stall information of the unit's cgroup in percent",
+        "properties": {
+          "CPU": {"$ref": "#/components/schemas/PressureStat"},
+          "Memory": {"$ref": "#/components/schemas/PressureStat"},
+          "IO": {"$ref": "#/components/schemas/PressureStat"}
+        }
+      },
+      "PressureStat": {
+        "type": "object",
+        "properties": {
+          "SomeAvg10": {"type": "number"},
+          "SomeAvg60": {"type": "number"},
+          "SomeAvg300": {"type": "number"},
+          "FullAvg10": {"type": "number"},
+          "FullAvg60": {"type": "number"},
+          "FullAvg300": {"type": "number"}
+        }
+      },
+      "ServiceList": {
+        "type": "object",
+        "properties": {
+          "Services": {"type": "array", "items": {"$ref": "#/components/schemas/ServiceInfo"}},
+          "Timestamp": {"type": "string", "format": "date-time"},
+          "Total": {"type": "integer"},
+          "Running": {"type": "integer"},
+          "Failed": {"type": "integer"},
+          "Stopped": {"type": "integer"}
+        }
+      },
+      "LogEntry": {
+        "type": "object",
+        "properties": {
+          "timestamp": {"type": "string", "format": "date-time"},
+          "service_name": {"type": "string"},
+          "message": {"type": "string"},
+          "level": {"type": "string"},
+          "priority": {"type": "integer"},
+          "message_level": {"type": "string"},
+          "pid": {"type": "integer"},
+          "hostname": {"type": "string"},
+          "identifier": {"type": "string"},
+          "boot_id": {"type": "string"},
+          "cursor": {"type": "string"},
+          "fields": {"type": "object", "additionalProperties": {"type": "string"}}
+        }
+      },
+      "ActionResult": {
+        "type": "object",
+        "properties": {
+          "action": {"type": "string"},
+          "unit": {"$ref": "#/components/schemas/ServiceInfo"},
+          "error": {"type": "string"}
+        }
+      }
+    }
+  }
+}
+`
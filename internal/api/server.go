@@ -0,0 +1,78 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/httpjson"
+	"github.com/andinianst93/systemd-monitoring/internal/systemd"
+)
+
+// Options configures the API server
+type Options struct {
+	Token         string        // bearer token required on /api; "" = no auth
+	ReadOnly      bool          // refuse start/stop/... actions; implied without a Token
+	ActionTimeout time.Duration // default wait for action jobs
+}
+
+// Server exposes a systemd client over HTTP
+type Server struct {
+	client *systemd.Client
+	opts   Options
+	mux    *http.ServeMux
+}
+
+// NewServer creates an API server for client. Without a token it is
+// read-only: anything that can reach it, a web page included, could
+// otherwise stop units.
+func NewServer(client *systemd.Client, opts Options) *Server {
+	if opts.ActionTimeout <= 0 {
+		opts.ActionTimeout = 30 * time.Second
+	}
+	if opts.Token == "" {
+		opts.ReadOnly = true
+	}
+
+	s := &Server{client: client, opts: opts, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.HandleFunc("GET /api/v1/openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("GET /api/v1/units", s.handleListUnits)
+	s.mux.HandleFunc("GET /api/v1/units/{name}", s.handleGetUnit)
+	s.mux.HandleFunc("GET /api/v1/units/{name}/logs", s.handleLogs)
+	s.mux.HandleFunc("GET /api/v1/units/{name}/logs/stream", s.handleLogStream)
+	s.mux.HandleFunc("POST /api/v1/units/{name}/{action}", s.handleAction)
+
+	return s
+}
+
+// ServeHTTP authenticates API requests and dispatches them
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The health check and the API description stay public so load
+	// balancers and tooling work without the token
+	public := r.URL.Path == "/healthz" || r.URL.Path == "/api/v1/openapi.json"
+
+	if s.opts.Token != "" && !public && !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="systemd-monitoring"`)
+		httpjson.Error(w, http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized checks the Authorization header in constant time
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) == 1
+}
+
+// ReadOnly reports whether the server refuses actions
+func (s *Server) ReadOnly() bool {
+	return s.opts.ReadOnly
+}
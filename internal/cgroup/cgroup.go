@@ -0,0 +1,215 @@
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// DefaultRoot is where the cgroup v2 hierarchy is mounted
+const DefaultRoot = "/sys/fs/cgroup"
+
+// Reader reads unit resource usage from the cgroup v2 interface files
+// below Root. Pointing Root at a copy of the tree makes the parsing
+// testable without a live system.
+type Reader struct {
+	Root string
+}
+
+// NewReader creates a reader for the hierarchy mounted at root
+func NewReader(root string) *Reader {
+	if root == "" {
+		root = DefaultRoot
+	}
+	return &Reader{Root: root}
+}
+
+// Stats is one reading of a cgroup. Values of controllers that are not
+// enabled for the cgroup stay zero.
+type Stats struct {
+	CPUUsage      time.Duration // cpu.stat usage_usec
+	MemoryCurrent int64         // memory.current
+	MemoryPeak    int64         // memory.peak (kernel 5.19+)
+	SwapCurrent   int64         // memory.swap.current
+	IOReadBytes   int64         // io.stat rbytes, summed over devices
+	IOWriteBytes  int64         // io.stat wbytes, summed over devices
+	Tasks         int           // pids.current
+	Pressure      *models.Pressure
+}
+
+// Available reports whether Root holds a cgroup v2 hierarchy
+func (r *Reader) Available() bool {
+	_, err := os.Stat(filepath.Join(r.Root, "cgroup.controllers"))
+	return err == nil
+}
+
+// Read reads the stats of a cgroup given by its path relative to the
+// hierarchy, e.g. "/system.slice/nginx.service" (systemd's ControlGroup)
+func (r *Reader) Read(cgroupPath string) (*Stats, error) {
+	// 1. Resolve the directory; the path is cleaned so it cannot leave Root
+	if cgroupPath == "" {
+		return nil, errors.New("empty cgroup path")
+	}
+	dir := filepath.Join(r.Root, filepath.FromSlash(path.Clean("/"+cgroupPath)))
+	if info, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to read cgroup %s: %w", cgroupPath, err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("failed to read cgroup %s: not a directory", cgroupPath)
+	}
+
+	stats := &Stats{}
+	read := func(name string) (string, bool) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "", false
+		}
+		return string(data), true
+	}
+
+	// 2. CPU
+	if data, ok := read("cpu.stat"); ok {
+		if usec, ok := parseFlatKeyed(data)["usage_usec"]; ok {
+			stats.CPUUsage = time.Duration(usec) * time.Microsecond
+		}
+	}
+
+	// 3. Memory
+	if data, ok := read("memory.current"); ok {
+		stats.MemoryCurrent = parseSingleValue(data)
+	}
+	if data, ok := read("memory.peak"); ok {
+		stats.MemoryPeak = parseSingleValue(data)
+	}
+	if data, ok := read("memory.swap.current"); ok {
+		stats.SwapCurrent = parseSingleValue(data)
+	}
+
+	// 4. IO and tasks
+	if data, ok := read("io.stat"); ok {
+		stats.IOReadBytes, stats.IOWriteBytes = parseIOStat(data)
+	}
+	if data, ok := read("pids.current"); ok {
+		stats.Tasks = int(parseSingleValue(data))
+	}
+
+	// 5. Pressure; only reported when the kernel has PSI enabled
+	cpu, cpuOK := read("cpu.pressure")
+	memory, memoryOK := read("memory.pressure")
+	io, ioOK := read("io.pressure")
+	if cpuOK || memoryOK || ioOK {
+		stats.Pressure = &models.Pressure{
+			CPU:    parsePressure(cpu),
+			Memory: parsePressure(memory),
+			IO:     parsePressure(io),
+		}
+	}
+
+	return stats, nil
+}
+
+// parseSingleValue parses a file holding one number; "max" and invalid
+// content read as 0
+func parseSingleValue(data string) int64 {
+	value, err := strconv.ParseInt(strings.TrimSpace(data), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// parseFlatKeyed parses "key value" lines such as cpu.stat
+func parseFlatKeyed(data string) map[string]int64 {
+	values := make(map[string]int64)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values
+}
+
+// parseIOStat sums the read and written bytes of all devices in io.stat:
+// "8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0"
+func parseIOStat(data string) (int64, int64) {
+	var read, written int64
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rbytes":
+				read += n
+			case "wbytes":
+				written += n
+			}
+		}
+	}
+	return read, written
+}
+
+// parsePressure parses a PSI file:
+//
+//	some avg10=0.00 avg60=0.12 avg300=0.05 total=123456
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func parsePressure(data string) models.PressureStat {
+	var stat models.PressureStat
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var avg10, avg60, avg300 float64
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "avg10":
+				avg10 = n
+			case "avg60":
+				avg60 = n
+			case "avg300":
+				avg300 = n
+			}
+		}
+
+		switch fields[0] {
+		case "some":
+			stat.SomeAvg10, stat.SomeAvg60, stat.SomeAvg300 = avg10, avg60, avg300
+		case "full":
+			stat.FullAvg10, stat.FullAvg60, stat.FullAvg300 = avg10, avg60, avg300
+		}
+	}
+	return stat
+}
@@ -0,0 +1,174 @@
+package cgroup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// writeTree creates files below root; keys are slash-separated paths
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"cgroup.controllers": "cpuset cpu io memory hugetlb pids rdma misc\n",
+
+		// Every controller enabled
+		"system.slice/nginx.service/cpu.stat": "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\n" +
+			"nr_periods 0\nnr_throttled 0\nthrottled_usec 0\n",
+		"system.slice/nginx.service/memory.current":      "15728640\n",
+		"system.slice/nginx.service/memory.peak":         "20971520\n",
+		"system.slice/nginx.service/memory.swap.current": "4096\n",
+		"system.slice/nginx.service/io.stat": "8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0\n" +
+			"253:0 rbytes=800 wbytes=200 rios=1 wios=1 dbytes=0 dios=0\n",
+		"system.slice/nginx.service/pids.current":    "3\n",
+		"system.slice/nginx.service/cpu.pressure":    "some avg10=1.50 avg60=0.12 avg300=0.05 total=123456\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		"system.slice/nginx.service/memory.pressure": "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nfull avg10=0.25 avg60=0.10 avg300=0.01 total=42\n",
+
+		// Only the CPU controller, no PSI; swap accounting disabled
+		"system.slice/cron.service/cpu.stat":            "usage_usec 1000\n",
+		"system.slice/cron.service/memory.swap.current": "max\n",
+
+		// Empty directory: no controller files at all
+		"system.slice/idle.service/.keep": "",
+	})
+	r := NewReader(root)
+
+	if !r.Available() {
+		t.Fatal("Available() = false for a v2 tree")
+	}
+
+	tests := []struct {
+		path string
+		want Stats
+	}{
+		{
+			path: "/system.slice/nginx.service",
+			want: Stats{
+				CPUUsage:      2500 * time.Millisecond,
+				MemoryCurrent: 15728640,
+				MemoryPeak:    20971520,
+				SwapCurrent:   4096,
+				IOReadBytes:   1460000,
+				IOWriteBytes:  314773704,
+				Tasks:         3,
+				Pressure: &models.Pressure{
+					CPU:    models.PressureStat{SomeAvg10: 1.5, SomeAvg60: 0.12, SomeAvg300: 0.05},
+					Memory: models.PressureStat{FullAvg10: 0.25, FullAvg60: 0.1, FullAvg300: 0.01},
+				},
+			},
+		},
+		{
+			// Paths without the leading slash resolve the same way
+			path: "system.slice/cron.service",
+			want: Stats{CPUUsage: time.Millisecond},
+		},
+		{
+			path: "/system.slice/idle.service",
+			want: Stats{},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := r.Read(tt.path)
+		if err != nil {
+			t.Errorf("Read(%q): %v", tt.path, err)
+			continue
+		}
+		if !statsEqual(got, &tt.want) {
+			t.Errorf("Read(%q) = %+v (pressure %+v), want %+v (pressure %+v)",
+				tt.path, *got, got.Pressure, tt.want, tt.want.Pressure)
+		}
+	}
+}
+
+func statsEqual(a, b *Stats) bool {
+	if (a.Pressure == nil) != (b.Pressure == nil) {
+		return false
+	}
+	if a.Pressure != nil && *a.Pressure != *b.Pressure {
+		return false
+	}
+	x, y := *a, *b
+	x.Pressure, y.Pressure = nil, nil
+	return x == y
+}
+
+func TestReadErrors(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "cgroup")
+	writeTree(t, base, map[string]string{
+		"cgroup/cgroup.controllers":                  "cpu memory\n",
+		"cgroup/system.slice/nginx.service/cpu.stat": "usage_usec 1\n",
+		"cgroup/system.slice/not-a-dir.service":      "",
+		"secret/cpu.stat":                            "usage_usec 99\n",
+	})
+	r := NewReader(root)
+
+	tests := []string{
+		"",
+		"/system.slice/missing.service",
+		"/system.slice/not-a-dir.service",
+		// Paths cannot leave the root
+		"/../secret",
+		"../secret",
+	}
+	for _, path := range tests {
+		if stats, err := r.Read(path); err == nil {
+			t.Errorf("Read(%q) = %+v, want an error", path, stats)
+		}
+	}
+}
+
+func TestReadCgroupV1Unsupported(t *testing.T) {
+	// Only the unified (v2) hierarchy is read. cgroup v1 keeps one
+	// hierarchy per controller and has no cgroup.controllers at the root
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"cpuacct/system.slice/nginx.service/cpuacct.usage":        "2500000000\n",
+		"memory/system.slice/nginx.service/memory.usage_in_bytes": "15728640\n",
+		"systemd/system.slice/nginx.service/cgroup.procs":         "1397\n",
+	})
+	r := NewReader(root)
+
+	if r.Available() {
+		t.Error("Available() = true for a v1 tree")
+	}
+	// systemd's ControlGroup does not exist below a v1 root, so callers
+	// keep the values of the unit properties
+	if stats, err := r.Read("/system.slice/nginx.service"); err == nil {
+		t.Errorf("Read on a v1 tree = %+v, want an error", stats)
+	}
+}
+
+func TestParseSingleValue(t *testing.T) {
+	tests := []struct {
+		data string
+		want int64
+	}{
+		{data: "4096\n", want: 4096},
+		{data: "  12 ", want: 12},
+		{data: "max\n", want: 0},
+		{data: "", want: 0},
+	}
+	for _, tt := range tests {
+		if got := parseSingleValue(tt.data); got != tt.want {
+			t.Errorf("parseSingleValue(%q) = %d, want %d", tt.data, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,1077 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/history"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+	"github.com/andinianst93/systemd-monitoring/internal/notify"
+	"github.com/andinianst93/systemd-monitoring/internal/pool"
+	"github.com/andinianst93/systemd-monitoring/internal/probe"
+	"github.com/andinianst93/systemd-monitoring/internal/remediate"
+	"github.com/andinianst93/systemd-monitoring/internal/rules"
+)
+
+// Config is the declarative configuration of the monitor command
+type Config struct {
+	File     string // path the config was loaded from
+	Interval time.Duration
+	Backend  string
+	Sudo     bool
+	Type     models.UnitType // default type for names without a suffix
+	// Workers bounds how many status checks run at once
+	Workers int
+	// CheckTimeout cancels a single status check taking longer; 0 = none
+	CheckTimeout time.Duration
+	Logging      LoggingConfig
+	History      HistoryConfig
+	Alerts       AlertsConfig
+	Metrics      MetricsConfig
+	// Remediation is the policy services with "remediation: true" use, and
+	// the base that per-service remediation blocks override
+	Remediation remediate.Policy
+	// Rules apply to every watched unit matching their glob
+	Rules    []*rules.Rule
+	Services []ServiceConfig
+}
+
+// LoggingConfig selects where monitor events are written
+type LoggingConfig struct {
+	File        string
+	Journal     bool
+	ChangesOnly bool
+}
+
+// HistoryConfig sets where samples and transitions are stored and for how
+// long
+type HistoryConfig struct {
+	File      string // "" = no history
+	Retention history.Retention
+}
+
+// MetricsConfig enables the Prometheus /metrics endpoint
+type MetricsConfig struct {
+	Addr    string // listen address, e.g. ":9558"; "" = disabled
+	Include []string
+	Exclude []string
+}
+
+// AlertsConfig declares alert routes and delivery settings
+type AlertsConfig struct {
+	Retries       int
+	Repeat        time.Duration
+	DefaultRoutes []string // routes for services without their own; empty = all routes
+	Routes        []RouteConfig
+}
+
+// RouteConfig is one named alert destination
+type RouteConfig struct {
+	Name string
+	Type string // webhook, smtp or command
+	Line int
+
+	// webhook
+	URL     string
+	Headers map[string]string
+
+	// smtp
+	Addr        string
+	From        string
+	To          []string
+	Username    string
+	PasswordEnv string // environment variable holding the SMTP password
+
+	// command
+	Command string
+}
+
+// ServiceConfig is one watched unit, or every unit matching a glob pattern
+type ServiceConfig struct {
+	Name       string
+	Pattern    string
+	Type       models.UnitType      // overrides Config.Type for this entry
+	Interval   time.Duration        // 0 = Config.Interval
+	Expected   models.ServiceStatus // "" = only alert when failed
+	Alerts     []string             // route names; nil = default routes
+	Thresholds Thresholds
+	Rules      []*rules.Rule  // resource rules for this entry's units
+	Probes     []*probe.Probe // health probes; failing one marks the unit degraded
+	// Remediation repairs the unit when it fails; nil = only watch
+	Remediation *remediate.Policy
+	Line        int
+}
+
+// Thresholds raise an alert while a unit is outside the given limits
+type Thresholds struct {
+	MaxRestarts int           // NRestarts above this (0 = no limit)
+	MinUptime   time.Duration // running for less than this means flapping (0 = off)
+}
+
+// Default returns the configuration used when no file is given; it
+// matches the monitor command's flag defaults
+func Default() *Config {
+	return &Config{
+		Interval:     30 * time.Second,
+		Backend:      "auto",
+		Type:         models.UnitService,
+		Workers:      pool.DefaultWorkers,
+		CheckTimeout: 10 * time.Second,
+		Logging: LoggingConfig{
+			File: "logs/monitor.log",
+		},
+		History: HistoryConfig{
+			File:      "logs/history.jsonl",
+			Retention: history.DefaultRetention(),
+		},
+		Alerts: AlertsConfig{
+			Retries: 3,
+		},
+		Remediation: remediate.DefaultPolicy(),
+	}
+}
+
+// Load reads and validates a config file. Files ending in .toml are parsed
+// as TOML, everything else as YAML.
+func Load(filePath string) (*Config, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return Parse(filePath, string(data))
+}
+
+// Parse parses and validates config data; filePath is used for the format
+// and in error messages. Validation problems are returned as Errors.
+func Parse(filePath, data string) (*Config, error) {
+	root, err := parseFile(filePath, data)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{file: filePath}
+	cfg := d.decodeConfig(root)
+	cfg.File = filePath
+
+	if len(d.errs) > 0 {
+		sort.SliceStable(d.errs, func(i, j int) bool { return d.errs[i].Line < d.errs[j].Line })
+		return nil, d.errs
+	}
+	return cfg, nil
+}
+
+// parseFile parses data as TOML when filePath ends in .toml and as YAML
+// otherwise; syntax errors are returned as Errors
+func parseFile(filePath, data string) (*Node, error) {
+	var root *Node
+	var err error
+	if strings.EqualFold(filepath.Ext(filePath), ".toml") {
+		root, err = ParseTOML(data)
+	} else {
+		root, err = ParseYAML(data)
+	}
+	if err != nil {
+		if syntaxErr, ok := err.(*SyntaxError); ok {
+			return nil, Errors{{File: filePath, Line: syntaxErr.Line, Msg: syntaxErr.Msg}}
+		}
+		return nil, err
+	}
+	return root, nil
+}
+
+// Error is a validation problem at a line of the config file
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Errors collects every problem found in a config file
+type Errors []*Error
+
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Notifier creates the notifier for a route
+func (r *RouteConfig) Notifier() notify.Notifier {
+	switch r.Type {
+	case "webhook":
+		return notify.NewWebhookNotifier(r.URL, r.Headers)
+	case "smtp":
+		password := ""
+		if r.PasswordEnv != "" {
+			password = os.Getenv(r.PasswordEnv)
+		}
+		return notify.NewSMTPNotifier(r.Addr, r.From, r.To, r.Username, password)
+	default:
+		return notify.NewCommandNotifier(r.Command)
+	}
+}
+
+// UnitName returns the normalized unit name of a name entry
+func (s *ServiceConfig) UnitName(defaultType models.UnitType) string {
+	return models.NormalizeUnitName(s.Name, s.unitType(defaultType))
+}
+
+// UnitPattern returns the glob of a pattern entry with the unit suffix
+// added when missing, e.g. "docker-*" -> "docker-*.service"
+func (s *ServiceConfig) UnitPattern(defaultType models.UnitType) string {
+	return models.NormalizeUnitName(s.Pattern, s.unitType(defaultType))
+}
+
+// UnitType returns the unit type a pattern entry lists
+func (s *ServiceConfig) UnitType(defaultType models.UnitType) models.UnitType {
+	if t := models.UnitTypeOf(s.UnitPattern(defaultType)); t != "" {
+		return t
+	}
+	return s.unitType(defaultType)
+}
+
+func (s *ServiceConfig) unitType(defaultType models.UnitType) models.UnitType {
+	if s.Type != "" {
+		return s.Type
+	}
+	return defaultType
+}
+
+// decoder converts the node tree into a Config, collecting errors
+type decoder struct {
+	file string
+	errs Errors
+}
+
+func (d *decoder) errorf(line int, format string, args ...interface{}) {
+	d.errs = append(d.errs, &Error{File: d.file, Line: line, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (d *decoder) decodeConfig(root *Node) *Config {
+	cfg := Default()
+
+	if !d.expect(root, MappingNode, "the config file") {
+		return cfg
+	}
+
+	// 1. Top-level settings
+	var servicesNode, rulesNode *Node
+	for _, pair := range root.Pairs {
+		switch pair.Key {
+		case "interval":
+			cfg.Interval = d.positiveDuration(pair)
+		case "backend":
+			cfg.Backend = d.str(pair.Value)
+			switch cfg.Backend {
+			case "auto", "dbus", "exec":
+			default:
+				d.errorf(pair.Value.Line, "backend must be auto, dbus or exec, got %q", cfg.Backend)
+			}
+		case "sudo":
+			cfg.Sudo = d.boolean(pair.Value)
+		case "type":
+			cfg.Type = d.unitType(pair.Value)
+		case "workers":
+			cfg.Workers = d.integer(pair.Value)
+			if cfg.Workers < 1 {
+				d.errorf(pair.Value.Line, "workers must be at least 1")
+			}
+		case "check_timeout":
+			cfg.CheckTimeout = d.duration(pair.Value)
+		case "logging":
+			d.decodeLogging(pair.Value, &cfg.Logging)
+		case "history":
+			d.decodeHistory(pair.Value, &cfg.History)
+		case "alerts":
+			d.decodeAlerts(pair.Value, &cfg.Alerts)
+		case "metrics":
+			d.decodeMetrics(pair.Value, &cfg.Metrics)
+		case "remediation":
+			d.decodeRemediation(pair.Value, &cfg.Remediation)
+		case "rules":
+			rulesNode = pair.Value
+		case "services":
+			servicesNode = pair.Value
+		default:
+			d.unknownKey(pair, "top level")
+		}
+	}
+
+	// 2. Rules, after the unit type their globs default to
+	if rulesNode != nil {
+		cfg.Rules = d.decodeRules(rulesNode, true, cfg.Type)
+	}
+
+	// 3. Services, after alerts so route references can be checked and
+	// after remediation so services inherit its policy
+	if servicesNode == nil {
+		d.errorf(root.Line, "no services configured")
+		return cfg
+	}
+	d.decodeServices(servicesNode, cfg)
+
+	return cfg
+}
+
+func (d *decoder) decodeLogging(node *Node, logging *LoggingConfig) {
+	if !d.expect(node, MappingNode, "logging") {
+		return
+	}
+
+	for _, pair := range node.Pairs {
+		switch pair.Key {
+		case "file":
+			logging.File = d.str(pair.Value)
+			if logging.File == "" {
+				d.errorf(pair.Value.Line, "logging.file must not be empty")
+			}
+		case "journal":
+			logging.Journal = d.boolean(pair.Value)
+		case "changes_only":
+			logging.ChangesOnly = d.boolean(pair.Value)
+		default:
+			d.unknownKey(pair, "logging")
+		}
+	}
+}
+
+func (d *decoder) decodeHistory(node *Node, hist *HistoryConfig) {
+	if !d.expect(node, MappingNode, "history") {
+		return
+	}
+
+	for _, pair := range node.Pairs {
+		switch pair.Key {
+		case "file":
+			hist.File = d.str(pair.Value) // "" disables the history
+		case "retention":
+			hist.Retention.MaxAge = d.duration(pair.Value)
+		case "raw":
+			hist.Retention.Raw = d.duration(pair.Value)
+		case "resolution":
+			hist.Retention.Resolution = d.duration(pair.Value)
+		default:
+			d.unknownKey(pair, "history")
+		}
+	}
+
+	if r := hist.Retention; r.MaxAge > 0 && r.Raw > r.MaxAge {
+		d.errorf(node.Line, "history.raw (%s) must not exceed history.retention (%s)", r.Raw, r.MaxAge)
+	}
+}
+
+func (d *decoder) decodeMetrics(node *Node, metrics *MetricsConfig) {
+	if !d.expect(node, MappingNode, "metrics") {
+		return
+	}
+
+	for _, pair := range node.Pairs {
+		switch pair.Key {
+		case "addr":
+			metrics.Addr = d.str(pair.Value)
+			if _, _, err := net.SplitHostPort(metrics.Addr); err != nil {
+				d.errorf(pair.Value.Line, "metrics.addr must be [host]:port, got %q", metrics.Addr)
+			}
+		case "include":
+			metrics.Include = d.patterns(pair.Value)
+		case "exclude":
+			metrics.Exclude = d.patterns(pair.Value)
+		default:
+			d.unknownKey(pair, "metrics")
+		}
+	}
+}
+
+func (d *decoder) decodeAlerts(node *Node, alerts *AlertsConfig) {
+	if !d.expect(node, MappingNode, "alerts") {
+		return
+	}
+
+	var defaultRoutes *Pair
+	for _, pair := range node.Pairs {
+		switch pair.Key {
+		case "retries":
+			alerts.Retries = d.integer(pair.Value)
+			if alerts.Retries < 1 {
+				d.errorf(pair.Value.Line, "alerts.retries must be at least 1")
+			}
+		case "repeat":
+			alerts.Repeat = d.duration(pair.Value)
+		case "default_routes":
+			defaultRoutes = pair
+		case "routes":
+			if !d.expect(pair.Value, SequenceNode, "alerts.routes") {
+				continue
+			}
+			seen := make(map[string]int)
+			for _, item := range pair.Value.Items {
+				route, ok := d.decodeRoute(item)
+				if !ok {
+					continue
+				}
+				if prev, dup := seen[route.Name]; dup {
+					d.errorf(route.Line, "duplicate route %q (first defined on line %d)", route.Name, prev)
+					continue
+				}
+				seen[route.Name] = route.Line
+				alerts.Routes = append(alerts.Routes, route)
+			}
+		default:
+			d.unknownKey(pair, "alerts")
+		}
+	}
+
+	if defaultRoutes != nil {
+		alerts.DefaultRoutes = d.routeRefs(defaultRoutes.Value, alerts.Routes)
+	}
+}
+
+func (d *decoder) decodeRoute(node *Node) (RouteConfig, bool) {
+	route := RouteConfig{Line: node.Line}
+	if !d.expect(node, MappingNode, "an alert route") {
+		return route, false
+	}
+
+	// Fields that only make sense for one route type
+	typeOnly := make(map[string]*Pair)
+
+	for _, pair := range node.Pairs {
+		switch pair.Key {
+		case "name":
+			route.Name = d.str(pair.Value)
+		case "type":
+			route.Type = d.str(pair.Value)
+		case "url":
+			route.URL = d.str(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "headers":
+			route.Headers = d.stringMap(pair.Value, "headers")
+			typeOnly[pair.Key] = pair
+		case "addr":
+			route.Addr = d.str(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "from":
+			route.From = d.str(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "to":
+			route.To = d.stringList(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "username":
+			route.Username = d.str(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "password_env":
+			route.PasswordEnv = d.str(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "command":
+			route.Command = d.str(pair.Value)
+			typeOnly[pair.Key] = pair
+		default:
+			d.unknownKey(pair, "alert route")
+		}
+	}
+
+	if route.Name == "" {
+		d.errorf(node.Line, "alert route needs a name")
+		return route, false
+	}
+
+	allowed := map[string][]string{
+		"webhook": {"url", "headers"},
+		"smtp":    {"addr", "from", "to", "username", "password_env"},
+		"command": {"command"},
+	}
+	fields, ok := allowed[route.Type]
+	if !ok {
+		d.errorf(node.Line, "route %q: type must be webhook, smtp or command, got %q", route.Name, route.Type)
+		return route, false
+	}
+	for key, pair := range typeOnly {
+		if !contains(fields, key) {
+			d.errorf(pair.Line, "route %q: %s is not valid for %s routes", route.Name, key, route.Type)
+		}
+	}
+
+	switch route.Type {
+	case "webhook":
+		if u, err := url.Parse(route.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			d.errorf(node.Line, "route %q: url must be an http(s) URL", route.Name)
+		}
+	case "smtp":
+		if _, _, err := net.SplitHostPort(route.Addr); err != nil {
+			d.errorf(node.Line, "route %q: addr must be host:port", route.Name)
+		}
+		if len(route.To) == 0 {
+			d.errorf(node.Line, "route %q: at least one recipient (to) is required", route.Name)
+		}
+		if route.From == "" {
+			route.From = "monitor@localhost"
+		}
+	case "command":
+		if strings.TrimSpace(route.Command) == "" {
+			d.errorf(node.Line, "route %q: command is required", route.Name)
+		}
+	}
+
+	return route, true
+}
+
+func (d *decoder) decodeServices(node *Node, cfg *Config) {
+	if !d.expect(node, SequenceNode, "services") {
+		return
+	}
+	if len(node.Items) == 0 {
+		d.errorf(node.Line, "no services configured")
+		return
+	}
+
+	seen := make(map[string]int)
+	for _, item := range node.Items {
+		// A bare string is shorthand for {name: ...}
+		if item.Kind == ScalarNode {
+			svc := ServiceConfig{Name: d.str(item), Line: item.Line}
+			if svc.Name == "" {
+				d.errorf(item.Line, "service name must not be empty")
+				continue
+			}
+			if d.checkDuplicate(seen, svc.UnitName(cfg.Type), item.Line) {
+				cfg.Services = append(cfg.Services, svc)
+			}
+			continue
+		}
+
+		svc, ok := d.decodeService(item, cfg)
+		if !ok {
+			continue
+		}
+
+		key := "pattern " + svc.UnitPattern(cfg.Type)
+		if svc.Name != "" {
+			key = svc.UnitName(cfg.Type)
+		}
+		if d.checkDuplicate(seen, key, item.Line) {
+			cfg.Services = append(cfg.Services, svc)
+		}
+	}
+}
+
+func (d *decoder) decodeService(node *Node, cfg *Config) (ServiceConfig, bool) {
+	svc := ServiceConfig{Line: node.Line}
+	if !d.expect(node, MappingNode, "a service entry") {
+		return svc, false
+	}
+
+	for _, pair := range node.Pairs {
+		switch pair.Key {
+		case "name":
+			svc.Name = d.str(pair.Value)
+		case "pattern":
+			svc.Pattern = d.str(pair.Value)
+			if _, err := path.Match(svc.Pattern, ""); err != nil {
+				d.errorf(pair.Value.Line, "invalid pattern %q: %v", svc.Pattern, err)
+			}
+		case "type":
+			svc.Type = d.unitType(pair.Value)
+		case "interval":
+			svc.Interval = d.positiveDuration(pair)
+		case "expected":
+			switch value := d.str(pair.Value); value {
+			case string(models.StatusRunning), string(models.StatusStopped):
+				svc.Expected = models.ServiceStatus(value)
+			default:
+				d.errorf(pair.Value.Line, "expected must be running or stopped, got %q", value)
+			}
+		case "alerts":
+			svc.Alerts = d.routeRefs(pair.Value, cfg.Alerts.Routes)
+			if svc.Alerts == nil {
+				svc.Alerts = []string{} // explicitly no alerts
+			}
+		case "thresholds":
+			d.decodeThresholds(pair.Value, &svc.Thresholds)
+		case "rules":
+			svc.Rules = d.decodeRules(pair.Value, false, cfg.Type)
+		case "probes":
+			if !d.expect(pair.Value, SequenceNode, "probes") {
+				continue
+			}
+			for _, item := range pair.Value.Items {
+				if p, ok := d.decodeProbe(item); ok {
+					svc.Probes = append(svc.Probes, p)
+				}
+			}
+		case "remediation":
+			svc.Remediation = d.serviceRemediation(pair.Value, cfg.Remediation)
+		default:
+			d.unknownKey(pair, "service")
+		}
+	}
+
+	switch {
+	case svc.Name == "" && svc.Pattern == "":
+		d.errorf(node.Line, "service entry needs a name or a pattern")
+		return svc, false
+	case svc.Name != "" && svc.Pattern != "":
+		d.errorf(node.Line, "service entry has both name and pattern; use one")
+		return svc, false
+	}
+
+	return svc, true
+}
+
+func (d *decoder) decodeThresholds(node *Node, thresholds *Thresholds) {
+	if !d.expect(node, MappingNode, "thresholds") {
+		return
+	}
+
+	for _, pair := range node.Pairs {
+		switch pair.Key {
+		case "max_restarts":
+			thresholds.MaxRestarts = d.integer(pair.Value)
+			if thresholds.MaxRestarts < 0 {
+				d.errorf(pair.Value.Line, "max_restarts must not be negative")
+			}
+		case "min_uptime":
+			thresholds.MinUptime = d.duration(pair.Value)
+		default:
+			d.unknownKey(pair, "thresholds")
+		}
+	}
+}
+
+func (d *decoder) decodeProbe(node *Node) (*probe.Probe, bool) {
+	p := &probe.Probe{}
+	if !d.expect(node, MappingNode, "a probe") {
+		return p, false
+	}
+
+	// Fields that only make sense for one probe type
+	typeOnly := make(map[string]*Pair)
+
+	for _, pair := range node.Pairs {
+		switch pair.Key {
+		case "name":
+			p.Name = d.str(pair.Value)
+		case "type":
+			probeType, err := probe.ParseType(d.str(pair.Value))
+			if err != nil {
+				if pair.Value.Kind == ScalarNode {
+					d.errorf(pair.Value.Line, "%v", err)
+				}
+				return p, false
+			}
+			p.Type = probeType
+		case "timeout":
+			p.Timeout = d.positiveDuration(pair)
+		case "url":
+			p.URL = d.str(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "expect_status":
+			p.ExpectStatus = d.integer(pair.Value)
+			if p.ExpectStatus < 100 || p.ExpectStatus > 599 {
+				d.errorf(pair.Value.Line, "expect_status must be an HTTP status code, got %d", p.ExpectStatus)
+			}
+			typeOnly[pair.Key] = pair
+		case "expect_body":
+			p.ExpectBody = d.str(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "insecure":
+			p.Insecure = d.boolean(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "address":
+			p.Address = d.str(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "path":
+			p.Path = d.str(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "command":
+			p.Command = d.str(pair.Value)
+			typeOnly[pair.Key] = pair
+		case "expect_exit":
+			p.ExpectExit = d.integer(pair.Value)
+			typeOnly[pair.Key] = pair
+		default:
+			d.unknownKey(pair, "probe")
+		}
+	}
+
+	allowed := map[probe.Type][]string{
+		probe.TypeHTTP: {"url", "expect_status", "expect_body", "insecure"},
+		probe.TypeTCP:  {"address"},
+		probe.TypeUnix: {"path"},
+		probe.TypeExec: {"command", "expect_exit"},
+	}
+	fields, ok := allowed[p.Type]
+	if !ok {
+		d.errorf(node.Line, "probe needs a type (http, tcp, unix or exec)")
+		return p, false
+	}
+	for key, pair := range typeOnly {
+		if !contains(fields, key) {
+			d.errorf(pair.Line, "%s is not valid for %s probes", key, p.Type)
+		}
+	}
+
+	switch p.Type {
+	case probe.TypeHTTP:
+		if u, err := url.Parse(p.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			d.errorf(node.Line, "http probe: url must be an http(s) URL")
+		}
+	case probe.TypeTCP:
+		if _, _, err := net.SplitHostPort(p.Address); err != nil {
+			d.errorf(node.Line, "tcp probe: address must be host:port")
+		}
+	case probe.TypeUnix:
+		if p.Path == "" {
+			d.errorf(node.Line, "unix probe: path is required")
+		}
+	case probe.TypeExec:
+		if strings.TrimSpace(p.Command) == "" {
+			d.errorf(node.Line, "exec probe: command is required")
+		}
+	}
+
+	return p, true
+}
+
+// decodeRules decodes a list of rules. An entry is a condition, which
+// raises warnings, or a mapping with the condition under "when" and an
+// optional severity and clear level. Top-level rules (section "rules")
+// also take a "match" glob; without one they cover every watched unit.
+func (d *decoder) decodeRules(node *Node, topLevel bool, defaultType models.UnitType) []*rules.Rule {
+	if !d.expect(node, SequenceNode, "rules") {
+		return nil
+	}
+
+	var decoded []*rules.Rule
+	for _, item := range node.Items {
+		// A bare string is shorthand for {when: ...}
+		if item.Kind == ScalarNode {
+			rule, err := rules.Parse(d.str(item))
+			if err != nil {
+				d.errorf(item.Line, "%v", err)
+				continue
+			}
+			decoded = append(decoded, rule)
+			continue
+		}
+		if !d.expect(item, MappingNode, "a rule") {
+			continue
+		}
+
+		var rule *rules.Rule
+		var when, severity, clearLevel, match *Pair
+		for _, pair := range item.Pairs {
+			switch pair.Key {
+			case "when":
+				when = pair
+				parsed, err := rules.Parse(d.str(pair.Value))
+				if err != nil {
+					if pair.Value.Kind == ScalarNode {
+						d.errorf(pair.Value.Line, "%v", err)
+					}
+					continue
+				}
+				rule = parsed
+			case "severity":
+				severity = pair
+			case "clear":
+				clearLevel = pair
+			case "match":
+				if !topLevel {
+					d.errorf(pair.Line, "match is only valid for top-level rules; the service entry selects the units")
+					continue
+				}
+				match = pair
+			default:
+				d.unknownKey(pair, "rule")
+			}
+		}
+
+		if rule == nil {
+			if when == nil {
+				d.errorf(item.Line, "rule needs a condition (when)")
+			}
+			continue
+		}
+		if severity != nil {
+			if parsed, err := rules.ParseSeverity(d.str(severity.Value)); err != nil {
+				if severity.Value.Kind == ScalarNode {
+					d.errorf(severity.Value.Line, "%v", err)
+				}
+			} else {
+				rule.Severity = parsed
+			}
+		}
+		if clearLevel != nil {
+			d.ruleClear(clearLevel.Value, rule)
+		}
+		if match != nil {
+			rule.Match = models.NormalizeUnitName(d.str(match.Value), defaultType)
+			if _, err := path.Match(rule.Match, ""); err != nil {
+				d.errorf(match.Value.Line, "invalid pattern %q: %v", rule.Match, err)
+			}
+		}
+		decoded = append(decoded, rule)
+	}
+	return decoded
+}
+
+// ruleClear sets the level a rule clears at, which must lie on the safe
+// side of its threshold
+func (d *decoder) ruleClear(node *Node, rule *rules.Rule) {
+	value, err := rules.ParseValue(rule.Metric, d.str(node))
+	if err != nil {
+		if node.Kind == ScalarNode {
+			d.errorf(node.Line, "rule %q: clear: %v", rule.Expr, err)
+		}
+		return
+	}
+
+	lower := rule.Op == "<" || rule.Op == "<="
+	switch {
+	case !lower && value > rule.Value:
+		d.errorf(node.Line, "rule %q: clear must not be above the threshold", rule.Expr)
+	case lower && value < rule.Value:
+		d.errorf(node.Line, "rule %q: clear must not be below the threshold", rule.Expr)
+	default:
+		rule.Clear = value
+	}
+}
+
+// serviceRemediation decodes a service's remediation setting: true uses
+// the top-level policy, false disables it and a mapping overrides single
+// fields of the top-level policy
+func (d *decoder) serviceRemediation(node *Node, base remediate.Policy) *remediate.Policy {
+	if node.Kind == ScalarNode {
+		if !d.boolean(node) {
+			return nil
+		}
+		if base.Action == remediate.ActionCommand && base.Command == "" {
+			d.errorf(node.Line, "remediation action command needs a command")
+		}
+		return &base
+	}
+
+	policy := base
+	d.decodeRemediation(node, &policy)
+	return &policy
+}
+
+func (d *decoder) decodeRemediation(node *Node, policy *remediate.Policy) {
+	if !d.expect(node, MappingNode, "remediation") {
+		return
+	}
+
+	for _, pair := range node.Pairs {
+		switch pair.Key {
+		case "action":
+			action, err := remediate.ParseAction(d.str(pair.Value))
+			if err != nil {
+				if pair.Value.Kind == ScalarNode {
+					d.errorf(pair.Value.Line, "%v", err)
+				}
+				continue
+			}
+			policy.Action = action
+		case "command":
+			policy.Command = d.str(pair.Value)
+		case "max_attempts":
+			policy.MaxAttempts = d.integer(pair.Value)
+			if policy.MaxAttempts < 1 {
+				d.errorf(pair.Value.Line, "max_attempts must be at least 1")
+			}
+		case "window":
+			policy.Window = d.positiveDuration(pair)
+		case "backoff":
+			policy.Backoff = d.duration(pair.Value)
+		case "max_backoff":
+			policy.MaxBackoff = d.duration(pair.Value)
+		case "cooldown":
+			policy.Cooldown = d.duration(pair.Value)
+		case "timeout":
+			policy.Timeout = d.positiveDuration(pair)
+		default:
+			d.unknownKey(pair, "remediation")
+		}
+	}
+
+	if policy.Action == remediate.ActionCommand && policy.Command == "" {
+		d.errorf(node.Line, "remediation action command needs a command")
+	}
+}
+
+func (d *decoder) checkDuplicate(seen map[string]int, key string, line int) bool {
+	if prev, dup := seen[key]; dup {
+		d.errorf(line, "%s is already configured on line %d", key, prev)
+		return false
+	}
+	seen[key] = line
+	return true
+}
+
+// routeRefs decodes a list of route names and checks that they exist
+func (d *decoder) routeRefs(node *Node, routes []RouteConfig) []string {
+	names := d.stringList(node)
+	for _, name := range names {
+		found := false
+		for _, route := range routes {
+			if route.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			d.errorf(node.Line, "unknown alert route %q", name)
+		}
+	}
+	return names
+}
+
+func (d *decoder) unknownKey(pair *Pair, section string) {
+	d.errorf(pair.Line, "unknown key %q in %s", pair.Key, section)
+}
+
+// expect reports whether node has the wanted kind, recording an error if not
+func (d *decoder) expect(node *Node, kind NodeKind, what string) bool {
+	if node.Kind != kind {
+		d.errorf(node.Line, "%s must be a %s, got a %s", what, kind, node.Kind)
+		return false
+	}
+	return true
+}
+
+func (d *decoder) str(node *Node) string {
+	if !d.expect(node, ScalarNode, "value") {
+		return ""
+	}
+	return node.Value
+}
+
+func (d *decoder) boolean(node *Node) bool {
+	switch strings.ToLower(d.str(node)) {
+	case "true", "yes", "on":
+		return true
+	case "false", "no", "off":
+		return false
+	}
+	if node.Kind == ScalarNode {
+		d.errorf(node.Line, "expected true or false, got %q", node.Value)
+	}
+	return false
+}
+
+func (d *decoder) integer(node *Node) int {
+	value := d.str(node)
+	var n int
+	if _, err := fmt.Sscanf(value, "%d", &n); err != nil || fmt.Sprint(n) != value {
+		if node.Kind == ScalarNode {
+			d.errorf(node.Line, "expected an integer, got %q", value)
+		}
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) duration(node *Node) time.Duration {
+	return d.parseDuration(node, false)
+}
+
+func (d *decoder) positiveDuration(pair *Pair) time.Duration {
+	return d.parseDuration(pair.Value, true)
+}
+
+func (d *decoder) parseDuration(node *Node, positive bool) time.Duration {
+	value := d.str(node)
+	if node.Kind != ScalarNode {
+		return 0
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		d.errorf(node.Line, "expected a duration like 30s or 5m, got %q", value)
+		return 0
+	}
+	if positive && duration == 0 {
+		d.errorf(node.Line, "duration must be greater than zero")
+	}
+	return duration
+}
+
+func (d *decoder) unitType(node *Node) models.UnitType {
+	unitType, err := models.ParseUnitType(d.str(node))
+	if err != nil {
+		d.errorf(node.Line, "%v", err)
+		return ""
+	}
+	if unitType == models.UnitAll {
+		d.errorf(node.Line, "type all is not valid here; use a pattern such as \"*\"")
+		return ""
+	}
+	return unitType
+}
+
+// stringList accepts a list of strings or a single string
+func (d *decoder) stringList(node *Node) []string {
+	if node.Kind == ScalarNode {
+		if node.Null || node.Value == "" {
+			return nil
+		}
+		return []string{node.Value}
+	}
+	if !d.expect(node, SequenceNode, "value") {
+		return nil
+	}
+
+	var items []string
+	for _, item := range node.Items {
+		if value := d.str(item); value != "" {
+			items = append(items, value)
+		}
+	}
+	return items
+}
+
+// patterns decodes a list of glob patterns
+func (d *decoder) patterns(node *Node) []string {
+	patterns := d.stringList(node)
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			d.errorf(node.Line, "invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return patterns
+}
+
+func (d *decoder) stringMap(node *Node, what string) map[string]string {
+	if !d.expect(node, MappingNode, what) {
+		return nil
+	}
+
+	values := make(map[string]string, len(node.Pairs))
+	for _, pair := range node.Pairs {
+		values[pair.Key] = d.str(pair.Value)
+	}
+	return values
+}
+
+func contains(items []string, item string) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,505 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+func TestParseYAMLSyntax(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		line int
+		msg  string
+	}{
+		{
+			name: "indented key",
+			data: "interval: 30s\n  sudo: true\n",
+			line: 2, msg: "unexpected indentation",
+		},
+		{
+			name: "tab indentation",
+			data: "logging:\n\tfile: x.log\n",
+			line: 2, msg: "tabs are not allowed",
+		},
+		{
+			name: "list item in a mapping",
+			data: "logging:\n  file: x.log\n  - y\n",
+			line: 3, msg: "list item where a key was expected",
+		},
+		{
+			name: "indented list item",
+			data: "services:\n  - nginx\n    - redis\n",
+			line: 3, msg: "unexpected indentation",
+		},
+		{
+			name: "duplicate key",
+			data: "interval: 30s\nsudo: true\ninterval: 1m\n",
+			line: 3, msg: `duplicate key "interval" (first defined on line 1)`,
+		},
+		{
+			name: "duplicate nested key",
+			data: "logging:\n  file: a.log\n  file: b.log\n",
+			line: 3, msg: `duplicate key "file" (first defined on line 2)`,
+		},
+		{
+			name: "unterminated quote",
+			data: "logging:\n  file: \"x.log\n",
+			line: 2, msg: "unterminated quoted string",
+		},
+		{
+			name: "block scalar",
+			data: "logging:\n  file: |\n    x.log\n",
+			line: 2, msg: "block scalars",
+		},
+		{
+			name: "flow mapping",
+			data: "logging: {file: x.log}\n",
+			line: 1, msg: "flow mappings",
+		},
+		{
+			name: "not a pair",
+			data: "interval 30s\n",
+			line: 1, msg: `expected "key: value"`,
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseYAML(tt.data)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%s: err = %v, want a syntax error", tt.name, err)
+			continue
+		}
+		if syntaxErr.Line != tt.line || !strings.Contains(syntaxErr.Msg, tt.msg) {
+			t.Errorf("%s: got line %d %q, want line %d %q", tt.name, syntaxErr.Line, syntaxErr.Msg, tt.line, tt.msg)
+		}
+	}
+}
+
+func TestParseYAMLScalars(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+		null bool
+	}{
+		{line: "key: plain value", want: "plain value"},
+		{line: `key: "double # not a comment"`, want: "double # not a comment"},
+		{line: `key: 'single # not a comment'`, want: "single # not a comment"},
+		{line: "key: value # a comment", want: "value"},
+		{line: "key: a#b", want: "a#b"},
+		{line: `key: "tab\there \"quoted\" back\\slash"`, want: "tab\there \"quoted\" back\\slash"},
+		{line: `key: 'it''s'`, want: "it's"},
+		{line: `key: "a: b"`, want: "a: b"},
+		{line: `"quoted: key": v`, want: "v"},
+		{line: "key: ~", null: true},
+		{line: "key: null", null: true},
+		{line: "key:", null: true},
+	}
+
+	for _, tt := range tests {
+		root, err := ParseYAML(tt.line)
+		if err != nil {
+			t.Errorf("%s: %v", tt.line, err)
+			continue
+		}
+		if len(root.Pairs) != 1 {
+			t.Errorf("%s: got %d pairs, want 1", tt.line, len(root.Pairs))
+			continue
+		}
+		value := root.Pairs[0].Value
+		if value.Kind != ScalarNode || value.Value != tt.want || value.Null != tt.null {
+			t.Errorf("%s: got %s %q (null %t), want %q (null %t)", tt.line, value.Kind, value.Value, value.Null, tt.want, tt.null)
+		}
+	}
+}
+
+func TestParseYAMLSequences(t *testing.T) {
+	data := `
+flow: [a, "b, c", 'd']
+block:
+  - x
+  - name: y
+    type: timer
+same_indent:
+- z
+`
+	root, err := ParseYAML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flow := root.Pairs[0].Value
+	if got := scalars(flow); !reflect.DeepEqual(got, []string{"a", "b, c", "d"}) {
+		t.Errorf("flow = %q", got)
+	}
+
+	block := root.Pairs[1].Value
+	if block.Kind != SequenceNode || len(block.Items) != 2 {
+		t.Fatalf("block = %s with %d items, want a list of 2", block.Kind, len(block.Items))
+	}
+	if item := block.Items[1]; item.Kind != MappingNode || len(item.Pairs) != 2 || item.Line != 5 {
+		t.Errorf("block[1] = %s with %d pairs on line %d, want a mapping of 2 on line 5", item.Kind, len(item.Pairs), item.Line)
+	}
+
+	if got := scalars(root.Pairs[2].Value); !reflect.DeepEqual(got, []string{"z"}) {
+		t.Errorf("same_indent = %q", got)
+	}
+}
+
+func scalars(node *Node) []string {
+	var values []string
+	for _, item := range node.Items {
+		values = append(values, item.Value)
+	}
+	return values
+}
+
+func TestParseTOMLSyntax(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		line int
+		msg  string
+	}{
+		{
+			name: "duplicate key",
+			data: "interval = \"30s\"\nsudo = true\ninterval = \"1m\"\n",
+			line: 3, msg: `duplicate key "interval" (first defined on line 1)`,
+		},
+		{
+			name: "dotted key",
+			data: "logging.file = \"x.log\"\n",
+			line: 1, msg: "dotted keys are not supported",
+		},
+		{
+			name: "unterminated string",
+			data: "[logging]\nfile = \"x.log\n",
+			line: 2, msg: "unterminated string",
+		},
+		{
+			name: "unterminated header",
+			data: "[logging\n",
+			line: 1, msg: "unterminated table header",
+		},
+		{
+			name: "table over a value",
+			data: "logging = \"x\"\n[logging]\n",
+			line: 2, msg: `"logging" is already defined on line 1 as a value`,
+		},
+		{
+			name: "multi-line array",
+			data: "services = [\n  \"nginx\",\n]\n",
+			line: 1, msg: "multi-line arrays are not supported",
+		},
+		{
+			name: "missing value",
+			data: "interval =\n",
+			line: 1, msg: "missing value",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseTOML(tt.data)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%s: err = %v, want a syntax error", tt.name, err)
+			continue
+		}
+		if syntaxErr.Line != tt.line || !strings.Contains(syntaxErr.Msg, tt.msg) {
+			t.Errorf("%s: got line %d %q, want line %d %q", tt.name, syntaxErr.Line, syntaxErr.Msg, tt.line, tt.msg)
+		}
+	}
+}
+
+func TestParseValidation(t *testing.T) {
+	const routes = `alerts:
+  routes:
+    - name: ops
+      type: command
+      command: "true"
+`
+
+	tests := []struct {
+		name string
+		data string
+		want []string // "line: message" of every error, in order
+	}{
+		{
+			name: "no services",
+			data: "interval: 30s\n",
+			want: []string{"1: no services configured"},
+		},
+		{
+			name: "empty services",
+			data: "services: []\n",
+			want: []string{"1: no services configured"},
+		},
+		{
+			name: "unknown top-level key",
+			data: "intervall: 30s\nservices: [nginx]\n",
+			want: []string{`1: unknown key "intervall" in top level`},
+		},
+		{
+			name: "unknown service key",
+			data: "services:\n  - name: nginx\n    expect: running\n",
+			want: []string{`3: unknown key "expect" in service`},
+		},
+		{
+			name: "unknown route key",
+			data: routes + "    - name: web\n      type: webhook\n      url: https://example.com\n      method: POST\nservices: [nginx]\n",
+			want: []string{`9: unknown key "method" in alert route`},
+		},
+		{
+			name: "unknown route reference",
+			data: routes + "services:\n  - name: nginx\n    alerts: [ops, pager]\n",
+			want: []string{`8: unknown alert route "pager"`},
+		},
+		{
+			name: "unknown default route",
+			data: "alerts:\n  default_routes: pager\nservices: [nginx]\n",
+			want: []string{`2: unknown alert route "pager"`},
+		},
+		{
+			name: "duplicate route",
+			data: routes + "    - name: ops\n      type: command\n      command: \"false\"\nservices: [nginx]\n",
+			want: []string{`6: duplicate route "ops" (first defined on line 3)`},
+		},
+		{
+			name: "duplicate service",
+			data: "services:\n  - nginx\n  - name: nginx.service\n",
+			want: []string{"3: nginx.service is already configured on line 2"},
+		},
+		{
+			name: "bad durations",
+			data: "interval: 30\ncheck_timeout: -1s\nservices:\n  - name: nginx\n    interval: 0s\n    thresholds:\n      min_uptime: soon\n",
+			want: []string{
+				`1: expected a duration like 30s or 5m, got "30"`,
+				`2: expected a duration like 30s or 5m, got "-1s"`,
+				"5: duration must be greater than zero",
+				`7: expected a duration like 30s or 5m, got "soon"`,
+			},
+		},
+		{
+			name: "duration given as a list",
+			data: "interval: [30s]\nservices: [nginx]\n",
+			want: []string{"1: value must be a value, got a list"},
+		},
+		{
+			name: "errors are sorted by line",
+			data: "services:\n  - name: nginx\n    expected: up\nbackend: systemd\n",
+			want: []string{
+				`3: expected must be running or stopped, got "up"`,
+				`4: backend must be auto, dbus or exec, got "systemd"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse("monitor.yaml", tt.data)
+		var errs Errors
+		if !errors.As(err, &errs) {
+			t.Errorf("%s: err = %v, want Errors", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, e := range errs {
+			if e.File != "monitor.yaml" {
+				t.Errorf("%s: error names file %q", tt.name, e.File)
+			}
+			got = append(got, strings.TrimPrefix(e.Error(), "monitor.yaml:"))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseSyntaxErrorHasFile(t *testing.T) {
+	_, err := Parse("monitor.toml", "interval = 30s\ninterval = 1m\n")
+	if err == nil || err.Error() != `monitor.toml:2: duplicate key "interval" (first defined on line 1)` {
+		t.Errorf("err = %v", err)
+	}
+}
+
+const equivalentYAML = `
+interval: 15s
+backend: exec
+type: service
+workers: 4
+logging:
+  file: /var/log/monitor.log
+  changes_only: true
+history:
+  retention: 48h
+  raw: 6h
+  resolution: 1m
+alerts:
+  retries: 2
+  repeat: 30m
+  default_routes: [ops]
+  routes:
+    - name: ops
+      type: webhook
+      url: https://hooks.example.com/systemd
+      headers:
+        Authorization: "Bearer token"
+    - name: mail
+      type: smtp
+      addr: smtp.example.com:587
+      to: [oncall@example.com]
+remediation:
+  action: reset-start
+  max_attempts: 2
+rules:
+  - match: "docker-*"
+    when: tasks > 1000
+    severity: critical
+services:
+  - redis
+  - name: postgresql
+    expected: running
+    alerts: [ops, mail]
+    thresholds:
+      max_restarts: 3
+      min_uptime: 2m
+    rules:
+      - memory > 512MiB for 5m
+    probes:
+      - type: tcp
+        address: 127.0.0.1:5432
+    remediation: true
+  - pattern: "docker-*"
+    remediation:
+      cooldown: 2h
+  - name: backup
+    type: timer
+    alerts: []
+`
+
+const equivalentTOML = `
+interval = "15s"
+backend = "exec"
+type = "service"
+workers = 4
+
+[logging]
+file = "/var/log/monitor.log"
+changes_only = true
+
+[history]
+retention = "48h"
+raw = "6h"
+resolution = "1m"
+
+[alerts]
+retries = 2
+repeat = "30m"
+default_routes = ["ops"]
+
+[[alerts.routes]]
+name = "ops"
+type = "webhook"
+url = "https://hooks.example.com/systemd"
+headers = { Authorization = "Bearer token" }
+
+[[alerts.routes]]
+name = "mail"
+type = "smtp"
+addr = "smtp.example.com:587"
+to = ["oncall@example.com"]
+
+[remediation]
+action = "reset-start"
+max_attempts = 2
+
+[[rules]]
+match = "docker-*"
+when = "tasks > 1000"
+severity = "critical"
+
+[[services]]
+name = "redis"
+
+[[services]]
+name = "postgresql"
+expected = "running"
+alerts = ["ops", "mail"]
+thresholds = { max_restarts = 3, min_uptime = "2m" }
+rules = ["memory > 512MiB for 5m"]
+remediation = true
+
+[[services.probes]]
+type = "tcp"
+address = "127.0.0.1:5432"
+
+[[services]]
+pattern = "docker-*"
+remediation = { cooldown = "2h" }
+
+[[services]]
+name = "backup"
+type = "timer"
+alerts = []
+`
+
+func TestTOMLMatchesYAML(t *testing.T) {
+	fromYAML, err := Parse("monitor.yaml", equivalentYAML)
+	if err != nil {
+		t.Fatalf("YAML: %v", err)
+	}
+	fromTOML, err := Parse("monitor.toml", equivalentTOML)
+	if err != nil {
+		t.Fatalf("TOML: %v", err)
+	}
+
+	// Only the file name and line numbers may differ
+	for _, cfg := range []*Config{fromYAML, fromTOML} {
+		cfg.File = ""
+		for i := range cfg.Alerts.Routes {
+			cfg.Alerts.Routes[i].Line = 0
+		}
+		for i := range cfg.Services {
+			cfg.Services[i].Line = 0
+		}
+	}
+
+	if !reflect.DeepEqual(fromYAML, fromTOML) {
+		t.Errorf("configs differ:\nYAML %+v\nTOML %+v", fromYAML, fromTOML)
+	}
+
+	// And both decoded what was written, not two equal sets of defaults
+	if fromYAML.Interval != 15*time.Second || len(fromYAML.Services) != 4 || len(fromYAML.Alerts.Routes) != 2 {
+		t.Errorf("interval %s, %d services, %d routes; want 15s, 4, 2",
+			fromYAML.Interval, len(fromYAML.Services), len(fromYAML.Alerts.Routes))
+	}
+	if svc := fromYAML.Services[3]; svc.Type != models.UnitTimer || svc.Alerts == nil || len(svc.Alerts) != 0 {
+		t.Errorf("backup = type %q, alerts %#v; want timer with no alerts", svc.Type, svc.Alerts)
+	}
+	if policy := fromYAML.Services[2].Remediation; policy == nil || policy.Cooldown != 2*time.Hour || policy.MaxAttempts != 2 {
+		t.Errorf("docker-* remediation = %+v, want the top-level policy with a 2h cooldown", policy)
+	}
+}
+
+func TestLoadExamples(t *testing.T) {
+	cfg, err := Load("../../config.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Services) == 0 || len(cfg.Alerts.Routes) == 0 {
+		t.Errorf("config.example.yaml: %d services, %d routes", len(cfg.Services), len(cfg.Alerts.Routes))
+	}
+
+	hosts, err := LoadHosts("../../hosts.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) == 0 {
+		t.Error("hosts.example.yaml: no hosts")
+	}
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/andinianst93/systemd-monitoring/internal/systemd"
+)
+
+// Host is a remote machine whose units are checked over ssh
+type Host struct {
+	Target systemd.SSHTarget
+	Sudo   bool // run systemctl/journalctl through sudo on the host
+	Line   int
+}
+
+// LoadHosts reads and validates a hosts file. Like the config file it is
+// YAML, or TOML when the name ends in .toml:
+//
+//	user: monitor                 # defaults for every host
+//	known_hosts: /etc/systemd-monitoring/known_hosts
+//	hosts:
+//	  - web1.example.com          # shorthand for [user@]host[:port]
+//	  - name: db1
+//	    address: 10.0.0.12
+//	    port: 2222
+//	    jump: admin@bastion.example.com
+func LoadHosts(filePath string) ([]Host, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	return ParseHosts(filePath, string(data))
+}
+
+// ParseHosts parses and validates hosts file data; see LoadHosts
+func ParseHosts(filePath, data string) ([]Host, error) {
+	root, err := parseFile(filePath, data)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{file: filePath}
+	hosts := d.decodeHosts(root)
+
+	if len(d.errs) > 0 {
+		sort.SliceStable(d.errs, func(i, j int) bool { return d.errs[i].Line < d.errs[j].Line })
+		return nil, d.errs
+	}
+	return hosts, nil
+}
+
+func (d *decoder) decodeHosts(root *Node) []Host {
+	if !d.expect(root, MappingNode, "the hosts file") {
+		return nil
+	}
+
+	// 1. Defaults, then the list inheriting them
+	var defaults Host
+	var hostsNode *Node
+	for _, pair := range root.Pairs {
+		if pair.Key == "hosts" {
+			hostsNode = pair.Value
+			continue
+		}
+		if !d.hostSetting(pair, &defaults) {
+			d.unknownKey(pair, "top level")
+		}
+	}
+
+	if hostsNode == nil {
+		d.errorf(root.Line, "no hosts configured")
+		return nil
+	}
+	if !d.expect(hostsNode, SequenceNode, "hosts") {
+		return nil
+	}
+	if len(hostsNode.Items) == 0 {
+		d.errorf(hostsNode.Line, "no hosts configured")
+		return nil
+	}
+
+	// 2. Hosts
+	var hosts []Host
+	seen := make(map[string]int)
+	for _, item := range hostsNode.Items {
+		host, ok := d.decodeHost(item, defaults)
+		if !ok {
+			continue
+		}
+		if err := host.Target.Validate(); err != nil {
+			d.errorf(item.Line, "%v", err)
+			continue
+		}
+		if d.checkDuplicate(seen, "host "+host.Target.Label(), item.Line) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// decodeHost decodes one entry of the hosts list: a "[user@]host[:port]"
+// string or a mapping
+func (d *decoder) decodeHost(node *Node, defaults Host) (Host, bool) {
+	host := defaults
+	host.Line = node.Line
+
+	if node.Kind == ScalarNode {
+		target, err := systemd.ParseSSHTarget(d.str(node))
+		if err != nil {
+			d.errorf(node.Line, "%v", err)
+			return host, false
+		}
+		host.Target.Host, host.Target.Port = target.Host, target.Port
+		if target.Port == 0 {
+			host.Target.Port = defaults.Target.Port
+		}
+		if target.User != "" {
+			host.Target.User = target.User
+		}
+		return host, true
+	}
+
+	if !d.expect(node, MappingNode, "a host entry") {
+		return host, false
+	}
+	for _, pair := range node.Pairs {
+		switch pair.Key {
+		case "name":
+			host.Target.Name = d.str(pair.Value)
+		case "address":
+			host.Target.Host = d.str(pair.Value)
+		default:
+			if !d.hostSetting(pair, &host) {
+				d.unknownKey(pair, "host")
+			}
+		}
+	}
+
+	if host.Target.Host == "" {
+		d.errorf(node.Line, "host entry needs an address")
+		return host, false
+	}
+	return host, true
+}
+
+// hostSetting decodes a key allowed both as a default and per host; it
+// returns false for keys it does not know
+func (d *decoder) hostSetting(pair *Pair, host *Host) bool {
+	switch pair.Key {
+	case "user":
+		host.Target.User = d.str(pair.Value)
+	case "port":
+		host.Target.Port = d.integer(pair.Value)
+		if host.Target.Port < 1 || host.Target.Port > 65535 {
+			d.errorf(pair.Value.Line, "port must be between 1 and 65535")
+		}
+	case "identity_file":
+		host.Target.IdentityFile = d.str(pair.Value)
+	case "known_hosts":
+		host.Target.KnownHosts = d.str(pair.Value)
+	case "jump":
+		host.Target.Jump = d.str(pair.Value)
+	case "sudo":
+		host.Sudo = d.boolean(pair.Value)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseTOML parses the TOML subset the config file needs into the same
+// node tree as ParseYAML: [tables], [[arrays of tables]], key = value with
+// strings, integers, booleans, single-line arrays and inline tables.
+func ParseTOML(data string) (*Node, error) {
+	root := &Node{Kind: MappingNode, Line: 1}
+	current := root
+
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		num := i + 1
+		content := strings.TrimSpace(stripComment(raw))
+		if content == "" {
+			continue
+		}
+
+		// 1. [[array.of.tables]]
+		if strings.HasPrefix(content, "[[") {
+			if !strings.HasSuffix(content, "]]") {
+				return nil, &SyntaxError{Line: num, Msg: "unterminated table header"}
+			}
+			keys, err := splitTableKey(content[2:len(content)-2], num)
+			if err != nil {
+				return nil, err
+			}
+			parent, err := walkTables(root, keys[:len(keys)-1], num)
+			if err != nil {
+				return nil, err
+			}
+
+			last := keys[len(keys)-1]
+			list := findPair(parent, last)
+			if list == nil {
+				list = &Pair{Key: last, Line: num, Value: &Node{Kind: SequenceNode, Line: num}}
+				parent.Pairs = append(parent.Pairs, list)
+			} else if list.Value.Kind != SequenceNode {
+				return nil, &SyntaxError{Line: num, Msg: fmt.Sprintf("%q is already defined on line %d and is not an array of tables", last, list.Line)}
+			}
+
+			current = &Node{Kind: MappingNode, Line: num}
+			list.Value.Items = append(list.Value.Items, current)
+			continue
+		}
+
+		// 2. [table]
+		if strings.HasPrefix(content, "[") {
+			if !strings.HasSuffix(content, "]") {
+				return nil, &SyntaxError{Line: num, Msg: "unterminated table header"}
+			}
+			keys, err := splitTableKey(content[1:len(content)-1], num)
+			if err != nil {
+				return nil, err
+			}
+			table, err := walkTables(root, keys, num)
+			if err != nil {
+				return nil, err
+			}
+			current = table
+			continue
+		}
+
+		// 3. key = value
+		eq := strings.Index(content, "=")
+		if eq <= 0 {
+			return nil, &SyntaxError{Line: num, Msg: fmt.Sprintf("expected \"key = value\", got %q", content)}
+		}
+		key := unquote(strings.TrimSpace(content[:eq]))
+		if strings.Contains(key, ".") && !strings.HasPrefix(strings.TrimSpace(content[:eq]), "\"") {
+			return nil, &SyntaxError{Line: num, Msg: "dotted keys are not supported; use a [table] header"}
+		}
+		if prev := findPair(current, key); prev != nil {
+			return nil, &SyntaxError{Line: num, Msg: fmt.Sprintf("duplicate key %q (first defined on line %d)", key, prev.Line)}
+		}
+
+		value, err := parseTOMLValue(strings.TrimSpace(content[eq+1:]), num)
+		if err != nil {
+			return nil, err
+		}
+		current.Pairs = append(current.Pairs, &Pair{Key: key, Line: num, Value: value})
+	}
+
+	return root, nil
+}
+
+// walkTables returns the table at keys, creating missing ones. A key that
+// holds an array of tables resolves to its last element.
+func walkTables(root *Node, keys []string, num int) (*Node, error) {
+	table := root
+	for _, key := range keys {
+		pair := findPair(table, key)
+		if pair == nil {
+			pair = &Pair{Key: key, Line: num, Value: &Node{Kind: MappingNode, Line: num}}
+			table.Pairs = append(table.Pairs, pair)
+		}
+
+		switch pair.Value.Kind {
+		case MappingNode:
+			table = pair.Value
+		case SequenceNode:
+			if len(pair.Value.Items) == 0 || pair.Value.Items[len(pair.Value.Items)-1].Kind != MappingNode {
+				return nil, &SyntaxError{Line: num, Msg: fmt.Sprintf("%q is not a table", key)}
+			}
+			table = pair.Value.Items[len(pair.Value.Items)-1]
+		default:
+			return nil, &SyntaxError{Line: num, Msg: fmt.Sprintf("%q is already defined on line %d as a value", key, pair.Line)}
+		}
+	}
+	return table, nil
+}
+
+func findPair(node *Node, key string) *Pair {
+	for _, pair := range node.Pairs {
+		if pair.Key == key {
+			return pair
+		}
+	}
+	return nil
+}
+
+func splitTableKey(s string, num int) ([]string, error) {
+	var keys []string
+	for _, part := range strings.Split(s, ".") {
+		part = unquote(strings.TrimSpace(part))
+		if part == "" {
+			return nil, &SyntaxError{Line: num, Msg: "empty table name"}
+		}
+		keys = append(keys, part)
+	}
+	return keys, nil
+}
+
+func parseTOMLValue(value string, num int) (*Node, error) {
+	if value == "" {
+		return nil, &SyntaxError{Line: num, Msg: "missing value"}
+	}
+
+	switch value[0] {
+	case '"', '\'':
+		if len(value) < 2 || value[len(value)-1] != value[0] {
+			return nil, &SyntaxError{Line: num, Msg: "unterminated string"}
+		}
+		return &Node{Kind: ScalarNode, Line: num, Value: unquote(value)}, nil
+
+	case '[':
+		if !strings.HasSuffix(value, "]") {
+			return nil, &SyntaxError{Line: num, Msg: "multi-line arrays are not supported"}
+		}
+		node := &Node{Kind: SequenceNode, Line: num}
+		inner := strings.TrimSpace(value[1 : len(value)-1])
+		for _, item := range splitFlow(inner) {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue // trailing comma
+			}
+			child, err := parseTOMLValue(item, num)
+			if err != nil {
+				return nil, err
+			}
+			node.Items = append(node.Items, child)
+		}
+		return node, nil
+
+	case '{':
+		if !strings.HasSuffix(value, "}") {
+			return nil, &SyntaxError{Line: num, Msg: "unterminated inline table"}
+		}
+		node := &Node{Kind: MappingNode, Line: num}
+		inner := strings.TrimSpace(value[1 : len(value)-1])
+		if inner == "" {
+			return node, nil
+		}
+		for _, item := range splitFlow(inner) {
+			eq := strings.Index(item, "=")
+			if eq <= 0 {
+				return nil, &SyntaxError{Line: num, Msg: fmt.Sprintf("expected \"key = value\" in inline table, got %q", strings.TrimSpace(item))}
+			}
+			child, err := parseTOMLValue(strings.TrimSpace(item[eq+1:]), num)
+			if err != nil {
+				return nil, err
+			}
+			node.Pairs = append(node.Pairs, &Pair{Key: unquote(strings.TrimSpace(item[:eq])), Line: num, Value: child})
+		}
+		return node, nil
+	}
+
+	// Bare values: booleans and numbers
+	return &Node{Kind: ScalarNode, Line: num, Value: value}, nil
+}
@@ -0,0 +1,391 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This file implements the subset of YAML the config file needs: block
+// mappings and sequences, plain and quoted scalars, flow sequences of
+// scalars ([a, b]) and comments. Every node remembers its line so
+// validation errors can point at the offending line.
+
+// NodeKind is the type of a parsed YAML node
+type NodeKind int
+
+const (
+	ScalarNode NodeKind = iota
+	MappingNode
+	SequenceNode
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case MappingNode:
+		return "mapping"
+	case SequenceNode:
+		return "list"
+	}
+	return "value"
+}
+
+// Node is a parsed YAML value
+type Node struct {
+	Kind  NodeKind
+	Line  int
+	Value string  // ScalarNode
+	Pairs []*Pair // MappingNode, in file order
+	Items []*Node // SequenceNode
+	Null  bool    // ScalarNode with no value ("key:" with nothing below)
+}
+
+// Pair is one key of a mapping
+type Pair struct {
+	Key   string
+	Line  int
+	Value *Node
+}
+
+// SyntaxError is a parse error at a specific line
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// line is one meaningful source line
+type line struct {
+	num     int
+	indent  int
+	content string
+}
+
+// ParseYAML parses a YAML document into a node tree
+func ParseYAML(data string) (*Node, error) {
+	lines, err := splitLines(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return &Node{Kind: MappingNode, Line: 1}, nil
+	}
+
+	p := &parser{lines: lines}
+	node, err := p.parseBlock(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		return nil, &SyntaxError{Line: l.num, Msg: "unexpected indentation"}
+	}
+	return node, nil
+}
+
+// splitLines drops blank lines, comments and document markers
+func splitLines(data string) ([]line, error) {
+	var lines []line
+
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		num := i + 1
+
+		if strings.Contains(raw, "\t") && strings.TrimLeft(raw, " ") != strings.TrimLeft(raw, " \t") {
+			return nil, &SyntaxError{Line: num, Msg: "tabs are not allowed for indentation"}
+		}
+
+		content := strings.TrimRight(stripComment(raw), " ")
+		trimmed := strings.TrimLeft(content, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if trimmed == "..." {
+			break
+		}
+
+		lines = append(lines, line{
+			num:     num,
+			indent:  len(content) - len(trimmed),
+			content: trimmed,
+		})
+	}
+
+	return lines, nil
+}
+
+// stripComment removes a trailing "# comment" outside of quotes
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+func (p *parser) peek() (line, bool) {
+	if p.pos >= len(p.lines) {
+		return line{}, false
+	}
+	return p.lines[p.pos], true
+}
+
+// parseBlock parses the mapping or sequence starting at the current line
+func (p *parser) parseBlock(indent int) (*Node, error) {
+	l, _ := p.peek()
+	if isSequenceItem(l.content) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func isSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+func (p *parser) parseSequence(indent int) (*Node, error) {
+	first, _ := p.peek()
+	node := &Node{Kind: SequenceNode, Line: first.num}
+
+	for {
+		l, ok := p.peek()
+		if !ok || l.indent < indent {
+			return node, nil
+		}
+		if l.indent > indent {
+			return nil, &SyntaxError{Line: l.num, Msg: "unexpected indentation"}
+		}
+		if !isSequenceItem(l.content) {
+			return node, nil
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(l.content, "-"), " ")
+
+		// "-" alone: the item is the indented block below
+		if rest == "" {
+			p.pos++
+			next, ok := p.peek()
+			if !ok || next.indent <= indent {
+				node.Items = append(node.Items, &Node{Kind: ScalarNode, Line: l.num, Null: true})
+				continue
+			}
+			item, err := p.parseBlock(next.indent)
+			if err != nil {
+				return nil, err
+			}
+			node.Items = append(node.Items, item)
+			continue
+		}
+
+		// "- key: value": a mapping whose first key sits after the dash.
+		// Rewrite the line as if the key started on its own line.
+		if _, _, isPair := splitPair(rest); isPair || isSequenceItem(rest) {
+			offset := len(l.content) - len(rest)
+			p.lines[p.pos] = line{num: l.num, indent: indent + offset, content: rest}
+			item, err := p.parseBlock(indent + offset)
+			if err != nil {
+				return nil, err
+			}
+			node.Items = append(node.Items, item)
+			continue
+		}
+
+		// "- scalar"
+		p.pos++
+		item, err := parseInline(rest, l.num)
+		if err != nil {
+			return nil, err
+		}
+		node.Items = append(node.Items, item)
+	}
+}
+
+func (p *parser) parseMapping(indent int) (*Node, error) {
+	first, _ := p.peek()
+	node := &Node{Kind: MappingNode, Line: first.num}
+	seen := make(map[string]int)
+
+	for {
+		l, ok := p.peek()
+		if !ok || l.indent < indent {
+			return node, nil
+		}
+		if l.indent > indent {
+			return nil, &SyntaxError{Line: l.num, Msg: "unexpected indentation"}
+		}
+		if isSequenceItem(l.content) {
+			return nil, &SyntaxError{Line: l.num, Msg: "list item where a key was expected"}
+		}
+
+		key, value, isPair := splitPair(l.content)
+		if !isPair {
+			return nil, &SyntaxError{Line: l.num, Msg: fmt.Sprintf("expected \"key: value\", got %q", l.content)}
+		}
+		if prev, dup := seen[key]; dup {
+			return nil, &SyntaxError{Line: l.num, Msg: fmt.Sprintf("duplicate key %q (first defined on line %d)", key, prev)}
+		}
+		seen[key] = l.num
+		p.pos++
+
+		pair := &Pair{Key: key, Line: l.num}
+
+		if value != "" {
+			if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+				return nil, &SyntaxError{Line: l.num, Msg: "block scalars (| and >) are not supported; use a quoted string"}
+			}
+			if strings.HasPrefix(value, "&") || strings.HasPrefix(value, "*") {
+				return nil, &SyntaxError{Line: l.num, Msg: "anchors and aliases are not supported"}
+			}
+			v, err := parseInline(value, l.num)
+			if err != nil {
+				return nil, err
+			}
+			pair.Value = v
+		} else {
+			next, ok := p.peek()
+			switch {
+			case ok && next.indent > indent:
+				v, err := p.parseBlock(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				pair.Value = v
+			case ok && next.indent == indent && isSequenceItem(next.content):
+				// "key:" followed by a list at the same indentation
+				v, err := p.parseSequence(indent)
+				if err != nil {
+					return nil, err
+				}
+				pair.Value = v
+			default:
+				pair.Value = &Node{Kind: ScalarNode, Line: l.num, Null: true}
+			}
+		}
+
+		node.Pairs = append(node.Pairs, pair)
+	}
+}
+
+// splitPair splits "key: value" (or "key:") outside of quotes
+func splitPair(content string) (string, string, bool) {
+	var quote byte
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 {
+				quote = c
+			}
+		case c == ':' && (i == len(content)-1 || content[i+1] == ' '):
+			key := strings.TrimSpace(content[:i])
+			if key == "" {
+				return "", "", false
+			}
+			return unquote(key), strings.TrimSpace(content[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// parseInline parses a scalar or a flow sequence on a single line
+func parseInline(value string, num int) (*Node, error) {
+	if strings.HasPrefix(value, "{") {
+		return nil, &SyntaxError{Line: num, Msg: "flow mappings ({...}) are not supported; use an indented block"}
+	}
+
+	if strings.HasPrefix(value, "[") {
+		if !strings.HasSuffix(value, "]") {
+			return nil, &SyntaxError{Line: num, Msg: "unterminated list"}
+		}
+		node := &Node{Kind: SequenceNode, Line: num}
+		inner := strings.TrimSpace(value[1 : len(value)-1])
+		if inner == "" {
+			return node, nil
+		}
+		for _, item := range splitFlow(inner) {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				return nil, &SyntaxError{Line: num, Msg: "empty list item"}
+			}
+			scalar, err := parseScalar(item, num)
+			if err != nil {
+				return nil, err
+			}
+			node.Items = append(node.Items, scalar)
+		}
+		return node, nil
+	}
+
+	return parseScalar(value, num)
+}
+
+// splitFlow splits flow sequence items on commas outside of quotes
+func splitFlow(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+func parseScalar(value string, num int) (*Node, error) {
+	if value == "~" || value == "null" {
+		return &Node{Kind: ScalarNode, Line: num, Null: true}, nil
+	}
+
+	if value[0] == '"' || value[0] == '\'' {
+		if len(value) < 2 || value[len(value)-1] != value[0] {
+			return nil, &SyntaxError{Line: num, Msg: "unterminated quoted string"}
+		}
+	}
+
+	return &Node{Kind: ScalarNode, Line: num, Value: unquote(value)}, nil
+}
+
+// unquote strips matching quotes; double quotes support \" \\ \n \t
+func unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	switch {
+	case s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	case s[0] == '"' && s[len(s)-1] == '"':
+		r := strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\t`, "\t")
+		return r.Replace(s[1 : len(s)-1])
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notifier sends state changes to the service manager over the sd_notify
+// protocol: newline-separated VAR=value assignments in one datagram on
+// the unix socket named by $NOTIFY_SOCKET. A nil Notifier, returned when
+// not running under systemd, ignores every call.
+type Notifier struct {
+	addr     *net.UnixAddr
+	watchdog time.Duration // 0 = no watchdog
+}
+
+// FromEnv returns a notifier for $NOTIFY_SOCKET, or nil when the variable
+// is unset. WatchdogSec is picked up from $WATCHDOG_USEC when it is meant
+// for this process.
+func FromEnv() *Notifier {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// Names starting with "@" are abstract sockets; net maps the "@" to
+	// the leading NUL byte
+	n := &Notifier{addr: &net.UnixAddr{Name: socket, Net: "unixgram"}}
+
+	if usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64); err == nil && usec > 0 {
+		pid := os.Getenv("WATCHDOG_PID")
+		if pid == "" || pid == strconv.Itoa(os.Getpid()) {
+			n.watchdog = time.Duration(usec) * time.Microsecond
+		}
+	}
+
+	return n
+}
+
+// Notify sends the given assignments, e.g. "READY=1", as one message
+func (n *Notifier) Notify(state ...string) error {
+	if n == nil {
+		return nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, n.addr)
+	if err != nil {
+		return fmt.Errorf("sd_notify: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(strings.Join(state, "\n") + "\n")); err != nil {
+		return fmt.Errorf("sd_notify: %w", err)
+	}
+	return nil
+}
+
+// Ready tells systemd start-up has finished, with a status line
+func (n *Notifier) Ready(status string) error {
+	return n.Notify("READY=1", "STATUS="+status)
+}
+
+// Reloading tells systemd the configuration is being reloaded; Ready
+// has to follow once done
+func (n *Notifier) Reloading() error {
+	return n.Notify("RELOADING=1")
+}
+
+// Stopping tells systemd the service is shutting down
+func (n *Notifier) Stopping(status string) error {
+	return n.Notify("STOPPING=1", "STATUS="+status)
+}
+
+// Status updates the free-form status shown by systemctl status
+func (n *Notifier) Status(status string) error {
+	return n.Notify("STATUS=" + status)
+}
+
+// Watchdog pings the watchdog; without one configured it does nothing
+func (n *Notifier) Watchdog() error {
+	if n.WatchdogInterval() == 0 {
+		return nil
+	}
+	return n.Notify("WATCHDOG=1")
+}
+
+// WatchdogInterval returns the watchdog timeout (WatchdogSec=), 0 when
+// there is none
+func (n *Notifier) WatchdogInterval() time.Duration {
+	if n == nil {
+		return 0
+	}
+	return n.watchdog
+}
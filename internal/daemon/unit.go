@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UnitOptions describes the unit file generated for the monitor itself
+type UnitOptions struct {
+	Description string
+	// Directory names the state and log directories under /var/lib and
+	// /var/log; the state directory is the working directory, so relative
+	// log and history paths end up there
+	Directory string
+	Binary    string        // absolute path of the monitor binary
+	Args      []string      // arguments after the binary, e.g. monitor --config ...
+	Watchdog  time.Duration // WatchdogSec=; 0 = no watchdog
+	// ReadWritePaths lists files or directories outside the state and log
+	// directories the monitor writes to (log file, history)
+	ReadWritePaths []string
+}
+
+// MinWatchdog is the smallest watchdog timeout WatchdogFor returns, so
+// short intervals don't make a busy host restart the monitor
+const MinWatchdog = time.Minute
+
+// WatchdogFor returns a WatchdogSec= for a monitor waking up every tick
+// whose status checks may take up to checkTimeout: a ping is expected
+// after every pass, so allow three passes plus one slow one
+func WatchdogFor(tick, checkTimeout time.Duration) time.Duration {
+	watchdog := 3*tick + checkTimeout
+	if watchdog < MinWatchdog {
+		watchdog = MinWatchdog
+	}
+	return watchdog.Round(time.Second)
+}
+
+// Unit renders a hardened Type=notify unit file. The monitor runs as root
+// because it restarts other units; the sandbox keeps it from writing
+// anywhere except its state and log directories and ReadWritePaths.
+func Unit(opts UnitOptions) string {
+	var b strings.Builder
+
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format+"\n", args...)
+	}
+
+	line("[Unit]")
+	line("Description=%s", opts.Description)
+	line("Documentation=https://github.com/andinianst93/systemd-monitoring")
+	line("Wants=network-online.target")
+	line("After=network-online.target")
+	line("")
+
+	line("[Service]")
+	line("Type=notify")
+	line("NotifyAccess=main")
+	line("ExecStart=%s", execLine(opts.Binary, opts.Args))
+	line("ExecReload=/bin/kill -HUP $MAINPID")
+	line("Restart=on-failure")
+	line("RestartSec=5s")
+	line("TimeoutStopSec=30s")
+	if opts.Watchdog > 0 {
+		line("WatchdogSec=%ds", int(opts.Watchdog/time.Second))
+	}
+	line("StateDirectory=%s", opts.Directory)
+	line("LogsDirectory=%s", opts.Directory)
+	// Shared ssh connections to remote hosts keep their sockets here
+	line("RuntimeDirectory=%s", opts.Directory)
+	line("WorkingDirectory=%%S/%s", opts.Directory)
+	line("")
+
+	line("# Hardening")
+	line("NoNewPrivileges=yes")
+	line("ProtectSystem=strict")
+	line("ProtectHome=read-only")
+	line("PrivateTmp=yes")
+	line("PrivateDevices=yes")
+	line("ProtectKernelTunables=yes")
+	line("ProtectKernelModules=yes")
+	line("ProtectKernelLogs=yes")
+	line("ProtectControlGroups=yes")
+	line("ProtectClock=yes")
+	line("ProtectHostname=yes")
+	line("RestrictNamespaces=yes")
+	line("RestrictRealtime=yes")
+	line("RestrictSUIDSGID=yes")
+	line("LockPersonality=yes")
+	line("MemoryDenyWriteExecute=yes")
+	line("SystemCallArchitectures=native")
+	line("SystemCallFilter=@system-service")
+	line("RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6 AF_NETLINK")
+	if len(opts.ReadWritePaths) > 0 {
+		line("ReadWritePaths=%s", strings.Join(quoteAll(opts.ReadWritePaths), " "))
+	}
+	line("")
+
+	line("[Install]")
+	line("WantedBy=multi-user.target")
+
+	return b.String()
+}
+
+// execLine joins a command line, quoting words systemd would split and
+// escaping "$" so it is not taken for a variable
+func execLine(binary string, args []string) string {
+	words := append([]string{binary}, args...)
+	for i, word := range words {
+		words[i] = strings.ReplaceAll(word, "$", "$$")
+	}
+	return strings.Join(quoteAll(words), " ")
+}
+
+// quoteAll escapes "%" specifiers and quotes words containing whitespace,
+// quotes or backslashes
+func quoteAll(words []string) []string {
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.ReplaceAll(word, "%", "%%")
+		if word == "" || strings.ContainsAny(word, " \t\"'\\") {
+			word = `"` + quote.Replace(word) + `"`
+		}
+		quoted = append(quoted, word)
+	}
+	return quoted
+}
@@ -0,0 +1,676 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+	"github.com/andinianst93/systemd-monitoring/internal/systemd"
+)
+
+// Options configures the dashboard
+type Options struct {
+	UnitType      models.UnitType // units listed in "all" mode; suffix for watchlist names
+	Watchlist     []string        // units shown in watchlist mode
+	Interval      time.Duration   // how often statuses are refreshed
+	LogLines      int             // journal lines shown when a unit is selected
+	ActionTimeout time.Duration   // wait for restart/stop jobs
+}
+
+// sortColumn is a column the unit list can be sorted by
+type sortColumn int
+
+const (
+	sortStatus sortColumn = iota
+	sortName
+	sortCPU
+	sortUptime
+	sortMemory
+	sortRestarts
+)
+
+var sortColumnNames = []string{"status", "name", "cpu", "uptime", "memory", "restarts"}
+
+const (
+	// maxLogEntries bounds the log pane's buffer
+	maxLogEntries = 1000
+
+	// logDelay debounces log streams while the selection moves, so
+	// scrolling through the list does not start a journalctl per row
+	logDelay = 300 * time.Millisecond
+)
+
+// refreshResult is the outcome of one status refresh
+type refreshResult struct {
+	watch bool // fetched for watchlist mode
+	units []*models.ServiceInfo
+	at    time.Time
+	err   error
+}
+
+// pendingAction is an action waiting for confirmation
+type pendingAction struct {
+	action systemd.ControlAction
+	unit   string
+}
+
+// actionResult is the outcome of a confirmed action
+type actionResult struct {
+	action  systemd.ControlAction
+	unit    string
+	service *models.ServiceInfo
+	err     error
+}
+
+// Dashboard is a full-screen, auto-refreshing view of systemd units
+type Dashboard struct {
+	client *systemd.Client
+	opts   Options
+	term   *terminal
+	out    *bufio.Writer
+
+	// Unit list
+	units       []*models.ServiceInfo // last refresh
+	rows        []*models.ServiceInfo // units after filter and sort
+	refreshing  bool
+	refreshedAt time.Time
+	refreshErr  error
+	refreshed   chan refreshResult
+
+	// Watchlist
+	watchlist []string // unit names in the order given
+	watched   map[string]bool
+	watchMode bool
+
+	// View state
+	sortBy    sortColumn
+	reverse   bool
+	filter    string
+	filtering bool
+	selected  string // name of the selected unit
+	cursor    int
+	offset    int
+
+	// Actions
+	confirm    *pendingAction
+	actionDone chan actionResult
+	message    string
+	messageErr bool
+
+	// Log pane
+	logUnit   string
+	logs      *models.LogBuffer
+	logErr    string
+	logChan   <-chan *models.LogEntry
+	logErrs   <-chan error
+	logCancel context.CancelFunc
+	logTimer  *time.Timer
+}
+
+// New creates a dashboard for client. It starts in watchlist mode when a
+// watchlist is given.
+func New(client *systemd.Client, opts Options) *Dashboard {
+	if opts.Interval <= 0 {
+		opts.Interval = 2 * time.Second
+	}
+	if opts.LogLines <= 0 {
+		opts.LogLines = 100
+	}
+	if opts.ActionTimeout <= 0 {
+		opts.ActionTimeout = 30 * time.Second
+	}
+
+	d := &Dashboard{
+		client:     client,
+		opts:       opts,
+		out:        bufio.NewWriterSize(os.Stdout, 64*1024),
+		watched:    make(map[string]bool),
+		refreshed:  make(chan refreshResult, 1),
+		actionDone: make(chan actionResult, 4),
+		logs:       &models.LogBuffer{MaxSize: maxLogEntries},
+	}
+
+	for _, name := range opts.Watchlist {
+		unitName := models.NormalizeUnitName(name, opts.UnitType)
+		if !d.watched[unitName] {
+			d.watched[unitName] = true
+			d.watchlist = append(d.watchlist, unitName)
+		}
+	}
+	d.watchMode = len(d.watchlist) > 0
+
+	return d
+}
+
+// Run takes over the terminal until the user quits
+func (d *Dashboard) Run() error {
+	// 1. Switch to raw mode and the alternate screen
+	term, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	d.term = term
+	defer d.close()
+
+	d.out.WriteString("\033[?1049h\033[?25l")
+
+	keys := make(chan []key)
+	go readKeys(keys)
+
+	resize := make(chan os.Signal, 1)
+	if signals := resizeSignals(); len(signals) > 0 {
+		signal.Notify(resize, signals...)
+		defer signal.Stop(resize)
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	d.logTimer = time.NewTimer(logDelay)
+	d.logTimer.Stop()
+
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+
+	// 2. Redraw after every event
+	d.refresh()
+	for {
+		d.draw()
+
+		select {
+		case batch, ok := <-keys:
+			if !ok {
+				return errors.New("terminal input closed")
+			}
+			for _, k := range batch {
+				if !d.handleKey(k) {
+					return nil
+				}
+			}
+
+		case <-ticker.C:
+			d.refresh()
+
+		case result := <-d.refreshed:
+			d.applyRefresh(result)
+
+		case result := <-d.actionDone:
+			d.applyAction(result)
+
+		case entry, ok := <-d.logChan:
+			if !ok {
+				d.logChan = nil
+				continue
+			}
+			d.logs.Add(entry)
+			d.drainLogs()
+
+		case err, ok := <-d.logErrs:
+			if !ok {
+				d.logErrs = nil
+				continue
+			}
+			if err != nil {
+				d.logErr = err.Error()
+			}
+
+		case <-d.logTimer.C:
+			d.followLogs()
+
+		case <-resize:
+
+		case <-quit:
+			return nil
+		}
+	}
+}
+
+// close stops the log stream and gives the terminal back
+func (d *Dashboard) close() {
+	if d.logCancel != nil {
+		d.logCancel()
+	}
+	d.out.WriteString("\033[0m\033[?25h\033[?1049l")
+	d.out.Flush()
+	d.term.restore()
+}
+
+// readKeys forwards key presses until stdin fails
+func readKeys(keys chan<- []key) {
+	buf := make([]byte, 256)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		if parsed := parseKeys(buf[:n]); len(parsed) > 0 {
+			keys <- parsed
+		}
+	}
+}
+
+// handleKey applies one key press; it returns false to quit
+func (d *Dashboard) handleKey(k key) bool {
+	if k.code == keyCtrlC {
+		return false
+	}
+
+	// 1. A pending action takes y to confirm, anything else cancels
+	if d.confirm != nil {
+		pending := d.confirm
+		d.confirm = nil
+		if k.code == keyRune && (k.r == 'y' || k.r == 'Y') {
+			d.runAction(pending.action, pending.unit)
+		} else {
+			d.setMessage("Cancelled", false)
+		}
+		return true
+	}
+
+	// 2. The filter box takes text until Enter or Escape
+	if d.filtering {
+		switch k.code {
+		case keyEnter:
+			d.filtering = false
+		case keyEscape:
+			d.filtering = false
+			d.filter = ""
+		case keyBackspace:
+			if runes := []rune(d.filter); len(runes) > 0 {
+				d.filter = string(runes[:len(runes)-1])
+			}
+		case keyRune:
+			d.filter += string(k.r)
+		}
+		d.applyView()
+		return true
+	}
+
+	// 3. Navigation and commands
+	d.message = ""
+	page := d.tableRows()
+
+	switch k.code {
+	case keyUp:
+		d.moveCursor(-1)
+	case keyDown:
+		d.moveCursor(1)
+	case keyPageUp:
+		d.moveCursor(-page)
+	case keyPageDown:
+		d.moveCursor(page)
+	case keyHome:
+		d.moveCursor(-len(d.rows))
+	case keyEnd:
+		d.moveCursor(len(d.rows))
+	case keyEscape:
+		d.filter = ""
+		d.applyView()
+	case keyRune:
+		switch k.r {
+		case 'q':
+			return false
+		case 'k':
+			d.moveCursor(-1)
+		case 'j':
+			d.moveCursor(1)
+		case 'g':
+			d.moveCursor(-len(d.rows))
+		case 'G':
+			d.moveCursor(len(d.rows))
+		case '/':
+			d.filtering = true
+		case 's':
+			d.sortBy = (d.sortBy + 1) % sortColumn(len(sortColumnNames))
+			d.reverse = false
+			d.applyView()
+		case 'S':
+			d.reverse = !d.reverse
+			d.applyView()
+		case 'w':
+			d.toggleMode()
+		case ' ':
+			d.toggleWatched()
+		case 'r':
+			d.askAction(systemd.ActionRestart)
+		case 'x':
+			d.askAction(systemd.ActionStop)
+		}
+	}
+
+	return true
+}
+
+// moveCursor moves the selection by delta rows, clamped to the list
+func (d *Dashboard) moveCursor(delta int) {
+	if len(d.rows) == 0 {
+		return
+	}
+	d.cursor = max(0, min(len(d.rows)-1, d.cursor+delta))
+	d.selected = d.rows[d.cursor].Name
+	d.scheduleLogs()
+}
+
+// toggleMode switches between all units and the watchlist
+func (d *Dashboard) toggleMode() {
+	if !d.watchMode && len(d.watchlist) == 0 {
+		d.setMessage("The watchlist is empty; add units with space", true)
+		return
+	}
+
+	d.watchMode = !d.watchMode
+	d.units = nil
+	d.refreshedAt = time.Time{}
+	d.applyView()
+	d.refresh()
+}
+
+// toggleWatched adds the selected unit to the watchlist or removes it
+func (d *Dashboard) toggleWatched() {
+	if d.selected == "" {
+		return
+	}
+
+	if !d.watched[d.selected] {
+		d.watched[d.selected] = true
+		d.watchlist = append(d.watchlist, d.selected)
+		d.setMessage("Added "+d.selected+" to the watchlist", false)
+		return
+	}
+
+	delete(d.watched, d.selected)
+	for i, name := range d.watchlist {
+		if name == d.selected {
+			d.watchlist = append(d.watchlist[:i], d.watchlist[i+1:]...)
+			break
+		}
+	}
+	d.setMessage("Removed "+d.selected+" from the watchlist", false)
+
+	// The watchlist view drops the unit right away
+	if d.watchMode {
+		d.units = d.watchedUnits(d.units)
+		d.applyView()
+	}
+}
+
+// askAction asks to confirm action on the selected unit
+func (d *Dashboard) askAction(action systemd.ControlAction) {
+	if d.selected == "" {
+		return
+	}
+	d.confirm = &pendingAction{action: action, unit: d.selected}
+}
+
+// runAction runs a confirmed action in the background
+func (d *Dashboard) runAction(action systemd.ControlAction, unit string) {
+	d.setMessage(fmt.Sprintf("Running %s on %s...", action, unit), false)
+
+	go func() {
+		service, err := d.client.ControlUnit(context.Background(), action, unit, d.opts.ActionTimeout)
+		d.actionDone <- actionResult{action: action, unit: unit, service: service, err: err}
+	}()
+}
+
+// applyAction reports an action's outcome and refreshes the list
+func (d *Dashboard) applyAction(result actionResult) {
+	if result.err != nil {
+		d.setMessage(fmt.Sprintf("Failed to %s %s: %v", result.action, result.unit, result.err), true)
+	} else {
+		status := ""
+		if result.service != nil {
+			status = fmt.Sprintf(" (now %s)", result.service.Status)
+		}
+		d.setMessage(fmt.Sprintf("%s %s: done%s", capitalize(string(result.action)), result.unit, status), false)
+	}
+
+	// Show the new status before the next refresh comes in
+	if result.service != nil {
+		for i, unit := range d.units {
+			if unit.Name == result.unit {
+				d.units[i] = result.service
+			}
+		}
+		d.applyView()
+	}
+	d.refresh()
+}
+
+func (d *Dashboard) setMessage(message string, isErr bool) {
+	d.message = message
+	d.messageErr = isErr
+}
+
+// refresh fetches unit statuses in the background unless a fetch is
+// already running
+func (d *Dashboard) refresh() {
+	if d.refreshing {
+		return
+	}
+	d.refreshing = true
+
+	watch := d.watchMode
+	names := append([]string(nil), d.watchlist...)
+
+	go func() {
+		result := refreshResult{watch: watch}
+		if watch {
+			// Watched units are queried one by one so inactive units that
+			// list-units would skip still show up
+			for _, status := range d.client.GetServiceStatuses(context.Background(), names, systemd.CheckOptions{}) {
+				service := status.Service
+				if status.Err != nil {
+					if result.err == nil {
+						result.err = status.Err
+					}
+					service = models.NewServiceInfo(status.Name)
+				}
+				result.units = append(result.units, service)
+			}
+		} else {
+			result.units, result.err = d.client.ListUnitStatuses(context.Background(), d.opts.UnitType, nil)
+		}
+		result.at = time.Now()
+		d.refreshed <- result
+	}()
+}
+
+// applyRefresh takes over a refresh result
+func (d *Dashboard) applyRefresh(result refreshResult) {
+	d.refreshing = false
+
+	// The mode changed while fetching
+	if result.watch != d.watchMode {
+		d.refresh()
+		return
+	}
+
+	d.refreshErr = result.err
+	if result.err != nil && !result.watch {
+		return
+	}
+
+	d.units = result.units
+	if result.watch {
+		d.units = d.watchedUnits(result.units)
+	}
+	d.refreshedAt = result.at
+	d.applyView()
+}
+
+// watchedUnits drops units that have left the watchlist
+func (d *Dashboard) watchedUnits(units []*models.ServiceInfo) []*models.ServiceInfo {
+	kept := make([]*models.ServiceInfo, 0, len(units))
+	for _, unit := range units {
+		if d.watched[unit.Name] {
+			kept = append(kept, unit)
+		}
+	}
+	return kept
+}
+
+// applyView filters and sorts the units into rows and keeps the selected
+// unit selected if it is still listed
+func (d *Dashboard) applyView() {
+	// 1. Filter
+	filter := strings.ToLower(d.filter)
+	d.rows = d.rows[:0]
+	for _, unit := range d.units {
+		if filter == "" || strings.Contains(strings.ToLower(unit.Name), filter) {
+			d.rows = append(d.rows, unit)
+		}
+	}
+
+	// 2. Sort; ties are ordered by name
+	sort.SliceStable(d.rows, func(i, j int) bool {
+		a, b := d.rows[i], d.rows[j]
+		if d.reverse {
+			a, b = b, a
+		}
+		if c := compareUnits(a, b, d.sortBy); c != 0 {
+			return c < 0
+		}
+		return a.Name < b.Name
+	})
+
+	// 3. Follow the selected unit
+	found := false
+	for i, unit := range d.rows {
+		if unit.Name == d.selected {
+			d.cursor = i
+			found = true
+			break
+		}
+	}
+	if !found {
+		d.cursor = max(0, min(d.cursor, len(d.rows)-1))
+		d.selected = ""
+		if len(d.rows) > 0 {
+			d.selected = d.rows[d.cursor].Name
+		}
+	}
+	d.scheduleLogs()
+}
+
+// compareUnits compares two units by column; status puts failed units first
+func compareUnits(a, b *models.ServiceInfo, column sortColumn) int {
+	switch column {
+	case sortStatus:
+		return statusRank(a.Status) - statusRank(b.Status)
+	case sortCPU:
+		if a.CPUPercent != b.CPUPercent {
+			if a.CPUPercent < b.CPUPercent {
+				return -1
+			}
+			return 1
+		}
+		return compareInt64(int64(a.CPUUsage), int64(b.CPUUsage))
+	case sortUptime:
+		return compareInt64(int64(a.Uptime), int64(b.Uptime))
+	case sortMemory:
+		return compareInt64(a.MemoryBytes, b.MemoryBytes)
+	case sortRestarts:
+		return compareInt64(int64(a.Restarts), int64(b.Restarts))
+	}
+	return strings.Compare(a.Name, b.Name)
+}
+
+func statusRank(status models.ServiceStatus) int {
+	switch status {
+	case models.StatusFailed:
+		return 0
+	case models.StatusUnknown:
+		return 1
+	case models.StatusStopped:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// scheduleLogs starts following the selected unit's logs once the
+// selection has settled
+func (d *Dashboard) scheduleLogs() {
+	if d.logTimer == nil || d.selected == d.logUnit {
+		return
+	}
+	d.logTimer.Reset(logDelay)
+}
+
+// followLogs replaces the log pane's stream with one for the selected unit
+func (d *Dashboard) followLogs() {
+	if d.selected == d.logUnit {
+		return
+	}
+
+	// 1. Stop the previous stream
+	if d.logCancel != nil {
+		d.logCancel()
+		d.logCancel = nil
+	}
+	d.logChan, d.logErrs = nil, nil
+	d.logs.Clear()
+	d.logErr = ""
+	d.logUnit = d.selected
+	if d.logUnit == "" {
+		return
+	}
+
+	// 2. Follow the new unit, starting with its last lines
+	opts := models.NewLogOptions()
+	opts.Lines = d.opts.LogLines
+	opts.Follow = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	logChan, errChan, err := d.client.GetServiceLogsStream(ctx, d.logUnit, opts)
+	if err != nil {
+		cancel()
+		d.logErr = err.Error()
+		return
+	}
+	d.logCancel = cancel
+	d.logChan, d.logErrs = logChan, errChan
+}
+
+// drainLogs adds entries that are already waiting, so a burst of lines
+// causes one redraw instead of one per line
+func (d *Dashboard) drainLogs() {
+	for {
+		select {
+		case entry, ok := <-d.logChan:
+			if !ok {
+				d.logChan = nil
+				return
+			}
+			d.logs.Add(entry)
+		default:
+			return
+		}
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
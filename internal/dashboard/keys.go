@@ -0,0 +1,102 @@
+package dashboard
+
+import "unicode/utf8"
+
+// keyCode identifies a special key; printable keys use keyRune
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyEscape
+	keyBackspace
+	keyCtrlC
+)
+
+// key is one key press
+type key struct {
+	code keyCode
+	r    rune // set for keyRune
+}
+
+// escapeKeys maps the escape sequences sent by common terminals
+var escapeKeys = map[string]keyCode{
+	"[A": keyUp, "OA": keyUp,
+	"[B": keyDown, "OB": keyDown,
+	"[5~": keyPageUp, "[6~": keyPageDown,
+	"[H": keyHome, "OH": keyHome, "[1~": keyHome, "[7~": keyHome,
+	"[F": keyEnd, "OF": keyEnd, "[4~": keyEnd, "[8~": keyEnd,
+}
+
+// parseKeys splits one read from the terminal into key presses. Escape
+// sequences arrive in a single read, so a lone ESC is the Escape key.
+// Unknown sequences are dropped.
+func parseKeys(buf []byte) []key {
+	var keys []key
+	for len(buf) > 0 {
+		switch b := buf[0]; {
+		case b == 0x1b:
+			if len(buf) == 1 {
+				keys = append(keys, key{code: keyEscape})
+				buf = buf[1:]
+				continue
+			}
+			n := escapeLength(buf)
+			if code, ok := escapeKeys[string(buf[1:n])]; ok {
+				keys = append(keys, key{code: code})
+			}
+			buf = buf[n:]
+
+		case b == '\r' || b == '\n':
+			keys = append(keys, key{code: keyEnter})
+			buf = buf[1:]
+
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, key{code: keyBackspace})
+			buf = buf[1:]
+
+		case b == 0x03:
+			keys = append(keys, key{code: keyCtrlC})
+			buf = buf[1:]
+
+		case b < 0x20:
+			buf = buf[1:]
+
+		default:
+			r, size := utf8.DecodeRune(buf)
+			keys = append(keys, key{code: keyRune, r: r})
+			buf = buf[size:]
+		}
+	}
+	return keys
+}
+
+// escapeLength returns the length of the escape sequence at the start of
+// buf: ESC [ params final, ESC O final, or ESC followed by one byte
+func escapeLength(buf []byte) int {
+	if len(buf) < 2 {
+		return len(buf)
+	}
+	switch buf[1] {
+	case '[':
+		for i := 2; i < len(buf); i++ {
+			if buf[i] >= 0x40 && buf[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(buf)
+	case 'O':
+		if len(buf) >= 3 {
+			return 3
+		}
+		return len(buf)
+	default:
+		return 2
+	}
+}
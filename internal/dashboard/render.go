@@ -0,0 +1,376 @@
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+	"github.com/andinianst93/systemd-monitoring/internal/output"
+)
+
+const (
+	attrReset   = "\033[0m"
+	attrBold    = "\033[1m"
+	attrReverse = "\033[7m"
+	attrDim     = "\033[2m"
+	colorCyan   = "\033[36m"
+	colorNormal = "\033[39m" // default foreground; keeps reverse video on
+)
+
+// Table layout: " * " + name + "  " + status(8) + "  " + sub(9) + "  " +
+// cpu(6) + "  " + uptime(9) + "  " + memory(8) + "  " + restarts(4) + " "
+const (
+	tableFixedWidth = 3 + 2 + 8 + 2 + 9 + 2 + 6 + 2 + 9 + 2 + 8 + 2 + 4 + 1
+	minNameWidth    = 20
+	maxNameWidth    = 40
+	minLogWidth     = 30
+)
+
+// layout is where the table and the log pane go on the screen
+type layout struct {
+	width, height int
+	tableWidth    int
+	nameWidth     int
+	tableRows     int
+	side          bool // log pane right of the table, otherwise below
+	logWidth      int
+	logRows       int
+}
+
+// computeLayout puts the log pane beside the table when the terminal is
+// wide enough and below it otherwise
+func computeLayout(width, height int) layout {
+	l := layout{width: width, height: height}
+	body := height - 4 // title, column header, status line, help line
+
+	minTable := tableFixedWidth + minNameWidth
+	if width >= minTable+1+minLogWidth {
+		l.side = true
+		l.tableWidth = min(max(minTable, width*11/20), tableFixedWidth+maxNameWidth, width-1-minLogWidth)
+		l.logWidth = width - l.tableWidth - 1
+		l.tableRows = body
+		l.logRows = body
+	} else {
+		l.tableWidth = width
+		l.logWidth = width
+		l.tableRows = max(1, body/2)
+		l.logRows = body - l.tableRows - 1
+	}
+	l.nameWidth = max(8, l.tableWidth-tableFixedWidth)
+
+	return l
+}
+
+// tableRows returns how many units fit on the screen
+func (d *Dashboard) tableRows() int {
+	width, height := d.term.size()
+	return max(1, computeLayout(width, height).tableRows)
+}
+
+// draw repaints the whole screen
+func (d *Dashboard) draw() {
+	width, height := d.term.size()
+	screen := make([]string, height)
+
+	if width < 40 || height < 8 {
+		screen[0] = fit("Terminal too small", width)
+		d.flush(screen)
+		return
+	}
+
+	l := computeLayout(width, height)
+
+	// 1. Keep the cursor visible
+	if d.cursor < d.offset {
+		d.offset = d.cursor
+	}
+	if d.cursor >= d.offset+l.tableRows {
+		d.offset = d.cursor - l.tableRows + 1
+	}
+	d.offset = max(0, min(d.offset, len(d.rows)-l.tableRows))
+
+	// 2. Fixed lines
+	screen[0] = attrReverse + fit(d.titleLine(), width) + attrReset
+	screen[height-2] = d.statusLine(width)
+	screen[height-1] = attrReverse + fit(" ↑↓ select  / filter  s sort  S reverse  w watchlist  space watch  r restart  x stop  q quit", width) + attrReset
+
+	// 3. Table and log pane
+	table := d.tableLines(l)
+	logs := d.logLines(l.logWidth, l.logRows)
+	logTitle := attrBold + fit(d.logTitle(), l.logWidth) + attrReset
+
+	if l.side {
+		separator := attrDim + "│" + attrReset
+		for i := 0; i < len(table); i++ {
+			right := logTitle
+			if i > 0 {
+				right = logs[i-1]
+			}
+			screen[1+i] = table[i] + separator + right
+		}
+	} else {
+		row := 1
+		for _, line := range table {
+			screen[row] = line
+			row++
+		}
+		screen[row] = attrReverse + fit(d.logTitle(), width) + attrReset
+		row++
+		for _, line := range logs {
+			screen[row] = line
+			row++
+		}
+	}
+
+	d.flush(screen)
+}
+
+// flush writes the screen line by line at absolute positions
+func (d *Dashboard) flush(screen []string) {
+	for i, line := range screen {
+		fmt.Fprintf(d.out, "\033[%d;1H%s\033[K", i+1, line)
+	}
+	d.out.Flush()
+}
+
+// titleLine shows the mode and the status counters
+func (d *Dashboard) titleLine() string {
+	mode := fmt.Sprintf("All %s units", d.opts.UnitType)
+	if d.opts.UnitType == models.UnitAll {
+		mode = "All units"
+	}
+	if d.watchMode {
+		mode = fmt.Sprintf("Watchlist (%d)", len(d.watchlist))
+	}
+	if scope := d.client.Scope(); scope.User != "" {
+		mode += " of the " + scope.String()
+	}
+
+	var running, failed, stopped int
+	for _, unit := range d.units {
+		switch unit.Status {
+		case models.StatusRunning:
+			running++
+		case models.StatusFailed:
+			failed++
+		case models.StatusStopped:
+			stopped++
+		}
+	}
+
+	updated := "loading..."
+	if !d.refreshedAt.IsZero() {
+		updated = "updated " + d.refreshedAt.Format("15:04:05")
+	}
+
+	return fmt.Sprintf(" SYSTEMD DASHBOARD │ %s │ Total: %d  Running: %d  Failed: %d  Stopped: %d │ %s",
+		mode, len(d.units), running, failed, stopped, updated)
+}
+
+// tableLines renders the column header and the visible units
+func (d *Dashboard) tableLines(l layout) []string {
+	lines := make([]string, 0, l.tableRows+1)
+
+	// 1. Column header; the sort column carries an arrow
+	headers := []string{"UNIT", "STATUS", "SUB", "CPU", "UPTIME", "MEMORY", "RST"}
+	sortHeader := map[sortColumn]int{sortName: 0, sortStatus: 1, sortCPU: 3, sortUptime: 4, sortMemory: 5, sortRestarts: 6}
+	arrow := "↑"
+	if d.reverse {
+		arrow = "↓"
+	}
+	headers[sortHeader[d.sortBy]] += arrow
+
+	header := fmt.Sprintf("   %s  %s  %s  %s  %s  %s  %s ",
+		fit(headers[0], l.nameWidth), fit(headers[1], 8), fit(headers[2], 9), fitRight(headers[3], 6),
+		fitRight(headers[4], 9), fitRight(headers[5], 8), fitRight(headers[6], 4))
+	lines = append(lines, attrBold+fit(header, l.tableWidth)+attrReset)
+
+	// 2. Units
+	for i := 0; i < l.tableRows; i++ {
+		index := d.offset + i
+		if index >= len(d.rows) {
+			line := ""
+			if i == 0 {
+				line = "   " + d.emptyText()
+			}
+			lines = append(lines, fit(line, l.tableWidth))
+			continue
+		}
+		lines = append(lines, d.unitLine(d.rows[index], index == d.cursor, l))
+	}
+
+	return lines
+}
+
+// unitLine renders one unit; the selected one in reverse video
+func (d *Dashboard) unitLine(unit *models.ServiceInfo, selected bool, l layout) string {
+	mark := " "
+	if d.watched[unit.Name] {
+		mark = "*"
+	}
+
+	cpu, uptime, memory := "-", "-", "-"
+	if unit.CPUSampled {
+		cpu = fmt.Sprintf("%.1f%%", unit.CPUPercent)
+	}
+	if unit.IsRunning() {
+		uptime = unit.GetUptimeString()
+	}
+	if unit.MemoryBytes > 0 {
+		memory = unit.MemoryUsage
+	}
+
+	// The status cell is colored; colorNormal ends it without dropping the
+	// selection's reverse video
+	line := fmt.Sprintf(" %s %s  %s%s%s  %s  %s  %s  %s  %s ",
+		mark,
+		fit(unit.Name, l.nameWidth),
+		statusColor(unit.Status), fit(string(unit.Status), 8), colorNormal,
+		fit(unit.SubState, 9),
+		fitRight(cpu, 6),
+		fitRight(uptime, 9),
+		fitRight(memory, 8),
+		fitRight(fmt.Sprint(unit.Restarts), 4))
+	line += strings.Repeat(" ", max(0, l.tableWidth-tableFixedWidth-l.nameWidth))
+
+	if selected {
+		return attrReverse + line + attrReset
+	}
+	return line + attrReset
+}
+
+// emptyText explains an empty list
+func (d *Dashboard) emptyText() string {
+	switch {
+	case d.refreshedAt.IsZero() && d.refreshErr == nil:
+		return "Loading..."
+	case d.filter != "":
+		return "No units match the filter"
+	default:
+		return "No units"
+	}
+}
+
+func statusColor(status models.ServiceStatus) string {
+	switch status {
+	case models.StatusRunning:
+		return output.ColorGreen
+	case models.StatusFailed:
+		return output.ColorRed
+	case models.StatusStopped:
+		return output.ColorYellow
+	default:
+		return output.ColorWhite
+	}
+}
+
+func (d *Dashboard) logTitle() string {
+	if d.logUnit == "" {
+		return " Logs"
+	}
+	return " Logs: " + d.logUnit
+}
+
+// logLines renders the newest log entries that fit in rows lines
+func (d *Dashboard) logLines(width, rows int) []string {
+	lines := make([]string, 0, rows)
+	if rows <= 0 {
+		return lines
+	}
+
+	available := rows
+	if d.logErr != "" {
+		available--
+	}
+
+	entries := d.logs.GetLatest(available)
+	for _, entry := range entries {
+		text := entry.Timestamp.Format("15:04:05") + " " + sanitize(entry.Message)
+		color := ""
+		switch {
+		case entry.Priority <= 3: // err and worse
+			color = output.ColorRed
+		case entry.Priority == 4: // warning
+			color = output.ColorYellow
+		}
+		lines = append(lines, color+fit(text, width)+attrReset)
+	}
+	if len(entries) == 0 && d.logUnit != "" && d.logErr == "" {
+		lines = append(lines, attrDim+fit(" No log entries yet", width)+attrReset)
+	}
+	if d.logErr != "" {
+		lines = append(lines, output.ColorRed+fit(" Log stream ended: "+d.logErr, width)+attrReset)
+	}
+
+	for len(lines) < rows {
+		lines = append(lines, fit("", width))
+	}
+	return lines
+}
+
+// statusLine shows the filter box, a confirmation prompt or the last
+// message, falling back to the sort and filter in effect
+func (d *Dashboard) statusLine(width int) string {
+	switch {
+	case d.filtering:
+		return fit(" Filter: "+d.filter+"_", width)
+	case d.confirm != nil:
+		prompt := fmt.Sprintf(" %s %s? [y/N]", capitalize(string(d.confirm.action)), d.confirm.unit)
+		return attrBold + output.ColorYellow + fit(prompt, width) + attrReset
+	case d.message != "":
+		color := output.ColorGreen
+		if d.messageErr {
+			color = output.ColorRed
+		}
+		return color + fit(" "+d.message, width) + attrReset
+	case d.refreshErr != nil:
+		return output.ColorRed + fit(" Refresh failed: "+d.refreshErr.Error(), width) + attrReset
+	}
+
+	order := "ascending"
+	if d.reverse {
+		order = "descending"
+	}
+	info := fmt.Sprintf(" Sort: %s (%s)", sortColumnNames[d.sortBy], order)
+	if d.filter != "" {
+		info += fmt.Sprintf("  Filter: %q (%d of %d)", d.filter, len(d.rows), len(d.units))
+	}
+	return colorCyan + fit(info, width) + attrReset
+}
+
+// fit pads or truncates s to exactly width columns
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	n := utf8.RuneCountInString(s)
+	if n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// fitRight is fit with the text aligned to the right
+func fitRight(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n >= width {
+		return fit(s, width)
+	}
+	return strings.Repeat(" ", width-n) + s
+}
+
+// sanitize removes control characters, including escape sequences that
+// a log message could use to mess up the screen
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0):
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,64 @@
+//go:build linux
+
+package dashboard
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminal puts the controlling terminal into raw mode and restores it
+type terminal struct {
+	fd    int
+	saved syscall.Termios
+}
+
+// openTerminal switches stdin to raw mode: no echo, no line buffering and
+// no signal keys, so every key press reaches the dashboard as typed
+func openTerminal() (*terminal, error) {
+	t := &terminal{fd: int(os.Stdin.Fd())}
+	if err := ioctl(t.fd, syscall.TCGETS, unsafe.Pointer(&t.saved)); err != nil {
+		return nil, errors.New("stdin is not a terminal")
+	}
+
+	raw := t.saved
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := ioctl(t.fd, syscall.TCSETS, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// restore puts the terminal back into the mode it had before
+func (t *terminal) restore() {
+	ioctl(t.fd, syscall.TCSETS, unsafe.Pointer(&t.saved))
+}
+
+// size returns the terminal size in columns and rows
+func (t *terminal) size() (int, int) {
+	var ws struct{ Row, Col, X, Y uint16 }
+	if err := ioctl(int(os.Stdout.Fd()), syscall.TIOCGWINSZ, unsafe.Pointer(&ws)); err != nil || ws.Col == 0 || ws.Row == 0 {
+		return 80, 24
+	}
+	return int(ws.Col), int(ws.Row)
+}
+
+// resizeSignals are the signals sent when the terminal is resized
+func resizeSignals() []os.Signal {
+	return []os.Signal{syscall.SIGWINCH}
+}
+
+func ioctl(fd int, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package dashboard
+
+import (
+	"errors"
+	"os"
+)
+
+// terminal is only implemented for Linux, the only platform with systemd
+type terminal struct{}
+
+func openTerminal() (*terminal, error) {
+	return nil, errors.New("the dashboard needs a Linux terminal")
+}
+
+func (t *terminal) restore() {}
+
+func (t *terminal) size() (int, int) {
+	return 80, 24
+}
+
+func resizeSignals() []os.Signal {
+	return nil
+}
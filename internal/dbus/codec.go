@@ -0,0 +1,644 @@
+package dbus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ObjectPath is a D-Bus object path (type code 'o')
+type ObjectPath string
+
+// Signature is a D-Bus type signature (type code 'g')
+type Signature string
+
+// Variant is a value together with its own signature (type code 'v')
+type Variant struct {
+	Signature Signature
+	Value     interface{}
+}
+
+// MakeVariant wraps a basic Go value into a Variant, inferring its signature
+func MakeVariant(value interface{}) Variant {
+	sig, err := signatureOf(value)
+	if err != nil {
+		sig = "s"
+		value = fmt.Sprint(value)
+	}
+	return Variant{Signature: Signature(sig), Value: value}
+}
+
+var errShortBuffer = errors.New("dbus: message truncated")
+
+// signatureOf infers the signature of the basic Go types we send as arguments
+func signatureOf(v interface{}) (string, error) {
+	switch v.(type) {
+	case byte:
+		return "y", nil
+	case bool:
+		return "b", nil
+	case int16:
+		return "n", nil
+	case uint16:
+		return "q", nil
+	case int32:
+		return "i", nil
+	case uint32:
+		return "u", nil
+	case int64:
+		return "x", nil
+	case uint64:
+		return "t", nil
+	case float64:
+		return "d", nil
+	case string:
+		return "s", nil
+	case ObjectPath:
+		return "o", nil
+	case Signature:
+		return "g", nil
+	case Variant:
+		return "v", nil
+	case []string:
+		return "as", nil
+	case []ObjectPath:
+		return "ao", nil
+	case map[string]Variant:
+		return "a{sv}", nil
+	}
+	return "", fmt.Errorf("dbus: cannot infer signature for %T", v)
+}
+
+// splitSignature splits a signature into its complete types
+func splitSignature(sig string) ([]string, error) {
+	var types []string
+	for sig != "" {
+		single, rest, err := nextType(sig)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, single)
+		sig = rest
+	}
+	return types, nil
+}
+
+// nextType returns the first complete type of sig and the remainder
+func nextType(sig string) (string, string, error) {
+	if sig == "" {
+		return "", "", errors.New("dbus: empty signature")
+	}
+
+	switch sig[0] {
+	case 'a':
+		elem, rest, err := nextType(sig[1:])
+		if err != nil {
+			return "", "", err
+		}
+		return "a" + elem, rest, nil
+
+	case '(', '{':
+		closing := byte(')')
+		if sig[0] == '{' {
+			closing = '}'
+		}
+		depth := 0
+		for i := 0; i < len(sig); i++ {
+			switch sig[i] {
+			case '(', '{':
+				depth++
+			case ')', '}':
+				depth--
+				if depth == 0 {
+					if i == 1 {
+						return "", "", fmt.Errorf("dbus: empty container in %q", sig)
+					}
+					if sig[i] != closing {
+						return "", "", fmt.Errorf("dbus: mismatched brackets in %q", sig)
+					}
+					return sig[:i+1], sig[i+1:], nil
+				}
+			}
+		}
+		return "", "", fmt.Errorf("dbus: unterminated container in %q", sig)
+
+	case 'y', 'b', 'n', 'q', 'i', 'u', 'x', 't', 'd', 'h', 's', 'o', 'g', 'v':
+		return sig[:1], sig[1:], nil
+	}
+
+	return "", "", fmt.Errorf("dbus: unknown type code %q", sig[0])
+}
+
+// alignment returns the wire alignment for the type starting at sig[0]
+func alignment(sig string) int {
+	switch sig[0] {
+	case 'y', 'g', 'v':
+		return 1
+	case 'n', 'q':
+		return 2
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	}
+	return 4
+}
+
+// encoder marshals values in little-endian wire format
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) align(n int) {
+	for e.buf.Len()%n != 0 {
+		e.buf.WriteByte(0)
+	}
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.align(4)
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) encodeAll(sig string, values []interface{}) error {
+	types, err := splitSignature(sig)
+	if err != nil {
+		return err
+	}
+	if len(types) != len(values) {
+		return fmt.Errorf("dbus: signature %q expects %d values, got %d", sig, len(types), len(values))
+	}
+	for i, t := range types {
+		if err := e.encode(t, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) encode(sig string, v interface{}) error {
+	mismatch := fmt.Errorf("dbus: cannot encode %T as %q", v, sig)
+
+	switch sig[0] {
+	case 'y':
+		b, ok := v.(byte)
+		if !ok {
+			return mismatch
+		}
+		e.buf.WriteByte(b)
+
+	case 'b':
+		b, ok := v.(bool)
+		if !ok {
+			return mismatch
+		}
+		if b {
+			e.uint32(1)
+		} else {
+			e.uint32(0)
+		}
+
+	case 'n', 'q':
+		var u uint16
+		switch n := v.(type) {
+		case int16:
+			u = uint16(n)
+		case uint16:
+			u = n
+		default:
+			return mismatch
+		}
+		e.align(2)
+		var b [2]byte
+		binary.LittleEndian.PutUint16(b[:], u)
+		e.buf.Write(b[:])
+
+	case 'i', 'u', 'h':
+		switch n := v.(type) {
+		case int32:
+			e.uint32(uint32(n))
+		case uint32:
+			e.uint32(n)
+		case int:
+			e.uint32(uint32(n))
+		default:
+			return mismatch
+		}
+
+	case 'x', 't', 'd':
+		var u uint64
+		switch n := v.(type) {
+		case int64:
+			u = uint64(n)
+		case uint64:
+			u = n
+		case float64:
+			u = math.Float64bits(n)
+		default:
+			return mismatch
+		}
+		e.align(8)
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], u)
+		e.buf.Write(b[:])
+
+	case 's', 'o':
+		var s string
+		switch str := v.(type) {
+		case string:
+			s = str
+		case ObjectPath:
+			s = string(str)
+		default:
+			return mismatch
+		}
+		e.uint32(uint32(len(s)))
+		e.buf.WriteString(s)
+		e.buf.WriteByte(0)
+
+	case 'g':
+		var s string
+		switch str := v.(type) {
+		case Signature:
+			s = string(str)
+		case string:
+			s = str
+		default:
+			return mismatch
+		}
+		e.buf.WriteByte(byte(len(s)))
+		e.buf.WriteString(s)
+		e.buf.WriteByte(0)
+
+	case 'v':
+		variant, ok := v.(Variant)
+		if !ok {
+			variant = MakeVariant(v)
+		}
+		if err := e.encode("g", variant.Signature); err != nil {
+			return err
+		}
+		return e.encode(string(variant.Signature), variant.Value)
+
+	case 'a':
+		return e.encodeArray(sig, v)
+
+	case '(':
+		fields, ok := v.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		e.align(8)
+		return e.encodeAll(sig[1:len(sig)-1], fields)
+
+	default:
+		return mismatch
+	}
+
+	return nil
+}
+
+func (e *encoder) encodeArray(sig string, v interface{}) error {
+	elem := sig[1:]
+
+	// Reserve the length field, then pad to the element alignment;
+	// the padding is not part of the array length.
+	e.uint32(0)
+	lengthPos := e.buf.Len() - 4
+	e.align(alignment(elem))
+	start := e.buf.Len()
+
+	switch elem[0] {
+	case '{':
+		kv, err := splitSignature(elem[1 : len(elem)-1])
+		if err != nil || len(kv) != 2 {
+			return fmt.Errorf("dbus: invalid dict signature %q", sig)
+		}
+		switch m := v.(type) {
+		case map[string]Variant:
+			for _, k := range sortedKeys(m) {
+				e.align(8)
+				if err := e.encode(kv[0], k); err != nil {
+					return err
+				}
+				if err := e.encode(kv[1], m[k]); err != nil {
+					return err
+				}
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				e.align(8)
+				if err := e.encode(kv[0], k); err != nil {
+					return err
+				}
+				if err := e.encode(kv[1], m[k]); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("dbus: cannot encode %T as %q", v, sig)
+		}
+
+	default:
+		switch items := v.(type) {
+		case []string:
+			for _, item := range items {
+				if err := e.encode(elem, item); err != nil {
+					return err
+				}
+			}
+		case []ObjectPath:
+			for _, item := range items {
+				if err := e.encode(elem, item); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			for _, item := range items {
+				if err := e.encode(elem, item); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("dbus: cannot encode %T as %q", v, sig)
+		}
+	}
+
+	length := e.buf.Len() - start
+	binary.LittleEndian.PutUint32(e.buf.Bytes()[lengthPos:], uint32(length))
+	return nil
+}
+
+func sortedKeys(m map[string]Variant) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// maxDepth is the deepest nesting of arrays, structs and variants the
+// specification allows in a message
+const maxDepth = 64
+
+// decoder unmarshals values from wire format
+type decoder struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+	depth int // containers being decoded
+}
+
+// enter counts one more level of containers and refuses nesting beyond
+// maxDepth; a variant can nest without bound otherwise
+func (d *decoder) enter() error {
+	d.depth++
+	if d.depth > maxDepth {
+		return fmt.Errorf("dbus: values nested deeper than %d", maxDepth)
+	}
+	return nil
+}
+
+func (d *decoder) align(n int) error {
+	for d.pos%n != 0 {
+		d.pos++
+	}
+	if d.pos > len(d.data) {
+		return errShortBuffer
+	}
+	return nil
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if d.pos+n > len(d.data) {
+		return nil, errShortBuffer
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) uint32() (uint32, error) {
+	if err := d.align(4); err != nil {
+		return 0, err
+	}
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return d.order.Uint32(b), nil
+}
+
+func (d *decoder) decodeAll(sig string) ([]interface{}, error) {
+	types, err := splitSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, 0, len(types))
+	for _, t := range types {
+		v, err := d.decode(t)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (d *decoder) decode(sig string) (interface{}, error) {
+	switch sig[0] {
+	case 'v', 'a', '(':
+		defer func() { d.depth-- }()
+		if err := d.enter(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch sig[0] {
+	case 'y':
+		b, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0], nil
+
+	case 'b':
+		u, err := d.uint32()
+		return u != 0, err
+
+	case 'n', 'q':
+		if err := d.align(2); err != nil {
+			return nil, err
+		}
+		b, err := d.read(2)
+		if err != nil {
+			return nil, err
+		}
+		u := d.order.Uint16(b)
+		if sig[0] == 'n' {
+			return int16(u), nil
+		}
+		return u, nil
+
+	case 'i':
+		u, err := d.uint32()
+		return int32(u), err
+
+	case 'u', 'h':
+		return d.uint32()
+
+	case 'x', 't', 'd':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		b, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		u := d.order.Uint64(b)
+		switch sig[0] {
+		case 'x':
+			return int64(u), nil
+		case 'd':
+			return math.Float64frombits(u), nil
+		}
+		return u, nil
+
+	case 's', 'o':
+		n, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(int(n) + 1)
+		if err != nil {
+			return nil, err
+		}
+		if sig[0] == 'o' {
+			return ObjectPath(b[:n]), nil
+		}
+		return string(b[:n]), nil
+
+	case 'g':
+		return d.signature()
+
+	case 'v':
+		s, err := d.signature()
+		if err != nil {
+			return nil, err
+		}
+		if _, rest, err := nextType(string(s)); err != nil || rest != "" {
+			return nil, fmt.Errorf("dbus: invalid variant signature %q", s)
+		}
+		v, err := d.decode(string(s))
+		if err != nil {
+			return nil, err
+		}
+		return Variant{Signature: s, Value: v}, nil
+
+	case 'a':
+		return d.decodeArray(sig)
+
+	case '(':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		return d.decodeAll(sig[1 : len(sig)-1])
+	}
+
+	return nil, fmt.Errorf("dbus: cannot decode type %q", sig)
+}
+
+func (d *decoder) signature() (Signature, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return "", err
+	}
+	s, err := d.read(int(b[0]) + 1)
+	if err != nil {
+		return "", err
+	}
+	return Signature(s[:b[0]]), nil
+}
+
+func (d *decoder) decodeArray(sig string) (interface{}, error) {
+	elem := sig[1:]
+
+	n, err := d.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.align(alignment(elem)); err != nil {
+		return nil, err
+	}
+	end := d.pos + int(n)
+	if end > len(d.data) {
+		return nil, errShortBuffer
+	}
+
+	if elem[0] == '{' {
+		kv, err := splitSignature(elem[1 : len(elem)-1])
+		if err != nil || len(kv) != 2 {
+			return nil, fmt.Errorf("dbus: invalid dict signature %q", sig)
+		}
+
+		// String-keyed dicts (the common a{sv}) decode into a plain map
+		if kv[0] == "s" {
+			m := make(map[string]interface{})
+			for d.pos < end {
+				if err := d.align(8); err != nil {
+					return nil, err
+				}
+				k, err := d.decode(kv[0])
+				if err != nil {
+					return nil, err
+				}
+				v, err := d.decode(kv[1])
+				if err != nil {
+					return nil, err
+				}
+				m[k.(string)] = v
+			}
+			return m, nil
+		}
+
+		m := make(map[interface{}]interface{})
+		for d.pos < end {
+			if err := d.align(8); err != nil {
+				return nil, err
+			}
+			k, err := d.decode(kv[0])
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.decode(kv[1])
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	}
+
+	items := make([]interface{}, 0)
+	for d.pos < end {
+		start := d.pos
+		v, err := d.decode(elem)
+		if err != nil {
+			return nil, err
+		}
+		if d.pos == start {
+			return nil, fmt.Errorf("dbus: array of empty elements %q", sig)
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
@@ -0,0 +1,329 @@
+package dbus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSignature(t *testing.T) {
+	tests := []struct {
+		sig     string
+		want    []string
+		wantErr bool
+	}{
+		{sig: "s", want: []string{"s"}},
+		{sig: "sua{sv}", want: []string{"s", "u", "a{sv}"}},
+		{sig: "a(ssso)ao", want: []string{"a(ssso)", "ao"}},
+		{sig: "aa{s(iv)}y", want: []string{"aa{s(iv)}", "y"}},
+		{sig: "a", wantErr: true},
+		{sig: "(su", wantErr: true},
+		{sig: "(s}", wantErr: true},
+		{sig: "()", wantErr: true},
+		{sig: "z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := splitSignature(tt.sig)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("splitSignature(%q) = %v, want an error", tt.sig, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitSignature(%q): %v", tt.sig, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitSignature(%q) = %q, want %q", tt.sig, got, tt.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		sig   string
+		value interface{}
+		want  interface{} // decoded value; nil = same as value
+	}{
+		{name: "byte", sig: "y", value: byte(7)},
+		{name: "bool", sig: "b", value: true},
+		{name: "int16", sig: "n", value: int16(-2)},
+		{name: "uint16", sig: "q", value: uint16(65535)},
+		{name: "int32", sig: "i", value: int32(-5)},
+		{name: "uint32", sig: "u", value: uint32(1 << 31)},
+		{name: "int64", sig: "x", value: int64(-1 << 40)},
+		{name: "uint64", sig: "t", value: uint64(1 << 63)},
+		{name: "double", sig: "d", value: 1.5},
+		{name: "string", sig: "s", value: "nginx.service"},
+		{name: "empty string", sig: "s", value: ""},
+		{name: "object path", sig: "o", value: ObjectPath("/org/freedesktop/systemd1/unit/nginx_2eservice")},
+		{name: "signature", sig: "g", value: Signature("a{sv}")},
+		{name: "variant", sig: "v", value: Variant{Signature: "s", Value: "active"}},
+		{name: "variant of array", sig: "v", value: Variant{Signature: "as", Value: []string{"a", "b"}},
+			want: Variant{Signature: "as", Value: []interface{}{"a", "b"}}},
+		{name: "nested variant", sig: "v", value: Variant{Signature: "v", Value: Variant{Signature: "t", Value: uint64(42)}}},
+		{name: "string array", sig: "as", value: []string{"a", "bc"}, want: []interface{}{"a", "bc"}},
+		{name: "empty array", sig: "at", value: []interface{}{}},
+		{name: "path array", sig: "ao", value: []ObjectPath{"/a", "/b"}, want: []interface{}{ObjectPath("/a"), ObjectPath("/b")}},
+		{name: "struct", sig: "(su)", value: []interface{}{"a", uint32(1)}},
+		{name: "struct array", sig: "a(sx)", value: []interface{}{
+			[]interface{}{"a", int64(1)},
+			[]interface{}{"b", int64(2)},
+		}},
+		{name: "dict", sig: "a{sv}", value: map[string]Variant{
+			"ActiveState": {Signature: "s", Value: "active"},
+			"NRestarts":   {Signature: "u", Value: uint32(3)},
+		}, want: map[string]interface{}{
+			"ActiveState": Variant{Signature: "s", Value: "active"},
+			"NRestarts":   Variant{Signature: "u", Value: uint32(3)},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e encoder
+			if err := e.encode(tt.sig, tt.value); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			d := &decoder{data: e.buf.Bytes(), order: binary.LittleEndian}
+			got, err := d.decode(tt.sig)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			want := tt.want
+			if want == nil {
+				want = tt.value
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded %#v, want %#v", got, want)
+			}
+			if d.pos != len(d.data) {
+				t.Errorf("decoded %d of %d bytes", d.pos, len(d.data))
+			}
+		})
+	}
+}
+
+func TestEncodeAlignment(t *testing.T) {
+	tests := []struct {
+		name   string
+		sig    string
+		values []interface{}
+		want   []byte
+	}{
+		{
+			name:   "int64 after byte is padded to 8",
+			sig:    "yx",
+			values: []interface{}{byte(1), int64(2)},
+			want:   []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:   "uint16 after byte is padded to 2",
+			sig:    "yq",
+			values: []interface{}{byte(1), uint16(2)},
+			want:   []byte{1, 0, 2, 0},
+		},
+		{
+			name:   "string is length, bytes and a NUL",
+			sig:    "ys",
+			values: []interface{}{byte(1), "ab"},
+			want:   []byte{1, 0, 0, 0, 2, 0, 0, 0, 'a', 'b', 0},
+		},
+		{
+			name:   "signature has a one byte length",
+			sig:    "g",
+			values: []interface{}{Signature("as")},
+			want:   []byte{2, 'a', 's', 0},
+		},
+		{
+			// The padding after the length is not part of the array
+			name:   "empty array of int64 is padded to 8",
+			sig:    "at",
+			values: []interface{}{[]interface{}{}},
+			want:   []byte{0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:   "array length excludes padding",
+			sig:    "at",
+			values: []interface{}{[]interface{}{uint64(1)}},
+			want:   []byte{8, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:   "struct starts on 8",
+			sig:    "y(y)",
+			values: []interface{}{byte(1), []interface{}{byte(2)}},
+			want:   []byte{1, 0, 0, 0, 0, 0, 0, 0, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e encoder
+			if err := e.encodeAll(tt.sig, tt.values); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if !bytes.Equal(e.buf.Bytes(), tt.want) {
+				t.Errorf("encoded % x, want % x", e.buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeMismatch(t *testing.T) {
+	tests := []struct {
+		sig    string
+		values []interface{}
+	}{
+		{sig: "s", values: []interface{}{uint32(1)}},
+		{sig: "u", values: []interface{}{"1"}},
+		{sig: "as", values: []interface{}{"a"}},
+		{sig: "(su)", values: []interface{}{"a"}},
+		{sig: "su", values: []interface{}{"a"}},
+	}
+
+	for _, tt := range tests {
+		var e encoder
+		if err := e.encodeAll(tt.sig, tt.values); err == nil {
+			t.Errorf("encodeAll(%q, %#v) succeeded, want an error", tt.sig, tt.values)
+		}
+	}
+}
+
+func TestDecodeBigEndian(t *testing.T) {
+	data := []byte{0, 0, 0, 2, 'a', 'b', 0}
+	d := &decoder{data: data, order: binary.BigEndian}
+	got, err := d.decode("s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "ab" {
+		t.Errorf("decoded %q, want %q", got, "ab")
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	var e encoder
+	if err := e.encodeAll("sa{sv}", []interface{}{"nginx", map[string]Variant{"a": MakeVariant("b")}}); err != nil {
+		t.Fatal(err)
+	}
+	data := e.buf.Bytes()
+
+	for n := 0; n < len(data); n++ {
+		d := &decoder{data: data[:n], order: binary.LittleEndian}
+		if _, err := d.decodeAll("sa{sv}"); err == nil {
+			t.Errorf("decoding %d of %d bytes succeeded, want an error", n, len(data))
+		}
+	}
+}
+
+// nestedVariants encodes depth variants, each holding the next, around a
+// byte
+func nestedVariants(depth int) []byte {
+	var value interface{} = byte(1)
+	sig := Signature("y")
+	for i := 0; i < depth; i++ {
+		value = Variant{Signature: sig, Value: value}
+		sig = "v"
+	}
+
+	var e encoder
+	if err := e.encode("v", value); err != nil {
+		panic(err)
+	}
+	return e.buf.Bytes()
+}
+
+func TestDecodeDepthLimit(t *testing.T) {
+	tests := []struct {
+		depth   int
+		wantErr bool
+	}{
+		{depth: 1},
+		{depth: maxDepth},
+		{depth: maxDepth + 1, wantErr: true},
+		{depth: 10000, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		data := nestedVariants(tt.depth)
+		d := &decoder{data: data, order: binary.LittleEndian}
+		_, err := d.decode("v")
+		if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "nested")) {
+			t.Errorf("depth %d: err = %v, want a nesting error", tt.depth, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("depth %d: %v", tt.depth, err)
+		}
+	}
+}
+
+func TestDecodeInvalidVariant(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "two types", data: []byte{2, 's', 's', 0}},
+		{name: "unknown type", data: []byte{1, 'z', 0}},
+		{name: "empty struct", data: []byte{2, '(', ')', 0}},
+	}
+
+	for _, tt := range tests {
+		d := &decoder{data: tt.data, order: binary.LittleEndian}
+		if _, err := d.decode("v"); err == nil {
+			t.Errorf("%s: decode succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	msg := &message{
+		Type:        typeMethodCall,
+		Serial:      7,
+		Path:        "/org/freedesktop/systemd1",
+		Interface:   "org.freedesktop.systemd1.Manager",
+		Member:      "GetUnit",
+		Destination: "org.freedesktop.systemd1",
+		Signature:   "s",
+		Body:        []interface{}{"nginx.service"},
+	}
+
+	data, err := msg.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, msg) {
+		t.Errorf("read %#v, want %#v", got, msg)
+	}
+}
+
+func TestReadMessageRejectsGarbage(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "bad endianness", data: append([]byte{'x'}, make([]byte, 15)...)},
+		{name: "too large", data: []byte{'l', 1, 0, 1, 0xff, 0xff, 0xff, 0xff, 1, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "short header", data: []byte{'l', 1, 0}},
+	}
+
+	for _, tt := range tests {
+		_, err := readMessage(bytes.NewReader(tt.data))
+		if err == nil {
+			t.Errorf("%s: readMessage succeeded, want an error", tt.name)
+		}
+		if errors.Is(err, errShortBuffer) {
+			t.Errorf("%s: decoded past the end of the message", tt.name)
+		}
+	}
+}
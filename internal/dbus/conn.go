@@ -0,0 +1,450 @@
+package dbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Well-known names of the bus daemon itself
+const (
+	busName      = "org.freedesktop.DBus"
+	busPath      = ObjectPath("/org/freedesktop/DBus")
+	busInterface = "org.freedesktop.DBus"
+)
+
+// DefaultSystemBusAddress is used when DBUS_SYSTEM_BUS_ADDRESS is unset
+const DefaultSystemBusAddress = "unix:path=/run/dbus/system_bus_socket"
+
+// DefaultCallTimeout bounds how long Call waits for a reply
+const DefaultCallTimeout = 25 * time.Second
+
+// ErrClosed is returned for calls on a closed connection
+var ErrClosed = errors.New("dbus: connection closed")
+
+// Error is an error reply received from (or sent to) a peer
+type Error struct {
+	Name    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Name
+	}
+	return fmt.Sprintf("%s: %s", e.Name, e.Message)
+}
+
+// Handler serves method calls for one exported interface. It returns the
+// reply signature and values, or an error that is sent back as an error reply.
+type Handler func(method string, args []interface{}) (Signature, []interface{}, error)
+
+// Conn is an authenticated connection to a message bus
+type Conn struct {
+	conn       net.Conn
+	uniqueName string
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	serial  uint32
+	pending map[uint32]chan *message
+	objects map[ObjectPath]map[string]Handler
+	closed  bool
+	err     error
+}
+
+// SystemBus connects to the system bus
+func SystemBus() (*Conn, error) {
+	address := os.Getenv("DBUS_SYSTEM_BUS_ADDRESS")
+	if address == "" {
+		address = DefaultSystemBusAddress
+	}
+	return Dial(address)
+}
+
+// SessionBus connects to the session bus of the current user
+func SessionBus() (*Conn, error) {
+	address := os.Getenv("DBUS_SESSION_BUS_ADDRESS")
+	if address == "" {
+		address = fmt.Sprintf("unix:path=/run/user/%d/bus", os.Getuid())
+	}
+	return Dial(address)
+}
+
+// Dial connects to the bus at address (e.g. "unix:path=/tmp/bus"),
+// authenticates and registers with the bus daemon.
+func Dial(address string) (*Conn, error) {
+	var lastErr error
+
+	// An address may list several alternatives separated by ";"
+	for _, addr := range strings.Split(address, ";") {
+		if addr == "" {
+			continue
+		}
+		nc, err := dialAddress(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		c, err := newConn(nc)
+		if err != nil {
+			nc.Close()
+			lastErr = err
+			continue
+		}
+		return c, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("dbus: no usable address in %q", address)
+	}
+	return nil, lastErr
+}
+
+// dialAddress opens the transport for a single unix: address
+func dialAddress(addr string) (net.Conn, error) {
+	transport, params, ok := strings.Cut(addr, ":")
+	if !ok || transport != "unix" {
+		return nil, fmt.Errorf("dbus: unsupported address %q", addr)
+	}
+
+	for _, kv := range strings.Split(params, ",") {
+		key, value, _ := strings.Cut(kv, "=")
+		value = unescapeAddress(value)
+		switch key {
+		case "path":
+			return net.DialTimeout("unix", value, 5*time.Second)
+		case "abstract":
+			return net.DialTimeout("unix", "@"+value, 5*time.Second)
+		}
+	}
+
+	return nil, fmt.Errorf("dbus: address %q has no path", addr)
+}
+
+// unescapeAddress decodes %XX escapes used in bus addresses
+func unescapeAddress(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func newConn(nc net.Conn) (*Conn, error) {
+	if err := authenticate(nc); err != nil {
+		return nil, err
+	}
+
+	c := &Conn{
+		conn:    nc,
+		pending: make(map[uint32]chan *message),
+		objects: make(map[ObjectPath]map[string]Handler),
+	}
+	go c.readLoop()
+
+	reply, err := c.Call(busName, busPath, busInterface, "Hello")
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("dbus: Hello failed: %w", err)
+	}
+	if len(reply) > 0 {
+		c.uniqueName, _ = reply[0].(string)
+	}
+
+	return c, nil
+}
+
+// authenticate runs the SASL EXTERNAL handshake
+func authenticate(nc net.Conn) error {
+	nc.SetDeadline(time.Now().Add(5 * time.Second))
+	defer nc.SetDeadline(time.Time{})
+
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := fmt.Fprintf(nc, "\x00AUTH EXTERNAL %s\r\n", uid); err != nil {
+		return fmt.Errorf("dbus: auth failed: %w", err)
+	}
+
+	// Read byte by byte so nothing after the OK line is buffered away
+	line, err := readLine(nc)
+	if err != nil {
+		return fmt.Errorf("dbus: auth failed: %w", err)
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("dbus: auth rejected: %s", line)
+	}
+
+	if _, err := fmt.Fprint(nc, "BEGIN\r\n"); err != nil {
+		return fmt.Errorf("dbus: auth failed: %w", err)
+	}
+	return nil
+}
+
+func readLine(nc net.Conn) (string, error) {
+	var b strings.Builder
+	buf := make([]byte, 1)
+	for {
+		if _, err := nc.Read(buf); err != nil {
+			return "", err
+		}
+		if buf[0] == '\n' {
+			return strings.TrimSuffix(b.String(), "\r"), nil
+		}
+		b.WriteByte(buf[0])
+		if b.Len() > 4096 {
+			return "", errors.New("auth line too long")
+		}
+	}
+}
+
+// UniqueName returns the name the bus assigned to this connection
+func (c *Conn) UniqueName() string {
+	return c.uniqueName
+}
+
+// Call invokes a method and waits for its reply
+func (c *Conn) Call(dest string, path ObjectPath, iface, method string, args ...interface{}) ([]interface{}, error) {
+	return c.CallContext(context.Background(), dest, path, iface, method, args...)
+}
+
+// CallContext is Call that also gives up waiting when ctx is done. The
+// call itself cannot be withdrawn; a late reply is dropped.
+func (c *Conn) CallContext(ctx context.Context, dest string, path ObjectPath, iface, method string, args ...interface{}) ([]interface{}, error) {
+	var sig strings.Builder
+	for _, arg := range args {
+		s, err := signatureOf(arg)
+		if err != nil {
+			return nil, err
+		}
+		sig.WriteString(s)
+	}
+
+	msg := &message{
+		Type:        typeMethodCall,
+		Path:        path,
+		Interface:   iface,
+		Member:      method,
+		Destination: dest,
+		Signature:   Signature(sig.String()),
+		Body:        args,
+	}
+
+	replyChan := make(chan *message, 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	c.serial++
+	msg.Serial = c.serial
+	c.pending[msg.Serial] = replyChan
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, msg.Serial)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(msg); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(DefaultCallTimeout)
+	defer timer.Stop()
+
+	select {
+	case reply, ok := <-replyChan:
+		if !ok {
+			return nil, c.closeErr()
+		}
+		if reply.Type == typeError {
+			return nil, replyError(reply)
+		}
+		return reply.Body, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("dbus: %s.%s timed out", iface, method)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("dbus: %s.%s: %w", iface, method, ctx.Err())
+	}
+}
+
+// RequestName asks the bus to assign a well-known name to this connection
+func (c *Conn) RequestName(name string) error {
+	// flags 0x4 = DBUS_NAME_FLAG_DO_NOT_QUEUE
+	reply, err := c.Call(busName, busPath, busInterface, "RequestName", name, uint32(0x4))
+	if err != nil {
+		return err
+	}
+	// 1 = primary owner, 4 = already owner
+	if code, _ := reply[0].(uint32); code != 1 && code != 4 {
+		return fmt.Errorf("dbus: name %s is already taken", name)
+	}
+	return nil
+}
+
+// Export serves calls to iface on path with handler
+func (c *Conn) Export(path ObjectPath, iface string, handler Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.objects[path] == nil {
+		c.objects[path] = make(map[string]Handler)
+	}
+	c.objects[path][iface] = handler
+}
+
+// Close shuts the connection down and fails all pending calls
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	return c.conn.Close()
+}
+
+func (c *Conn) send(msg *message) error {
+	data, err := msg.marshal()
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if _, err := c.conn.Write(data); err != nil {
+		return fmt.Errorf("dbus: write failed: %w", err)
+	}
+	return nil
+}
+
+func (c *Conn) closeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	return ErrClosed
+}
+
+// readLoop routes replies to waiting callers and dispatches incoming calls
+func (c *Conn) readLoop() {
+	r := bufio.NewReader(c.conn)
+
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			c.mu.Lock()
+			c.closed = true
+			c.err = fmt.Errorf("dbus: connection lost: %w", err)
+			for serial, ch := range c.pending {
+				close(ch)
+				delete(c.pending, serial)
+			}
+			c.mu.Unlock()
+			c.conn.Close()
+			return
+		}
+
+		switch msg.Type {
+		case typeMethodReturn, typeError:
+			c.mu.Lock()
+			ch, ok := c.pending[msg.ReplySerial]
+			if ok {
+				delete(c.pending, msg.ReplySerial)
+			}
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+
+		case typeMethodCall:
+			go c.dispatch(msg)
+		}
+		// Signals are ignored; nothing here subscribes to them
+	}
+}
+
+func (c *Conn) dispatch(call *message) {
+	c.mu.Lock()
+	handler := c.objects[call.Path][call.Interface]
+	c.mu.Unlock()
+
+	var (
+		sig  Signature
+		body []interface{}
+		err  error
+	)
+	if handler == nil {
+		err = &Error{
+			Name:    "org.freedesktop.DBus.Error.UnknownMethod",
+			Message: fmt.Sprintf("no handler for %s on %s", call.Interface, call.Path),
+		}
+	} else {
+		sig, body, err = handler(call.Member, call.Body)
+	}
+
+	if call.Flags&flagNoReplyExpected != 0 {
+		return
+	}
+
+	reply := &message{
+		Type:        typeMethodReturn,
+		ReplySerial: call.Serial,
+		Destination: call.Sender,
+		Signature:   sig,
+		Body:        body,
+	}
+	if err != nil {
+		name, text := "org.freedesktop.DBus.Error.Failed", err.Error()
+		var dbusErr *Error
+		if errors.As(err, &dbusErr) {
+			name, text = dbusErr.Name, dbusErr.Message
+		}
+		reply.Type = typeError
+		reply.ErrorName = name
+		reply.Signature = "s"
+		reply.Body = []interface{}{text}
+	}
+
+	c.mu.Lock()
+	c.serial++
+	reply.Serial = c.serial
+	c.mu.Unlock()
+
+	c.send(reply)
+}
+
+// replyError converts an error reply into an *Error
+func replyError(reply *message) error {
+	e := &Error{Name: reply.ErrorName}
+	if len(reply.Body) > 0 {
+		e.Message, _ = reply.Body[0].(string)
+	}
+	return e
+}
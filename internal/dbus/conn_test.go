@@ -0,0 +1,241 @@
+package dbus
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubBus plays the bus daemon and a systemd manager on the far end of a
+// pipe: it accepts the SASL handshake, answers Hello and GetUnit and can
+// call methods exported by the connection under test
+type stubBus struct {
+	t      *testing.T
+	conn   net.Conn
+	r      *bufio.Reader
+	serial uint32
+}
+
+func newStubBus(t *testing.T) (*Conn, *stubBus) {
+	t.Helper()
+	client, server := net.Pipe()
+	bus := &stubBus{t: t, conn: server, r: bufio.NewReader(server)}
+
+	handshake := make(chan error, 1)
+	go func() { handshake <- bus.handshake() }()
+
+	c, err := newConn(client)
+	if err != nil {
+		t.Fatalf("newConn: %v", err)
+	}
+	if err := <-handshake; err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+		server.Close()
+	})
+	return c, bus
+}
+
+// handshake runs the server side of the SASL exchange and answers Hello
+func (b *stubBus) handshake() error {
+	line, err := b.r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "\x00AUTH EXTERNAL ") {
+		return errors.New("unexpected auth line " + line)
+	}
+	if _, err := b.conn.Write([]byte("OK 0123456789abcdef\r\n")); err != nil {
+		return err
+	}
+	if line, err = b.r.ReadString('\n'); err != nil || line != "BEGIN\r\n" {
+		return errors.New("expected BEGIN")
+	}
+
+	hello, err := readMessage(b.r)
+	if err != nil {
+		return err
+	}
+	if hello.Member != "Hello" || hello.Destination != busName {
+		return errors.New("expected Hello, got " + hello.Member)
+	}
+	return b.reply(hello, "s", ":1.42")
+}
+
+func (b *stubBus) send(msg *message) error {
+	b.serial++
+	msg.Serial = b.serial
+	data, err := msg.marshal()
+	if err != nil {
+		return err
+	}
+	_, err = b.conn.Write(data)
+	return err
+}
+
+func (b *stubBus) reply(call *message, sig Signature, body ...interface{}) error {
+	return b.send(&message{Type: typeMethodReturn, ReplySerial: call.Serial, Signature: sig, Body: body})
+}
+
+// serveManager answers count calls like systemd's manager object would
+func (b *stubBus) serveManager(count int) {
+	for i := 0; i < count; i++ {
+		call, err := readMessage(b.r)
+		if err != nil {
+			return
+		}
+		switch call.Member {
+		case "GetUnit":
+			name, _ := call.Body[0].(string)
+			if name != "nginx.service" {
+				b.send(&message{
+					Type:        typeError,
+					ReplySerial: call.Serial,
+					ErrorName:   "org.freedesktop.systemd1.NoSuchUnit",
+					Signature:   "s",
+					Body:        []interface{}{"Unit " + name + " not loaded."},
+				})
+				continue
+			}
+			b.reply(call, "o", ObjectPath("/org/freedesktop/systemd1/unit/nginx_2eservice"))
+		case "GetAll":
+			b.reply(call, "a{sv}", map[string]Variant{
+				"ActiveState": MakeVariant("active"),
+				"SubState":    MakeVariant("running"),
+				"MainPID":     MakeVariant(uint32(1234)),
+			})
+		}
+	}
+}
+
+func TestConnHello(t *testing.T) {
+	c, _ := newStubBus(t)
+	if c.UniqueName() != ":1.42" {
+		t.Errorf("UniqueName() = %q, want %q", c.UniqueName(), ":1.42")
+	}
+}
+
+func TestConnCall(t *testing.T) {
+	c, bus := newStubBus(t)
+	go bus.serveManager(3)
+
+	// A reply
+	reply, err := c.Call("org.freedesktop.systemd1", "/org/freedesktop/systemd1",
+		"org.freedesktop.systemd1.Manager", "GetUnit", "nginx.service")
+	if err != nil {
+		t.Fatalf("GetUnit: %v", err)
+	}
+	path, _ := reply[0].(ObjectPath)
+	if path != "/org/freedesktop/systemd1/unit/nginx_2eservice" {
+		t.Errorf("GetUnit returned %v", reply)
+	}
+
+	// A dict of variants
+	reply, err = c.Call("org.freedesktop.systemd1", path,
+		"org.freedesktop.DBus.Properties", "GetAll", "org.freedesktop.systemd1.Unit")
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	props, _ := reply[0].(map[string]interface{})
+	if state, _ := props["ActiveState"].(Variant); state.Value != "active" {
+		t.Errorf("ActiveState = %v, want active", props["ActiveState"])
+	}
+	if pid, _ := props["MainPID"].(Variant); pid.Value != uint32(1234) {
+		t.Errorf("MainPID = %v, want 1234", props["MainPID"])
+	}
+
+	// An error reply
+	_, err = c.Call("org.freedesktop.systemd1", "/org/freedesktop/systemd1",
+		"org.freedesktop.systemd1.Manager", "GetUnit", "missing.service")
+	var dbusErr *Error
+	if !errors.As(err, &dbusErr) || dbusErr.Name != "org.freedesktop.systemd1.NoSuchUnit" {
+		t.Fatalf("GetUnit of a missing unit: err = %v, want NoSuchUnit", err)
+	}
+	if dbusErr.Message != "Unit missing.service not loaded." {
+		t.Errorf("error message = %q", dbusErr.Message)
+	}
+}
+
+func TestConnCallContext(t *testing.T) {
+	c, bus := newStubBus(t)
+	// Read the call but never answer it
+	go readMessage(bus.r)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.CallContext(ctx, "org.freedesktop.systemd1", "/org/freedesktop/systemd1",
+		"org.freedesktop.systemd1.Manager", "GetUnit", "nginx.service")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want a deadline error", err)
+	}
+}
+
+func TestConnLost(t *testing.T) {
+	c, bus := newStubBus(t)
+	go func() {
+		readMessage(bus.r)
+		bus.conn.Close()
+	}()
+
+	_, err := c.Call("org.freedesktop.systemd1", "/org/freedesktop/systemd1",
+		"org.freedesktop.systemd1.Manager", "GetUnit", "nginx.service")
+	if err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Errorf("err = %v, want a lost connection", err)
+	}
+
+	if _, err := c.Call(busName, busPath, busInterface, "Hello"); !errors.Is(err, ErrClosed) {
+		t.Errorf("call after the connection was lost: err = %v, want ErrClosed", err)
+	}
+}
+
+func TestConnExport(t *testing.T) {
+	c, bus := newStubBus(t)
+	c.Export("/test", "org.example.Test", func(method string, args []interface{}) (Signature, []interface{}, error) {
+		if method != "Echo" {
+			return "", nil, &Error{Name: "org.example.Unknown", Message: method}
+		}
+		return "s", []interface{}{args[0]}, nil
+	})
+
+	tests := []struct {
+		iface, member string
+		wantType      byte
+		wantBody      interface{}
+	}{
+		{iface: "org.example.Test", member: "Echo", wantType: typeMethodReturn, wantBody: "hi"},
+		{iface: "org.example.Test", member: "Other", wantType: typeError, wantBody: "Other"},
+		{iface: "org.example.Missing", member: "Echo", wantType: typeError},
+	}
+
+	for _, tt := range tests {
+		if err := bus.send(&message{
+			Type:      typeMethodCall,
+			Path:      "/test",
+			Interface: tt.iface,
+			Member:    tt.member,
+			Sender:    ":1.1",
+			Signature: "s",
+			Body:      []interface{}{"hi"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		reply, err := readMessage(bus.r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply.Type != tt.wantType || reply.ReplySerial != bus.serial {
+			t.Errorf("%s.%s: reply type %d to %d, want type %d to %d",
+				tt.iface, tt.member, reply.Type, reply.ReplySerial, tt.wantType, bus.serial)
+		}
+		if tt.wantBody != nil && (len(reply.Body) != 1 || reply.Body[0] != tt.wantBody) {
+			t.Errorf("%s.%s: reply body %v, want %v", tt.iface, tt.member, reply.Body, tt.wantBody)
+		}
+	}
+}
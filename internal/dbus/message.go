@@ -0,0 +1,174 @@
+package dbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Message types
+const (
+	typeMethodCall   byte = 1
+	typeMethodReturn byte = 2
+	typeError        byte = 3
+	typeSignal       byte = 4
+)
+
+// Header field codes
+const (
+	fieldPath        byte = 1
+	fieldInterface   byte = 2
+	fieldMember      byte = 3
+	fieldErrorName   byte = 4
+	fieldReplySerial byte = 5
+	fieldDestination byte = 6
+	fieldSender      byte = 7
+	fieldSignature   byte = 8
+)
+
+// flagNoReplyExpected marks calls the peer must not answer
+const flagNoReplyExpected byte = 0x1
+
+// maxMessageSize mirrors the limit enforced by dbus-daemon
+const maxMessageSize = 128 << 20
+
+// message is a single D-Bus message with its header fields unpacked
+type message struct {
+	Type        byte
+	Flags       byte
+	Serial      uint32
+	Path        ObjectPath
+	Interface   string
+	Member      string
+	ErrorName   string
+	ReplySerial uint32
+	Destination string
+	Sender      string
+	Signature   Signature
+	Body        []interface{}
+}
+
+// marshal encodes the message in little-endian wire format
+func (m *message) marshal() ([]byte, error) {
+	var body encoder
+	if m.Signature != "" {
+		if err := body.encodeAll(string(m.Signature), m.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := make([]interface{}, 0, 8)
+	addField := func(code byte, sig string, value interface{}) {
+		fields = append(fields, []interface{}{code, Variant{Signature: Signature(sig), Value: value}})
+	}
+	if m.Path != "" {
+		addField(fieldPath, "o", m.Path)
+	}
+	if m.Interface != "" {
+		addField(fieldInterface, "s", m.Interface)
+	}
+	if m.Member != "" {
+		addField(fieldMember, "s", m.Member)
+	}
+	if m.ErrorName != "" {
+		addField(fieldErrorName, "s", m.ErrorName)
+	}
+	if m.ReplySerial != 0 {
+		addField(fieldReplySerial, "u", m.ReplySerial)
+	}
+	if m.Destination != "" {
+		addField(fieldDestination, "s", m.Destination)
+	}
+	if m.Signature != "" {
+		addField(fieldSignature, "g", m.Signature)
+	}
+
+	var header encoder
+	header.buf.Write([]byte{'l', m.Type, m.Flags, 1})
+	header.uint32(uint32(body.buf.Len()))
+	header.uint32(m.Serial)
+	if err := header.encode("a(yv)", fields); err != nil {
+		return nil, err
+	}
+	header.align(8)
+
+	return append(header.buf.Bytes(), body.buf.Bytes()...), nil
+}
+
+// readMessage reads and decodes one message from r
+func readMessage(r io.Reader) (*message, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+
+	var order binary.ByteOrder
+	switch fixed[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("dbus: invalid endianness marker %q", fixed[0])
+	}
+
+	bodyLen := order.Uint32(fixed[4:8])
+	fieldsLen := order.Uint32(fixed[12:16])
+	if bodyLen > maxMessageSize || fieldsLen > maxMessageSize {
+		return nil, fmt.Errorf("dbus: message too large")
+	}
+
+	headerLen := 16 + int(fieldsLen)
+	padded := (headerLen + 7) &^ 7
+
+	data := make([]byte, padded+int(bodyLen))
+	copy(data, fixed)
+	if _, err := io.ReadFull(r, data[16:]); err != nil {
+		return nil, err
+	}
+
+	m := &message{
+		Type:   fixed[1],
+		Flags:  fixed[2],
+		Serial: order.Uint32(fixed[8:12]),
+	}
+
+	hd := &decoder{data: data[:headerLen], pos: 12, order: order}
+	raw, err := hd.decode("a(yv)")
+	if err != nil {
+		return nil, fmt.Errorf("dbus: invalid header: %w", err)
+	}
+	for _, f := range raw.([]interface{}) {
+		field := f.([]interface{})
+		code := field[0].(byte)
+		value := field[1].(Variant).Value
+		switch code {
+		case fieldPath:
+			m.Path, _ = value.(ObjectPath)
+		case fieldInterface:
+			m.Interface, _ = value.(string)
+		case fieldMember:
+			m.Member, _ = value.(string)
+		case fieldErrorName:
+			m.ErrorName, _ = value.(string)
+		case fieldReplySerial:
+			m.ReplySerial, _ = value.(uint32)
+		case fieldDestination:
+			m.Destination, _ = value.(string)
+		case fieldSender:
+			m.Sender, _ = value.(string)
+		case fieldSignature:
+			m.Signature, _ = value.(Signature)
+		}
+	}
+
+	if m.Signature != "" {
+		bd := &decoder{data: data[padded:], order: order}
+		m.Body, err = bd.decodeAll(string(m.Signature))
+		if err != nil {
+			return nil, fmt.Errorf("dbus: invalid body: %w", err)
+		}
+	}
+
+	return m, nil
+}
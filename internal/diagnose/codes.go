@@ -0,0 +1,127 @@
+package diagnose
+
+// resultExplanations explains the Result= values of units
+// (systemd.service(5), systemd.exec(5))
+var resultExplanations = map[string]string{
+	"exit-code":       "main process exited with a non-zero status",
+	"signal":          "main process was killed by a signal",
+	"core-dump":       "main process crashed and dumped core",
+	"timeout":         "a timeout was hit (TimeoutStartSec=, TimeoutStopSec= or RuntimeMaxSec=)",
+	"start-limit-hit": "started too often in a short time (StartLimitBurst=); systemctl reset-failed allows new starts",
+	"oom-kill":        "the kernel OOM killer killed a process of the unit; check MemoryMax= and the host's memory",
+	"watchdog":        "stopped sending watchdog keep-alives within WatchdogSec=",
+	"resources":       "systemd could not set up the resources to run it, e.g. a missing PIDFile= or failed fork",
+	"protocol":        "broke the service protocol, e.g. Type=forking without a PID file or Type=notify without READY=1",
+	"exec-condition":  "an ExecCondition= command told systemd to skip the start",
+}
+
+// exitStatusNames are the symbolic names systemctl status shows for exit
+// statuses: the LSB ones and those systemd uses when setting up the
+// process failed
+var exitStatusNames = map[int]string{
+	0:   "SUCCESS",
+	1:   "FAILURE",
+	2:   "INVALIDARGUMENT",
+	3:   "NOTIMPLEMENTED",
+	4:   "NOPERMISSION",
+	5:   "NOTINSTALLED",
+	6:   "NOTCONFIGURED",
+	7:   "NOTRUNNING",
+	200: "CHDIR",
+	201: "NICE",
+	202: "FDS",
+	203: "EXEC",
+	204: "MEMORY",
+	205: "LIMITS",
+	206: "OOM_ADJUST",
+	207: "SIGNAL_MASK",
+	208: "STDIN",
+	209: "STDOUT",
+	210: "CHROOT",
+	211: "IOPRIO",
+	212: "TIMERSLACK",
+	213: "SECUREBITS",
+	214: "SETSCHEDULER",
+	215: "CPUAFFINITY",
+	216: "GROUP",
+	217: "USER",
+	218: "CAPABILITIES",
+	219: "CGROUP",
+	220: "SETSID",
+	221: "CONFIRM",
+	222: "STDERR",
+	224: "PAM",
+	225: "NETWORK",
+	226: "NAMESPACE",
+	227: "NO_NEW_PRIVILEGES",
+	228: "SECCOMP",
+	229: "SELINUX_CONTEXT",
+	230: "PERSONALITY",
+	231: "APPARMOR_PROFILE",
+	232: "ADDRESS_FAMILIES",
+	233: "RUNTIME_DIRECTORY",
+	235: "CHOWN",
+	236: "SMACK_PROCESS_LABEL",
+	237: "KEYRING",
+	238: "STATE_DIRECTORY",
+	239: "CACHE_DIRECTORY",
+	240: "LOGS_DIRECTORY",
+	241: "CONFIGURATION_DIRECTORY",
+	242: "NUMA_POLICY",
+	243: "CREDENTIALS",
+	245: "BPF",
+}
+
+// exitStatusHints say what usually causes an exit status
+var exitStatusHints = map[int]string{
+	1:   "the program reported a generic error; see its logs",
+	2:   "the program was started with invalid arguments; check ExecStart=",
+	4:   "the program lacks a permission it needs",
+	5:   "the program or a file it needs is not installed",
+	6:   "the program is not configured",
+	200: "WorkingDirectory= is missing or not accessible",
+	203: "the ExecStart= program is missing, not executable or has a bad #! line",
+	204: "out of memory while starting the process",
+	209: "StandardOutput= could not be set up",
+	216: "the Group= does not exist",
+	217: "the User= does not exist",
+	218: "the capabilities could not be set up (CapabilityBoundingSet=, AmbientCapabilities=)",
+	219: "the unit's cgroup could not be set up",
+	226: "the sandbox could not be set up; a path in ReadWritePaths= or similar may be missing",
+	233: "RuntimeDirectory= could not be created",
+	238: "StateDirectory= could not be created",
+	239: "CacheDirectory= could not be created",
+	240: "LogsDirectory= could not be created",
+	241: "ConfigurationDirectory= could not be created",
+	243: "a LoadCredential= or SetCredential= could not be read",
+}
+
+// signalNames are the names of the standard signals without SIG
+var signalNames = map[int]string{
+	1:  "HUP",
+	2:  "INT",
+	3:  "QUIT",
+	4:  "ILL",
+	5:  "TRAP",
+	6:  "ABRT",
+	7:  "BUS",
+	8:  "FPE",
+	9:  "KILL",
+	10: "USR1",
+	11: "SEGV",
+	12: "USR2",
+	13: "PIPE",
+	14: "ALRM",
+	15: "TERM",
+}
+
+// signalHints say what usually sends a signal
+var signalHints = map[int]string{
+	6:  "the program aborted itself, e.g. on a failed assertion",
+	7:  "the program crashed (bus error)",
+	8:  "the program crashed (arithmetic error)",
+	9:  "often the OOM killer, or systemd after TimeoutStopSec=",
+	11: "the program crashed (segmentation fault)",
+	13: "the program wrote to a closed pipe or socket",
+	15: "it was asked to stop, by systemd or another process",
+}
@@ -0,0 +1,253 @@
+package diagnose
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+	"github.com/andinianst93/systemd-monitoring/internal/systemd"
+)
+
+// Options configures what Explain gathers
+type Options struct {
+	Lines  int           // journal lines to include
+	Window time.Duration // journal lines are read this long around the failure
+}
+
+// Diagnosis explains why a unit is not running: what systemd recorded
+// about its last run, the errors it logged and what it depends on
+type Diagnosis struct {
+	Unit        string               `json:"unit"`
+	Host        string               `json:"host,omitempty"`
+	User        string               `json:"user,omitempty"`
+	Status      models.ServiceStatus `json:"status"`
+	ActiveState string               `json:"active_state"`
+	SubState    string               `json:"sub_state"`
+
+	Result         string    `json:"result"`                   // success, exit-code, signal, timeout, ...
+	ExecMainCode   string    `json:"exec_main_code,omitempty"` // exited, killed or dumped
+	ExecMainStatus int       `json:"exec_main_status"`         // exit status, or signal number
+	Exit           string    `json:"exit,omitempty"`           // e.g. "code=exited, status=203/EXEC"
+	Restarts       int       `json:"restarts"`
+	FailedAt       time.Time `json:"failed_at,omitzero"` // when the main process exited or the state last changed
+
+	FailedConditions   []models.Condition       `json:"failed_conditions"`
+	FailedDependencies []*models.DependencyNode `json:"failed_dependencies"` // required units that failed or cannot load
+	Logs               []*models.LogEntry       `json:"logs"`
+	LogPriority        string                   `json:"log_priority"` // "err", or "" when no error lines were found
+
+	Causes  []string `json:"causes"`  // most specific first
+	Summary string   `json:"summary"` // the first cause, or the unit's state
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// Failed reports whether the unit failed
+func (d *Diagnosis) Failed() bool {
+	return d.Status == models.StatusFailed
+}
+
+// Explain gathers why a unit failed. Only the unit's status is required;
+// details that cannot be read are recorded in Errors and left out of the
+// diagnosis.
+func Explain(ctx context.Context, client *systemd.Client, unit string, opts Options) (*Diagnosis, error) {
+	// 1. Status and what systemd recorded about the last run
+	service, err := client.GetServiceStatus(ctx, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Diagnosis{
+		Unit:               service.Name,
+		Host:               service.Host,
+		User:               service.User,
+		Status:             service.Status,
+		ActiveState:        service.ActiveState,
+		SubState:           service.SubState,
+		Restarts:           service.Restarts,
+		FailedConditions:   []models.Condition{},
+		FailedDependencies: []*models.DependencyNode{},
+		Logs:               []*models.LogEntry{},
+		Causes:             []string{},
+	}
+
+	details, err := client.GetFailureDetails(ctx, service.Name)
+	if err != nil {
+		d.Errors = append(d.Errors, err.Error())
+		details = &models.FailureDetails{}
+	}
+	d.Result = details.Result
+	d.ExecMainCode = details.ExecMainCode
+	d.ExecMainStatus = details.ExecMainStatus
+	d.Exit = formatExit(details.ExecMainCode, details.ExecMainStatus)
+	if details.Restarts > d.Restarts {
+		d.Restarts = details.Restarts
+	}
+	d.FailedAt = details.ExitedAt
+	if d.FailedAt.IsZero() {
+		d.FailedAt = details.ChangedAt
+	}
+	for _, condition := range details.Conditions {
+		if condition.Result == "failed" {
+			d.FailedConditions = append(d.FailedConditions, condition)
+		}
+	}
+
+	// 2. Error lines logged around the failure; many programs log
+	// everything at info level, so fall back to all priorities
+	logOpts := &models.LogOptions{Lines: opts.Lines, Priority: "err"}
+	if !d.FailedAt.IsZero() && opts.Window > 0 {
+		logOpts.Since = fmt.Sprintf("@%d", d.FailedAt.Add(-opts.Window).Unix())
+		logOpts.Until = fmt.Sprintf("@%d", d.FailedAt.Add(opts.Window).Unix())
+	}
+	if opts.Lines > 0 {
+		logs, err := client.GetServiceLogs(ctx, service.Name, logOpts)
+		if err == nil && len(logs) == 0 {
+			logOpts.Priority = ""
+			logs, err = client.GetServiceLogs(ctx, service.Name, logOpts)
+		}
+		if err != nil {
+			d.Errors = append(d.Errors, err.Error())
+		}
+		if len(logs) > 0 {
+			d.Logs = logs
+		}
+		d.LogPriority = logOpts.Priority
+	}
+
+	// 3. Required units that failed or cannot be loaded
+	graph, err := client.GetDependencyGraph(ctx, service.Name, systemd.DependencyOptions{
+		Kinds: []models.DependencyKind{models.DependencyRequires, models.DependencyBindsTo},
+	})
+	if err != nil {
+		d.Errors = append(d.Errors, err.Error())
+	} else {
+		for _, node := range graph.Nodes {
+			if node.Name != graph.Root && (node.Failed || brokenLoadStates[node.LoadState]) {
+				d.FailedDependencies = append(d.FailedDependencies, node)
+			}
+		}
+	}
+
+	// 4. Conclusions
+	d.Causes = causes(d, details)
+	switch {
+	case len(d.Causes) > 0:
+		d.Summary = d.Causes[0]
+	case d.Failed():
+		d.Summary = "failed without a recorded cause; see the logs"
+	default:
+		d.Summary = fmt.Sprintf("no failure recorded; the unit is %s (%s)", d.ActiveState, d.SubState)
+	}
+
+	return d, nil
+}
+
+// brokenLoadStates are the load states of units that cannot be started
+var brokenLoadStates = map[string]bool{
+	"not-found":   true,
+	"masked":      true,
+	"bad-setting": true,
+	"error":       true,
+}
+
+// causes lists the likely causes of a failure, most specific first: a
+// refused or skipped start, how the main process ended, then broken
+// dependencies, which explain a unit that never ran
+func causes(d *Diagnosis, details *models.FailureDetails) []string {
+	var causes []string
+
+	for _, condition := range d.FailedConditions {
+		switch {
+		case condition.IsAssert() && details.AssertFailed:
+			causes = append(causes, fmt.Sprintf("start refused: %s failed", condition))
+		case !condition.IsAssert() && details.ConditionFailed:
+			causes = append(causes, fmt.Sprintf("start skipped: %s failed", condition))
+		}
+	}
+
+	if cause := resultCause(d.Result, d.ExecMainCode, d.ExecMainStatus); cause != "" {
+		causes = append(causes, cause)
+	}
+
+	for _, node := range d.FailedDependencies {
+		problem := "failed"
+		if brokenLoadStates[node.LoadState] {
+			problem = "is " + node.LoadState
+		}
+		via := ""
+		if node.Depth > 1 {
+			via = fmt.Sprintf(" (%d levels down)", node.Depth)
+		}
+		causes = append(causes, fmt.Sprintf("required unit %s %s%s", node.Name, problem, via))
+	}
+
+	if d.Restarts > 0 && d.Failed() {
+		causes = append(causes, fmt.Sprintf("systemd restarted it %d times before giving up", d.Restarts))
+	}
+
+	return causes
+}
+
+// resultCause explains a Result= value, naming the exit status or signal
+// of the main process where it tells more
+func resultCause(result, code string, status int) string {
+	switch result {
+	case "", "success":
+		return ""
+	case "exit-code":
+		if code == "exited" && status != 0 {
+			cause := fmt.Sprintf("main process exited with status %s", exitStatusName(status))
+			if hint := exitStatusHints[status]; hint != "" {
+				cause += ": " + hint
+			}
+			return cause
+		}
+	case "signal", "core-dump":
+		if code == "killed" || code == "dumped" {
+			cause := fmt.Sprintf("main process was killed by SIG%s", signalName(status))
+			if code == "dumped" {
+				cause += " and dumped core"
+			}
+			if hint := signalHints[status]; hint != "" {
+				cause += ": " + hint
+			}
+			return cause
+		}
+	}
+
+	if explanation, ok := resultExplanations[result]; ok {
+		return explanation
+	}
+	return "the last run ended with result " + result
+}
+
+// formatExit renders how the main process ended as systemctl status
+// does, e.g. "code=exited, status=203/EXEC" or "code=killed,
+// status=9/KILL"; "" when it never ran
+func formatExit(code string, status int) string {
+	switch code {
+	case "exited":
+		return "code=exited, status=" + exitStatusName(status)
+	case "killed", "dumped":
+		return fmt.Sprintf("code=%s, status=%d/%s", code, status, signalName(status))
+	}
+	return ""
+}
+
+// exitStatusName renders an exit status with its symbolic name, e.g.
+// "203/EXEC"
+func exitStatusName(status int) string {
+	if name, ok := exitStatusNames[status]; ok {
+		return fmt.Sprintf("%d/%s", status, name)
+	}
+	return fmt.Sprint(status)
+}
+
+// signalName returns the name of a signal without the SIG prefix
+func signalName(signal int) string {
+	if name, ok := signalNames[signal]; ok {
+		return name
+	}
+	return fmt.Sprint(signal)
+}
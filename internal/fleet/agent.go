@@ -0,0 +1,169 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// maxPendingEvents caps the events kept while the collector is
+// unreachable; the oldest are dropped first
+const maxPendingEvents = 1000
+
+// flushTimeout bounds the last report sent when the agent stops
+const flushTimeout = 5 * time.Second
+
+// Agent pushes the monitor's state to a collector. The monitor hands it
+// every pass with Push; Run sends the latest state in the background, so
+// a slow or unreachable collector never delays a check.
+type Agent struct {
+	target  string // collector URL receiving reports
+	host    string
+	secret  []byte
+	client  *http.Client
+	started time.Time
+	wake    chan struct{}
+	onError func(err error)
+
+	mu       sync.Mutex
+	services []*models.ServiceInfo
+	interval time.Duration
+	events   []*models.Event // changes not yet accepted by the collector
+	first    uint64          // number of events ever removed from the front of events
+	dropped  int
+	seq      uint64
+	failing  bool
+}
+
+// NewAgent creates an agent reporting as host to the collector at
+// collectorURL, signing its reports with secret
+func NewAgent(collectorURL, host string, secret []byte) (*Agent, error) {
+	target, err := endpoint(collectorURL, ReportsPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateHostName(host); err != nil {
+		return nil, err
+	}
+
+	return &Agent{
+		target:  target,
+		host:    host,
+		secret:  secret,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		started: time.Now(),
+		wake:    make(chan struct{}, 1),
+	}, nil
+}
+
+// Host returns the name the agent reports as
+func (a *Agent) Host() string {
+	return a.host
+}
+
+// OnError sets a callback for the first failed report of a streak; it is
+// called with nil once reports go through again
+func (a *Agent) OnError(fn func(err error)) {
+	a.onError = fn
+}
+
+// Push queues the latest snapshot of every watched unit and the changes
+// of a pass; interval is how often the monitor passes. It never blocks.
+func (a *Agent) Push(services []*models.ServiceInfo, events []*models.Event, interval time.Duration) {
+	// Snapshots are copied since they are sent from another goroutine
+	snapshots := make([]*models.ServiceInfo, len(services))
+	for i, service := range services {
+		snapshot := *service
+		snapshots[i] = &snapshot
+	}
+
+	a.mu.Lock()
+	a.services = snapshots
+	a.interval = interval
+	a.events = append(a.events, events...)
+	if over := len(a.events) - maxPendingEvents; over > 0 {
+		a.events = append([]*models.Event(nil), a.events[over:]...)
+		a.first += uint64(over)
+		a.dropped += over
+	}
+	a.mu.Unlock()
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends a report whenever a pass was pushed until ctx is done, then
+// tries once more so the last changes are not lost
+func (a *Agent) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			defer cancel()
+			a.send(flushCtx)
+			return
+		case <-a.wake:
+			a.send(ctx)
+		}
+	}
+}
+
+// send posts the latest state and the pending events; events stay queued
+// until the collector accepted them
+func (a *Agent) send(ctx context.Context) {
+	a.mu.Lock()
+	if a.services == nil {
+		a.mu.Unlock()
+		return
+	}
+	a.seq++
+	report := &Report{
+		Host:     a.host,
+		Started:  a.started,
+		Seq:      a.seq,
+		Sent:     time.Now(),
+		Interval: a.interval,
+		Services: a.services,
+		Events:   a.events[:len(a.events):len(a.events)],
+		Dropped:  a.dropped,
+	}
+	end := a.first + uint64(len(a.events))
+	a.mu.Unlock()
+
+	data, err := json.Marshal(report)
+	if err == nil {
+		_, err = do(ctx, a.client, http.MethodPost, a.target, a.secret, data)
+	}
+	if ctx.Err() != nil && err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	if err == nil {
+		// Events pushed while sending are kept for the next report; some
+		// of the sent ones may have been dropped meanwhile
+		if end > a.first {
+			sent := min(end-a.first, uint64(len(a.events)))
+			a.events = a.events[sent:]
+			a.first += sent
+		}
+		a.dropped -= report.Dropped
+	}
+	changed := a.failing != (err != nil)
+	a.failing = err != nil
+	a.mu.Unlock()
+
+	if changed && a.onError != nil {
+		if err != nil {
+			err = fmt.Errorf("failed to report to the collector: %w", err)
+		}
+		a.onError(err)
+	}
+}
@@ -0,0 +1,287 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/history"
+	"github.com/andinianst93/systemd-monitoring/internal/httpjson"
+	"github.com/andinianst93/systemd-monitoring/internal/logger"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+	"github.com/andinianst93/systemd-monitoring/internal/output"
+)
+
+// maxBodySize caps a report or view; a few thousand units fit easily
+const maxBodySize = 16 << 20
+
+// staleReports is how many report intervals a host may miss before it is
+// stale, unless CollectorOptions.Stale is set
+const staleReports = 3
+
+// staleCheckInterval is how often Run looks for hosts that went stale
+const staleCheckInterval = 5 * time.Second
+
+// CollectorOptions configures a Collector
+type CollectorOptions struct {
+	Secret     []byte             // shared secret agents sign their reports with
+	Stale      time.Duration      // silence after which a host is stale; 0 = three report intervals
+	FileLogger *logger.FileLogger // changes and hosts going stale
+	History    *history.Store     // nil = no history
+}
+
+// Collector receives agent reports and keeps the latest state of every
+// host. It serves the fleet-wide view over HTTP.
+type Collector struct {
+	opts CollectorOptions
+	mux  *http.ServeMux
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState is what the collector knows about one host
+type hostState struct {
+	name       string
+	address    string
+	started    time.Time // agent start of the latest report
+	seq        uint64
+	interval   time.Duration
+	lastReport time.Time // collector clock
+	services   []*models.ServiceInfo
+	stale      bool
+}
+
+// NewCollector creates a collector
+func NewCollector(opts CollectorOptions) *Collector {
+	c := &Collector{opts: opts, mux: http.NewServeMux(), hosts: make(map[string]*hostState)}
+
+	c.mux.HandleFunc("GET /healthz", c.handleHealth)
+	c.mux.HandleFunc("POST "+ReportsPath, c.handleReport)
+	c.mux.HandleFunc("GET "+ViewPath, c.handleView)
+
+	return c
+}
+
+// ServeHTTP dispatches collector requests
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mux.ServeHTTP(w, r)
+}
+
+// Run marks hosts stale when they stop reporting until ctx is done
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(staleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.checkStale(now)
+		}
+	}
+}
+
+// staleAfter returns how long a host may stay silent
+func (c *Collector) staleAfter(host *hostState) time.Duration {
+	if c.opts.Stale > 0 {
+		return c.opts.Stale
+	}
+	return staleReports * host.interval
+}
+
+// checkStale logs hosts whose last report is too old
+func (c *Collector) checkStale(now time.Time) {
+	var silent []string
+
+	c.mu.Lock()
+	for _, host := range c.hosts {
+		if !host.stale && now.Sub(host.lastReport) > c.staleAfter(host) {
+			host.stale = true
+			silent = append(silent, fmt.Sprintf("Host %s stopped reporting, last report %s ago",
+				host.name, now.Sub(host.lastReport).Round(time.Second)))
+		}
+	}
+	c.mu.Unlock()
+
+	sort.Strings(silent)
+	for _, message := range silent {
+		c.opts.FileLogger.Warn(message)
+		fmt.Println("Warning:", message)
+	}
+}
+
+func (c *Collector) handleHealth(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	hosts := len(c.hosts)
+	c.mu.Unlock()
+
+	httpjson.Write(w, http.StatusOK, map[string]interface{}{
+		"status": "ok",
+		"hosts":  hosts,
+	})
+}
+
+// handleReport serves POST /api/v1/fleet/reports
+func (c *Collector) handleReport(w http.ResponseWriter, r *http.Request) {
+	// 1. Authenticate
+	body, ok := c.readSigned(w, r)
+	if !ok {
+		return
+	}
+
+	// 2. Decode
+	var report Report
+	if err := json.Unmarshal(body, &report); err != nil {
+		httpjson.Error(w, http.StatusBadRequest, fmt.Sprintf("invalid report: %v", err))
+		return
+	}
+	if err := ValidateHostName(report.Host); err != nil {
+		httpjson.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, service := range report.Services {
+		service.Host = report.Host
+	}
+	sort.Slice(report.Services, func(i, j int) bool {
+		return report.Services[i].Name < report.Services[j].Name
+	})
+	for _, event := range report.Events {
+		event.Host = report.Host
+		if event.Service != nil {
+			event.Service.Host = report.Host
+		}
+	}
+
+	// 3. Keep the snapshot unless a newer report arrived first
+	address, _, _ := net.SplitHostPort(r.RemoteAddr)
+	current, recovered := c.update(&report, address, time.Now())
+	if !current {
+		httpjson.Error(w, http.StatusConflict, "report is older than the last one of this host")
+		return
+	}
+	if recovered {
+		message := fmt.Sprintf("Host %s is reporting again", report.Host)
+		c.opts.FileLogger.Info(message)
+		fmt.Println(message)
+	}
+
+	// 4. Log and record what happened on the host
+	if report.Dropped > 0 {
+		message := fmt.Sprintf("Host %s dropped %d events while the collector was unreachable", report.Host, report.Dropped)
+		c.opts.FileLogger.Warn(message)
+		fmt.Println("Warning:", message)
+	}
+	for _, event := range report.Events {
+		c.opts.FileLogger.WriteEvent(event)
+		output.PrintEvent(event)
+	}
+	c.record(&report)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// update stores a report as the host's latest state. It reports whether
+// the report was newer than the stored one and whether the host was stale.
+func (c *Collector) update(report *Report, address string, now time.Time) (current, recovered bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	host, ok := c.hosts[report.Host]
+	if !ok {
+		host = &hostState{name: report.Host}
+		c.hosts[report.Host] = host
+	} else if report.Started.Before(host.started) || (report.Started.Equal(host.started) && report.Seq <= host.seq) {
+		return false, false
+	}
+
+	recovered = host.stale
+	host.address = address
+	host.started = report.Started
+	host.seq = report.Seq
+	host.interval = report.Interval
+	host.lastReport = now
+	host.services = report.Services
+	host.stale = false
+	return true, recovered
+}
+
+// record stores the report's snapshots and changes in the history
+func (c *Collector) record(report *Report) {
+	if c.opts.History == nil {
+		return
+	}
+
+	for _, service := range report.Services {
+		if err := c.opts.History.RecordSample(service); err != nil {
+			c.opts.FileLogger.Error(err)
+		}
+	}
+	for _, event := range report.Events {
+		if err := c.opts.History.RecordEvent(event); err != nil {
+			c.opts.FileLogger.Error(err)
+		}
+	}
+}
+
+// handleView serves GET /api/v1/fleet
+func (c *Collector) handleView(w http.ResponseWriter, r *http.Request) {
+	if _, ok := c.readSigned(w, r); !ok {
+		return
+	}
+	httpjson.Write(w, http.StatusOK, c.View(time.Now()))
+}
+
+// View returns the latest state of every host, sorted by host name
+func (c *Collector) View(now time.Time) *View {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.hosts))
+	for name := range c.hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	view := &View{Timestamp: now, Hosts: []HostSummary{}, Services: []*models.ServiceInfo{}}
+	for _, name := range names {
+		host := c.hosts[name]
+		summary := HostSummary{
+			Name:       host.name,
+			Address:    host.address,
+			LastReport: host.lastReport,
+			Stale:      now.Sub(host.lastReport) > c.staleAfter(host),
+			Units:      len(host.services),
+		}
+		for _, service := range host.services {
+			if service.IsFailed() {
+				summary.Failed++
+			}
+		}
+		view.Hosts = append(view.Hosts, summary)
+		view.Services = append(view.Services, host.services...)
+	}
+	return view
+}
+
+// readSigned reads the request body and checks its signature, answering
+// the request itself when it is refused
+func (c *Collector) readSigned(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		httpjson.Error(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("failed to read request: %v", err))
+		return nil, false
+	}
+	if err := verify(r, c.opts.Secret, body, time.Now()); err != nil {
+		httpjson.Error(w, http.StatusUnauthorized, err.Error())
+		return nil, false
+	}
+	return body, true
+}
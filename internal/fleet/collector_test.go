@@ -0,0 +1,138 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/httpjson"
+	"github.com/andinianst93/systemd-monitoring/internal/logger"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+func newTestCollector(t *testing.T) (*Collector, *httptest.Server) {
+	t.Helper()
+	fileLogger, err := logger.NewFileLogger(filepath.Join(t.TempDir(), "collector.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := NewCollector(CollectorOptions{Secret: testSecret, FileLogger: fileLogger})
+	server := httptest.NewServer(collector)
+	t.Cleanup(server.Close)
+	return collector, server
+}
+
+// postReport sends a signed report and returns the response status and
+// error message
+func postReport(t *testing.T, server *httptest.Server, secret []byte, report *Report) (int, string) {
+	t.Helper()
+	body, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+ReportsPath, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign(req, secret, body)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var apiErr httpjson.ErrorBody
+	json.NewDecoder(resp.Body).Decode(&apiErr)
+	return resp.StatusCode, apiErr.Error
+}
+
+func testReport(started time.Time, seq uint64, status models.ServiceStatus) *Report {
+	return &Report{
+		Host:     "web-01",
+		Started:  started,
+		Seq:      seq,
+		Sent:     time.Now(),
+		Interval: 30 * time.Second,
+		Services: []*models.ServiceInfo{{Name: "nginx.service", Status: status}},
+	}
+}
+
+func TestCollectorReplay(t *testing.T) {
+	collector, server := newTestCollector(t)
+	started := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		report *Report
+		want   int
+	}{
+		{name: "first report", report: testReport(started, 5, models.StatusRunning), want: http.StatusNoContent},
+		{name: "replayed", report: testReport(started, 5, models.StatusFailed), want: http.StatusConflict},
+		{name: "older sequence", report: testReport(started, 4, models.StatusFailed), want: http.StatusConflict},
+		{name: "next sequence", report: testReport(started, 6, models.StatusRunning), want: http.StatusNoContent},
+		// A restarted agent counts from 1 again
+		{name: "restarted agent", report: testReport(started.Add(time.Hour), 1, models.StatusRunning), want: http.StatusNoContent},
+		{name: "previous agent run", report: testReport(started, 7, models.StatusFailed), want: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		if got, message := postReport(t, server, testSecret, tt.report); got != tt.want {
+			t.Errorf("%s: status %d (%s), want %d", tt.name, got, message, tt.want)
+		}
+	}
+
+	// Refused reports left the stored state alone
+	view := collector.View(time.Now())
+	if len(view.Services) != 1 || view.Services[0].Status != models.StatusRunning {
+		t.Errorf("view services = %v, want nginx running", view.Services)
+	}
+}
+
+func TestCollectorRejectsUnsigned(t *testing.T) {
+	collector, server := newTestCollector(t)
+
+	status, message := postReport(t, server, []byte("guess"), testReport(time.Now(), 1, models.StatusFailed))
+	if status != http.StatusUnauthorized || message != "missing or invalid signature" {
+		t.Errorf("status %d (%s), want 401 with the reason", status, message)
+	}
+	if view := collector.View(time.Now()); len(view.Hosts) != 0 {
+		t.Errorf("view has %d hosts, want none", len(view.Hosts))
+	}
+
+	if _, err := FetchView(context.Background(), server.URL, []byte("guess")); err == nil {
+		t.Error("FetchView with a wrong secret succeeded")
+	}
+}
+
+func TestCollectorView(t *testing.T) {
+	_, server := newTestCollector(t)
+
+	report := testReport(time.Now(), 1, models.StatusFailed)
+	report.Services = append(report.Services, &models.ServiceInfo{Name: "cron.service", Status: models.StatusRunning})
+	if status, message := postReport(t, server, testSecret, report); status != http.StatusNoContent {
+		t.Fatalf("status %d (%s)", status, message)
+	}
+
+	view, err := FetchView(context.Background(), server.URL, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(view.Hosts) != 1 {
+		t.Fatalf("got %d hosts, want 1", len(view.Hosts))
+	}
+	host := view.Hosts[0]
+	if host.Name != "web-01" || host.Units != 2 || host.Failed != 1 || host.Stale || host.Address != "127.0.0.1" {
+		t.Errorf("host = %+v", host)
+	}
+
+	// Sorted by name and labelled with the reporting host
+	if len(view.Services) != 2 || view.Services[0].Name != "cron.service" || view.Services[1].Host != "web-01" {
+		t.Errorf("services = %v", view.Services)
+	}
+}
@@ -0,0 +1,171 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/httpjson"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+const (
+	// ReportsPath is where agents post their reports
+	ReportsPath = "/api/v1/fleet/reports"
+	// ViewPath serves the latest state of the fleet
+	ViewPath = "/api/v1/fleet"
+
+	headerTimestamp = "X-Sysmon-Timestamp"
+	headerSignature = "X-Sysmon-Signature"
+
+	// maxClockSkew is how far a request's timestamp may be from the
+	// collector's clock; older requests are refused so a captured one
+	// cannot be replayed later
+	maxClockSkew = 5 * time.Minute
+)
+
+// Report is what an agent sends after every pass: the latest snapshot of
+// every unit it watches and the changes since its previous report
+type Report struct {
+	Host     string                `json:"host"`
+	Started  time.Time             `json:"started"` // when the agent started; orders reports with Seq
+	Seq      uint64                `json:"seq"`
+	Sent     time.Time             `json:"sent"`
+	Interval time.Duration         `json:"interval"` // how often the agent reports
+	Services []*models.ServiceInfo `json:"services"`
+	Events   []*models.Event       `json:"events,omitempty"`
+	Dropped  int                   `json:"dropped,omitempty"` // events lost while the collector was unreachable
+}
+
+// HostSummary is the state of one reporting host
+type HostSummary struct {
+	Name       string    `json:"name"`
+	Address    string    `json:"address"` // where the last report came from
+	LastReport time.Time `json:"last_report"`
+	Stale      bool      `json:"stale"` // no report within the stale period
+	Units      int       `json:"units"`
+	Failed     int       `json:"failed"`
+}
+
+// View is the fleet-wide state served by the collector
+type View struct {
+	Timestamp time.Time             `json:"timestamp"`
+	Hosts     []HostSummary         `json:"hosts"`
+	Services  []*models.ServiceInfo `json:"services"`
+}
+
+// ValidateHostName rejects names that cannot label a host's units
+func ValidateHostName(name string) error {
+	if name == "" {
+		return errors.New("host name must not be empty")
+	}
+	if strings.ContainsAny(name, " \t\n/") {
+		return fmt.Errorf("invalid host name %q", name)
+	}
+	return nil
+}
+
+// signature returns the HMAC-SHA256 of a request, covering the method,
+// the path with its query, the timestamp and the body
+func signature(secret []byte, method, uri string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%d\n", method, uri, timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sign adds the timestamp and signature headers to req
+func sign(req *http.Request, secret, body []byte) {
+	timestamp := time.Now().Unix()
+	req.Header.Set(headerTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(headerSignature, signature(secret, req.Method, req.URL.RequestURI(), timestamp, body))
+}
+
+// verify checks the signature of a request in constant time
+func verify(r *http.Request, secret, body []byte, now time.Time) error {
+	timestamp, err := strconv.ParseInt(r.Header.Get(headerTimestamp), 10, 64)
+	if err != nil {
+		return errors.New("missing or invalid timestamp")
+	}
+	if skew := now.Sub(time.Unix(timestamp, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return fmt.Errorf("timestamp is %s off the collector's clock", skew.Round(time.Second))
+	}
+
+	expected := signature(secret, r.Method, r.URL.RequestURI(), timestamp, body)
+	if !hmac.Equal([]byte(r.Header.Get(headerSignature)), []byte(expected)) {
+		return errors.New("missing or invalid signature")
+	}
+	return nil
+}
+
+// endpoint joins the collector's base URL and a path
+func endpoint(collectorURL, path string) (string, error) {
+	base, err := url.Parse(collectorURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return "", fmt.Errorf("invalid collector URL %q", collectorURL)
+	}
+	return strings.TrimSuffix(base.String(), "/") + path, nil
+}
+
+// do sends a signed request and returns the response body of a 2xx
+// response
+func do(ctx context.Context, client *http.Client, method, target string, secret, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", "systemd-monitoring")
+	sign(req, secret, body)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr httpjson.ErrorBody
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("collector returned %s: %s", resp.Status, apiErr.Error)
+		}
+		return nil, fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return data, nil
+}
+
+// FetchView reads the fleet-wide state from a collector
+func FetchView(ctx context.Context, collectorURL string, secret []byte) (*View, error) {
+	target, err := endpoint(collectorURL, ViewPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := do(ctx, &http.Client{Timeout: 10 * time.Second}, http.MethodGet, target, secret, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var view View
+	if err := json.Unmarshal(data, &view); err != nil {
+		return nil, fmt.Errorf("failed to decode fleet view: %w", err)
+	}
+	return &view, nil
+}
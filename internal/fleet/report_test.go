@@ -0,0 +1,162 @@
+package fleet
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("s3cret")
+
+// signedAt builds a request signed with secret as if sent at timestamp
+func signedAt(method, uri string, secret, body []byte, timestamp time.Time) *http.Request {
+	req := httptest.NewRequest(method, uri, bytes.NewReader(body))
+	req.Header.Set(headerTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(headerSignature, signature(secret, method, uri, timestamp.Unix(), body))
+	return req
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"host":"web-01"}`)
+	req := httptest.NewRequest(http.MethodPost, ReportsPath+"?v=1", bytes.NewReader(body))
+	sign(req, testSecret, body)
+
+	if !strings.HasPrefix(req.Header.Get(headerSignature), "sha256=") {
+		t.Fatalf("signature = %q, want sha256=<hex>", req.Header.Get(headerSignature))
+	}
+	if err := verify(req, testSecret, body, time.Now()); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	now := time.Unix(1705320000, 0)
+	body := []byte(`{"host":"web-01"}`)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		body []byte // body the collector received; nil = body
+		want string // "" = accepted
+	}{
+		{
+			name: "valid",
+			req:  signedAt(http.MethodPost, ReportsPath, testSecret, body, now),
+		},
+		{
+			name: "other secret",
+			req:  signedAt(http.MethodPost, ReportsPath, []byte("guess"), body, now),
+			want: "missing or invalid signature",
+		},
+		{
+			name: "body changed",
+			req:  signedAt(http.MethodPost, ReportsPath, testSecret, body, now),
+			body: []byte(`{"host":"db-01"}`),
+			want: "missing or invalid signature",
+		},
+		{
+			name: "signed for another path",
+			req: func() *http.Request {
+				req := signedAt(http.MethodPost, ViewPath, testSecret, body, now)
+				req.URL.Path = ReportsPath
+				return req
+			}(),
+			want: "missing or invalid signature",
+		},
+		{
+			name: "signed for another method",
+			req: func() *http.Request {
+				req := signedAt(http.MethodGet, ReportsPath, testSecret, body, now)
+				req.Method = http.MethodPost
+				return req
+			}(),
+			want: "missing or invalid signature",
+		},
+		{
+			name: "timestamp moved after signing",
+			req: func() *http.Request {
+				req := signedAt(http.MethodPost, ReportsPath, testSecret, body, now.Add(-time.Minute))
+				req.Header.Set(headerTimestamp, strconv.FormatInt(now.Unix(), 10))
+				return req
+			}(),
+			want: "missing or invalid signature",
+		},
+		{
+			name: "no signature",
+			req: func() *http.Request {
+				req := signedAt(http.MethodPost, ReportsPath, testSecret, body, now)
+				req.Header.Del(headerSignature)
+				return req
+			}(),
+			want: "missing or invalid signature",
+		},
+		{
+			name: "no timestamp",
+			req: func() *http.Request {
+				req := signedAt(http.MethodPost, ReportsPath, testSecret, body, now)
+				req.Header.Del(headerTimestamp)
+				return req
+			}(),
+			want: "missing or invalid timestamp",
+		},
+	}
+
+	for _, tt := range tests {
+		received := tt.body
+		if received == nil {
+			received = body
+		}
+		err := verify(tt.req, testSecret, received, now)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.want != "" && (err == nil || err.Error() != tt.want):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestVerifyClockSkew(t *testing.T) {
+	now := time.Unix(1705320000, 0)
+	body := []byte(`{}`)
+
+	tests := []struct {
+		sent time.Time
+		want string // "" = accepted
+	}{
+		{sent: now},
+		{sent: now.Add(-maxClockSkew)},
+		{sent: now.Add(maxClockSkew)},
+		{sent: now.Add(-maxClockSkew - time.Second), want: "timestamp is 5m1s off the collector's clock"},
+		{sent: now.Add(maxClockSkew + time.Second), want: "timestamp is -5m1s off the collector's clock"},
+		{sent: now.Add(-time.Hour), want: "timestamp is 1h0m0s off the collector's clock"},
+	}
+
+	for _, tt := range tests {
+		req := signedAt(http.MethodPost, ReportsPath, testSecret, body, tt.sent)
+		err := verify(req, testSecret, body, now)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("sent %s: %v", tt.sent.Sub(now), err)
+		case tt.want != "" && (err == nil || err.Error() != tt.want):
+			t.Errorf("sent %s: err = %v, want %q", tt.sent.Sub(now), err, tt.want)
+		}
+	}
+}
+
+func TestValidateHostName(t *testing.T) {
+	for _, name := range []string{"web-01", "db.example.com"} {
+		if err := ValidateHostName(name); err != nil {
+			t.Errorf("ValidateHostName(%q): %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "web 01", "web/01", "web\t01"} {
+		if err := ValidateHostName(name); err == nil {
+			t.Errorf("ValidateHostName(%q) succeeded, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package history
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// Kind tells what a record holds
+type Kind string
+
+const (
+	// KindSample is one status snapshot of a unit
+	KindSample Kind = "sample"
+	// KindEvent is a change the monitor detected (transition, restart, ...)
+	KindEvent Kind = "event"
+)
+
+// Record is one line of the history file
+type Record struct {
+	Kind   Kind                 `json:"kind"`
+	Time   time.Time            `json:"time"`
+	Unit   string               `json:"unit"`
+	Host   string               `json:"host,omitempty"` // remote host of the unit; "" = this machine
+	User   string               `json:"user,omitempty"` // owner of the unit's user manager; "" = the system manager
+	Status models.ServiceStatus `json:"status"`
+
+	// Samples
+	ActiveState   string `json:"active_state,omitempty"`
+	SubState      string `json:"sub_state,omitempty"`
+	PID           int    `json:"pid,omitempty"`
+	Restarts      int    `json:"restarts,omitempty"`
+	MemoryBytes   int64  `json:"memory_bytes,omitempty"`
+	UptimeSeconds int64  `json:"uptime_seconds,omitempty"`
+
+	// Events
+	Event   models.EventType     `json:"event,omitempty"`
+	From    models.ServiceStatus `json:"from,omitempty"`
+	Message string               `json:"message,omitempty"`
+}
+
+// SampleRecord converts a status snapshot into a record
+func SampleRecord(service *models.ServiceInfo) *Record {
+	return &Record{
+		Kind:          KindSample,
+		Time:          service.CheckedAt,
+		Unit:          service.Name,
+		Host:          service.Host,
+		User:          service.User,
+		Status:        service.Status,
+		ActiveState:   service.ActiveState,
+		SubState:      service.SubState,
+		PID:           service.PID,
+		Restarts:      service.Restarts,
+		MemoryBytes:   service.MemoryBytes,
+		UptimeSeconds: int64(service.Uptime / time.Second),
+	}
+}
+
+// EventRecord converts a monitor event into a record
+func EventRecord(event *models.Event) *Record {
+	return &Record{
+		Kind:    KindEvent,
+		Time:    event.Timestamp,
+		Unit:    event.Unit,
+		Host:    event.Host,
+		User:    event.User,
+		Status:  event.To,
+		Event:   event.Type,
+		From:    event.From,
+		Message: event.String(),
+	}
+}
+
+// Query selects records for one unit in a time range
+type Query struct {
+	Unit  string    // normalized unit name; "" = every unit
+	Host  string    // remote host; "" = units of this machine
+	User  string    // owner of the user manager; "" = system units
+	Since time.Time // zero = from the beginning
+	Until time.Time // zero = up to now
+	Kind  Kind      // "" = samples and events
+}
+
+// Match reports whether a record is selected by the query
+func (q Query) Match(record *Record) bool {
+	if q.Unit != "" && record.Unit != q.Unit {
+		return false
+	}
+	if record.Host != q.Host || record.User != q.User {
+		return false
+	}
+	if q.Kind != "" && record.Kind != q.Kind {
+		return false
+	}
+	if !q.Since.IsZero() && record.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && record.Time.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// ParseTime parses a --since/--until value: a duration back from now such
+// as "90m", "24h" or "7d", or a timestamp like "2024-12-22",
+// "2024-12-22 15:04", "2024-12-22 15:04:05" or RFC 3339
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" || value == "now" {
+		return now, nil
+	}
+
+	// 1. Relative: journalctl-style "7d" plus everything ParseDuration takes
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * 24 * time.Hour), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+
+	// 2. Absolute, in local time unless a zone is given
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q (use e.g. 24h, 7d, 2024-12-22 or 2024-12-22 15:04)", value)
+}
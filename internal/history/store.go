@@ -0,0 +1,264 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// Retention bounds the size of the history file
+type Retention struct {
+	MaxAge     time.Duration // drop records older than this (0 = keep forever)
+	Raw        time.Duration // keep every sample this long...
+	Resolution time.Duration // ...then one sample per unit per Resolution (0 = no downsampling)
+}
+
+// DefaultRetention keeps 30 days: every sample of the last day, then one
+// sample per unit every 5 minutes. Events are never downsampled.
+func DefaultRetention() Retention {
+	return Retention{
+		MaxAge:     30 * 24 * time.Hour,
+		Raw:        24 * time.Hour,
+		Resolution: 5 * time.Minute,
+	}
+}
+
+// compactInterval is how often an open store applies its retention
+const compactInterval = time.Hour
+
+// maxRecordLine bounds a single line of the history file
+const maxRecordLine = 1024 * 1024
+
+// Store is an append-only history file with one JSON record per line.
+// Only the process that opened it with Open compacts it; readers use Read.
+type Store struct {
+	path      string
+	retention Retention
+
+	mu        sync.Mutex
+	file      *os.File
+	compacted time.Time
+	now       func() time.Time
+}
+
+// Open opens (creating if needed) the history file at path and applies
+// the retention once
+func Open(path string, retention Retention) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	s := &Store{path: path, retention: retention, now: time.Now}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	if err := s.reopen(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path returns the history file path
+func (s *Store) Path() string {
+	return s.path
+}
+
+// RecordSample appends a status snapshot
+func (s *Store) RecordSample(service *models.ServiceInfo) error {
+	return s.append(SampleRecord(service))
+}
+
+// RecordEvent appends a monitor event
+func (s *Store) RecordEvent(event *models.Event) error {
+	return s.append(EventRecord(event))
+}
+
+// Close closes the history file
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// append writes one record as a single line; a write per record keeps
+// lines whole for concurrent readers
+func (s *Store) append(record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode history record: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+
+	// Apply the retention from time to time
+	if s.now().Sub(s.compacted) >= compactInterval {
+		s.file.Close()
+		if err := s.compact(); err != nil {
+			s.reopen()
+			return err
+		}
+		return s.reopen()
+	}
+	return nil
+}
+
+func (s *Store) reopen() error {
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+
+	// A crash mid-write leaves a partial last line; end it so the next
+	// record starts on a line of its own instead of being glued onto it
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			if _, err := file.Write([]byte{'\n'}); err != nil {
+				file.Close()
+				return fmt.Errorf("failed to write history: %w", err)
+			}
+		}
+	}
+
+	s.file = file
+	return nil
+}
+
+// compact rewrites the file without expired records and with old samples
+// downsampled; the file is replaced atomically
+func (s *Store) compact() error {
+	now := s.now()
+	s.compacted = now
+
+	records, err := Read(s.path, Query{})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	kept := applyRetention(records, s.retention, now)
+	if len(kept) == len(records) {
+		return nil
+	}
+
+	// 1. Write the kept records next to the file
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(w)
+	for _, record := range kept {
+		if err := encoder.Encode(record); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to compact history: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+
+	// 2. Swap it in
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	return nil
+}
+
+// applyRetention drops records older than MaxAge and keeps only the last
+// sample per unit and Resolution bucket once a sample is older than Raw
+func applyRetention(records []*Record, retention Retention, now time.Time) []*Record {
+	// 1. Find the sample that represents each downsampled bucket
+	type bucket struct {
+		unit  string // unit key, see models.UnitKey
+		start time.Time
+	}
+	last := make(map[bucket]int)
+	downsample := func(record *Record) bool {
+		return record.Kind == KindSample && retention.Resolution > 0 && now.Sub(record.Time) > retention.Raw
+	}
+	for i, record := range records {
+		if downsample(record) {
+			last[bucket{models.UnitKey(record.Host, models.ScopedUnit(record.User, record.Unit)), record.Time.Truncate(retention.Resolution)}] = i
+		}
+	}
+
+	// 2. Keep what is young enough and represents its bucket
+	var kept []*Record
+	for i, record := range records {
+		if retention.MaxAge > 0 && now.Sub(record.Time) > retention.MaxAge {
+			continue
+		}
+		if downsample(record) && last[bucket{models.UnitKey(record.Host, models.ScopedUnit(record.User, record.Unit)), record.Time.Truncate(retention.Resolution)}] != i {
+			continue
+		}
+		kept = append(kept, record)
+	}
+	return kept
+}
+
+// Read returns the records of the history file at path that match the
+// query, oldest first. Lines that do not decode, such as a write in
+// progress or one cut short by a crash, are skipped.
+func Read(path string, query Query) ([]*Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := readRecords(file, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history %s: %w", path, err)
+	}
+	return records, nil
+}
+
+func readRecords(r io.Reader, query Query) ([]*Record, error) {
+	var records []*Record
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordLine)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+
+		if query.Match(&record) {
+			records = append(records, &record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Records are appended in check order; sort in case clocks or
+	// concurrent writers interleaved them
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
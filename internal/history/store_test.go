@@ -0,0 +1,247 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+func openTestStore(t *testing.T, path string, retention Retention) *Store {
+	t.Helper()
+	store, err := Open(path, retention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func sample(host, unit string, status models.ServiceStatus, at time.Time) *models.ServiceInfo {
+	return &models.ServiceInfo{Name: unit, Host: host, Status: status, CheckedAt: at, PID: 42}
+}
+
+func TestStoreAppendAndQuery(t *testing.T) {
+	// Open creates the directory
+	store := openTestStore(t, filepath.Join(t.TempDir(), "logs", "history.jsonl"), Retention{})
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	samples := []*models.ServiceInfo{
+		sample("", "nginx.service", models.StatusRunning, start),
+		sample("", "db.service", models.StatusRunning, start.Add(time.Minute)),
+		sample("web-01", "nginx.service", models.StatusFailed, start.Add(2*time.Minute)),
+		// Out of order, as a concurrent writer might leave it
+		sample("", "nginx.service", models.StatusRunning, start.Add(-time.Minute)),
+	}
+	for _, service := range samples {
+		if err := store.RecordSample(service); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failed := sample("", "nginx.service", models.StatusFailed, start.Add(3*time.Minute))
+	event := models.NewEvent(models.EventTransition, failed)
+	event.From = models.StatusRunning
+	if err := store.RecordEvent(event); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []time.Time
+	}{
+		{
+			name:  "one local unit, oldest first",
+			query: Query{Unit: "nginx.service"},
+			want:  []time.Time{start.Add(-time.Minute), start, start.Add(3 * time.Minute)},
+		},
+		{
+			name:  "samples only",
+			query: Query{Unit: "nginx.service", Kind: KindSample},
+			want:  []time.Time{start.Add(-time.Minute), start},
+		},
+		{
+			name:  "remote host",
+			query: Query{Unit: "nginx.service", Host: "web-01"},
+			want:  []time.Time{start.Add(2 * time.Minute)},
+		},
+		{
+			name:  "every local unit in a range",
+			query: Query{Since: start, Until: start.Add(time.Minute)},
+			want:  []time.Time{start, start.Add(time.Minute)},
+		},
+		{
+			name:  "nothing",
+			query: Query{Unit: "cron.service"},
+		},
+	}
+
+	for _, tt := range tests {
+		records, err := Read(store.Path(), tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []time.Time
+		for _, record := range records {
+			got = append(got, record.Time)
+		}
+		if !equalTimes(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	records, err := Read(store.Path(), Query{Unit: "nginx.service", Kind: KindEvent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Event != models.EventTransition || records[0].From != models.StatusRunning || records[0].Status != models.StatusFailed {
+		t.Errorf("event records = %+v", records)
+	}
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	retention := Retention{MaxAge: 48 * time.Hour, Raw: time.Hour, Resolution: 10 * time.Minute}
+
+	at := func(ago time.Duration) time.Time { return now.Add(-ago) }
+	records := []*Record{
+		{Kind: KindSample, Unit: "a.service", Time: at(72 * time.Hour)},                              // 0: expired
+		{Kind: KindEvent, Unit: "a.service", Time: at(72 * time.Hour)},                               // 1: expired
+		{Kind: KindSample, Unit: "a.service", Time: at(3*time.Hour + 9*time.Minute)},                 // 2: bucket 08:50
+		{Kind: KindSample, Unit: "a.service", Time: at(3*time.Hour + 5*time.Minute)},                 // 3: bucket 08:50
+		{Kind: KindSample, Unit: "b.service", Time: at(3*time.Hour + 4*time.Minute)},                 // 4: another unit
+		{Kind: KindSample, Unit: "a.service", Host: "web-01", Time: at(3*time.Hour + 3*time.Minute)}, // 5: another host
+		{Kind: KindEvent, Unit: "a.service", Time: at(3*time.Hour + 2*time.Minute)},                  // 6: events stay
+		{Kind: KindSample, Unit: "a.service", Time: at(3*time.Hour + time.Minute)},                   // 7: bucket 08:50, last
+		{Kind: KindSample, Unit: "a.service", Time: at(2*time.Hour + 55*time.Minute)},                // 8: bucket 09:00
+		{Kind: KindSample, Unit: "a.service", Time: at(30 * time.Minute)},                            // 9: raw
+		{Kind: KindSample, Unit: "a.service", Time: at(29 * time.Minute)},                            // 10: raw
+	}
+
+	kept := applyRetention(records, retention, now)
+	want := []int{4, 5, 6, 7, 8, 9, 10}
+	if len(kept) != len(want) {
+		t.Fatalf("kept %d records, want %d", len(kept), len(want))
+	}
+	for i, index := range want {
+		if kept[i] != records[index] {
+			t.Errorf("kept[%d] = %+v, want record %d", i, kept[i], index)
+		}
+	}
+
+	// Without downsampling only the age counts
+	kept = applyRetention(records, Retention{MaxAge: 48 * time.Hour}, now)
+	if len(kept) != len(records)-2 {
+		t.Errorf("kept %d records, want %d", len(kept), len(records)-2)
+	}
+}
+
+func TestStoreCompacts(t *testing.T) {
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	// A history written by an earlier run: two hours of samples every
+	// minute, starting on a 30m boundary
+	old := openTestStore(t, path, Retention{})
+	base := now.Truncate(30 * time.Minute).Add(-3 * time.Hour)
+	for i := 0; i < 120; i++ {
+		old.RecordSample(sample("", "nginx.service", models.StatusRunning, base.Add(time.Duration(i)*time.Minute)))
+	}
+	old.RecordSample(sample("", "nginx.service", models.StatusRunning, now.Add(-100*time.Hour)))
+	old.Close()
+
+	// Opening compacts: one sample per 30m bucket, none past MaxAge
+	retention := Retention{MaxAge: 72 * time.Hour, Raw: time.Hour, Resolution: 30 * time.Minute}
+	store := openTestStore(t, path, retention)
+	records, err := Read(path, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("%d records after compaction, want 4 (one per 30m bucket)", len(records))
+	}
+	for i, record := range records {
+		if want := base.Add(time.Duration(i)*30*time.Minute + 29*time.Minute); !record.Time.Equal(want) {
+			t.Errorf("bucket %d kept the sample of %s, want the last one (%s)", i, record.Time, want)
+		}
+	}
+
+	// Appends compact again once an hour has passed
+	for i := 0; i < 10; i++ {
+		store.RecordSample(sample("", "nginx.service", models.StatusRunning, now.Add(-30*time.Minute+time.Duration(i)*time.Second)))
+	}
+	before, _ := Read(path, Query{})
+	store.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if err := store.RecordSample(sample("", "nginx.service", models.StatusRunning, now)); err != nil {
+		t.Fatal(err)
+	}
+	after, err := Read(path, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) >= len(before) {
+		t.Errorf("%d records after the hourly compaction, had %d before", len(after), len(before))
+	}
+
+	// The store keeps appending to the new file
+	store.now = func() time.Time { return now.Add(2*time.Hour + time.Minute) }
+	if err := store.RecordSample(sample("", "db.service", models.StatusRunning, now)); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := Read(path, Query{Unit: "db.service"}); len(records) != 1 {
+		t.Errorf("got %d db.service records after compaction, want 1", len(records))
+	}
+}
+
+func TestStoreToleratesPartialLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Now().Truncate(time.Second)
+
+	store := openTestStore(t, path, Retention{})
+	store.RecordSample(sample("", "nginx.service", models.StatusRunning, now.Add(-2*time.Minute)))
+	store.Close()
+
+	// A crash cut the last write short, and a corrupt line sits in between
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("not json\n")
+	file.WriteString(`{"kind":"sample","time":"` + now.Add(-time.Minute).Format(time.RFC3339) + `","unit":"nginx.ser`)
+	file.Close()
+
+	records, err := Read(path, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Errorf("got %d records, want the 1 complete one", len(records))
+	}
+
+	// The next run appends after the partial line, not onto it
+	store = openTestStore(t, path, Retention{})
+	if err := store.RecordSample(sample("", "nginx.service", models.StatusFailed, now)); err != nil {
+		t.Fatal(err)
+	}
+	records, err = Read(path, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].Status != models.StatusFailed {
+		t.Errorf("got %+v, want the old record and the new one", records)
+	}
+}
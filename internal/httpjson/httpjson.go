@@ -0,0 +1,36 @@
+// Package httpjson writes the JSON responses shared by the HTTP API and the
+// fleet collector
+package httpjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ErrorBody is the body of every error response
+type ErrorBody struct {
+	Error string `json:"error"`
+}
+
+// Write sends value as indented JSON with the given status
+func Write(w http.ResponseWriter, status int, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		Error(w, http.StatusInternalServerError, fmt.Sprintf("failed to marshal JSON: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// Error sends {"error": message} with the given status
+func Error(w http.ResponseWriter, status int, message string) {
+	data, _ := json.Marshal(ErrorBody{Error: message})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
@@ -4,17 +4,31 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
 )
 
 // FileLogger handles writing logs to a file
 type FileLogger struct {
+	mu       sync.Mutex // guards file against Reopen
 	filepath string
 	file     *os.File
 }
 
 // NewFileLogger creates a new file logger
 func NewFileLogger(filePath string) (*FileLogger, error) {
+	file, err := openLogFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &FileLogger{filepath: filePath, file: file}, nil
+}
+
+// openLogFile opens a log file for appending, creating its directory
+func openLogFile(filePath string) (*os.File, error) {
 	// 1. Create directory if not exists
 	dir := filepath.Dir(filePath)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
@@ -28,9 +42,37 @@ func NewFileLogger(filePath string) (*FileLogger, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open log file: %w", err)
 	}
+	return file, nil
+}
 
-	// 3. Return &FileLogger{filepath: filePath, file: file}
-	return &FileLogger{filepath: filePath, file: file}, nil
+// Reopen switches to a freshly opened file at filePath ("" = the current
+// path), e.g. after logrotate moved the old one away. The old file stays
+// in use when the new one cannot be opened.
+func (fl *FileLogger) Reopen(filePath string) error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	if filePath == "" {
+		filePath = fl.filepath
+	}
+	file, err := openLogFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if fl.file != nil {
+		fl.file.Close()
+	}
+	fl.filepath = filePath
+	fl.file = file
+	return nil
+}
+
+// Path returns the file currently written to
+func (fl *FileLogger) Path() string {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	return fl.filepath
 }
 
 // WriteLog writes a log message with timestamp
@@ -39,6 +81,9 @@ func (fl *FileLogger) WriteLog(message string) error {
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	// 2. Create log line: line := fmt.Sprintf("[%s] %s\n", timestamp, message)
 	line := fmt.Sprintf("[%s] %s\n", timestamp, message)
+
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
 	_, err := fl.file.WriteString(line)
 	if err != nil {
 		return err
@@ -58,6 +103,26 @@ func (fl *FileLogger) WriteServiceStatus(serviceName string, status string) erro
 	return nil
 }
 
+// WriteEvent writes a monitor event to log. Changes are tagged so they
+// stand out from periodic heartbeat lines.
+func (fl *FileLogger) WriteEvent(event *models.Event) error {
+	if !event.IsChange() {
+		return fl.WriteServiceStatus(event.Key(), string(event.To))
+	}
+
+	// Format: "TRANSITION: Service nginx.service changed: running -> failed",
+	// with the remote host and user manager after the type when set:
+	// "TRANSITION: web1: user@alice: Service app.service changed: ..."
+	message := strings.ToUpper(string(event.Type))
+	if event.Host != "" {
+		message += ": " + event.Host
+	}
+	if event.User != "" {
+		message += ": user@" + event.User
+	}
+	return fl.WriteLog(message + ": " + event.String())
+}
+
 // Error logs an error message
 func (fl *FileLogger) Error(err error) error {
 	message := fmt.Sprintf("ERROR: %v", err)
@@ -70,8 +135,17 @@ func (fl *FileLogger) Info(message string) error {
 	return fl.WriteLog(logMessage)
 }
 
+// Warn logs a warning message
+func (fl *FileLogger) Warn(message string) error {
+	logMessage := fmt.Sprintf("WARNING: %s", message)
+	return fl.WriteLog(logMessage)
+}
+
 // Close closes the log file
 func (fl *FileLogger) Close() error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
 	if fl.file != nil {
 		return fl.file.Close()
 	}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
 )
 
 // JournalLogger handles writing logs to systemd journal
@@ -93,7 +95,7 @@ func (jl *JournalLogger) WriteServiceStatus(serviceName, status string) error {
 		priority = "err"
 	case "running":
 		priority = "info"
-	case "stopped":
+	case "stopped", "degraded":
 		priority = "warning"
 	default:
 		priority = "notice"
@@ -102,6 +104,35 @@ func (jl *JournalLogger) WriteServiceStatus(serviceName, status string) error {
 	return jl.WriteToJournal(message, priority)
 }
 
+// WriteEvent writes a monitor event to journal with a priority matching
+// how serious the event is
+func (jl *JournalLogger) WriteEvent(event *models.Event) error {
+	if !event.IsChange() {
+		return jl.WriteServiceStatus(event.Key(), string(event.To))
+	}
+
+	priority := "notice"
+	switch {
+	case event.Type == models.EventTransition && event.To == models.StatusFailed:
+		priority = "err"
+	case event.Type == models.EventThreshold && event.Severity == "critical":
+		priority = "crit"
+	case event.Type == models.EventTransition && event.To == models.StatusDegraded:
+		priority = "warning"
+	case event.Type == models.EventPIDChanged, event.Type == models.EventRestarted, event.Type == models.EventThreshold:
+		priority = "warning"
+	}
+
+	message := event.String()
+	if event.User != "" {
+		message = "user@" + event.User + ": " + message
+	}
+	if event.Host != "" {
+		message = event.Host + ": " + message
+	}
+	return jl.WriteToJournal(fmt.Sprintf("[%s] %s", strings.ToUpper(string(event.Type)), message), priority)
+}
+
 // WriteMonitoringEvent writes a monitoring event to journal
 func (jl *JournalLogger) WriteMonitoringEvent(event string, details string) error {
 	message := fmt.Sprintf("[MONITORING] %s: %s", event, details)
@@ -0,0 +1,379 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// activeStates are the ActiveState values exported as one series each, so
+// alerts can match e.g. state="failed" == 1
+var activeStates = []string{"active", "reloading", "inactive", "failed", "activating", "deactivating"}
+
+// Filter selects which units are exported. Patterns are globs matched
+// against the unit name with and without its suffix ("nginx*" matches
+// nginx.service).
+type Filter struct {
+	Include []string // empty = every unit
+	Exclude []string
+}
+
+// Match reports whether a unit passes the filter
+func (f Filter) Match(unit string) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, unit) {
+		return false
+	}
+	return !matchAny(f.Exclude, unit)
+}
+
+func matchAny(patterns []string, unit string) bool {
+	base := strings.TrimSuffix(unit, path.Ext(unit))
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, unit); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RefreshFunc returns fresh unit snapshots for a scrape
+type RefreshFunc func() ([]*models.ServiceInfo, error)
+
+// unitRef names a unit on a host; host is "" for this machine and user
+// "" for the system manager
+type unitRef struct {
+	host string
+	user string
+	unit string
+}
+
+// key orders refs like ServiceInfo.Key
+func (r unitRef) key() string {
+	return models.UnitKey(r.host, models.ScopedUnit(r.user, r.unit))
+}
+
+// Exporter keeps the latest snapshot of every unit and serves it in the
+// Prometheus text exposition format. Units of remote hosts carry a host
+// label, user units a user label. Snapshots come either from the
+// monitor loop (Observe) or from a RefreshFunc called on every scrape.
+type Exporter struct {
+	filter  Filter
+	refresh RefreshFunc
+
+	refreshMu sync.Mutex // serializes scrape refreshes
+
+	mu          sync.Mutex
+	units       map[string]*models.ServiceInfo // by ServiceInfo.Key
+	checks      map[string]uint64              // per unit key
+	errors      map[unitRef]uint64
+	refreshErrs uint64 // failed scrape refreshes / unit listings
+	lastRefresh time.Time
+	lastTick    time.Duration // duration of the latest monitor pass
+	overruns    uint64        // monitor passes longer than their interval
+}
+
+// NewExporter creates an exporter for units passing filter
+func NewExporter(filter Filter) *Exporter {
+	return &Exporter{
+		filter: filter,
+		units:  make(map[string]*models.ServiceInfo),
+		checks: make(map[string]uint64),
+		errors: make(map[unitRef]uint64),
+	}
+}
+
+// SetRefresh makes every scrape fetch fresh snapshots with fn; units
+// missing from its result are dropped
+func (e *Exporter) SetRefresh(fn RefreshFunc) {
+	e.refresh = fn
+}
+
+// Observe records a unit snapshot and counts a check
+func (e *Exporter) Observe(service *models.ServiceInfo) {
+	if !e.filter.Match(service.Name) {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.units[service.Key()] = service
+	e.checks[service.Key()]++
+	e.lastRefresh = service.CheckedAt
+}
+
+// ObserveError counts a failed check of unit on host ("" = this
+// machine) in user's manager ("" = the system manager); an empty unit
+// counts a failed listing
+func (e *Exporter) ObserveError(host, user, unit string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if unit == "" {
+		e.refreshErrs++
+		return
+	}
+	if e.filter.Match(unit) {
+		e.errors[unitRef{host, user, unit}]++
+	}
+}
+
+// ObserveTick records how long a monitor pass took and counts it as an
+// overrun when it did not fit into the interval
+func (e *Exporter) ObserveTick(duration time.Duration, overran bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.lastTick = duration
+	if overran {
+		e.overruns++
+	}
+}
+
+// ServeHTTP renders /metrics
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if e.refresh != nil {
+		e.doRefresh()
+	}
+
+	var buf bytes.Buffer
+	e.WriteTo(&buf)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// doRefresh replaces the snapshot with the refresh function's result.
+// Scrapes are serialized so concurrent scrapes don't run systemctl twice.
+func (e *Exporter) doRefresh() {
+	e.refreshMu.Lock()
+	defer e.refreshMu.Unlock()
+
+	// The refresh function may call ObserveError, so it runs unlocked
+	services, err := e.refresh()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.refreshErrs++
+		return
+	}
+
+	units := make(map[string]*models.ServiceInfo, len(services))
+	for _, service := range services {
+		if !e.filter.Match(service.Name) {
+			continue
+		}
+		units[service.Key()] = service
+		e.checks[service.Key()]++
+	}
+	e.units = units
+	e.lastRefresh = time.Now()
+}
+
+// WriteTo writes all metrics in the text exposition format
+func (e *Exporter) WriteTo(buf *bytes.Buffer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	names := make([]string, 0, len(e.units))
+	for name := range e.units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := &writer{buf: buf}
+
+	// 1. Unit state
+	w.family("systemd_unit_active_state", "gauge", "Whether the unit is in the given ActiveState (1) or not (0).")
+	for _, name := range names {
+		unit := e.units[name]
+		for _, state := range activeStates {
+			value := 0.0
+			if unit.ActiveState == state {
+				value = 1
+			}
+			w.sample("systemd_unit_active_state", value, unitLabels(unit, "state", state)...)
+		}
+	}
+
+	w.family("systemd_unit_sub_state", "gauge", "The unit's current SubState; always 1.")
+	for _, name := range names {
+		unit := e.units[name]
+		if unit.SubState != "" {
+			w.sample("systemd_unit_sub_state", 1, unitLabels(unit, "state", unit.SubState)...)
+		}
+	}
+
+	w.family("systemd_unit_up", "gauge", "Whether the unit is running according to the monitor (1) or not (0).")
+	for _, name := range names {
+		unit := e.units[name]
+		value := 0.0
+		if unit.IsRunning() {
+			value = 1
+		}
+		w.sample("systemd_unit_up", value, unitLabels(unit)...)
+	}
+
+	// 2. Process and resources
+	w.family("systemd_unit_main_pid", "gauge", "Main PID of the unit, 0 if none.")
+	for _, name := range names {
+		unit := e.units[name]
+		w.sample("systemd_unit_main_pid", float64(unit.PID), unitLabels(unit)...)
+	}
+
+	w.family("systemd_unit_memory_current_bytes", "gauge", "Memory currently used by the unit's cgroup (MemoryCurrent).")
+	for _, name := range names {
+		unit := e.units[name]
+		if unit.MemoryBytes > 0 {
+			w.sample("systemd_unit_memory_current_bytes", float64(unit.MemoryBytes), unitLabels(unit)...)
+		}
+	}
+
+	w.family("systemd_unit_memory_peak_bytes", "gauge", "Highest memory use of the unit's cgroup.")
+	for _, name := range names {
+		unit := e.units[name]
+		if unit.MemoryPeak > 0 {
+			w.sample("systemd_unit_memory_peak_bytes", float64(unit.MemoryPeak), unitLabels(unit)...)
+		}
+	}
+
+	w.family("systemd_unit_swap_bytes", "gauge", "Swap used by the unit's cgroup.")
+	for _, name := range names {
+		unit := e.units[name]
+		if unit.SwapBytes > 0 {
+			w.sample("systemd_unit_swap_bytes", float64(unit.SwapBytes), unitLabels(unit)...)
+		}
+	}
+
+	w.family("systemd_unit_cpu_seconds_total", "counter", "CPU time consumed by the unit's cgroup; resets with the unit.")
+	for _, name := range names {
+		unit := e.units[name]
+		if unit.CPUUsage > 0 {
+			w.sample("systemd_unit_cpu_seconds_total", unit.CPUUsage.Seconds(), unitLabels(unit)...)
+		}
+	}
+
+	w.family("systemd_unit_io_bytes_total", "counter", "Bytes read and written by the unit's cgroup; resets with the unit.")
+	for _, name := range names {
+		unit := e.units[name]
+		if unit.IOReadBytes > 0 || unit.IOWriteBytes > 0 {
+			w.sample("systemd_unit_io_bytes_total", float64(unit.IOReadBytes), unitLabels(unit, "direction", "read")...)
+			w.sample("systemd_unit_io_bytes_total", float64(unit.IOWriteBytes), unitLabels(unit, "direction", "write")...)
+		}
+	}
+
+	w.family("systemd_unit_tasks", "gauge", "Processes and threads in the unit's cgroup.")
+	for _, name := range names {
+		unit := e.units[name]
+		if unit.Tasks > 0 {
+			w.sample("systemd_unit_tasks", float64(unit.Tasks), unitLabels(unit)...)
+		}
+	}
+
+	w.family("systemd_unit_pressure_some_avg10", "gauge", "Percent of the last 10s some task of the unit stalled on the resource (PSI).")
+	for _, name := range names {
+		unit := e.units[name]
+		if p := unit.Pressure; p != nil {
+			w.sample("systemd_unit_pressure_some_avg10", p.CPU.SomeAvg10, unitLabels(unit, "resource", "cpu")...)
+			w.sample("systemd_unit_pressure_some_avg10", p.Memory.SomeAvg10, unitLabels(unit, "resource", "memory")...)
+			w.sample("systemd_unit_pressure_some_avg10", p.IO.SomeAvg10, unitLabels(unit, "resource", "io")...)
+		}
+	}
+
+	w.family("systemd_unit_probe_success", "gauge", "Whether the unit's health probe succeeded (1) or not (0) at the last check.")
+	for _, name := range names {
+		unit := e.units[name]
+		for _, probe := range unit.Probes {
+			value := 0.0
+			if probe.Healthy {
+				value = 1
+			}
+			w.sample("systemd_unit_probe_success", value, unitLabels(unit, "probe", probe.Name)...)
+		}
+	}
+
+	w.family("systemd_unit_probe_duration_seconds", "gauge", "How long the unit's health probe took at the last check.")
+	for _, name := range names {
+		unit := e.units[name]
+		for _, probe := range unit.Probes {
+			w.sample("systemd_unit_probe_duration_seconds", probe.Latency.Seconds(), unitLabels(unit, "probe", probe.Name)...)
+		}
+	}
+
+	w.family("systemd_unit_uptime_seconds", "gauge", "Seconds since the unit entered the active state.")
+	for _, name := range names {
+		unit := e.units[name]
+		w.sample("systemd_unit_uptime_seconds", unit.Uptime.Seconds(), unitLabels(unit)...)
+	}
+
+	w.family("systemd_unit_restarts", "gauge", "Automatic restarts of the unit (NRestarts); resets with the unit.")
+	for _, name := range names {
+		unit := e.units[name]
+		w.sample("systemd_unit_restarts", float64(unit.Restarts), unitLabels(unit)...)
+	}
+
+	// 3. Monitor counters
+	w.family("systemd_monitor_checks_total", "counter", "Successful status checks per unit.")
+	for _, name := range names {
+		unit := e.units[name]
+		w.sample("systemd_monitor_checks_total", float64(e.checks[name]), unitLabels(unit)...)
+	}
+
+	w.family("systemd_monitor_check_errors_total", "counter", "Failed status checks per unit.")
+	errUnits := make([]unitRef, 0, len(e.errors))
+	for ref := range e.errors {
+		errUnits = append(errUnits, ref)
+	}
+	sort.Slice(errUnits, func(i, j int) bool {
+		return errUnits[i].key() < errUnits[j].key()
+	})
+	for _, ref := range errUnits {
+		labels := []string{"unit", ref.unit, "type", string(models.UnitTypeOf(ref.unit))}
+		if ref.host != "" {
+			labels = append(labels, "host", ref.host)
+		}
+		if ref.user != "" {
+			labels = append(labels, "user", ref.user)
+		}
+		w.sample("systemd_monitor_check_errors_total", float64(e.errors[ref]), labels...)
+	}
+
+	w.family("systemd_monitor_refresh_errors_total", "counter", "Failed unit listings or scrape refreshes.")
+	w.sample("systemd_monitor_refresh_errors_total", float64(e.refreshErrs))
+
+	// Only the monitor loop has ticks; serve-metrics scrapes on demand
+	if e.lastTick > 0 {
+		w.family("systemd_monitor_tick_duration_seconds", "gauge", "Duration of the latest monitor pass.")
+		w.sample("systemd_monitor_tick_duration_seconds", e.lastTick.Seconds())
+
+		w.family("systemd_monitor_tick_overruns_total", "counter", "Monitor passes that took longer than the check interval.")
+		w.sample("systemd_monitor_tick_overruns_total", float64(e.overruns))
+	}
+
+	w.family("systemd_monitor_last_refresh_timestamp_seconds", "gauge", "Unix time of the latest unit snapshot.")
+	if !e.lastRefresh.IsZero() {
+		w.sample("systemd_monitor_last_refresh_timestamp_seconds", float64(e.lastRefresh.UnixNano())/1e9)
+	}
+}
+
+// unitLabels returns the unit, type, host (for remote units) and user
+// (for user units) labels followed by extra pairs
+func unitLabels(unit *models.ServiceInfo, extra ...string) []string {
+	labels := []string{"unit", unit.Name, "type", string(unit.UnitType)}
+	if unit.Host != "" {
+		labels = append(labels, "host", unit.Host)
+	}
+	if unit.User != "" {
+		labels = append(labels, "user", unit.User)
+	}
+	return append(labels, extra...)
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DependencyKind is a unit property naming the units a unit depends on
+// or is ordered against
+type DependencyKind string
+
+const (
+	DependencyRequires DependencyKind = "Requires"
+	DependencyBindsTo  DependencyKind = "BindsTo"
+	DependencyPartOf   DependencyKind = "PartOf"
+	DependencyWants    DependencyKind = "Wants"
+	DependencyAfter    DependencyKind = "After"
+	DependencyBefore   DependencyKind = "Before"
+)
+
+// DependencyKinds lists every kind, strongest first
+var DependencyKinds = []DependencyKind{
+	DependencyRequires, DependencyBindsTo, DependencyPartOf,
+	DependencyWants, DependencyAfter, DependencyBefore,
+}
+
+// ParseDependencyKinds parses a comma-separated list of kinds, e.g.
+// "requires,wants"; "" and "all" select every kind
+func ParseDependencyKinds(s string) ([]DependencyKind, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "all") {
+		return DependencyKinds, nil
+	}
+
+	var kinds []DependencyKind
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		found := false
+		for _, kind := range DependencyKinds {
+			if strings.EqualFold(item, string(kind)) {
+				kinds = append(kinds, kind)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid dependency kind: %s (use requires, bindsto, partof, wants, after or before)", item)
+		}
+	}
+	return kinds, nil
+}
+
+// Reverse returns the property listing the units that depend on a unit
+// this way: Requires= is mirrored by RequiredBy=, After= by Before=, ...
+func (k DependencyKind) Reverse() string {
+	switch k {
+	case DependencyRequires:
+		return "RequiredBy"
+	case DependencyBindsTo:
+		return "BoundBy"
+	case DependencyPartOf:
+		return "ConsistsOf"
+	case DependencyWants:
+		return "WantedBy"
+	case DependencyAfter:
+		return string(DependencyBefore)
+	case DependencyBefore:
+		return string(DependencyAfter)
+	}
+	return ""
+}
+
+// Propagates reports whether a failed dependency of this kind stops the
+// depending unit or keeps it from starting. Wants= tolerates failures;
+// PartOf= only passes on stops and restarts; After= and Before= only
+// order jobs.
+func (k DependencyKind) Propagates() bool {
+	return k == DependencyRequires || k == DependencyBindsTo
+}
+
+// DependencyNode is a unit of a dependency graph
+type DependencyNode struct {
+	Name          string        `json:"name"`
+	Status        ServiceStatus `json:"status"`
+	ActiveState   string        `json:"active_state"`
+	SubState      string        `json:"sub_state"`
+	LoadState     string        `json:"load_state"` // loaded, not-found, masked, ...
+	UnitFileState string        `json:"unit_file_state,omitempty"`
+	Depth         int           `json:"depth"`  // distance from the root
+	Failed        bool          `json:"failed"` // the unit itself failed
+	// Affected is set when the unit requires or binds to a failed unit,
+	// directly or through other units
+	Affected bool `json:"affected,omitempty"`
+	// Truncated is set when the unit has dependencies that were not
+	// followed because of the depth limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// DependencyEdge says that From depends on To; in a reverse graph too
+type DependencyEdge struct {
+	From string         `json:"from"`
+	To   string         `json:"to"`
+	Kind DependencyKind `json:"kind"`
+	// Propagation marks the edges along which a failure of To spreads to
+	// From
+	Propagation bool `json:"propagation,omitempty"`
+}
+
+// DependencyGraph is the dependency graph around one unit: what it
+// depends on, or with Reverse what depends on it
+type DependencyGraph struct {
+	Root    string            `json:"root"`
+	Host    string            `json:"host,omitempty"`
+	User    string            `json:"user,omitempty"`
+	Reverse bool              `json:"reverse"`
+	Nodes   []*DependencyNode `json:"nodes"` // root first, then by distance
+	Edges   []*DependencyEdge `json:"edges"`
+}
+
+// Node returns the node of a unit, or nil
+func (g *DependencyGraph) Node(name string) *DependencyNode {
+	for _, node := range g.Nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return nil
+}
+
+// Children returns the edges leading one step away from the root through
+// a unit: its dependencies, or in a reverse graph the units depending on
+// it
+func (g *DependencyGraph) Children(name string) []*DependencyEdge {
+	var edges []*DependencyEdge
+	for _, edge := range g.Edges {
+		if (!g.Reverse && edge.From == name) || (g.Reverse && edge.To == name) {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// Child returns the unit an edge returned by Children leads to
+func (g *DependencyGraph) Child(edge *DependencyEdge) string {
+	if g.Reverse {
+		return edge.From
+	}
+	return edge.To
+}
+
+// HasFailures reports whether any unit of the graph failed
+func (g *DependencyGraph) HasFailures() bool {
+	for _, node := range g.Nodes {
+		if node.Failed {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkPropagation sets Affected on every unit that requires or binds to a
+// failed unit, directly or through other units, and Propagation on the
+// edges the failure spreads along
+func (g *DependencyGraph) MarkPropagation() {
+	broken := make(map[string]bool)
+	for _, node := range g.Nodes {
+		broken[node.Name] = node.Failed
+	}
+
+	// A unit is broken when it failed or depends on a broken unit through
+	// a propagating edge; repeat until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for _, edge := range g.Edges {
+			if edge.Kind.Propagates() && broken[edge.To] && !broken[edge.From] {
+				if _, ok := broken[edge.From]; ok {
+					broken[edge.From] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	for _, edge := range g.Edges {
+		edge.Propagation = edge.Kind.Propagates() && broken[edge.To] && broken[edge.From]
+	}
+	for _, node := range g.Nodes {
+		node.Affected = broken[node.Name] && !node.Failed
+	}
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventType classifies what the monitor observed about a unit
+type EventType string
+
+const (
+	// EventHeartbeat is a periodic status line; nothing changed
+	EventHeartbeat EventType = "heartbeat"
+	// EventTransition means the status changed, e.g. running -> failed
+	EventTransition EventType = "transition"
+	// EventPIDChanged means the main PID changed while the status stayed
+	// the same, i.e. the service restarted silently between checks
+	EventPIDChanged EventType = "pid_changed"
+	// EventRestarted means systemd's restart counter (NRestarts) increased
+	EventRestarted EventType = "restarted"
+	// EventThreshold means a unit crossed a configured threshold
+	EventThreshold EventType = "threshold"
+	// EventThresholdCleared means a unit is back within a threshold
+	EventThresholdCleared EventType = "threshold_cleared"
+	// EventRemediation reports an automatic repair attempt and its outcome
+	EventRemediation EventType = "remediation"
+	// EventEscalation means automatic repair gave up; a human is needed
+	EventEscalation EventType = "escalation"
+)
+
+type Event struct {
+	Type        EventType     `json:"type"`
+	Unit        string        `json:"unit"`
+	Host        string        `json:"host,omitempty"` // remote host of the unit; "" = this machine
+	User        string        `json:"user,omitempty"` // owner of the unit's user manager; "" = the system manager
+	From        ServiceStatus `json:"from,omitempty"`
+	To          ServiceStatus `json:"to,omitempty"`
+	OldPID      int           `json:"old_pid,omitempty"`
+	NewPID      int           `json:"new_pid,omitempty"`
+	OldRestarts int           `json:"old_restarts,omitempty"`
+	NewRestarts int           `json:"new_restarts,omitempty"`
+	Threshold   string        `json:"threshold,omitempty"` // name of the crossed threshold
+	Severity    string        `json:"severity,omitempty"`  // "warning" or "critical" for threshold rules
+	Message     string        `json:"message"`
+	Timestamp   time.Time     `json:"timestamp"`
+	Service     *ServiceInfo  `json:"service,omitempty"` // snapshot that triggered the event
+}
+
+// NewEvent creates an event for a service snapshot
+func NewEvent(eventType EventType, service *ServiceInfo) *Event {
+	return &Event{
+		Type:      eventType,
+		Unit:      service.Name,
+		Host:      service.Host,
+		User:      service.User,
+		To:        service.Status,
+		Timestamp: service.CheckedAt,
+		Service:   service,
+	}
+}
+
+// Key identifies the event's unit across hosts and managers, see
+// ServiceInfo.Key
+func (e *Event) Key() string {
+	return UnitKey(e.Host, ScopedUnit(e.User, e.Unit))
+}
+
+// IsChange reports whether the event describes a change rather than a
+// periodic heartbeat
+func (e *Event) IsChange() bool {
+	return e.Type != EventHeartbeat
+}
+
+// String renders the event as a single human-readable line
+func (e *Event) String() string {
+	if e.Message != "" {
+		return e.Message
+	}
+
+	switch e.Type {
+	case EventTransition:
+		message := fmt.Sprintf("Service %s changed: %s -> %s", e.Unit, e.From, e.To)
+		if e.To == StatusDegraded && e.Service != nil {
+			// Say which probe failed
+			for _, probe := range e.Service.Probes {
+				if !probe.Healthy {
+					message += fmt.Sprintf(" (%s: %s)", probe.Name, probe.Error)
+					break
+				}
+			}
+		}
+		return message
+	case EventPIDChanged:
+		return fmt.Sprintf("Service %s restarted silently: PID %d -> %d", e.Unit, e.OldPID, e.NewPID)
+	case EventRestarted:
+		return fmt.Sprintf("Service %s was restarted by systemd: restart count %d -> %d", e.Unit, e.OldRestarts, e.NewRestarts)
+	default:
+		return fmt.Sprintf("Service %s is %s", e.Unit, e.To)
+	}
+}
+
+// GetEventIcon returns emoji icon for the event type
+func (e *Event) GetEventIcon() string {
+	switch e.Type {
+	case EventTransition:
+		if e.To == StatusFailed {
+			return "🔴"
+		}
+		if e.To == StatusRunning {
+			return "🟢"
+		}
+		if e.To == StatusDegraded {
+			return "🟠"
+		}
+		return "🔄"
+	case EventPIDChanged, EventRestarted:
+		return "♻️"
+	case EventThreshold:
+		return "⚠️"
+	case EventThresholdCleared:
+		return "✅"
+	case EventRemediation:
+		return "🔧"
+	case EventEscalation:
+		return "🚨"
+	default:
+		return "💓"
+	}
+}
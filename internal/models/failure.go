@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Condition is a Condition*= or Assert*= check of a unit
+type Condition struct {
+	Type      string `json:"type"`              // e.g. ConditionPathExists
+	Parameter string `json:"parameter"`         // e.g. /etc/app.conf
+	Trigger   bool   `json:"trigger,omitempty"` // "|": one passing triggering condition suffices
+	Negate    bool   `json:"negate,omitempty"`  // "!": the check is inverted
+	Result    string `json:"result"`            // passed, failed or untested
+}
+
+// IsAssert reports whether the check is an Assert*=, which fails the
+// start instead of skipping it
+func (c Condition) IsAssert() bool {
+	return strings.HasPrefix(c.Type, "Assert")
+}
+
+// String renders the check as in a unit file, e.g.
+// "ConditionPathExists=!/etc/app.conf"
+func (c Condition) String() string {
+	s := c.Type + "="
+	if c.Trigger {
+		s += "|"
+	}
+	if c.Negate {
+		s += "!"
+	}
+	return s + c.Parameter
+}
+
+// FailureDetails is what systemd recorded about the last run of a unit
+type FailureDetails struct {
+	Result          string      `json:"result"`                     // success, exit-code, signal, timeout, ...
+	ExecMainCode    string      `json:"exec_main_code,omitempty"`   // exited, killed or dumped; "" = never ran
+	ExecMainStatus  int         `json:"exec_main_status"`           // exit status, or signal number when killed
+	ExitedAt        time.Time   `json:"exited_at,omitzero"`         // when the main process exited
+	ChangedAt       time.Time   `json:"changed_at,omitzero"`        // last change of the active state
+	Restarts        int         `json:"restarts"`                   // NRestarts
+	ConditionFailed bool        `json:"condition_failed,omitempty"` // the last start was skipped by a condition
+	AssertFailed    bool        `json:"assert_failed,omitempty"`    // the last start was refused by an assertion
+	Conditions      []Condition `json:"conditions,omitempty"`       // every Condition*= and Assert*=
+}
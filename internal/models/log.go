@@ -1,15 +1,36 @@
 package models
 
 import (
+	"strconv"
 	"strings"
 	"time"
 )
 
+// Syslog priorities as stored in the journal's PRIORITY field
+var priorityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// PriorityInfo is the default journal priority
+const PriorityInfo = 6
+
 type LogEntry struct {
 	Timestamp   time.Time `json:"timestamp"`
 	ServiceName string    `json:"service_name"`
+	Host        string    `json:"host,omitempty"` // remote host the entry was read from; "" = this machine
+	User        string    `json:"user,omitempty"` // owner of the user unit that logged it; "" = a system unit
 	Message     string    `json:"message"`
-	Level       string    `json:"level"` // e.g., "info", "warning", "error", "critical"
+	Level       string    `json:"level"`    // journal priority name: "emerg" ... "debug"
+	Priority    int       `json:"priority"` // journal PRIORITY, 0 (emerg) - 7 (debug)
+
+	// Level found inside the message text (level=warn, [ERROR], ...);
+	// an enrichment only, Level comes from the journal priority
+	MessageLevel string `json:"message_level,omitempty"`
+
+	PID        int               `json:"pid,omitempty"`
+	Hostname   string            `json:"hostname,omitempty"`
+	Identifier string            `json:"identifier,omitempty"` // SYSLOG_IDENTIFIER
+	BootID     string            `json:"boot_id,omitempty"`
+	Cursor     string            `json:"cursor,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"` // every other journal field
 }
 
 func NewLogEntry(serviceName, message string) *LogEntry {
@@ -18,7 +39,33 @@ func NewLogEntry(serviceName, message string) *LogEntry {
 		ServiceName: serviceName,
 		Message:     message,
 		Level:       "info",
+		Priority:    PriorityInfo,
+	}
+}
+
+// PriorityName returns the syslog name of a journal priority
+func PriorityName(priority int) string {
+	if priority < 0 || priority >= len(priorityNames) {
+		return "info"
+	}
+	return priorityNames[priority]
+}
+
+// ParsePriority converts a priority name ("err") or number ("3") into
+// its number
+func ParsePriority(value string) (int, bool) {
+	for i, name := range priorityNames {
+		if value == name || value == strconv.Itoa(i) {
+			return i, true
+		}
 	}
+	return 0, false
+}
+
+// SetPriority sets Priority and the matching Level
+func (l *LogEntry) SetPriority(priority int) {
+	l.Priority = priority
+	l.Level = PriorityName(priority)
 }
 
 // GetColorForLevel returns ANSI color code for log level
@@ -2,6 +2,8 @@ package models
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -12,22 +14,77 @@ const (
 	StatusStopped ServiceStatus = "stopped"
 	StatusFailed  ServiceStatus = "failed"
 	StatusUnknown ServiceStatus = "unknown"
+	// StatusDegraded means systemd reports the unit running but one of its
+	// health probes fails
+	StatusDegraded ServiceStatus = "degraded"
 )
 
 type ServiceInfo struct {
-	Name        string
-	Status      ServiceStatus
-	ActiveState string
-	SubState    string
-	Uptime      time.Duration
-	PID         int
-	MemoryUsage string
-	CheckedAt   time.Time
+	Name          string
+	Host          string // remote host the unit was checked on; "" = this machine
+	User          string // owner of the user manager running the unit; "" = the system manager
+	UnitType      UnitType
+	Status        ServiceStatus
+	ActiveState   string
+	SubState      string
+	UnitFileState string // enabled, disabled, masked, static, ...
+	Uptime        time.Duration
+	PID           int
+	Restarts      int // systemd's NRestarts counter
+	MemoryUsage   string
+	MemoryBytes   int64             // MemoryCurrent in bytes; 0 when unknown
+	Details       map[string]string // type-specific fields, e.g. a timer's NextElapse
+	CheckedAt     time.Time
+
+	// Resource usage of the unit's cgroup; zero when unknown
+	ControlGroup string        // cgroup path, e.g. /system.slice/nginx.service
+	CPUUsage     time.Duration // CPU time consumed since the unit started
+	CPUPercent   float64       // CPU use since the previous sample; 100 = one core
+	CPUSampled   bool          // CPUPercent is known (needs an earlier sample)
+	MemoryPeak   int64         // highest memory use in bytes
+	SwapBytes    int64         // swap in use in bytes
+	IOReadBytes  int64         // bytes read since the unit started
+	IOWriteBytes int64         // bytes written since the unit started
+	Tasks        int           // processes and threads in the cgroup
+	Pressure     *Pressure     // pressure stall information; nil when unavailable
+
+	// Health probe results; only set by the monitor for units with probes
+	Probes []ProbeResult
+}
+
+// ProbeResult is the outcome of one health probe of a unit
+type ProbeResult struct {
+	Name        string        // e.g. "http http://127.0.0.1:8080/health"
+	Healthy     bool          // the probe succeeded
+	Latency     time.Duration // how long the probe took
+	Error       string        // why the probe failed; "" when healthy
+	LastError   string        // most recent failure, kept after recovery
+	LastErrorAt time.Time     // when LastError happened; zero = never failed
+}
+
+// Pressure holds the pressure stall information (PSI) of a cgroup
+type Pressure struct {
+	CPU    PressureStat
+	Memory PressureStat
+	IO     PressureStat
+}
+
+// PressureStat holds the share of time, in percent over the last 10, 60
+// and 300 seconds, in which some or all tasks of a cgroup were stalled
+// waiting for a resource
+type PressureStat struct {
+	SomeAvg10  float64
+	SomeAvg60  float64
+	SomeAvg300 float64
+	FullAvg10  float64
+	FullAvg60  float64
+	FullAvg300 float64
 }
 
 func NewServiceInfo(name string) *ServiceInfo {
 	return &ServiceInfo{
 		Name:        name,
+		UnitType:    UnitTypeOf(name),
 		Status:      StatusUnknown,
 		ActiveState: "",
 		SubState:    "",
@@ -38,6 +95,31 @@ func NewServiceInfo(name string) *ServiceInfo {
 	}
 }
 
+// Key identifies the unit across hosts and managers: its name, prefixed
+// with the host for remote units and the user for user units
+func (s *ServiceInfo) Key() string {
+	return UnitKey(s.Host, ScopedUnit(s.User, s.Name))
+}
+
+// ScopedUnit returns "user@alice/unit" for a unit of alice's user manager
+// and the bare unit name for one of the system manager, after the
+// user@.service instance running the user's units
+func ScopedUnit(user, unit string) string {
+	if user == "" {
+		return unit
+	}
+	return "user@" + user + "/" + unit
+}
+
+// UnitKey returns "host/unit" for a remote unit and the bare unit name for
+// one on this machine; host names never contain a slash
+func UnitKey(host, unit string) string {
+	if host == "" {
+		return unit
+	}
+	return host + "/" + unit
+}
+
 func (s *ServiceInfo) IsRunning() bool {
 	return s.Status == StatusRunning
 }
@@ -57,6 +139,30 @@ func (s *ServiceInfo) GetUptimeString() string {
 	return fmt.Sprintf("%dh %dm", hours, minutes)
 }
 
+// HasResources reports whether any resource usage is known
+func (s *ServiceInfo) HasResources() bool {
+	return s.CPUUsage > 0 || s.MemoryBytes > 0 || s.Tasks > 0 || s.Pressure != nil
+}
+
+// GetDetailString returns the type-specific details as "Key=Value" pairs
+func (s *ServiceInfo) GetDetailString() string {
+	if len(s.Details) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(s.Details))
+	for key := range s.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+s.Details[key])
+	}
+	return strings.Join(parts, " ")
+}
+
 func (s *ServiceInfo) GetStatusIcon() string {
 	// TODO: Return emoji based on status
 	// ✅ for running, ❌ for failed, ⏸️ for stopped
@@ -67,6 +173,8 @@ func (s *ServiceInfo) GetStatusIcon() string {
 		return "❌"
 	case StatusStopped:
 		return "⏸️"
+	case StatusDegraded:
+		return "⚠️"
 	default:
 		return "❓"
 	}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnitType is the kind of a systemd unit, taken from its name suffix
+type UnitType string
+
+const (
+	UnitService   UnitType = "service"
+	UnitTimer     UnitType = "timer"
+	UnitSocket    UnitType = "socket"
+	UnitMount     UnitType = "mount"
+	UnitAutomount UnitType = "automount"
+	UnitPath      UnitType = "path"
+	UnitTarget    UnitType = "target"
+	UnitScope     UnitType = "scope"
+	UnitSlice     UnitType = "slice"
+	UnitDevice    UnitType = "device"
+	UnitSwap      UnitType = "swap"
+
+	// UnitAll is not a real unit type; it selects every type when listing
+	UnitAll UnitType = "all"
+)
+
+// UnitTypes lists every unit type systemd knows about
+var UnitTypes = []UnitType{
+	UnitService, UnitTimer, UnitSocket, UnitMount, UnitAutomount, UnitPath,
+	UnitTarget, UnitScope, UnitSlice, UnitDevice, UnitSwap,
+}
+
+// ParseUnitType converts a --type flag value into a UnitType
+func ParseUnitType(s string) (UnitType, error) {
+	s = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), ".")
+	if s == string(UnitAll) {
+		return UnitAll, nil
+	}
+	for _, t := range UnitTypes {
+		if s == string(t) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("invalid unit type: %s", s)
+}
+
+// Suffix returns the unit name suffix for the type, e.g. ".timer"
+func (t UnitType) Suffix() string {
+	return "." + string(t)
+}
+
+// UnitTypeOf returns the type encoded in a unit name's suffix, or "" if
+// the name has no known suffix
+func UnitTypeOf(name string) UnitType {
+	dot := strings.LastIndex(name, ".")
+	if dot < 0 {
+		return ""
+	}
+	suffix := UnitType(name[dot+1:])
+	for _, t := range UnitTypes {
+		if suffix == t {
+			return t
+		}
+	}
+	return ""
+}
+
+// NormalizeUnitName appends the suffix of defaultType unless the name
+// already carries an explicit unit suffix ("nginx" -> "nginx.service",
+// "backup.timer" stays "backup.timer")
+func NormalizeUnitName(name string, defaultType UnitType) string {
+	if UnitTypeOf(name) != "" {
+		return name
+	}
+	if defaultType == "" || defaultType == UnitAll {
+		defaultType = UnitService
+	}
+	return name + defaultType.Suffix()
+}
@@ -0,0 +1,657 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/daemon"
+	"github.com/andinianst93/systemd-monitoring/internal/fleet"
+	"github.com/andinianst93/systemd-monitoring/internal/history"
+	"github.com/andinianst93/systemd-monitoring/internal/logger"
+	"github.com/andinianst93/systemd-monitoring/internal/metrics"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+	"github.com/andinianst93/systemd-monitoring/internal/notify"
+	"github.com/andinianst93/systemd-monitoring/internal/output"
+	"github.com/andinianst93/systemd-monitoring/internal/pool"
+	"github.com/andinianst93/systemd-monitoring/internal/probe"
+	"github.com/andinianst93/systemd-monitoring/internal/remediate"
+	"github.com/andinianst93/systemd-monitoring/internal/rules"
+	"github.com/andinianst93/systemd-monitoring/internal/systemd"
+)
+
+// Target is one entry of the watch list: a unit, or every unit matching a
+// glob pattern
+type Target struct {
+	Name     string          // normalized unit name
+	Pattern  string          // glob matched against listed units, used when Name is empty
+	Type     models.UnitType // unit type listed for Pattern
+	Interval time.Duration   // 0 = Options.Interval
+
+	// Expected status; "" only alerts when the unit fails
+	Expected models.ServiceStatus
+	// Alert routes receiving this target's events
+	Routes []string
+
+	MaxRestarts int           // 0 = no limit
+	MinUptime   time.Duration // 0 = no flapping detection
+	Rules       []*rules.Rule // resource rules for this target's units
+	// Health probes; a running unit failing one is degraded
+	Probes []*probe.Probe
+
+	// Repair policy when the unit fails; nil = only watch
+	Remediation *remediate.Policy
+
+	// Client checks the target on a remote host or in a user manager;
+	// nil = the monitor's client. The same unit may be watched on several
+	// hosts.
+	Client *systemd.Client
+}
+
+// host returns the remote host the target is checked on; "" = this machine
+func (t Target) host() string {
+	if t.Client == nil {
+		return ""
+	}
+	return t.Client.Host()
+}
+
+// user returns the owner of the user manager the target is checked in;
+// "" = the system manager
+func (t Target) user() string {
+	if t.Client == nil {
+		return ""
+	}
+	return t.Client.Scope().User
+}
+
+// key returns the key of one of the target's units, see ServiceInfo.Key
+func (t Target) key(unit string) string {
+	return models.UnitKey(t.host(), models.ScopedUnit(t.user(), unit))
+}
+
+// Options configures a Monitor
+type Options struct {
+	Interval      time.Duration // default check interval
+	ChangesOnly   bool          // suppress heartbeat lines
+	FileLogger    *logger.FileLogger
+	JournalLogger *logger.JournalLogger // nil = no journal output
+	History       *history.Store        // nil = no history
+	Routes        map[string]*notify.Dispatcher
+	Metrics       *metrics.Exporter // nil = no metrics
+	Remediator    *remediate.Engine // nil = no remediation
+	Rules         []*rules.Rule     // rules for every unit matching their glob
+	Workers       int               // status checks running at once; 0 = pool.DefaultWorkers
+	CheckTimeout  time.Duration     // limit per status check; 0 = none
+	Notifier      *daemon.Notifier  // nil = not running under systemd
+	Agent         *fleet.Agent      // nil = no collector to report to
+}
+
+// Monitor periodically checks a set of targets, turns the results into
+// events and fans them out to the console, loggers and alert routes
+type Monitor struct {
+	client  *systemd.Client
+	targets []Target
+	opts    Options
+	tracker *Tracker
+
+	next       []time.Time    // next due check per target
+	owner      map[string]int // unit key -> index of the target watching it
+	thresholds map[string]bool
+	rules      *rules.Engine
+	probes     *probe.Runner
+
+	reloads chan reload          // new settings, applied between passes
+	retired []*notify.Dispatcher // routes replaced by a reload
+	stats   Stats
+	pass    passResult      // queries of the latest pass
+	changes []*models.Event // changes of the current pass, for the agent
+}
+
+// passResult counts the status queries of one pass
+type passResult struct {
+	queries  int
+	failures int
+}
+
+// healthy reports whether the pass could reach systemd at all; a pass in
+// which every query failed does not ping the watchdog
+func (p passResult) healthy() bool {
+	return p.queries == 0 || p.failures < p.queries
+}
+
+// reload is a new watch list and options handed to Run
+type reload struct {
+	targets []Target
+	opts    Options
+}
+
+// Stats counts what a monitor did since it was created
+type Stats struct {
+	Started  time.Time
+	Passes   int // completed check passes
+	Checks   int // unit snapshots taken
+	Errors   int // failed status queries
+	Changes  int // events other than heartbeats
+	Overruns int // passes longer than the tick interval
+	Reloads  int
+}
+
+// String summarizes the stats for the log file
+func (s Stats) String() string {
+	return fmt.Sprintf("ran %s: %d passes, %d checks, %d changes, %d errors, %d overruns, %d reloads",
+		time.Since(s.Started).Round(time.Second), s.Passes, s.Checks, s.Changes, s.Errors, s.Overruns, s.Reloads)
+}
+
+// New creates a monitor for targets
+func New(client *systemd.Client, targets []Target, opts Options) *Monitor {
+	m := &Monitor{
+		client:     client,
+		tracker:    NewTracker(),
+		thresholds: make(map[string]bool),
+		rules:      rules.NewEngine(),
+		probes:     probe.NewRunner(),
+		reloads:    make(chan reload, 1),
+		stats:      Stats{Started: time.Now()},
+	}
+	m.setTargets(targets, opts)
+
+	return m
+}
+
+// watches reports whether any target covers the unit on its host
+func (m *Monitor) watches(service *models.ServiceInfo) bool {
+	for _, target := range m.targets {
+		if target.host() != service.Host || target.user() != service.User {
+			continue
+		}
+		if target.Name == service.Name {
+			return true
+		}
+		if target.Name == "" {
+			if matched, _ := path.Match(target.Pattern, service.Name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setTargets replaces the watch list; every target is due at once
+func (m *Monitor) setTargets(targets []Target, opts Options) {
+	// Targets without a client of their own use the monitor's
+	m.targets = make([]Target, len(targets))
+	for i, target := range targets {
+		if target.Client == nil {
+			target.Client = m.client
+		}
+		m.targets[i] = target
+	}
+	m.opts = opts
+	m.next = make([]time.Time, len(targets))
+	m.owner = make(map[string]int)
+
+	// Explicit names win over patterns matching the same unit
+	for i, target := range m.targets {
+		if target.Name != "" {
+			key := target.key(target.Name)
+			if _, taken := m.owner[key]; !taken {
+				m.owner[key] = i
+			}
+		}
+	}
+}
+
+// Reload hands Run a new watch list and options, e.g. after the config
+// file changed. They take effect before the next pass; unit states,
+// thresholds and rule windows carry over.
+func (m *Monitor) Reload(targets []Target, opts Options) {
+	m.reloads <- reload{targets: targets, opts: opts}
+}
+
+// Stats returns the counters; call it once Run has returned
+func (m *Monitor) Stats() Stats {
+	return m.stats
+}
+
+// TickInterval returns how often Run wakes up: the shortest interval of
+// any target
+func (m *Monitor) TickInterval() time.Duration {
+	tick := m.opts.Interval
+	for _, target := range m.targets {
+		if target.Interval > 0 && target.Interval < tick {
+			tick = target.Interval
+		}
+	}
+	return tick
+}
+
+// Run checks the targets until ctx is done. A pass interrupted by ctx
+// is dropped, so shutting down does not report its units as failing.
+func (m *Monitor) Run(ctx context.Context) {
+	tick := m.TickInterval()
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case r := <-m.reloads:
+			// Deliveries still in flight on replaced routes finish in Wait
+			for name, dispatcher := range m.opts.Routes {
+				if r.opts.Routes[name] != dispatcher {
+					m.retired = append(m.retired, dispatcher)
+				}
+			}
+			m.setTargets(r.targets, r.opts)
+			m.stats.Reloads++
+
+			// Units no longer watched drop out of the status summary
+			for _, service := range m.tracker.Services() {
+				if !m.watches(service) {
+					m.tracker.Forget(service.Key())
+				}
+			}
+
+			tick = m.TickInterval()
+			ticker.Reset(tick)
+
+		case now := <-ticker.C:
+			m.Check(ctx, now)
+			if ctx.Err() == nil {
+				m.reportTick(time.Since(now), tick)
+				m.notifyPass()
+			}
+		}
+	}
+}
+
+// notifyPass pings the systemd watchdog after a healthy pass and
+// updates the status line shown by systemctl status
+func (m *Monitor) notifyPass() {
+	notifier := m.opts.Notifier
+	if notifier == nil {
+		return
+	}
+
+	state := []string{"STATUS=" + m.Summary()}
+	if m.pass.healthy() && notifier.WatchdogInterval() > 0 {
+		state = append(state, "WATCHDOG=1")
+	}
+	notifier.Notify(state...)
+}
+
+// Summary describes the last known state of the watched units, e.g.
+// "12 services, 1 failed"
+func (m *Monitor) Summary() string {
+	return m.tracker.Summary()
+}
+
+// reportTick warns about a pass that took longer than the tick interval,
+// which delays the following checks (the ticker drops missed ticks)
+func (m *Monitor) reportTick(elapsed, tick time.Duration) {
+	overran := elapsed > tick
+	if m.opts.Metrics != nil {
+		m.opts.Metrics.ObserveTick(elapsed, overran)
+	}
+	if !overran {
+		return
+	}
+	m.stats.Overruns++
+
+	message := fmt.Sprintf("Check took %s, longer than the %s interval", elapsed.Round(time.Millisecond), tick)
+	m.opts.FileLogger.Warn(message)
+	fmt.Println("Warning:", message)
+}
+
+// observation is a unit snapshot and the target it was checked for
+type observation struct {
+	target  Target
+	service *models.ServiceInfo
+}
+
+// fetch is the status query of one due target, run in the worker pool
+type fetch struct {
+	index    int
+	statuses []*models.ServiceInfo
+	err      error
+}
+
+// Check runs one pass over every target that is due at now. Status
+// queries run concurrently; their results are handled in target order.
+// Nothing is reported when ctx is done before the queries finish.
+func (m *Monitor) Check(ctx context.Context, now time.Time) {
+	due := 0
+	var fetches []*fetch
+
+	for i, target := range m.targets {
+		// 1. Skip targets with a longer interval that are not due yet;
+		// half a tick of slack absorbs ticker jitter
+		if now.Add(m.TickInterval() / 2).Before(m.next[i]) {
+			continue
+		}
+		m.next[i] = now.Add(m.intervalOf(target))
+
+		if due++; due == 1 && !m.opts.ChangesOnly {
+			fmt.Println("\n--- Checking services ---")
+		}
+
+		if target.Name != "" && m.owner[target.key(target.Name)] != i {
+			continue
+		}
+		fetches = append(fetches, &fetch{index: i})
+	}
+	m.pass = passResult{queries: len(fetches)}
+
+	// 2. Query the due targets through the worker pool
+	pool.Run(ctx, len(fetches), m.opts.Workers, m.opts.CheckTimeout, func(ctx context.Context, i int) {
+		m.fetch(ctx, fetches[i])
+	})
+	if ctx.Err() != nil {
+		return
+	}
+
+	// 3. Resolve the results into unit snapshots
+	var observations []observation
+	for _, f := range fetches {
+		target := m.targets[f.index]
+		if target.Name != "" {
+			if f.err != nil {
+				if m.opts.Metrics != nil {
+					m.opts.Metrics.ObserveError(target.host(), target.user(), target.Name)
+				}
+				m.opts.FileLogger.Error(hostError(target.host(), f.err))
+				fmt.Printf("Error checking %s: %v\n", target.key(target.Name), f.err)
+				m.stats.Errors++
+				m.pass.failures++
+				continue
+			}
+			observations = append(observations, observation{target, f.statuses[0]})
+		} else {
+			for _, service := range m.expand(f, target) {
+				observations = append(observations, observation{target, service})
+			}
+		}
+	}
+
+	// 4. Probe the running units, all at once
+	m.probe(ctx, observations)
+
+	// 5. Turn each snapshot into events
+	for _, obs := range observations {
+		m.observe(ctx, obs.target, obs.service)
+	}
+	m.stats.Passes++
+	m.stats.Checks += len(observations)
+	m.report()
+
+	// Log summary
+	if !m.opts.ChangesOnly && len(observations) > 0 {
+		m.opts.FileLogger.Info(fmt.Sprintf("Checked %d services", len(observations)))
+	}
+}
+
+// probe runs the health probes of every running unit concurrently and
+// marks the units failing one as degraded
+func (m *Monitor) probe(ctx context.Context, observations []observation) {
+	var checks []probe.Check
+	var probed []*models.ServiceInfo
+	for _, obs := range observations {
+		if len(obs.target.Probes) == 0 || !obs.service.IsRunning() {
+			continue
+		}
+		checks = append(checks, probe.Check{Unit: obs.service.Name, Host: obs.service.Host, User: obs.service.User, Probes: obs.target.Probes})
+		probed = append(probed, obs.service)
+	}
+	if len(checks) == 0 {
+		return
+	}
+
+	results := m.probes.RunAll(ctx, checks)
+	for i, service := range probed {
+		probe.Apply(service, results[i])
+	}
+}
+
+// Wait blocks until all in-flight alert deliveries have finished
+func (m *Monitor) Wait() {
+	for _, dispatcher := range m.opts.Routes {
+		dispatcher.Wait()
+	}
+	for _, dispatcher := range m.retired {
+		dispatcher.Wait()
+	}
+}
+
+func (m *Monitor) intervalOf(target Target) time.Duration {
+	if target.Interval > 0 {
+		return target.Interval
+	}
+	return m.opts.Interval
+}
+
+// fetch queries the status of a target: its unit, or every unit
+// matching its pattern
+func (m *Monitor) fetch(ctx context.Context, f *fetch) {
+	target := m.targets[f.index]
+	client := m.client
+	if target.Client != nil {
+		client = target.Client
+	}
+
+	if target.Name != "" {
+		service, err := client.GetServiceStatus(ctx, target.Name)
+		f.statuses, f.err = []*models.ServiceInfo{service}, timeoutError(ctx, err, m.opts.CheckTimeout)
+		return
+	}
+
+	f.statuses, f.err = client.ListUnitStatuses(ctx, target.Type, func(name string) bool {
+		matched, _ := path.Match(target.Pattern, name)
+		return matched
+	})
+	f.err = timeoutError(ctx, f.err, m.opts.CheckTimeout)
+}
+
+// timeoutError replaces the error of a check cancelled by its timeout
+// with one saying so
+func timeoutError(ctx context.Context, err error, timeout time.Duration) error {
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("check timed out after %s", timeout)
+	}
+	return err
+}
+
+// hostError prefixes the error of a check on a remote host with the host
+func hostError(host string, err error) error {
+	if host == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", host, err)
+}
+
+// expand returns the statuses fetched for a pattern target, skipping
+// units that another target already watches
+func (m *Monitor) expand(f *fetch, target Target) []*models.ServiceInfo {
+	if f.err != nil {
+		if m.opts.Metrics != nil {
+			m.opts.Metrics.ObserveError(target.host(), target.user(), "")
+		}
+		m.opts.FileLogger.Error(hostError(target.host(), f.err))
+		fmt.Printf("Error listing units for %s: %v\n", target.key(target.Pattern), f.err)
+		m.stats.Errors++
+		m.pass.failures++
+		return nil
+	}
+
+	var services []*models.ServiceInfo
+	for _, service := range f.statuses {
+		if owner, taken := m.owner[service.Key()]; taken && owner != f.index {
+			continue
+		}
+		m.owner[service.Key()] = f.index
+		services = append(services, service)
+	}
+	return services
+}
+
+// observe records a snapshot and handles the resulting events; ctx
+// bounds the repairs of failed units
+func (m *Monitor) observe(ctx context.Context, target Target, service *models.ServiceInfo) {
+	if m.opts.Metrics != nil {
+		m.opts.Metrics.Observe(service)
+	}
+
+	events := m.tracker.Observe(service)
+	events = append(events, m.checkThresholds(target, service)...)
+	events = append(events, m.checkRules(target, service)...)
+
+	m.record(service, events)
+	for _, event := range events {
+		if event.IsChange() {
+			m.stats.Changes++
+			m.changes = append(m.changes, event)
+		}
+	}
+
+	for _, event := range events {
+		// Alerts see every event so still-failed units can repeat
+		m.alert(target, event)
+
+		// Heartbeats are suppressed in changes-only mode
+		if !event.IsChange() && m.opts.ChangesOnly {
+			continue
+		}
+
+		// Log to file
+		m.opts.FileLogger.WriteEvent(event)
+
+		// Print to console
+		if event.IsChange() {
+			output.PrintEvent(event)
+			if m.opts.JournalLogger != nil {
+				m.opts.JournalLogger.WriteEvent(event)
+			}
+		} else {
+			output.PrintService(service)
+		}
+	}
+
+	// Repair failed units; the engine keeps its own audit trail, so the
+	// outcome is only shown and alerted here
+	for _, event := range m.remediate(ctx, target, events) {
+		m.record(nil, []*models.Event{event})
+		m.changes = append(m.changes, event)
+		m.alert(target, event)
+		output.PrintEvent(event)
+	}
+}
+
+// report hands the state of every watched unit and the pass's changes to
+// the agent, even when no target was due
+func (m *Monitor) report() {
+	if m.opts.Agent != nil {
+		m.opts.Agent.Push(m.tracker.Services(), m.changes, m.TickInterval())
+	}
+	m.changes = nil
+}
+
+// record stores a snapshot (when not nil) and the changes among events
+// in the history
+func (m *Monitor) record(service *models.ServiceInfo, events []*models.Event) {
+	if m.opts.History == nil {
+		return
+	}
+
+	if service != nil {
+		if err := m.opts.History.RecordSample(service); err != nil {
+			m.opts.FileLogger.Error(err)
+		}
+	}
+	for _, event := range events {
+		if !event.IsChange() {
+			continue
+		}
+		if err := m.opts.History.RecordEvent(event); err != nil {
+			m.opts.FileLogger.Error(err)
+		}
+	}
+}
+
+// remediate hands a target's events to the remediation engine and returns
+// the events describing what it did
+func (m *Monitor) remediate(ctx context.Context, target Target, events []*models.Event) []*models.Event {
+	if target.Remediation == nil || m.opts.Remediator == nil {
+		return nil
+	}
+
+	var results []*models.Event
+	for _, event := range events {
+		results = append(results, m.opts.Remediator.HandleEvent(ctx, event, *target.Remediation)...)
+	}
+	return results
+}
+
+func (m *Monitor) alert(target Target, event *models.Event) {
+	for _, name := range target.Routes {
+		dispatcher, ok := m.opts.Routes[name]
+		if !ok {
+			continue
+		}
+		if target.Expected != "" {
+			dispatcher.HandleEventExpecting(event, target.Expected)
+		} else {
+			dispatcher.HandleEvent(event)
+		}
+	}
+}
+
+// checkThresholds returns an event when a unit crosses one of its target's
+// thresholds and another once it is back within it
+func (m *Monitor) checkThresholds(target Target, service *models.ServiceInfo) []*models.Event {
+	var events []*models.Event
+
+	check := func(name string, exceeded bool, message string) {
+		key := service.Key() + "/" + name
+		if exceeded == m.thresholds[key] {
+			return
+		}
+		m.thresholds[key] = exceeded
+
+		eventType := models.EventThreshold
+		if !exceeded {
+			eventType = models.EventThresholdCleared
+			message = fmt.Sprintf("Service %s is back within %s", service.Name, name)
+		}
+		event := models.NewEvent(eventType, service)
+		event.Threshold = name
+		event.Message = message
+		events = append(events, event)
+	}
+
+	if target.MaxRestarts > 0 {
+		check("max_restarts", service.Restarts > target.MaxRestarts,
+			fmt.Sprintf("Service %s restarted %d times (max %d)", service.Name, service.Restarts, target.MaxRestarts))
+	}
+
+	if target.MinUptime > 0 {
+		flapping := service.IsRunning() && service.Uptime > 0 && service.Uptime < target.MinUptime
+		check("min_uptime", flapping,
+			fmt.Sprintf("Service %s is flapping: up for %s (min %s)", service.Name, service.Uptime.Round(time.Second), target.MinUptime))
+	}
+
+	return events
+}
+
+// checkRules evaluates the target's rules and the global rules matching
+// the unit
+func (m *Monitor) checkRules(target Target, service *models.ServiceInfo) []*models.Event {
+	if len(target.Rules) == 0 && len(m.opts.Rules) == 0 {
+		return nil
+	}
+
+	applicable := append(append([]*rules.Rule(nil), target.Rules...), m.opts.Rules...)
+	return m.rules.Evaluate(applicable, service)
+}
@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// Tracker remembers the last observed ServiceInfo per unit and turns new
+// observations into events. Units are keyed by ServiceInfo.Key, so the same
+// unit on several hosts is tracked separately.
+type Tracker struct {
+	previous map[string]*models.ServiceInfo
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{
+		previous: make(map[string]*models.ServiceInfo),
+	}
+}
+
+// Observe records a new snapshot of a unit and returns what happened since
+// the previous one. The first observation of a unit and any observation
+// without changes yield a single heartbeat event.
+func (t *Tracker) Observe(service *models.ServiceInfo) []*models.Event {
+	prev, seen := t.previous[service.Key()]
+	t.previous[service.Key()] = service
+
+	if !seen {
+		return []*models.Event{models.NewEvent(models.EventHeartbeat, service)}
+	}
+
+	var events []*models.Event
+
+	// 1. Status changed (running -> failed, failed -> running, ...)
+	if prev.Status != service.Status {
+		event := models.NewEvent(models.EventTransition, service)
+		event.From = prev.Status
+		event.OldPID = prev.PID
+		event.NewPID = service.PID
+		events = append(events, event)
+	} else if prev.PID > 0 && service.PID > 0 && prev.PID != service.PID && service.Restarts <= prev.Restarts {
+		// 2. Same status but a different main process that systemd's
+		// restart counter does not explain: silent restart
+		event := models.NewEvent(models.EventPIDChanged, service)
+		event.From = prev.Status
+		event.OldPID = prev.PID
+		event.NewPID = service.PID
+		events = append(events, event)
+	}
+
+	// 3. systemd restarted the unit (Restart= policy) since the last check
+	if service.Restarts > prev.Restarts {
+		event := models.NewEvent(models.EventRestarted, service)
+		event.From = prev.Status
+		event.OldRestarts = prev.Restarts
+		event.NewRestarts = service.Restarts
+		events = append(events, event)
+	}
+
+	if len(events) == 0 {
+		return []*models.Event{models.NewEvent(models.EventHeartbeat, service)}
+	}
+	return events
+}
+
+// Previous returns the last observed snapshot of a unit, if any; key is
+// the unit's ServiceInfo.Key
+func (t *Tracker) Previous(key string) (*models.ServiceInfo, bool) {
+	service, ok := t.previous[key]
+	return service, ok
+}
+
+// Forget drops the remembered state of a unit
+func (t *Tracker) Forget(key string) {
+	delete(t.previous, key)
+}
+
+// Services returns the last snapshot of every remembered unit
+func (t *Tracker) Services() []*models.ServiceInfo {
+	services := make([]*models.ServiceInfo, 0, len(t.previous))
+	for _, service := range t.previous {
+		services = append(services, service)
+	}
+	return services
+}
+
+// Summary describes the last known state of all units in one line, e.g.
+// "12 services, 1 failed, 1 degraded"
+func (t *Tracker) Summary() string {
+	counts := make(map[models.ServiceStatus]int)
+	for _, service := range t.previous {
+		counts[service.Status]++
+	}
+
+	summary := fmt.Sprintf("%d services", len(t.previous))
+	for _, status := range []models.ServiceStatus{models.StatusFailed, models.StatusDegraded, models.StatusStopped, models.StatusUnknown} {
+		if counts[status] > 0 {
+			summary += fmt.Sprintf(", %d %s", counts[status], status)
+		}
+	}
+	return summary
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// CommandNotifier runs a local shell command for every alert. Alert data
+// is passed in MONITOR_* environment variables.
+type CommandNotifier struct {
+	command string
+	timeout time.Duration
+}
+
+// NewCommandNotifier creates a notifier running command via "sh -c"
+func NewCommandNotifier(command string) *CommandNotifier {
+	return &CommandNotifier{
+		command: command,
+		timeout: 30 * time.Second,
+	}
+}
+
+// Name returns "command"
+func (c *CommandNotifier) Name() string {
+	return "command"
+}
+
+// Notify runs the command and fails if it exits non-zero
+func (c *CommandNotifier) Notify(alert *Alert) error {
+	// Stop commands that hang instead of blocking further alerts
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.command)
+	cmd.Env = append(os.Environ(), alertEnv(alert)...)
+	cmd.WaitDelay = 100 * time.Millisecond // don't wait on children keeping the pipes open
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("alert command failed: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// alertEnv returns the environment variables describing an alert
+func alertEnv(alert *Alert) []string {
+	env := []string{
+		"MONITOR_ALERT_KEY=" + alert.Key,
+		"MONITOR_ALERT_STATE=" + string(alert.State),
+		"MONITOR_SEVERITY=" + string(alert.Severity),
+		"MONITOR_UNIT=" + alert.Unit,
+		"MONITOR_HOST=" + alert.Host,
+		"MONITOR_USER=" + alert.User,
+		"MONITOR_SUMMARY=" + alert.Summary,
+		"MONITOR_STARTED_AT=" + alert.StartedAt.Format(time.RFC3339),
+		"MONITOR_TIMESTAMP=" + alert.Timestamp.Format(time.RFC3339),
+	}
+
+	if alert.Event != nil {
+		env = append(env,
+			"MONITOR_EVENT_TYPE="+string(alert.Event.Type),
+			"MONITOR_FROM="+string(alert.Event.From),
+			"MONITOR_TO="+string(alert.Event.To),
+			fmt.Sprintf("MONITOR_PID=%d", alert.Event.NewPID),
+		)
+	}
+
+	return env
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandNotify(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "alert")
+	c := NewCommandNotifier(`printf '%s %s %s %s' "$MONITOR_ALERT_STATE" "$MONITOR_SEVERITY" "$MONITOR_UNIT" "$MONITOR_TO" > ` + out)
+
+	if err := c.Notify(testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "firing critical nginx.service failed" {
+		t.Errorf("command saw %q", got)
+	}
+}
+
+func TestCommandNotifyFails(t *testing.T) {
+	err := NewCommandNotifier("echo no route to pager; exit 3").Notify(testAlert())
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "no route to pager") {
+		t.Errorf("err = %v, want the exit status and output", err)
+	}
+}
+
+func TestCommandNotifyTimeout(t *testing.T) {
+	// The background sleep keeps the output pipe open after sh is killed
+	c := NewCommandNotifier("sleep 5 & sleep 5")
+	c.timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err := c.Notify(testAlert())
+	if err == nil {
+		t.Fatal("a hanging command succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Notify returned after %s, want it bounded by the timeout", elapsed)
+	}
+}
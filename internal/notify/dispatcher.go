@@ -0,0 +1,306 @@
+package notify
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// RetryPolicy controls how often a failed delivery is retried
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts per notifier, including the first
+	InitialBackoff time.Duration // wait before the first retry
+	MaxBackoff     time.Duration // upper bound for the doubling backoff
+}
+
+// DefaultRetryPolicy tries 3 times, waiting 1s then 2s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+// DefaultDedupWindow suppresses repeated one-off alerts (restarts) for the
+// same unit within this window
+const DefaultDedupWindow = 10 * time.Minute
+
+// Dispatcher turns monitor events into alerts, deduplicates them and
+// delivers them to every notifier with retries
+type Dispatcher struct {
+	notifiers      []Notifier
+	retry          RetryPolicy
+	repeatInterval time.Duration // re-send still-firing alerts this often (0 = never)
+	dedupWindow    time.Duration
+
+	mu       sync.Mutex
+	active   map[string]*Alert    // firing alerts by key
+	lastSent map[string]time.Time // last delivery per key
+
+	wg      sync.WaitGroup
+	onError func(notifier string, alert *Alert, err error)
+	now     func() time.Time
+}
+
+// NewDispatcher creates a dispatcher delivering to notifiers
+func NewDispatcher(retry RetryPolicy, repeatInterval time.Duration, notifiers ...Notifier) *Dispatcher {
+	if retry.MaxAttempts < 1 {
+		retry.MaxAttempts = 1
+	}
+
+	return &Dispatcher{
+		notifiers:      notifiers,
+		retry:          retry,
+		repeatInterval: repeatInterval,
+		dedupWindow:    DefaultDedupWindow,
+		active:         make(map[string]*Alert),
+		lastSent:       make(map[string]time.Time),
+		now:            time.Now,
+	}
+}
+
+// AddNotifier registers another destination
+func (d *Dispatcher) AddNotifier(n Notifier) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notifiers = append(d.notifiers, n)
+}
+
+// HasNotifiers reports whether any destination is configured
+func (d *Dispatcher) HasNotifiers() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.notifiers) > 0
+}
+
+// Reconfigure replaces the destinations and delivery settings, e.g. after
+// the config file changed. Firing alerts and dedup state are kept, so a
+// reload neither re-sends nor forgets them.
+func (d *Dispatcher) Reconfigure(retry RetryPolicy, repeatInterval time.Duration, notifiers ...Notifier) {
+	if retry.MaxAttempts < 1 {
+		retry.MaxAttempts = 1
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notifiers = notifiers
+	d.retry = retry
+	d.repeatInterval = repeatInterval
+}
+
+// OnError registers a callback for deliveries that failed after all retries
+func (d *Dispatcher) OnError(fn func(notifier string, alert *Alert, err error)) {
+	d.onError = fn
+}
+
+// HandleEvent maps a monitor event to alerts:
+//   - a unit entering (or found in) the failed state fires a critical alert
+//   - a unit leaving the failed state resolves it (recovery notification)
+//   - a degraded unit (failing health probe) fires a warning until it
+//     leaves the degraded state
+//   - restarts fire a warning, at most once per dedup window
+//   - crossing a threshold fires a warning (or the severity of the rule)
+//     until it clears
+//   - an escalation fires a critical alert until the unit leaves failed
+func (d *Dispatcher) HandleEvent(event *models.Event) {
+	failedKey := event.Key() + "/failed"
+	degradedKey := event.Key() + "/degraded"
+
+	// Leaving a state resolves its alert, whatever the new state is
+	if event.Type == models.EventTransition {
+		switch event.From {
+		case models.StatusFailed:
+			d.Resolve(failedKey, event)
+			d.Resolve(event.Key()+"/escalation", event)
+		case models.StatusDegraded:
+			d.Resolve(degradedKey, event)
+		}
+	}
+
+	switch {
+	case event.To == models.StatusFailed && (event.Type == models.EventTransition || event.Type == models.EventHeartbeat):
+		d.Fire(NewAlert(failedKey, StateFiring, SeverityCritical, event))
+
+	case event.To == models.StatusDegraded && (event.Type == models.EventTransition || event.Type == models.EventHeartbeat):
+		d.Fire(NewAlert(degradedKey, StateFiring, SeverityWarning, event))
+
+	case event.Type == models.EventTransition:
+		// Nothing else to alert on
+
+	default:
+		d.handleNotice(event)
+	}
+}
+
+// HandleEventExpecting is HandleEvent for a unit with a declared expected
+// status: any other status fires (critical when failed, otherwise a
+// warning) and returning to the expected status resolves the alert
+func (d *Dispatcher) HandleEventExpecting(event *models.Event, expected models.ServiceStatus) {
+	stateKey := event.Key() + "/state"
+
+	switch {
+	case event.Type == models.EventTransition || event.Type == models.EventHeartbeat:
+		if event.Type == models.EventTransition && event.From == models.StatusFailed {
+			d.Resolve(event.Key()+"/escalation", event)
+		}
+		if event.To == expected {
+			d.Resolve(stateKey, event)
+			return
+		}
+
+		severity := SeverityWarning
+		if event.To == models.StatusFailed {
+			severity = SeverityCritical
+		}
+		alert := NewAlert(stateKey, StateFiring, severity, event)
+		alert.Summary = fmt.Sprintf("%s (expected %s)", event.String(), expected)
+		d.Fire(alert)
+
+	default:
+		d.handleNotice(event)
+	}
+}
+
+// handleNotice covers the events that alert the same way regardless of
+// the expected status
+func (d *Dispatcher) handleNotice(event *models.Event) {
+	thresholdKey := event.Key() + "/threshold/" + event.Threshold
+
+	switch event.Type {
+	case models.EventRestarted, models.EventPIDChanged:
+		d.Notice(NewAlert(event.Key()+"/"+string(event.Type), StateFiring, SeverityWarning, event))
+
+	case models.EventThreshold:
+		severity := SeverityWarning
+		if event.Severity == string(SeverityCritical) {
+			severity = SeverityCritical
+		}
+		d.Fire(NewAlert(thresholdKey, StateFiring, severity, event))
+
+	case models.EventThresholdCleared:
+		d.Resolve(thresholdKey, event)
+
+	case models.EventRemediation:
+		d.Notice(NewAlert(event.Key()+"/remediation", StateFiring, SeverityInfo, event))
+
+	case models.EventEscalation:
+		d.Fire(NewAlert(event.Key()+"/escalation", StateFiring, SeverityCritical, event))
+	}
+}
+
+// Fire sends a firing alert unless the same key is already firing. A
+// still-firing alert is re-sent every repeatInterval. Returns whether the
+// alert was delivered.
+func (d *Dispatcher) Fire(alert *Alert) bool {
+	d.mu.Lock()
+	now := d.now()
+
+	if existing, ok := d.active[alert.Key]; ok {
+		if d.repeatInterval <= 0 || now.Sub(d.lastSent[alert.Key]) < d.repeatInterval {
+			d.mu.Unlock()
+			return false
+		}
+		alert.StartedAt = existing.StartedAt
+	}
+
+	d.active[alert.Key] = alert
+	d.lastSent[alert.Key] = now
+	d.mu.Unlock()
+
+	d.send(alert)
+	return true
+}
+
+// Notice sends a one-off alert that never resolves, suppressing repeats of
+// the same key within the dedup window
+func (d *Dispatcher) Notice(alert *Alert) bool {
+	d.mu.Lock()
+	now := d.now()
+
+	if last, ok := d.lastSent[alert.Key]; ok && now.Sub(last) < d.dedupWindow {
+		d.mu.Unlock()
+		return false
+	}
+	d.lastSent[alert.Key] = now
+	d.mu.Unlock()
+
+	d.send(alert)
+	return true
+}
+
+// Resolve sends a recovery notification for a firing alert. Nothing is
+// sent if the key is not firing.
+func (d *Dispatcher) Resolve(key string, event *models.Event) bool {
+	d.mu.Lock()
+	firing, ok := d.active[key]
+	if !ok {
+		d.mu.Unlock()
+		return false
+	}
+	delete(d.active, key)
+	delete(d.lastSent, key)
+	d.mu.Unlock()
+
+	resolved := NewAlert(key, StateResolved, firing.Severity, event)
+	resolved.StartedAt = firing.StartedAt
+	resolved.Summary = "Recovered: " + event.String()
+
+	d.send(resolved)
+	return true
+}
+
+// IsFiring reports whether an alert with key is currently firing
+func (d *Dispatcher) IsFiring(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.active[key]
+	return ok
+}
+
+// Wait blocks until all in-flight deliveries have finished
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// send delivers the alert to every notifier in the background
+func (d *Dispatcher) send(alert *Alert) {
+	d.mu.Lock()
+	notifiers := append([]Notifier(nil), d.notifiers...)
+	retry := d.retry
+	d.mu.Unlock()
+
+	for _, n := range notifiers {
+		d.wg.Add(1)
+		go func(n Notifier) {
+			defer d.wg.Done()
+			if err := deliver(n, alert, retry); err != nil && d.onError != nil {
+				d.onError(n.Name(), alert, err)
+			}
+		}(n)
+	}
+}
+
+// deliver calls the notifier with exponential backoff between attempts
+func deliver(n Notifier, alert *Alert, retry RetryPolicy) error {
+	backoff := retry.InitialBackoff
+
+	var err error
+	for attempt := 1; attempt <= retry.MaxAttempts; attempt++ {
+		if err = n.Notify(alert); err == nil {
+			return nil
+		}
+
+		if attempt < retry.MaxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+			if retry.MaxBackoff > 0 && backoff > retry.MaxBackoff {
+				backoff = retry.MaxBackoff
+			}
+		}
+	}
+
+	return err
+}
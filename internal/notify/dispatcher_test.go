@@ -0,0 +1,251 @@
+package notify
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// recorder is a notifier that remembers what it was asked to deliver and
+// fails the first failures calls
+type recorder struct {
+	mu       sync.Mutex
+	alerts   []*Alert
+	calls    int
+	failures int
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Notify(alert *Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.calls <= r.failures {
+		return errors.New("unavailable")
+	}
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+// delivered returns the keys and states delivered so far
+func (r *recorder) delivered() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	for _, alert := range r.alerts {
+		out = append(out, alert.Key+" "+string(alert.State))
+	}
+	return out
+}
+
+// newTestDispatcher returns a dispatcher with a clock the test moves
+func newTestDispatcher(repeat time.Duration, notifiers ...Notifier) (*Dispatcher, *time.Time) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	d := NewDispatcher(RetryPolicy{MaxAttempts: 1}, repeat, notifiers...)
+	d.now = func() time.Time { return now }
+	return d, &now
+}
+
+func transition(unit string, from, to models.ServiceStatus) *models.Event {
+	return &models.Event{Type: models.EventTransition, Unit: unit, Host: "web-01", From: from, To: to}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDispatcherDedup(t *testing.T) {
+	r := &recorder{}
+	d, now := newTestDispatcher(0, r)
+
+	failed := transition("nginx.service", models.StatusRunning, models.StatusFailed)
+	d.HandleEvent(failed)
+	*now = now.Add(time.Hour)
+	d.HandleEvent(&models.Event{Type: models.EventHeartbeat, Unit: "nginx.service", Host: "web-01", To: models.StatusFailed})
+	d.Wait()
+
+	want := []string{"web-01/nginx.service/failed firing"}
+	if got := r.delivered(); !equal(got, want) {
+		t.Errorf("delivered %v, want %v", got, want)
+	}
+	if !d.IsFiring("web-01/nginx.service/failed") {
+		t.Error("alert is not firing")
+	}
+}
+
+func TestDispatcherRepeat(t *testing.T) {
+	r := &recorder{}
+	d, now := newTestDispatcher(30*time.Minute, r)
+
+	first := NewAlert("nginx/failed", StateFiring, SeverityCritical, &models.Event{Unit: "nginx.service", Timestamp: *now})
+	started := first.StartedAt
+	if !d.Fire(first) {
+		t.Fatal("first alert was not sent")
+	}
+
+	tests := []struct {
+		after time.Duration
+		sent  bool
+	}{
+		{after: 10 * time.Minute, sent: false},
+		{after: 20 * time.Minute, sent: true}, // 30m since the last send
+		{after: 29 * time.Minute, sent: false},
+		{after: time.Minute, sent: true},
+	}
+
+	for i, tt := range tests {
+		*now = now.Add(tt.after)
+		alert := NewAlert("nginx/failed", StateFiring, SeverityCritical, &models.Event{Unit: "nginx.service", Timestamp: *now})
+		if sent := d.Fire(alert); sent != tt.sent {
+			t.Errorf("step %d: sent = %v, want %v", i, sent, tt.sent)
+		}
+		if tt.sent && !alert.StartedAt.Equal(started) {
+			t.Errorf("step %d: repeat started at %s, want %s", i, alert.StartedAt, started)
+		}
+	}
+	d.Wait()
+
+	if got := len(r.delivered()); got != 3 {
+		t.Errorf("delivered %d alerts, want 3", got)
+	}
+}
+
+func TestDispatcherReconfigure(t *testing.T) {
+	before, after := &recorder{}, &recorder{}
+	d, now := newTestDispatcher(0, before)
+
+	d.HandleEvent(transition("nginx.service", models.StatusRunning, models.StatusFailed))
+	d.Wait()
+
+	// A reload swaps the destination but keeps the firing alert
+	d.Reconfigure(RetryPolicy{MaxAttempts: 1}, time.Hour, after)
+	*now = now.Add(30 * time.Minute)
+	d.HandleEvent(&models.Event{Type: models.EventHeartbeat, Unit: "nginx.service", Host: "web-01", To: models.StatusFailed})
+	*now = now.Add(30 * time.Minute)
+	d.HandleEvent(&models.Event{Type: models.EventHeartbeat, Unit: "nginx.service", Host: "web-01", To: models.StatusFailed})
+	d.Wait()
+	d.HandleEvent(transition("nginx.service", models.StatusFailed, models.StatusRunning))
+	d.Wait()
+
+	if got, want := before.delivered(), []string{"web-01/nginx.service/failed firing"}; !equal(got, want) {
+		t.Errorf("before the reload: delivered %v, want %v", got, want)
+	}
+	// Repeated once the new repeat interval passed, then resolved
+	want := []string{"web-01/nginx.service/failed firing", "web-01/nginx.service/failed resolved"}
+	if got := after.delivered(); !equal(got, want) {
+		t.Errorf("after the reload: delivered %v, want %v", got, want)
+	}
+	if !after.alerts[0].StartedAt.Equal(before.alerts[0].StartedAt) {
+		t.Errorf("repeat started at %s, want %s", after.alerts[0].StartedAt, before.alerts[0].StartedAt)
+	}
+}
+
+func TestDispatcherResolve(t *testing.T) {
+	r := &recorder{}
+	d, now := newTestDispatcher(0, r)
+
+	// Deliveries run in the background; wait to keep them in order
+	d.HandleEvent(transition("db.service", models.StatusRunning, models.StatusFailed))
+	d.Wait()
+	*now = now.Add(5 * time.Minute)
+	d.HandleEvent(transition("db.service", models.StatusFailed, models.StatusRunning))
+	// Nothing left to resolve
+	d.HandleEvent(transition("db.service", models.StatusFailed, models.StatusRunning))
+	if d.Resolve("web-01/db.service/degraded", transition("db.service", models.StatusDegraded, models.StatusRunning)) {
+		t.Error("resolved an alert that never fired")
+	}
+	d.Wait()
+
+	want := []string{"web-01/db.service/failed firing", "web-01/db.service/failed resolved"}
+	if got := r.delivered(); !equal(got, want) {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+
+	resolved := r.alerts[1]
+	if resolved.Severity != SeverityCritical || resolved.Summary != "Recovered: Service db.service changed: failed -> running" {
+		t.Errorf("resolved alert = %s %q", resolved.Severity, resolved.Summary)
+	}
+	if d.IsFiring("web-01/db.service/failed") {
+		t.Error("alert still firing after it resolved")
+	}
+
+	// A new failure fires again
+	d.HandleEvent(transition("db.service", models.StatusRunning, models.StatusFailed))
+	d.Wait()
+	if got := len(r.delivered()); got != 3 {
+		t.Errorf("delivered %d alerts after a new failure, want 3", got)
+	}
+}
+
+func TestDispatcherNoticeWindow(t *testing.T) {
+	r := &recorder{}
+	d, now := newTestDispatcher(0, r)
+
+	restarted := &models.Event{Type: models.EventRestarted, Unit: "nginx.service", Host: "web-01"}
+	steps := []struct {
+		after time.Duration
+		sent  bool
+	}{
+		{after: 0, sent: true},
+		{after: 5 * time.Minute, sent: false},
+		{after: 4 * time.Minute, sent: false},
+		{after: time.Minute, sent: true}, // DefaultDedupWindow since the first
+	}
+
+	for i, step := range steps {
+		*now = now.Add(step.after)
+		alert := NewAlert(restarted.Key()+"/restarted", StateFiring, SeverityWarning, restarted)
+		if sent := d.Notice(alert); sent != step.sent {
+			t.Errorf("step %d: sent = %v, want %v", i, sent, step.sent)
+		}
+	}
+	d.Wait()
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		attempts  int
+		delivered int
+		wantErr   bool
+	}{
+		{name: "first attempt", failures: 0, attempts: 1, delivered: 1},
+		{name: "after retries", failures: 2, attempts: 3, delivered: 1},
+		{name: "gives up", failures: 5, attempts: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{failures: tt.failures}
+			d := NewDispatcher(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}, 0, r)
+
+			var failedWith error
+			d.OnError(func(notifier string, alert *Alert, err error) {
+				failedWith = err
+			})
+
+			d.Fire(NewAlert("nginx/failed", StateFiring, SeverityCritical, &models.Event{Unit: "nginx.service"}))
+			d.Wait()
+
+			if r.calls != tt.attempts || len(r.alerts) != tt.delivered {
+				t.Errorf("%d attempts, %d delivered; want %d, %d", r.calls, len(r.alerts), tt.attempts, tt.delivered)
+			}
+			if (failedWith != nil) != tt.wantErr {
+				t.Errorf("OnError got %v, want an error: %v", failedWith, tt.wantErr)
+			}
+		})
+	}
+}
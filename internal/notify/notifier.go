@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// Severity of an alert
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// AlertState tells whether an alert starts or ends a problem
+type AlertState string
+
+const (
+	StateFiring   AlertState = "firing"
+	StateResolved AlertState = "resolved"
+)
+
+// Alert is what notifiers deliver to humans
+type Alert struct {
+	Key       string        `json:"key"` // deduplication key, e.g. "nginx.service/failed"
+	State     AlertState    `json:"state"`
+	Severity  Severity      `json:"severity"`
+	Unit      string        `json:"unit"`
+	Host      string        `json:"host"`
+	User      string        `json:"user,omitempty"` // owner of the unit's user manager; "" = a system unit
+	Summary   string        `json:"summary"`
+	StartedAt time.Time     `json:"started_at"`
+	Timestamp time.Time     `json:"timestamp"`
+	Event     *models.Event `json:"event,omitempty"`
+}
+
+// NewAlert creates an alert for a monitor event. Host is the remote host
+// of the event's unit, or this machine's hostname.
+func NewAlert(key string, state AlertState, severity Severity, event *models.Event) *Alert {
+	host := event.Host
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+
+	return &Alert{
+		Key:       key,
+		State:     state,
+		Severity:  severity,
+		Unit:      event.Unit,
+		Host:      host,
+		User:      event.User,
+		Summary:   event.String(),
+		StartedAt: event.Timestamp,
+		Timestamp: event.Timestamp,
+		Event:     event,
+	}
+}
+
+// Title returns a one-line headline, used as e.g. the mail subject
+func (a *Alert) Title() string {
+	if a.State == StateResolved {
+		return fmt.Sprintf("[RESOLVED] %s on %s", models.ScopedUnit(a.User, a.Unit), a.Host)
+	}
+	return fmt.Sprintf("[%s] %s on %s", a.severityLabel(), models.ScopedUnit(a.User, a.Unit), a.Host)
+}
+
+func (a *Alert) severityLabel() string {
+	switch a.Severity {
+	case SeverityCritical:
+		return "CRITICAL"
+	case SeverityWarning:
+		return "WARNING"
+	default:
+		return "INFO"
+	}
+}
+
+// Notifier delivers alerts to one destination
+type Notifier interface {
+	// Name identifies the notifier in logs, e.g. "webhook"
+	Name() string
+	// Notify delivers a single alert; errors are retried by the Dispatcher
+	Notify(alert *Alert) error
+}
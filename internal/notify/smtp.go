@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier sends alerts as plain-text email
+type SMTPNotifier struct {
+	addr     string // host:port
+	from     string
+	to       []string
+	username string
+	password string
+	timeout  time.Duration // bounds the whole exchange with the server
+}
+
+// NewSMTPNotifier creates an email notifier. Authentication is only used
+// when username is set; net/smtp refuses PLAIN auth over unencrypted
+// connections except to localhost.
+func NewSMTPNotifier(addr, from string, to []string, username, password string) *SMTPNotifier {
+	return &SMTPNotifier{
+		addr:     addr,
+		from:     from,
+		to:       to,
+		username: username,
+		password: password,
+		timeout:  30 * time.Second,
+	}
+}
+
+// Name returns "smtp"
+func (s *SMTPNotifier) Name() string {
+	return "smtp"
+}
+
+// Notify sends one email per alert to all recipients
+func (s *SMTPNotifier) Notify(alert *Alert) error {
+	if len(s.to) == 0 {
+		return fmt.Errorf("no recipients configured")
+	}
+
+	host, _, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %s: %w", s.addr, err)
+	}
+
+	if err := s.send(host, s.buildMessage(alert)); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// send delivers a message like smtp.SendMail, but gives up when the
+// server does not answer within the timeout instead of blocking the
+// delivery forever
+func (s *SMTPNotifier) send(host string, message []byte) error {
+	conn, err := net.DialTimeout("tcp", s.addr, s.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("server does not support AUTH")
+		}
+		if err := c.Auth(smtp.PlainAuth("", s.username, s.password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	for _, to := range s.to {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *SMTPNotifier) buildMessage(alert *Alert) []byte {
+	var b strings.Builder
+
+	// Headers
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", alert.Title())
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+
+	// Body
+	fmt.Fprintf(&b, "%s\r\n\r\n", alert.Summary)
+	fmt.Fprintf(&b, "State:    %s\r\n", alert.State)
+	fmt.Fprintf(&b, "Severity: %s\r\n", alert.Severity)
+	fmt.Fprintf(&b, "Unit:     %s\r\n", alert.Unit)
+	fmt.Fprintf(&b, "Host:     %s\r\n", alert.Host)
+	if alert.User != "" {
+		fmt.Fprintf(&b, "User:     %s\r\n", alert.User)
+	}
+	fmt.Fprintf(&b, "Since:    %s\r\n", alert.StartedAt.Format("2006-01-02 15:04:05"))
+	if alert.State == StateResolved {
+		fmt.Fprintf(&b, "Resolved: %s (after %s)\r\n",
+			alert.Timestamp.Format("2006-01-02 15:04:05"),
+			alert.Timestamp.Sub(alert.StartedAt).Round(time.Second))
+	}
+
+	return []byte(b.String())
+}
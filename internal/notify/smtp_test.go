@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveSMTP accepts one connection and answers it like a minimal SMTP
+// server without extensions, returning the received message on done
+func serveSMTP(ln net.Listener, done chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		done <- ""
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 mail.example.com ESMTP")
+
+	var data strings.Builder
+	inData := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			done <- data.String()
+			return
+		}
+		if inData {
+			if line == ".\r\n" {
+				inData = false
+				reply("250 queued")
+				continue
+			}
+			data.WriteString(line)
+			continue
+		}
+
+		switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250 mail.example.com")
+		case strings.HasPrefix(cmd, "MAIL FROM"), strings.HasPrefix(cmd, "RCPT TO"):
+			reply("250 ok")
+		case cmd == "DATA":
+			inData = true
+			reply("354 go ahead")
+		case cmd == "QUIT":
+			reply("221 bye")
+			done <- data.String()
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPNotify(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	done := make(chan string, 1)
+	go serveSMTP(ln, done)
+
+	s := NewSMTPNotifier(ln.Addr().String(), "monitor@example.com", []string{"ops@example.com"}, "", "")
+	if err := s.Notify(testAlert()); err != nil {
+		t.Fatal(err)
+	}
+
+	message := <-done
+	if !strings.Contains(message, "Subject: [CRITICAL] nginx.service on web-01") {
+		t.Errorf("message has no subject:\n%s", message)
+	}
+}
+
+func TestSMTPNotifyAuthUnsupported(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	done := make(chan string, 1)
+	go serveSMTP(ln, done)
+
+	// Credentials must not be dropped silently
+	s := NewSMTPNotifier(ln.Addr().String(), "monitor@example.com", []string{"ops@example.com"}, "monitor", "secret")
+	if err := s.Notify(testAlert()); err == nil || !strings.Contains(err.Error(), "AUTH") {
+		t.Errorf("err = %v, want an AUTH error", err)
+	}
+}
+
+func TestSMTPNotifyTimeout(t *testing.T) {
+	// A server that accepts but never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	s := NewSMTPNotifier(ln.Addr().String(), "monitor@example.com", []string{"ops@example.com"}, "", "")
+	s.timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	if err := s.Notify(testAlert()); err == nil {
+		t.Fatal("Notify succeeded against a silent server")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Notify returned after %s, want it bounded by the timeout", elapsed)
+	}
+}
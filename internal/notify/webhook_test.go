@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+func testAlert() *Alert {
+	return NewAlert("web-01/nginx.service/failed", StateFiring, SeverityCritical, &models.Event{
+		Type: models.EventTransition,
+		Unit: "nginx.service",
+		Host: "web-01",
+		From: models.StatusRunning,
+		To:   models.StatusFailed,
+	})
+}
+
+func TestWebhookNotify(t *testing.T) {
+	var got Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method %s, want POST", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type %q", ct)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := NewWebhookNotifier(server.URL, map[string]string{"Authorization": "Bearer secret"})
+	if err := w.Notify(testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Key != "web-01/nginx.service/failed" || got.State != StateFiring || got.Severity != SeverityCritical || got.Host != "web-01" {
+		t.Errorf("posted %+v", got)
+	}
+	if got.Event == nil || got.Event.To != models.StatusFailed {
+		t.Errorf("posted event %+v", got.Event)
+	}
+}
+
+func TestWebhookStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		body    string
+		wantErr string
+	}{
+		{status: http.StatusOK},
+		{status: http.StatusAccepted},
+		{status: http.StatusMovedPermanently, wantErr: "301"},
+		{status: http.StatusBadRequest, body: "  missing field text\n", wantErr: "400 Bad Request: missing field text"},
+		{status: http.StatusServiceUnavailable, body: strings.Repeat("x", 2000), wantErr: "503"},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+
+		err := NewWebhookNotifier(server.URL, nil).Notify(testAlert())
+		server.Close()
+
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%d: %v", tt.status, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%d: err = %v, want %q", tt.status, err, tt.wantErr)
+		}
+		// Error bodies are cut short
+		if err != nil && len(err.Error()) > 600 {
+			t.Errorf("%d: error of %d bytes", tt.status, len(err.Error()))
+		}
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			http.Error(w, "try again", http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, 0, NewWebhookNotifier(server.URL, nil))
+	var failed error
+	d.OnError(func(notifier string, alert *Alert, err error) { failed = err })
+
+	d.Fire(testAlert())
+	d.Wait()
+
+	if n := requests.Load(); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+	if failed != nil {
+		t.Errorf("delivery failed: %v", failed)
+	}
+}
+
+func TestWebhookUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	err := NewWebhookNotifier(url, nil).Notify(testAlert())
+	if err == nil || !strings.Contains(err.Error(), "failed to post webhook") {
+		t.Errorf("err = %v, want a connection error", err)
+	}
+}
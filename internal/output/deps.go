@@ -0,0 +1,228 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// PrintDependencyTree prints a dependency graph as an indented tree below
+// its root. A unit reached a second time is listed without its subtree.
+// Failed units are red; the edges a failure spreads along and the units
+// it drags down are marked.
+func PrintDependencyTree(graph *models.DependencyGraph) {
+	// 1. Header
+	title := "Dependencies of"
+	if graph.Reverse {
+		title = "Units depending on"
+	}
+	fmt.Printf("%s %s\n\n", title, hostPrefix(graph.Host)+models.ScopedUnit(graph.User, graph.Root))
+
+	// 2. The tree, depth first
+	root := graph.Node(graph.Root)
+	fmt.Println(formatDependencyNode(root))
+	expanded := map[string]bool{graph.Root: true}
+	printDependencyChildren(graph, graph.Root, "", expanded)
+
+	// 3. Summary and the chains a failure spreads along
+	failed, affected := 0, 0
+	for _, node := range graph.Nodes {
+		if node.Failed {
+			failed++
+		}
+		if node.Affected {
+			affected++
+		}
+	}
+	fmt.Printf("\n%d units, %d failed", len(graph.Nodes), failed)
+	if affected > 0 {
+		fmt.Printf(", %d affected", affected)
+	}
+	fmt.Println()
+
+	paths := failurePaths(graph)
+	if len(paths) > 0 {
+		fmt.Println("\nFailure propagation:")
+		for _, path := range paths {
+			fmt.Printf("  %s%s%s\n", ColorRed, path, ColorReset)
+		}
+	}
+}
+
+// printDependencyChildren prints the children of a unit, one line per
+// child with every kind linking them
+func printDependencyChildren(graph *models.DependencyGraph, name, indent string, expanded map[string]bool) {
+	children, kinds, propagation := groupChildren(graph, name)
+
+	for i, child := range children {
+		last := i == len(children)-1
+		branch, next := "├── ", "│   "
+		if last {
+			branch, next = "└── ", "    "
+		}
+
+		label := "[" + strings.Join(kinds[child], ", ") + "] "
+		if propagation[child] {
+			label = ColorRed + label + ColorReset
+		}
+
+		node := graph.Node(child)
+		line := indent + branch + label + formatDependencyNode(node)
+		if expanded[child] {
+			fmt.Println(line + " (see above)")
+			continue
+		}
+		if node.Truncated {
+			line += " ..."
+		}
+		fmt.Println(line)
+
+		expanded[child] = true
+		printDependencyChildren(graph, child, indent+next, expanded)
+	}
+}
+
+// groupChildren returns the children of a unit in edge order, the kinds
+// linking each of them and whether a failure spreads along any of those
+func groupChildren(graph *models.DependencyGraph, name string) ([]string, map[string][]string, map[string]bool) {
+	var children []string
+	kinds := make(map[string][]string)
+	propagation := make(map[string]bool)
+	for _, edge := range graph.Children(name) {
+		child := graph.Child(edge)
+		if _, ok := kinds[child]; !ok {
+			children = append(children, child)
+		}
+		kinds[child] = append(kinds[child], string(edge.Kind))
+		propagation[child] = propagation[child] || edge.Propagation
+	}
+	return children, kinds, propagation
+}
+
+// formatDependencyNode renders a unit as "✅ nginx.service running"
+func formatDependencyNode(node *models.DependencyNode) string {
+	state := string(node.Status)
+	if node.LoadState != "" && node.LoadState != "loaded" {
+		state += ", " + node.LoadState
+	}
+
+	s := fmt.Sprintf("%s %s %s%s%s", statusIcon(node.Status), node.Name, colorizeStatus(node.Status), state, ColorReset)
+	if node.Failed {
+		s = fmt.Sprintf("%s %s%s (%s)%s", statusIcon(node.Status), ColorRed, node.Name, state, ColorReset)
+	}
+	if node.Affected {
+		s += ColorYellow + " ⚠️  depends on a failed unit" + ColorReset
+	}
+	return s
+}
+
+// statusIcon returns the icon check and list show for a status
+func statusIcon(status models.ServiceStatus) string {
+	return (&models.ServiceInfo{Status: status}).GetStatusIcon()
+}
+
+// failurePaths returns the chains from the root along which a failure
+// spreads, walking away from the root like the tree. Arrows point from a
+// unit to what it depends on: "app.target -[Requires]-> db.service
+// (failed)", or in a reverse graph "db.service (failed) <-[Requires]-
+// app.target".
+func failurePaths(graph *models.DependencyGraph) []string {
+	label := func(name string) string {
+		if node := graph.Node(name); node != nil && node.Failed {
+			return name + " (failed)"
+		}
+		return name
+	}
+
+	var paths []string
+	onPath := map[string]bool{graph.Root: true}
+
+	var walk func(name, path string)
+	walk = func(name, path string) {
+		extended := false
+		for _, edge := range graph.Children(name) {
+			child := graph.Child(edge)
+			if !edge.Propagation || onPath[child] {
+				continue
+			}
+			arrow := fmt.Sprintf(" -[%s]-> ", edge.Kind)
+			if graph.Reverse {
+				arrow = fmt.Sprintf(" <-[%s]- ", edge.Kind)
+			}
+
+			extended = true
+			onPath[child] = true
+			walk(child, path+arrow+label(child))
+			onPath[child] = false
+		}
+
+		// A chain ends at the failed unit, or in a reverse graph at the
+		// last unit dragged down
+		if !extended && name != graph.Root {
+			paths = append(paths, path)
+		}
+	}
+	walk(graph.Root, label(graph.Root))
+	return paths
+}
+
+// PrintDependencyDOT prints a dependency graph in Graphviz DOT format,
+// e.g. for "dot -Tsvg". Failed units and the failure's propagation path
+// are red, units it drags down orange; Wants= edges are dashed and
+// ordering edges dotted.
+func PrintDependencyDOT(graph *models.DependencyGraph) {
+	fmt.Printf("digraph %s {\n", dotQuote(graph.Root))
+	fmt.Println("  rankdir=LR;")
+	fmt.Println("  node [shape=box, style=rounded];")
+
+	for _, node := range graph.Nodes {
+		attrs := []string{"label=" + dotQuote(node.Name+"\n"+string(node.Status))}
+		switch {
+		case node.Failed:
+			attrs = append(attrs, "color=red", "fontcolor=red", "penwidth=2")
+		case node.Affected:
+			attrs = append(attrs, "color=orange")
+		}
+		if node.Name == graph.Root {
+			attrs = append(attrs, "style=\"rounded,bold\"")
+		}
+		fmt.Printf("  %s [%s];\n", dotQuote(node.Name), strings.Join(attrs, ", "))
+	}
+
+	for _, edge := range graph.Edges {
+		attrs := []string{"label=" + dotQuote(string(edge.Kind))}
+		switch edge.Kind {
+		case models.DependencyWants:
+			attrs = append(attrs, "style=dashed")
+		case models.DependencyAfter, models.DependencyBefore:
+			attrs = append(attrs, "style=dotted")
+		}
+		if edge.Propagation {
+			attrs = append(attrs, "color=red", "fontcolor=red", "penwidth=2")
+		}
+		fmt.Printf("  %s -> %s [%s];\n", dotQuote(edge.From), dotQuote(edge.To), strings.Join(attrs, ", "))
+	}
+
+	fmt.Println("}")
+}
+
+// dotQuote quotes a DOT identifier
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+// PrintDependencyJSON prints a dependency graph as JSON
+func PrintDependencyJSON(graph *models.DependencyGraph) error {
+	data, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
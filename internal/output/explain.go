@@ -0,0 +1,96 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/andinianst93/systemd-monitoring/internal/diagnose"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// PrintDiagnosis prints a diagnosis as a short block: the likely causes
+// first, then the facts they were drawn from
+func PrintDiagnosis(d *diagnose.Diagnosis) {
+	color := colorizeStatus(d.Status)
+
+	// 1. Header and causes
+	fmt.Printf("%s[%s]%s %s - %s (%s/%s)\n",
+		color,
+		statusIcon(d.Status),
+		ColorReset,
+		hostPrefix(d.Host)+models.ScopedUnit(d.User, d.Unit),
+		d.Status,
+		d.ActiveState,
+		d.SubState)
+
+	fmt.Printf("  Diagnosis: %s%s%s\n", color, d.Summary, ColorReset)
+	for _, cause := range d.Causes[min(1, len(d.Causes)):] {
+		fmt.Printf("             %s\n", cause)
+	}
+
+	// 2. What systemd recorded
+	if d.Result != "" {
+		fmt.Printf("  Result: %s\n", d.Result)
+	}
+	if d.Exit != "" {
+		fmt.Printf("  Main process: %s\n", d.Exit)
+	}
+	if d.Restarts > 0 {
+		fmt.Printf("  Restarts: %d\n", d.Restarts)
+	}
+	if !d.FailedAt.IsZero() {
+		fmt.Printf("  Failed at: %s\n", d.FailedAt.Format("2006-01-02 15:04:05"))
+	}
+	if len(d.FailedConditions) > 0 {
+		conditions := make([]string, len(d.FailedConditions))
+		for i, condition := range d.FailedConditions {
+			conditions[i] = condition.String()
+		}
+		fmt.Printf("  Failed checks: %s\n", strings.Join(conditions, ", "))
+	}
+	if len(d.FailedDependencies) > 0 {
+		dependencies := make([]string, len(d.FailedDependencies))
+		for i, node := range d.FailedDependencies {
+			state := string(node.Status)
+			if node.LoadState != "" && node.LoadState != "loaded" {
+				state = node.LoadState
+			}
+			dependencies[i] = fmt.Sprintf("%s (%s)", node.Name, state)
+		}
+		fmt.Printf("  Failed dependencies: %s%s%s\n", ColorRed, strings.Join(dependencies, ", "), ColorReset)
+	}
+
+	// 3. Journal lines around the failure
+	if len(d.Logs) > 0 {
+		title := "Error logs"
+		if d.LogPriority == "" {
+			title = "Last logs (no error lines)"
+		}
+		fmt.Printf("  %s:\n", title)
+		for _, entry := range d.Logs {
+			fmt.Printf("    %s%s %s%s\n",
+				entry.GetColorForLevel(),
+				entry.Timestamp.Format("2006-01-02 15:04:05"),
+				entry.Message,
+				ColorReset)
+		}
+	}
+
+	for _, err := range d.Errors {
+		fmt.Printf("  %sWarning: %s%s\n", ColorYellow, err, ColorReset)
+	}
+	fmt.Println()
+}
+
+// PrintDiagnosisJSON prints a diagnosis as JSON
+func PrintDiagnosisJSON(d *diagnose.Diagnosis) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
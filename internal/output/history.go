@@ -0,0 +1,99 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/history"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// historyWidth is the inner width of the history table:
+// " " + 19 + " │ " + 17 + " │ " + 10 + " │ " + 50 + " "
+const historyWidth = 107
+
+// PrintHistory prints a unit's history records as a timeline table
+func PrintHistory(unit string, since, until time.Time, records []*history.Record) {
+	border := strings.Repeat("═", historyWidth)
+
+	// 1. Header
+	title := fmt.Sprintf(" HISTORY %s  %s - %s", unit, since.Format("2006-01-02 15:04"), until.Format("2006-01-02 15:04"))
+	fmt.Println("╔" + border + "╗")
+	fmt.Printf("║%-*s║\n", historyWidth, title)
+	fmt.Println("╠" + border + "╣")
+	fmt.Printf("║ %-19s │ %-17s │ %-10s │ %-50s ║\n", "Time", "Event", "Status", "Details")
+	fmt.Println("╠" + border + "╣")
+
+	// 2. One row per record
+	samples, events, failures := 0, 0, 0
+	for _, record := range records {
+		name := string(record.Event)
+		if record.Kind == history.KindSample {
+			name = "sample"
+			samples++
+		} else {
+			events++
+			if record.Event == models.EventTransition && record.Status == models.StatusFailed {
+				failures++
+			}
+		}
+
+		color := colorizeStatus(record.Status)
+		fmt.Printf("║ %-19s │ %-17s │ %s%-10s%s │ %-50s ║\n",
+			record.Time.Format("2006-01-02 15:04:05"),
+			truncateString(name, 17),
+			color,
+			truncateString(string(record.Status), 10),
+			ColorReset,
+			truncateString(recordDetails(record), 50))
+	}
+	if len(records) == 0 {
+		fmt.Printf("║%-*s║\n", historyWidth, " No records in this range")
+	}
+
+	// 3. Footer
+	summary := fmt.Sprintf(" Samples: %d  │ Events: %d  │ Failures: %d", samples, events, failures)
+	fmt.Println("╠" + border + "╣")
+	fmt.Printf("║%-*s║\n", historyWidth, summary)
+	fmt.Println("╚" + border + "╝")
+}
+
+// recordDetails summarizes a record for the Details column
+func recordDetails(record *history.Record) string {
+	if record.Kind == history.KindEvent {
+		return record.Message
+	}
+
+	parts := []string{record.ActiveState + "/" + record.SubState}
+	if record.PID > 0 {
+		parts = append(parts, fmt.Sprintf("pid %d", record.PID))
+	}
+	if record.Restarts > 0 {
+		parts = append(parts, fmt.Sprintf("restarts %d", record.Restarts))
+	}
+	if record.MemoryBytes > 0 {
+		parts = append(parts, fmt.Sprintf("mem %.1fM", float64(record.MemoryBytes)/(1024*1024)))
+	}
+	if record.UptimeSeconds > 0 {
+		parts = append(parts, "up "+(time.Duration(record.UptimeSeconds)*time.Second).String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// PrintHistoryJSON prints history records as a JSON array
+func PrintHistoryJSON(records []*history.Record) error {
+	if records == nil {
+		records = []*history.Record{}
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
@@ -37,3 +37,32 @@ func PrintJSONPretty(service *models.ServiceInfo) error {
 
 	return nil
 }
+
+// PrintLogEntriesJSON prints log entries as a JSON array
+func PrintLogEntriesJSON(entries []*models.LogEntry) error {
+	if entries == nil {
+		entries = []*models.LogEntry{}
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
+
+// PrintLogEntryJSON prints one log entry as a single JSON line, for
+// streaming
+func PrintLogEntryJSON(entry *models.LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/report"
+)
+
+// reportWidth is the inner width of the report table:
+// " " + 24 + " │ " + 8 + " │ " + 7 + " │ " + 10 + " │ " + 10 + " │ " + 10 + " │ " + 8 + " │ " + 7 + " "
+const reportWidth = 107
+
+// PrintReportTable prints availability reports as a table
+func PrintReportTable(reports []*report.UnitReport, since, until time.Time) {
+	border := strings.Repeat("═", reportWidth)
+
+	// 1. Header
+	title := fmt.Sprintf(" AVAILABILITY REPORT  %s - %s", since.Format("2006-01-02 15:04"), until.Format("2006-01-02 15:04"))
+	fmt.Println("╔" + border + "╗")
+	fmt.Printf("║%-*s║\n", reportWidth, title)
+	fmt.Println("╠" + border + "╣")
+	fmt.Printf("║ %-24s │ %8s │ %7s │ %10s │ %10s │ %10s │ %8s │ %-7s ║\n",
+		"Service", "Avail.", "Outages", "MTTR", "MTBF", "Longest", "Coverage", "Source")
+	fmt.Println("╠" + border + "╣")
+
+	// 2. One row per unit
+	for _, r := range reports {
+		avail := "-"
+		color := ColorWhite
+		if r.Observed > 0 {
+			avail = fmt.Sprintf("%.3f%%", r.Availability)
+			color = availabilityColor(r.Availability)
+		}
+
+		fmt.Printf("║ %-24s │ %s%8s%s │ %7d │ %10s │ %10s │ %10s │ %7.1f%% │ %-7s ║\n",
+			truncateString(r.Unit, 24),
+			color, avail, ColorReset,
+			len(r.Outages),
+			formatReportDuration(r.MTTR),
+			formatReportDuration(r.MTBF),
+			formatReportDuration(r.LongestOutage),
+			r.Coverage(),
+			r.Source)
+	}
+
+	// 3. Footer
+	fmt.Println("╠" + border + "╣")
+	fmt.Printf("║%-*s║\n", reportWidth, " Availability = running / observed time; unobserved time is excluded")
+	fmt.Println("╚" + border + "╝")
+}
+
+// availabilityColor highlights availability below common SLA levels
+func availabilityColor(availability float64) string {
+	switch {
+	case availability >= 99.9:
+		return ColorGreen
+	case availability >= 99:
+		return ColorYellow
+	default:
+		return ColorRed
+	}
+}
+
+// formatReportDuration renders a duration compactly, e.g. "3d 4h", "2h 15m",
+// "45s"; zero renders as "-"
+func formatReportDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d <= 0:
+		return "-"
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd %dh", d/(24*time.Hour), (d%(24*time.Hour))/time.Hour)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", d/time.Hour, (d%time.Hour)/time.Minute)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm %ds", d/time.Minute, (d%time.Minute)/time.Second)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+// PrintReportJSON prints availability reports as a JSON array
+func PrintReportJSON(reports []*report.UnitReport) error {
+	if reports == nil {
+		reports = []*report.UnitReport{}
+	}
+
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
+
+// PrintReportCSV prints availability reports as CSV, durations in seconds
+func PrintReportCSV(reports []*report.UnitReport) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{
+		"unit", "since", "until", "source", "availability_percent", "coverage_percent",
+		"uptime_seconds", "downtime_seconds", "outages", "mttr_seconds", "mtbf_seconds", "longest_outage_seconds",
+	})
+
+	seconds := func(d time.Duration) string {
+		return fmt.Sprintf("%.0f", d.Seconds())
+	}
+	for _, r := range reports {
+		w.Write([]string{
+			r.Unit,
+			r.Since.Format(time.RFC3339),
+			r.Until.Format(time.RFC3339),
+			r.Source,
+			fmt.Sprintf("%.4f", r.Availability),
+			fmt.Sprintf("%.2f", r.Coverage()),
+			seconds(r.Uptime),
+			seconds(r.Downtime),
+			fmt.Sprint(len(r.Outages)),
+			seconds(r.MTTR),
+			seconds(r.MTBF),
+			seconds(r.LongestOutage),
+		})
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// reportHTML is a self-contained page: inline CSS, no scripts or external
+// resources, so it can be mailed or archived as is
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": formatReportDuration,
+	"percent":  func(f float64) string { return fmt.Sprintf("%.3f%%", f) },
+	"time":     func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+	"grade": func(r *report.UnitReport) string {
+		switch {
+		case r.Observed == 0:
+			return "none"
+		case r.Availability >= 99.9:
+			return "good"
+		case r.Availability >= 99:
+			return "warn"
+		default:
+			return "bad"
+		}
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Availability report {{time .Since}} - {{time .Until}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin-top: 2em; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.35em 0.7em; text-align: right; }
+th { background: #f2f2f2; }
+td.name, th.name { text-align: left; font-family: monospace; }
+.good { color: #1a7f37; font-weight: bold; }
+.warn { color: #9a6700; font-weight: bold; }
+.bad { color: #cf222e; font-weight: bold; }
+.none { color: #888; }
+.note { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>Availability report</h1>
+<p>{{time .Since}} &ndash; {{time .Until}} &middot; generated {{time .Generated}} on {{.Host}}</p>
+<table>
+<tr><th class="name">Service</th><th>Availability</th><th>Outages</th><th>MTTR</th><th>MTBF</th><th>Longest outage</th><th>Downtime</th><th>Coverage</th><th>Source</th></tr>
+{{range .Reports}}<tr>
+<td class="name">{{.Unit}}</td>
+<td class="{{grade .}}">{{if .Observed}}{{percent .Availability}}{{else}}no data{{end}}</td>
+<td>{{len .Outages}}</td>
+<td>{{duration .MTTR}}</td>
+<td>{{duration .MTBF}}</td>
+<td>{{duration .LongestOutage}}</td>
+<td>{{duration .Downtime}}</td>
+<td>{{printf "%.1f%%" .Coverage}}</td>
+<td>{{.Source}}</td>
+</tr>
+{{end}}</table>
+<p class="note">Availability is running time divided by observed time; time without observations is excluded (see Coverage).</p>
+{{range .Reports}}{{if .Outages}}
+<h2>{{.Unit}}</h2>
+<table>
+<tr><th class="name">Start</th><th class="name">End</th><th>Duration</th><th class="name">Status</th></tr>
+{{range .Outages}}<tr><td class="name">{{time .Start}}</td><td class="name">{{if .Ongoing}}ongoing{{else}}{{time .End}}{{end}}</td><td>{{duration .Duration}}</td><td class="name">{{.Status}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// PrintReportHTML prints availability reports as a self-contained HTML page
+func PrintReportHTML(reports []*report.UnitReport, since, until time.Time) error {
+	host, _ := os.Hostname()
+
+	data := struct {
+		Reports   []*report.UnitReport
+		Since     time.Time
+		Until     time.Time
+		Generated time.Time
+		Host      string
+	}{reports, since, until, time.Now(), host}
+
+	if err := reportHTML.Execute(os.Stdout, data); err != nil {
+		return fmt.Errorf("failed to render HTML: %w", err)
+	}
+	return nil
+}
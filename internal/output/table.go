@@ -2,6 +2,9 @@ package output
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/andinianst93/systemd-monitoring/internal/models"
 )
@@ -14,31 +17,162 @@ const (
 	ColorWhite  = "\033[37m"
 )
 
+// detailsWidth is the width of the optional type-specific Details column
+const detailsWidth = 40
+
 // PrintTable prints services in a formatted table
 func PrintTable(serviceList *models.ServiceList) {
+	printTable(serviceList, nil)
+}
+
+// PrintFleetTable prints the units reported to a collector, marking the
+// rows of hosts that stopped reporting with the time of their last report
+func PrintFleetTable(serviceList *models.ServiceList, stale map[string]time.Time) {
+	printTable(serviceList, stale)
+}
+
+func printTable(serviceList *models.ServiceList, stale map[string]time.Time) {
+	// Only add the Details column when some unit has type-specific data,
+	// and the resource columns when some unit has resource usage
+	showDetails := hasDetails(serviceList)
+	showResources := hasResources(serviceList)
+
 	// 1. Print header
-	printHeader()
+	printHeader(showDetails, showResources)
+
+	// 2. Print services, under a row per host and manager when remote or
+	// user units are listed
+	width := tableWidth(showDetails, showResources)
+	groups := groupByHost(serviceList.Services)
+	scoped := false
+	for _, group := range groups {
+		scoped = scoped || group.user != ""
+	}
+	for i, group := range groups {
+		if group.host != "" || scoped || len(groups) > 1 {
+			lastReport, isStale := stale[group.host]
+			printHostRow(group, scoped, width, i > 0, isStale, lastReport)
+		}
+		printRows(group.services, showDetails, showResources)
+	}
+
+	// 3. Print footer with summary
+	printFooter(serviceList, showDetails, showResources)
+}
+
+// hostGroup is the units of one manager on one host, in list order
+type hostGroup struct {
+	host     string // "" = this machine
+	user     string // owner of the user manager; "" = the system manager
+	services []*models.ServiceInfo
+}
+
+// groupByHost splits units by host and manager, keeping the order in
+// which they and their units first appear
+func groupByHost(services []*models.ServiceInfo) []hostGroup {
+	var groups []hostGroup
+	index := make(map[string]int)
+	for _, service := range services {
+		key := models.UnitKey(service.Host, models.ScopedUnit(service.User, ""))
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, hostGroup{host: service.Host, user: service.User})
+		}
+		groups[i].services = append(groups[i].services, service)
+	}
+	return groups
+}
+
+// printHostRow prints the row introducing a host's units, e.g.
+// "web1: 12 units, 1 failed" or, for a host that stopped reporting,
+// "web1: 12 units, 1 failed, stale since 14:03:10". When user units are
+// listed too the row names the manager: "web1 (user alice): 3 units".
+func printHostRow(group hostGroup, scoped bool, width int, separate, stale bool, lastReport time.Time) {
+	host := group.host
+	if host == "" {
+		host = "localhost"
+	}
+	if group.user != "" {
+		host += " (user " + group.user + ")"
+	} else if scoped {
+		host += " (system)"
+	}
+	failed := 0
+	for _, service := range group.services {
+		if service.IsFailed() {
+			failed++
+		}
+	}
+
+	line := fmt.Sprintf(" %s: %d units", host, len(group.services))
+	if failed > 0 {
+		line += fmt.Sprintf(", %d failed", failed)
+	}
+	if stale {
+		line += ", stale since " + formatSince(lastReport)
+	}
+
+	thin := strings.Repeat("─", width)
+	if separate {
+		fmt.Println("╟" + thin + "╢")
+	}
+	fmt.Printf("║%-*s║\n", width, truncateString(line, width))
+	fmt.Println("╟" + thin + "╢")
+}
+
+// formatSince shows a time of today as a clock time and an older one
+// with its date
+func formatSince(t time.Time) string {
+	t = t.Local()
+	if t.Format("2006-01-02") == time.Now().Format("2006-01-02") {
+		return t.Format("15:04:05")
+	}
+	return t.Format("2006-01-02 15:04")
+}
 
-	// 2. Print services
-	for _, service := range serviceList.Services {
+// printRows prints one table row per unit
+func printRows(services []*models.ServiceInfo, showDetails, showResources bool) {
+	for _, service := range services {
 		// Get color based on status
 		color := colorizeStatus(service.Status)
 
 		// Format: Name (20 chars), Status with icon (12 chars), ActiveState (8 chars), Uptime (17 chars)
-		fmt.Printf("║ %-20s │ %s%-12s%s │ %-8s │ %-17s ║\n",
+		row := fmt.Sprintf("║ %-20s │ %s%-12s%s │ %-8s │ %-17s",
 			truncateString(service.Name, 20),
 			color,
 			service.GetStatusIcon()+" "+string(service.Status),
 			ColorReset,
 			service.ActiveState,
 			service.GetUptimeString())
+
+		if showResources {
+			row += fmt.Sprintf(" │ %7s │ %9s │ %5s", formatCPU(service), formatResourceBytes(service.MemoryBytes), formatCount(service.Tasks))
+		}
+		if showDetails {
+			row += fmt.Sprintf(" │ %-*s", detailsWidth, truncateString(service.GetDetailString(), detailsWidth))
+		}
+
+		fmt.Println(row + " ║")
 	}
+}
 
-	// 3. Print footer with summary
-	printFooter(serviceList)
+// tableWidth returns the inner width of the table box
+func tableWidth(showDetails, showResources bool) int {
+	// " " + 20 + " │ " + 12 + " │ " + 8 + " │ " + 17 + " "
+	width := 68
+	if showResources {
+		// " │ " + 7 + " │ " + 9 + " │ " + 5
+		width += 3 + 7 + 3 + 9 + 3 + 5
+	}
+	if showDetails {
+		width += 3 + detailsWidth
+	}
+	return width
 }
 
-func printHeader() {
+func printHeader(showDetails, showResources bool) {
 	// Print box drawing characters untuk header
 	// Example:
 	// ╔══════════════════════════════════════════════════════════╗
@@ -46,20 +180,104 @@ func printHeader() {
 	// ╠══════════════════════════════════════════════════════════╣
 	// ║ Service          │ Status    │ Active  │ Uptime          ║
 	// ╠══════════════════════════════════════════════════════════╣
+	width := tableWidth(showDetails, showResources)
+	border := strings.Repeat("═", width)
+
+	title := "SYSTEMD SERVICE MONITOR"
+	padLeft := (width - len(title)) / 2
+
+	columns := fmt.Sprintf(" %-20s │ %-12s │ %-8s │ %-17s", "Service", "Status", "Active", "Uptime")
+	if showResources {
+		columns += fmt.Sprintf(" │ %7s │ %9s │ %5s", "CPU", "Memory", "Tasks")
+	}
+	if showDetails {
+		columns += fmt.Sprintf(" │ %-*s", detailsWidth, "Details")
+	}
 
-	fmt.Println("╔══════════════════════════════════════════════════════════════╗")
-	fmt.Println("║              SYSTEMD SERVICE MONITOR                         ║")
-	fmt.Println("╠══════════════════════════════════════════════════════════════╣")
-	fmt.Println("║ Service          │ Status      │ Active  │ Uptime           ║")
-	fmt.Println("╠══════════════════════════════════════════════════════════════╣")
+	fmt.Println("╔" + border + "╗")
+	fmt.Printf("║%*s%-*s║\n", padLeft, "", width-padLeft, title)
+	fmt.Println("╠" + border + "╣")
+	fmt.Println("║" + columns + " ║")
+	fmt.Println("╠" + border + "╣")
 }
 
-func printFooter(sl *models.ServiceList) {
+func printFooter(sl *models.ServiceList, showDetails, showResources bool) {
 	// Print separator dan summary
-	fmt.Println("╠══════════════════════════════════════════════════════════════╣")
-	fmt.Printf("║ Total: %d  │ Running: %d  │ Failed: %d  │ Stopped: %d        ║\n",
+	width := tableWidth(showDetails, showResources)
+	border := strings.Repeat("═", width)
+
+	summary := fmt.Sprintf(" Total: %d  │ Running: %d  │ Failed: %d  │ Stopped: %d",
 		sl.Total, sl.Running, sl.Failed, sl.Stopped)
-	fmt.Println("╚══════════════════════════════════════════════════════════════╝")
+
+	fmt.Println("╠" + border + "╣")
+	fmt.Printf("║%-*s║\n", width, summary)
+	fmt.Println("╚" + border + "╝")
+}
+
+// hasDetails reports whether any unit in the list has type-specific details
+func hasDetails(sl *models.ServiceList) bool {
+	for _, service := range sl.Services {
+		if len(service.Details) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasResources reports whether any unit in the list has resource usage
+func hasResources(sl *models.ServiceList) bool {
+	for _, service := range sl.Services {
+		if service.HasResources() {
+			return true
+		}
+	}
+	return false
+}
+
+// formatCPU shows the CPU rate when an earlier sample exists and the
+// CPU time consumed otherwise
+func formatCPU(service *models.ServiceInfo) string {
+	switch {
+	case service.CPUSampled:
+		return fmt.Sprintf("%.1f%%", service.CPUPercent)
+	case service.CPUUsage > 0:
+		return formatCPUTime(service.CPUUsage)
+	default:
+		return "-"
+	}
+}
+
+// formatCPUTime renders CPU time, e.g. "0.35s", "12.10s", "2h 15m"
+func formatCPUTime(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.2fs", d.Seconds())
+	}
+	return formatReportDuration(d)
+}
+
+// formatResourceBytes renders a byte count like systemctl status, "-" when 0
+func formatResourceBytes(bytes int64) string {
+	if bytes <= 0 {
+		return "-"
+	}
+
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func formatCount(n int) string {
+	if n <= 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
 }
 
 // colorizeStatus returns ANSI color code based on service status
@@ -69,7 +287,7 @@ func colorizeStatus(status models.ServiceStatus) string {
 		return ColorGreen
 	case models.StatusFailed:
 		return ColorRed
-	case models.StatusStopped:
+	case models.StatusStopped, models.StatusDegraded:
 		return ColorYellow
 	default:
 		return ColorWhite
@@ -95,18 +313,131 @@ func PrintService(service *models.ServiceInfo) {
 		color,
 		service.GetStatusIcon(),
 		ColorReset,
-		service.Name,
+		hostPrefix(service.Host)+models.ScopedUnit(service.User, service.Name),
 		service.Status,
 		service.ActiveState)
 
+	if service.UnitType != "" && service.UnitType != models.UnitService {
+		fmt.Printf("  Type: %s\n", service.UnitType)
+	}
+	if service.UnitFileState != "" {
+		fmt.Printf("  Unit file: %s\n", service.UnitFileState)
+	}
 	if service.PID > 0 {
 		fmt.Printf("  PID: %d\n", service.PID)
 	}
 	if service.MemoryUsage != "" {
-		fmt.Printf("  Memory: %s\n", service.MemoryUsage)
+		memory := service.MemoryUsage
+		var extra []string
+		if service.MemoryPeak > 0 {
+			extra = append(extra, "peak "+formatResourceBytes(service.MemoryPeak))
+		}
+		if service.SwapBytes > 0 {
+			extra = append(extra, "swap "+formatResourceBytes(service.SwapBytes))
+		}
+		if len(extra) > 0 {
+			memory += " (" + strings.Join(extra, ", ") + ")"
+		}
+		fmt.Printf("  Memory: %s\n", memory)
 	}
 	if service.Uptime > 0 {
 		fmt.Printf("  Uptime: %s\n", service.GetUptimeString())
 	}
+	printResources(service)
+	printProbes(service)
+
+	// Type-specific details, e.g. a timer's next elapse or a mount's Where=
+	keys := make([]string, 0, len(service.Details))
+	for key := range service.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("  %s: %s\n", key, service.Details[key])
+	}
 	fmt.Println()
 }
+
+// PrintEvent prints a state-change event on a single highlighted line
+func PrintEvent(event *models.Event) {
+	color := colorizeStatus(event.To)
+
+	fmt.Printf("%s%s [%s] %s%s%s\n",
+		color,
+		event.GetEventIcon(),
+		event.Timestamp.Format("2006-01-02 15:04:05"),
+		hostPrefix(event.Host)+userPrefix(event.User),
+		event.String(),
+		ColorReset)
+}
+
+// hostPrefix returns "host: " for a remote unit and "" otherwise
+func hostPrefix(host string) string {
+	if host == "" {
+		return ""
+	}
+	return host + ": "
+}
+
+// userPrefix returns "user@alice: " for a unit of alice's user manager and
+// "" for a system unit
+func userPrefix(user string) string {
+	if user == "" {
+		return ""
+	}
+	return "user@" + user + ": "
+}
+
+// printProbes prints one line per health probe with its latency and the
+// current or last error
+func printProbes(service *models.ServiceInfo) {
+	for _, probe := range service.Probes {
+		latency := formatLatency(probe.Latency)
+		if probe.Healthy {
+			line := fmt.Sprintf("  Probe %s: %s✅ ok%s (%s)", probe.Name, ColorGreen, ColorReset, latency)
+			if probe.LastError != "" {
+				line += fmt.Sprintf(", last error %s: %s", probe.LastErrorAt.Format("2006-01-02 15:04:05"), probe.LastError)
+			}
+			fmt.Println(line)
+			continue
+		}
+		fmt.Printf("  Probe %s: %s❌ %s%s (%s)\n", probe.Name, ColorRed, probe.Error, ColorReset, latency)
+	}
+}
+
+// formatLatency renders a probe duration, e.g. "850µs", "12ms" or "1.5s"
+func formatLatency(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(100 * time.Millisecond).String()
+	}
+}
+
+// printResources prints the CPU, IO, task and pressure lines of a unit
+func printResources(service *models.ServiceInfo) {
+	if service.CPUUsage > 0 {
+		cpu := formatCPUTime(service.CPUUsage) + " total"
+		if service.CPUSampled {
+			cpu += fmt.Sprintf(", %.1f%% now", service.CPUPercent)
+		}
+		fmt.Printf("  CPU: %s\n", cpu)
+	}
+	if service.IOReadBytes > 0 || service.IOWriteBytes > 0 {
+		fmt.Printf("  IO: %s read, %s written\n",
+			formatResourceBytes(service.IOReadBytes), formatResourceBytes(service.IOWriteBytes))
+	}
+	if service.Tasks > 0 {
+		fmt.Printf("  Tasks: %d\n", service.Tasks)
+	}
+	if p := service.Pressure; p != nil {
+		// Share of time some task waited, averaged over 10s/60s/300s
+		fmt.Printf("  Pressure: cpu %.2f/%.2f/%.2f  memory %.2f/%.2f/%.2f  io %.2f/%.2f/%.2f (some, avg10/60/300)\n",
+			p.CPU.SomeAvg10, p.CPU.SomeAvg60, p.CPU.SomeAvg300,
+			p.Memory.SomeAvg10, p.Memory.SomeAvg60, p.Memory.SomeAvg300,
+			p.IO.SomeAvg10, p.IO.SomeAvg60, p.IO.SomeAvg300)
+	}
+}
@@ -0,0 +1,68 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultWorkers is used when no worker count is given
+const DefaultWorkers = 8
+
+// Run calls fn for every index in [0, n) on at most workers goroutines
+// and returns once every call has returned. Each call gets its own
+// context, cancelled after timeout (0 = only when ctx is done). Calls not
+// yet started when ctx is done still run, with the cancelled context, so
+// fn can record why its index was not checked.
+//
+// Results keep their order when fn stores them by index.
+func Run(ctx context.Context, n, workers int, timeout time.Duration, fn func(ctx context.Context, i int)) {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				call(ctx, timeout, i, fn)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+}
+
+// call runs fn for one index under its own timeout
+func call(ctx context.Context, timeout time.Duration, i int, fn func(ctx context.Context, i int)) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	fn(ctx, i)
+}
+
+// Split divides a budget of workers between n groups that each run their
+// own pool, e.g. hosts checking several units each. It returns how many
+// groups run at once and the workers each of them gets, so no more than
+// workers calls run in total.
+func Split(workers, n int) (groups, perGroup int) {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	groups = min(workers, max(n, 1))
+	return groups, workers / groups
+}
@@ -0,0 +1,214 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Type selects how a probe checks a service
+type Type string
+
+const (
+	TypeHTTP Type = "http" // GET a URL (http or https)
+	TypeTCP  Type = "tcp"  // connect to host:port
+	TypeUnix Type = "unix" // connect to a Unix socket
+	TypeExec Type = "exec" // run a command
+)
+
+// DefaultTimeout bounds a probe without its own timeout
+const DefaultTimeout = 5 * time.Second
+
+// maxBody is how much of an HTTP response is searched for ExpectBody
+const maxBody = 1 << 20
+
+// Probe is a health check of the application behind a unit
+type Probe struct {
+	Name    string // shown in output; defaults to type and target
+	Type    Type
+	Timeout time.Duration // 0 = DefaultTimeout
+
+	// http
+	URL          string
+	ExpectStatus int    // 0 = any 2xx
+	ExpectBody   string // substring the body must contain; "" = any
+	Insecure     bool   // skip TLS certificate verification
+
+	// tcp
+	Address string
+
+	// unix
+	Path string
+
+	// exec; run via "sh -c" with MONITOR_UNIT set
+	Command    string
+	ExpectExit int
+}
+
+// ParseType converts a probe type name into a Type
+func ParseType(s string) (Type, error) {
+	switch Type(s) {
+	case TypeHTTP, TypeTCP, TypeUnix, TypeExec:
+		return Type(s), nil
+	}
+	return "", fmt.Errorf("probe type must be http, tcp, unix or exec, got %q", s)
+}
+
+// Target returns what the probe checks: a URL, address, path or command
+func (p *Probe) Target() string {
+	switch p.Type {
+	case TypeHTTP:
+		return p.URL
+	case TypeTCP:
+		return p.Address
+	case TypeUnix:
+		return p.Path
+	default:
+		return p.Command
+	}
+}
+
+// DisplayName returns Name, or the type and target when unnamed
+func (p *Probe) DisplayName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("%s %s", p.Type, p.Target())
+}
+
+func (p *Probe) timeout() time.Duration {
+	if p.Timeout > 0 {
+		return p.Timeout
+	}
+	return DefaultTimeout
+}
+
+// Run checks a unit once and returns how long it took and why it failed
+// (nil when healthy). The probe is abandoned after its timeout.
+func (p *Probe) Run(ctx context.Context, unit string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout())
+	defer cancel()
+
+	start := time.Now()
+	var err error
+	switch p.Type {
+	case TypeHTTP:
+		err = p.runHTTP(ctx)
+	case TypeTCP:
+		err = dial(ctx, "tcp", p.Address)
+	case TypeUnix:
+		err = dial(ctx, "unix", p.Path)
+	case TypeExec:
+		err = p.runExec(ctx, unit)
+	default:
+		err = fmt.Errorf("unknown probe type %q", p.Type)
+	}
+	latency := time.Since(start)
+
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", p.timeout())
+	}
+	return latency, err
+}
+
+// runHTTP sends a GET request and checks the status and body
+func (p *Probe) runHTTP(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "systemd-monitoring-probe")
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if p.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	defer transport.CloseIdleConnections()
+
+	// Redirects are not followed; a 3xx is reported as it is
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// Drop the "Get <url>:" prefix; the probe name has the URL
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case p.ExpectStatus != 0 && resp.StatusCode != p.ExpectStatus:
+		return fmt.Errorf("status %d, expected %d", resp.StatusCode, p.ExpectStatus)
+	case p.ExpectStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299):
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	if p.ExpectBody != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+		if err != nil {
+			return fmt.Errorf("failed to read body: %w", err)
+		}
+		if !strings.Contains(string(body), p.ExpectBody) {
+			return fmt.Errorf("body does not contain %q", p.ExpectBody)
+		}
+	}
+	return nil
+}
+
+// runExec runs the command and checks its exit code
+func (p *Probe) runExec(ctx context.Context, unit string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", p.Command)
+	cmd.Env = append(os.Environ(), "MONITOR_UNIT="+unit)
+	cmd.WaitDelay = 100 * time.Millisecond // don't wait on children keeping the pipes open
+
+	output, err := cmd.CombinedOutput()
+	code := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+		code = exitErr.ExitCode()
+	}
+
+	if code != p.ExpectExit {
+		msg := fmt.Sprintf("exit code %d, expected %d", code, p.ExpectExit)
+		if out := strings.TrimSpace(string(output)); out != "" {
+			msg += ": " + firstLine(out)
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// dial opens and closes a connection
+func dial(ctx context.Context, network, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,96 @@
+package probe
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// Check is a unit and the probes to run against it
+type Check struct {
+	Unit   string
+	Host   string // remote host of the unit; probes still run from here
+	User   string // owner of the unit's user manager; "" = a system unit
+	Probes []*Probe
+}
+
+// failure is the most recent failed run of a probe
+type failure struct {
+	msg string
+	at  time.Time
+}
+
+// Runner runs probes concurrently and remembers the last failure of every
+// probe, so output can show it after the probe recovered
+type Runner struct {
+	mu   sync.Mutex
+	last map[string]failure // unit key + "\x00" + probe name
+}
+
+// NewRunner creates a runner without any history
+func NewRunner() *Runner {
+	return &Runner{last: make(map[string]failure)}
+}
+
+// RunAll runs every probe of every check at the same time and returns the
+// results in the order of checks and their probes. It returns once the
+// slowest probe finished or timed out.
+func (r *Runner) RunAll(ctx context.Context, checks []Check) [][]models.ProbeResult {
+	results := make([][]models.ProbeResult, len(checks))
+	var wg sync.WaitGroup
+
+	for i, check := range checks {
+		results[i] = make([]models.ProbeResult, len(check.Probes))
+		for j, p := range check.Probes {
+			wg.Add(1)
+			go func(check Check, p *Probe, result *models.ProbeResult) {
+				defer wg.Done()
+				*result = r.run(ctx, check, p)
+			}(check, p, &results[i][j])
+		}
+	}
+
+	wg.Wait()
+	return results
+}
+
+// run runs one probe and records its failure
+func (r *Runner) run(ctx context.Context, check Check, p *Probe) models.ProbeResult {
+	latency, err := p.Run(ctx, check.Unit)
+	result := models.ProbeResult{
+		Name:    p.DisplayName(),
+		Healthy: err == nil,
+		Latency: latency,
+	}
+
+	key := models.UnitKey(check.Host, models.ScopedUnit(check.User, check.Unit)) + "\x00" + result.Name
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		result.Error = err.Error()
+		r.last[key] = failure{msg: result.Error, at: time.Now()}
+	}
+	if last, ok := r.last[key]; ok {
+		result.LastError = last.msg
+		result.LastErrorAt = last.at
+	}
+	return result
+}
+
+// Apply stores probe results in a snapshot and marks a running unit as
+// degraded when any of its probes failed
+func Apply(service *models.ServiceInfo, results []models.ProbeResult) {
+	service.Probes = results
+	if service.Status != models.StatusRunning {
+		return
+	}
+	for _, result := range results {
+		if !result.Healthy {
+			service.Status = models.StatusDegraded
+			return
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package remediate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/logger"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+	"github.com/andinianst93/systemd-monitoring/internal/systemd"
+)
+
+// unitState is the remediation history of one unit
+type unitState struct {
+	attempts []time.Time // attempts within the current window
+	next     time.Time   // no attempt before this (backoff)
+	gaveUp   time.Time   // when the budget ran out; zero = still trying
+	active   bool        // repairs were attempted since the unit last failed
+}
+
+// Engine repairs failed units according to a Policy. Every decision and
+// outcome is written to the audit loggers.
+type Engine struct {
+	client        *systemd.Client
+	remotes       map[string]*systemd.Client // by host, for remote units
+	fileLogger    *logger.FileLogger
+	journalLogger *logger.JournalLogger // nil = no journal audit
+
+	units map[string]*unitState
+	now   func() time.Time
+}
+
+// NewEngine creates a remediation engine. journalLogger may be nil.
+func NewEngine(client *systemd.Client, fileLogger *logger.FileLogger, journalLogger *logger.JournalLogger) *Engine {
+	return &Engine{
+		client:        client,
+		remotes:       make(map[string]*systemd.Client),
+		fileLogger:    fileLogger,
+		journalLogger: journalLogger,
+		units:         make(map[string]*unitState),
+		now:           time.Now,
+	}
+}
+
+// AddRemote lets the engine repair units on a remote host; events whose
+// Host matches client.Host() are handled through it
+func (e *Engine) AddRemote(client *systemd.Client) {
+	e.remotes[client.Host()] = client
+}
+
+// HandleEvent reacts to a monitor event for a unit covered by policy. A
+// failed unit is repaired when its backoff has passed and the attempt
+// budget allows; once the budget is exhausted a single escalation event is
+// returned. The returned events describe what was done. A repair is
+// stopped when ctx is done, e.g. when the monitor shuts down.
+func (e *Engine) HandleEvent(ctx context.Context, event *models.Event, policy Policy) []*models.Event {
+	state, ok := e.units[event.Key()]
+	if !ok {
+		state = &unitState{}
+		e.units[event.Key()] = state
+	}
+	now := e.now()
+
+	// 1. Only failed units are repaired; a recovery resets the backoff
+	if event.To != models.StatusFailed {
+		if event.Type == models.EventTransition && event.From == models.StatusFailed && state.active {
+			e.audit("notice", "%s recovered, now %s", event.Key(), event.To)
+			state.active = false
+			state.next = time.Time{}
+			state.gaveUp = time.Time{}
+		}
+		return nil
+	}
+	if event.Type != models.EventTransition && event.Type != models.EventHeartbeat {
+		return nil
+	}
+
+	// 2. After giving up, wait out the cooldown with a fresh budget
+	if !state.gaveUp.IsZero() {
+		if now.Sub(state.gaveUp) < policy.Cooldown {
+			return nil
+		}
+		e.audit("notice", "%s cooldown of %s over, remediation re-enabled", event.Key(), policy.Cooldown)
+		state.gaveUp = time.Time{}
+		state.attempts = nil
+		state.next = time.Time{}
+	}
+
+	// 3. Forget attempts that left the window
+	kept := state.attempts[:0]
+	for _, attempt := range state.attempts {
+		if now.Sub(attempt) < policy.Window {
+			kept = append(kept, attempt)
+		}
+	}
+	state.attempts = kept
+
+	// 4. Give the previous attempt its backoff to take effect
+	if now.Before(state.next) {
+		return nil
+	}
+
+	// 5. Budget exhausted: escalate once
+	if len(state.attempts) >= policy.MaxAttempts {
+		state.gaveUp = now
+		message := fmt.Sprintf("Service %s still failed after %d remediation attempt(s) within %s; giving up for %s",
+			event.Key(), len(state.attempts), policy.Window, policy.Cooldown)
+		e.audit("crit", "%s", message)
+
+		escalation := models.NewEvent(models.EventEscalation, event.Service)
+		escalation.Message = message
+		escalation.Timestamp = now
+		return []*models.Event{escalation}
+	}
+
+	// 6. Attempt the repair
+	state.active = true
+	state.attempts = append(state.attempts, now)
+	attempt := len(state.attempts)
+	state.next = now.Add(policy.backoffAfter(attempt))
+
+	e.audit("warning", "%s failed, running %s (attempt %d/%d)", event.Key(), policy.Describe(), attempt, policy.MaxAttempts)
+	started := now
+	err := e.run(ctx, event.Host, event.User, event.Unit, policy)
+	duration := e.now().Sub(started).Round(time.Millisecond)
+
+	result := models.NewEvent(models.EventRemediation, event.Service)
+	result.Timestamp = e.now()
+	if err != nil {
+		result.Message = fmt.Sprintf("Remediation %s of %s failed (attempt %d/%d, %s): %v",
+			policy.Describe(), event.Key(), attempt, policy.MaxAttempts, duration, err)
+		e.audit("err", "%s; next attempt not before %s", result.Message, state.next.Format("15:04:05"))
+	} else {
+		result.Message = fmt.Sprintf("Remediation %s of %s succeeded (attempt %d/%d, %s)",
+			policy.Describe(), event.Key(), attempt, policy.MaxAttempts, duration)
+		e.audit("notice", "%s", result.Message)
+	}
+
+	return []*models.Event{result}
+}
+
+// run performs the policy's action on a unit of host ("" = this machine)
+// managed by user's manager ("" = the system manager)
+func (e *Engine) run(ctx context.Context, host, user, unit string, policy Policy) error {
+	// policy.Timeout bounds the action itself
+	client := e.client
+	if host != "" {
+		remote, ok := e.remotes[host]
+		if !ok {
+			return fmt.Errorf("no connection to host %s", host)
+		}
+		client = remote
+	}
+
+	switch policy.Action {
+	case ActionResetStart:
+		if err := client.ResetFailed(ctx, unit); err != nil {
+			return err
+		}
+		_, err := client.StartUnit(ctx, unit, policy.Timeout)
+		return err
+
+	case ActionCommand:
+		return runCommand(ctx, host, user, unit, policy.Command, policy.Timeout)
+
+	default:
+		_, err := client.RestartUnit(ctx, unit, policy.Timeout)
+		return err
+	}
+}
+
+// runCommand runs a custom remediation command via "sh -c" on this
+// machine, with MONITOR_HOST and MONITOR_USER naming the unit's host and
+// user manager like in alerts
+func runCommand(ctx context.Context, host, user, unit, command string, timeout time.Duration) error {
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+
+	// Kill commands that hang so the monitor loop keeps going
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), "MONITOR_UNIT="+unit, "MONITOR_HOST="+host, "MONITOR_USER="+user)
+	cmd.WaitDelay = 100 * time.Millisecond // don't wait on children keeping the pipes open
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("remediation command failed: %w (%s)", err, msg)
+		}
+		return fmt.Errorf("remediation command failed: %w", err)
+	}
+	return nil
+}
+
+// audit records a remediation step in the file log and the journal
+func (e *Engine) audit(priority, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+
+	e.fileLogger.WriteLog("REMEDIATION: " + message)
+	if e.journalLogger != nil {
+		e.journalLogger.WriteToJournal("[REMEDIATION] "+message, priority)
+	}
+}
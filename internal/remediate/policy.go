@@ -0,0 +1,75 @@
+package remediate
+
+import (
+	"fmt"
+	"time"
+)
+
+// Action is what the engine does to repair a failed unit
+type Action string
+
+const (
+	// ActionRestart runs "systemctl restart"
+	ActionRestart Action = "restart"
+	// ActionResetStart runs "systemctl reset-failed" then "systemctl start",
+	// for units that hit their start rate limit
+	ActionResetStart Action = "reset-start"
+	// ActionCommand runs a custom shell command
+	ActionCommand Action = "command"
+)
+
+// ParseAction converts a name into an Action
+func ParseAction(s string) (Action, error) {
+	switch Action(s) {
+	case ActionRestart, ActionResetStart, ActionCommand:
+		return Action(s), nil
+	}
+	return "", fmt.Errorf("unknown remediation action: %s (use restart, reset-start or command)", s)
+}
+
+// Policy limits how often and how aggressively a unit is repaired
+type Policy struct {
+	Action  Action
+	Command string // for ActionCommand; run via "sh -c" with MONITOR_UNIT set
+
+	MaxAttempts int           // attempts allowed within Window before giving up
+	Window      time.Duration // sliding window MaxAttempts applies to
+	Backoff     time.Duration // wait after the first attempt, doubled after each further one
+	MaxBackoff  time.Duration // upper bound for the backoff
+	Cooldown    time.Duration // after giving up, wait this long before trying again
+	Timeout     time.Duration // how long one action may take
+}
+
+// DefaultPolicy restarts up to 3 times in 15 minutes, waiting 10s, 20s, ...
+// between attempts, and retries an hour after giving up
+func DefaultPolicy() Policy {
+	return Policy{
+		Action:      ActionRestart,
+		MaxAttempts: 3,
+		Window:      15 * time.Minute,
+		Backoff:     10 * time.Second,
+		MaxBackoff:  5 * time.Minute,
+		Cooldown:    time.Hour,
+		Timeout:     30 * time.Second,
+	}
+}
+
+// backoffAfter returns the wait required after the nth attempt (1-based)
+func (p Policy) backoffAfter(attempt int) time.Duration {
+	backoff := p.Backoff
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// Describe returns the action as shown in the audit trail
+func (p Policy) Describe() string {
+	if p.Action == ActionCommand {
+		return fmt.Sprintf("command %q", p.Command)
+	}
+	return string(p.Action)
+}
@@ -0,0 +1,149 @@
+package report
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// Outage is a period the unit was observed not running
+type Outage struct {
+	Start   time.Time            `json:"start"`
+	End     time.Time            `json:"end"`
+	Status  models.ServiceStatus `json:"status"` // failed, or stopped if it never failed
+	Ongoing bool                 `json:"ongoing"`
+}
+
+// Duration returns how long the outage lasted within the window
+func (o Outage) Duration() time.Duration {
+	return o.End.Sub(o.Start)
+}
+
+// UnitReport holds the availability figures of one unit over a window.
+// Time the unit was not observed counts neither as up nor as down.
+type UnitReport struct {
+	Unit   string
+	Source string
+	Since  time.Time
+	Until  time.Time
+
+	Observed      time.Duration // time with a known status
+	Uptime        time.Duration // time running
+	Downtime      time.Duration // time observed not running
+	Availability  float64       // Uptime / Observed in percent
+	Outages       []Outage
+	MTTR          time.Duration // mean outage duration
+	MTBF          time.Duration // uptime per outage
+	LongestOutage time.Duration
+}
+
+// Compute derives the availability figures from a timeline. An outage is
+// a stretch of observed non-running time; unknown time in between does
+// not split it.
+func Compute(timeline *Timeline) *UnitReport {
+	r := &UnitReport{
+		Unit:   timeline.Unit,
+		Source: timeline.Source,
+		Since:  timeline.Since,
+		Until:  timeline.Until,
+	}
+
+	// 1. Sum up the spans and group non-running ones into outages
+	var current *Outage
+	for _, span := range timeline.Spans {
+		duration := span.End.Sub(span.Start)
+
+		switch span.Status {
+		case models.StatusUnknown:
+			continue
+		case models.StatusRunning:
+			r.Observed += duration
+			r.Uptime += duration
+			current = nil
+			continue
+		}
+
+		r.Observed += duration
+		r.Downtime += duration
+		if current == nil {
+			r.Outages = append(r.Outages, Outage{Start: span.Start, Status: span.Status})
+			current = &r.Outages[len(r.Outages)-1]
+		}
+		current.End = span.End
+		if span.Status == models.StatusFailed {
+			current.Status = models.StatusFailed
+		}
+	}
+
+	// An outage reaching the end of the window has not been seen to end
+	if n := len(r.Outages); n > 0 && current != nil {
+		last := timeline.Spans[len(timeline.Spans)-1]
+		r.Outages[n-1].Ongoing = last.Status != models.StatusUnknown && last.End.Equal(timeline.Until)
+	}
+
+	// 2. Derived figures
+	if r.Observed > 0 {
+		r.Availability = 100 * float64(r.Uptime) / float64(r.Observed)
+	}
+	if n := len(r.Outages); n > 0 {
+		r.MTTR = r.Downtime / time.Duration(n)
+		r.MTBF = r.Uptime / time.Duration(n)
+		for _, outage := range r.Outages {
+			if outage.Duration() > r.LongestOutage {
+				r.LongestOutage = outage.Duration()
+			}
+		}
+	}
+
+	return r
+}
+
+// Coverage returns the observed share of the window in percent
+func (r *UnitReport) Coverage() float64 {
+	window := r.Until.Sub(r.Since)
+	if window <= 0 {
+		return 0
+	}
+	return 100 * float64(r.Observed) / float64(window)
+}
+
+// MarshalJSON renders durations as seconds
+func (r *UnitReport) MarshalJSON() ([]byte, error) {
+	outages := r.Outages
+	if outages == nil {
+		outages = []Outage{}
+	}
+
+	return json.Marshal(struct {
+		Unit                 string    `json:"unit"`
+		Source               string    `json:"source"`
+		Since                time.Time `json:"since"`
+		Until                time.Time `json:"until"`
+		AvailabilityPercent  float64   `json:"availability_percent"`
+		CoveragePercent      float64   `json:"coverage_percent"`
+		ObservedSeconds      float64   `json:"observed_seconds"`
+		UptimeSeconds        float64   `json:"uptime_seconds"`
+		DowntimeSeconds      float64   `json:"downtime_seconds"`
+		OutageCount          int       `json:"outage_count"`
+		MTTRSeconds          float64   `json:"mttr_seconds"`
+		MTBFSeconds          float64   `json:"mtbf_seconds"`
+		LongestOutageSeconds float64   `json:"longest_outage_seconds"`
+		Outages              []Outage  `json:"outages"`
+	}{
+		Unit:                 r.Unit,
+		Source:               r.Source,
+		Since:                r.Since,
+		Until:                r.Until,
+		AvailabilityPercent:  r.Availability,
+		CoveragePercent:      r.Coverage(),
+		ObservedSeconds:      r.Observed.Seconds(),
+		UptimeSeconds:        r.Uptime.Seconds(),
+		DowntimeSeconds:      r.Downtime.Seconds(),
+		OutageCount:          len(r.Outages),
+		MTTRSeconds:          r.MTTR.Seconds(),
+		MTBFSeconds:          r.MTBF.Seconds(),
+		LongestOutageSeconds: r.LongestOutage.Seconds(),
+		Outages:              outages,
+	})
+}
@@ -0,0 +1,304 @@
+package report
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/history"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// The window of every test: one hour
+var (
+	since = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	until = since.Add(time.Hour)
+)
+
+// at returns the time minutes into the window
+func at(minutes float64) time.Time {
+	return since.Add(time.Duration(minutes * float64(time.Minute)))
+}
+
+// samples records status every 5 minutes from start (inclusive) to end
+// (exclusive), in minutes into the window
+func samples(status models.ServiceStatus, start, end float64) []*history.Record {
+	var records []*history.Record
+	for m := start; m < end; m += 5 {
+		records = append(records, &history.Record{Kind: history.KindSample, Unit: "nginx.service", Time: at(m), Status: status})
+	}
+	return records
+}
+
+func concat(parts ...[]*history.Record) []*history.Record {
+	var records []*history.Record
+	for _, part := range parts {
+		records = append(records, part...)
+	}
+	return records
+}
+
+// want is the expected outcome of a report
+type want struct {
+	availability float64 // percent
+	coverage     float64 // percent
+	downtime     time.Duration
+	outages      int
+	longest      time.Duration
+	ongoing      bool // the last outage
+}
+
+func checkReport(t *testing.T, name string, r *UnitReport, w want) {
+	t.Helper()
+	if math.Abs(r.Availability-w.availability) > 0.01 || math.Abs(r.Coverage()-w.coverage) > 0.01 {
+		t.Errorf("%s: availability %.2f%%, coverage %.2f%%; want %.2f%%, %.2f%%",
+			name, r.Availability, r.Coverage(), w.availability, w.coverage)
+	}
+	if r.Downtime != w.downtime || len(r.Outages) != w.outages || r.LongestOutage != w.longest {
+		t.Errorf("%s: downtime %s, %d outages, longest %s; want %s, %d, %s",
+			name, r.Downtime, len(r.Outages), r.LongestOutage, w.downtime, w.outages, w.longest)
+	}
+	if n := len(r.Outages); n > 0 && r.Outages[n-1].Ongoing != w.ongoing {
+		t.Errorf("%s: last outage ongoing = %t, want %t", name, r.Outages[n-1].Ongoing, w.ongoing)
+	}
+}
+
+func TestFromHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []*history.Record
+		want    want
+	}{
+		{
+			name:    "running throughout",
+			records: samples(models.StatusRunning, 0, 60),
+			want:    want{availability: 100, coverage: 100},
+		},
+		{
+			name: "failed for 15 minutes",
+			records: concat(
+				samples(models.StatusRunning, 0, 30),
+				samples(models.StatusFailed, 30, 45),
+				samples(models.StatusRunning, 45, 60),
+			),
+			want: want{availability: 75, coverage: 100, downtime: 15 * time.Minute, outages: 1, longest: 15 * time.Minute},
+		},
+		{
+			name: "outage reaching the end of the window",
+			records: concat(
+				samples(models.StatusRunning, 0, 30),
+				samples(models.StatusFailed, 30, 60),
+			),
+			want: want{availability: 50, coverage: 100, downtime: 30 * time.Minute, outages: 1, longest: 30 * time.Minute, ongoing: true},
+		},
+		{
+			// 12:00 holds for maxGap only; 12:10-12:30 is unknown
+			name: "gap longer than maxGap",
+			records: concat(
+				samples(models.StatusRunning, 0, 5),
+				samples(models.StatusRunning, 30, 60),
+			),
+			want: want{availability: 100, coverage: 66.67},
+		},
+		{
+			// Samples 10 minutes apart are still within maxGap
+			name: "gap of exactly maxGap",
+			records: concat(
+				samples(models.StatusRunning, 0, 5),
+				samples(models.StatusRunning, 10, 60),
+			),
+			want: want{availability: 100, coverage: 100},
+		},
+		{
+			// Unknown time inside an outage does not split it
+			name: "outage with a gap",
+			records: concat(
+				samples(models.StatusRunning, 0, 10),
+				samples(models.StatusFailed, 10, 15),
+				samples(models.StatusFailed, 40, 45),
+				samples(models.StatusRunning, 45, 60),
+			),
+			want: want{availability: 62.5, coverage: 66.67, downtime: 15 * time.Minute, outages: 1, longest: 35 * time.Minute},
+		},
+		{
+			// 11:58 failed holds into the window until the 12:05 sample;
+			// the sample at 13:00 is outside it
+			name: "window edges",
+			records: concat(
+				[]*history.Record{{Unit: "nginx.service", Time: at(-2), Status: models.StatusFailed}},
+				samples(models.StatusRunning, 5, 60),
+				[]*history.Record{{Unit: "nginx.service", Time: at(60), Status: models.StatusFailed}},
+			),
+			want: want{availability: 91.67, coverage: 100, downtime: 5 * time.Minute, outages: 1, longest: 5 * time.Minute},
+		},
+		{
+			// 11:45 is more than maxGap before the window
+			name: "stale sample before the window",
+			records: concat(
+				[]*history.Record{{Unit: "nginx.service", Time: at(-15), Status: models.StatusFailed}},
+				samples(models.StatusRunning, 5, 60),
+			),
+			want: want{availability: 100, coverage: 91.67},
+		},
+		{
+			name: "other units and records without a status",
+			records: concat(
+				samples(models.StatusRunning, 0, 60),
+				[]*history.Record{
+					{Unit: "db.service", Time: at(20), Status: models.StatusFailed},
+					{Kind: history.KindEvent, Unit: "nginx.service", Time: at(21)},
+				},
+			),
+			want: want{availability: 100, coverage: 100},
+		},
+		{
+			name: "nothing recorded",
+			want: want{},
+		},
+	}
+
+	for _, tt := range tests {
+		timeline := FromHistory("nginx.service", tt.records, since, until, DefaultMaxGap)
+		checkReport(t, tt.name, Compute(timeline), tt.want)
+	}
+}
+
+func TestFromHistorySpans(t *testing.T) {
+	records := concat(
+		samples(models.StatusRunning, 0, 5),
+		samples(models.StatusStopped, 20, 30),
+	)
+	timeline := FromHistory("nginx.service", records, since, until, DefaultMaxGap)
+
+	want := []Span{
+		{Start: at(0), End: at(10), Status: models.StatusRunning},
+		{Start: at(10), End: at(20), Status: models.StatusUnknown},
+		{Start: at(20), End: at(35), Status: models.StatusStopped},
+		{Start: at(35), End: at(60), Status: models.StatusUnknown},
+	}
+	if len(timeline.Spans) != len(want) {
+		t.Fatalf("spans = %v, want %v", timeline.Spans, want)
+	}
+	for i := range want {
+		if timeline.Spans[i] != want[i] {
+			t.Errorf("span %d = %v, want %v", i, timeline.Spans[i], want[i])
+		}
+	}
+}
+
+func journalEntry(minutes float64, message string, fields map[string]string) *models.LogEntry {
+	return &models.LogEntry{Timestamp: at(minutes), Message: message, Fields: fields}
+}
+
+func TestFromJournal(t *testing.T) {
+	started := map[string]string{"MESSAGE_ID": messageUnitStarted, "JOB_RESULT": "done"}
+
+	tests := []struct {
+		name    string
+		before  []*models.LogEntry
+		entries []*models.LogEntry
+		want    want
+	}{
+		{
+			name:   "started before the window",
+			before: []*models.LogEntry{journalEntry(-60, "Started nginx.service - A web server.", nil)},
+			want:   want{availability: 100, coverage: 100},
+		},
+		{
+			// The last change before the window wins; Reloading is no change
+			name: "failed and restarted",
+			before: []*models.LogEntry{
+				journalEntry(-90, "Stopped nginx.service.", nil),
+				journalEntry(-60, "Started nginx.service.", nil),
+				journalEntry(-30, "Reloading nginx.service...", nil),
+			},
+			entries: []*models.LogEntry{
+				journalEntry(15, "nginx.service: Failed with result 'exit-code'.", nil),
+				journalEntry(20, "", started),
+				journalEntry(50, "nginx.service: Deactivated successfully.", nil),
+			},
+			want: want{availability: 75, coverage: 100, downtime: 15 * time.Minute, outages: 2, longest: 10 * time.Minute, ongoing: true},
+		},
+		{
+			name:    "no message before the window",
+			entries: []*models.LogEntry{journalEntry(30, "Started nginx.service.", nil)},
+			want:    want{availability: 100, coverage: 50},
+		},
+		{
+			// A start job that did not finish means the start failed
+			name:   "failed start job",
+			before: []*models.LogEntry{journalEntry(-5, "Started nginx.service.", nil)},
+			entries: []*models.LogEntry{
+				journalEntry(30, "Stopping nginx.service...", nil),
+				journalEntry(31, "Stopped nginx.service.", nil),
+				journalEntry(36, "", map[string]string{"MESSAGE_ID": messageUnitStarted, "JOB_RESULT": "failed"}),
+			},
+			want: want{availability: 51.67, coverage: 100, downtime: 29 * time.Minute, outages: 1, longest: 29 * time.Minute, ongoing: true},
+		},
+		{
+			name:    "messages after the window",
+			before:  []*models.LogEntry{journalEntry(-5, "Started nginx.service.", nil)},
+			entries: []*models.LogEntry{journalEntry(60, "Failed to start nginx.service.", nil)},
+			want:    want{availability: 100, coverage: 100},
+		},
+		{
+			name:    "no state changes",
+			entries: []*models.LogEntry{journalEntry(10, "worker process 42 exited", nil)},
+			want:    want{},
+		},
+	}
+
+	for _, tt := range tests {
+		timeline := FromJournal("nginx.service", tt.before, tt.entries, since, until)
+		if timeline.Source != SourceJournal {
+			t.Errorf("%s: source %q", tt.name, timeline.Source)
+		}
+		checkReport(t, tt.name, Compute(timeline), tt.want)
+	}
+}
+
+func TestManagerStatus(t *testing.T) {
+	tests := []struct {
+		entry *models.LogEntry
+		want  models.ServiceStatus // "" = no change
+	}{
+		{entry: &models.LogEntry{Message: "Started cron.service - Regular background program processing daemon."}, want: models.StatusRunning},
+		{entry: &models.LogEntry{Message: "Failed to start db.service."}, want: models.StatusFailed},
+		{entry: &models.LogEntry{Message: "db.service: Failed with result 'timeout'."}, want: models.StatusFailed},
+		{entry: &models.LogEntry{Message: "Stopped nginx.service."}, want: models.StatusStopped},
+		{entry: &models.LogEntry{Message: "backup.service: Deactivated successfully."}, want: models.StatusStopped},
+		{entry: &models.LogEntry{Message: "Finished backup.service - Nightly backup."}, want: models.StatusStopped},
+		{entry: &models.LogEntry{Message: "Starting nginx.service..."}},
+		{entry: &models.LogEntry{Message: "Reloaded nginx.service."}},
+		{entry: &models.LogEntry{Fields: map[string]string{"MESSAGE_ID": messageUnitSuccess}}, want: models.StatusStopped},
+		{entry: &models.LogEntry{Fields: map[string]string{"MESSAGE_ID": messageUnitFailureResult}}, want: models.StatusFailed},
+		// The ID wins over the text
+		{entry: &models.LogEntry{Message: "Started x", Fields: map[string]string{"MESSAGE_ID": messageUnitFailed}}, want: models.StatusFailed},
+	}
+
+	for _, tt := range tests {
+		got, ok := ManagerStatus(tt.entry)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("ManagerStatus(%q, %v) = %q, %t; want %q", tt.entry.Message, tt.entry.Fields, got, ok, tt.want)
+		}
+	}
+}
+
+func TestComputeMeans(t *testing.T) {
+	records := concat(
+		samples(models.StatusRunning, 0, 10),
+		samples(models.StatusFailed, 10, 15),
+		samples(models.StatusRunning, 15, 40),
+		samples(models.StatusStopped, 40, 55),
+		samples(models.StatusRunning, 55, 60),
+	)
+	r := Compute(FromHistory("nginx.service", records, since, until, DefaultMaxGap))
+
+	// 20m down in two outages, 40m up
+	if r.MTTR != 10*time.Minute || r.MTBF != 20*time.Minute {
+		t.Errorf("MTTR %s, MTBF %s; want 10m, 20m", r.MTTR, r.MTBF)
+	}
+	if len(r.Outages) != 2 || r.Outages[0].Status != models.StatusFailed || r.Outages[1].Status != models.StatusStopped {
+		t.Errorf("outages = %+v, want a failed one and a stopped one", r.Outages)
+	}
+}
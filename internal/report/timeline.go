@@ -0,0 +1,180 @@
+package report
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/history"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// Source names where a timeline was reconstructed from
+const (
+	SourceHistory = "history"
+	SourceJournal = "journal"
+)
+
+// Observation is a unit's status seen at a point in time
+type Observation struct {
+	Time   time.Time
+	Status models.ServiceStatus
+}
+
+// Span is a period during which a unit had one status. StatusUnknown
+// marks time nothing was observed, e.g. while the monitor was not running.
+type Span struct {
+	Start  time.Time
+	End    time.Time
+	Status models.ServiceStatus
+}
+
+// Timeline is the reconstructed status of a unit over a window
+type Timeline struct {
+	Unit   string
+	Source string
+	Since  time.Time
+	Until  time.Time
+	Spans  []Span
+}
+
+// DefaultMaxGap is how long a history observation is trusted when no
+// newer one follows. It must exceed the monitor interval and the history
+// resolution (5m by default).
+const DefaultMaxGap = 10 * time.Minute
+
+// FromHistory builds a timeline from recorded samples and events. Each
+// observation holds until the next one, but at most maxGap; time beyond
+// that counts as unknown.
+func FromHistory(unit string, records []*history.Record, since, until time.Time, maxGap time.Duration) *Timeline {
+	observations := make([]Observation, 0, len(records))
+	for _, record := range records {
+		if record.Unit != unit || record.Status == "" {
+			continue
+		}
+		observations = append(observations, Observation{Time: record.Time, Status: record.Status})
+	}
+
+	return build(unit, SourceHistory, observations, since, until, maxGap)
+}
+
+// FromJournal builds a timeline from systemd's own messages about a unit.
+// before holds messages preceding the window and sets the initial status;
+// a status lasts until the next message changes it.
+func FromJournal(unit string, before, entries []*models.LogEntry, since, until time.Time) *Timeline {
+	var observations []Observation
+
+	// 1. The last state change before the window is the starting status
+	for i := len(before) - 1; i >= 0; i-- {
+		if status, ok := ManagerStatus(before[i]); ok {
+			observations = append(observations, Observation{Time: since, Status: status})
+			break
+		}
+	}
+
+	// 2. State changes inside the window
+	for _, entry := range entries {
+		if status, ok := ManagerStatus(entry); ok {
+			observations = append(observations, Observation{Time: entry.Timestamp, Status: status})
+		}
+	}
+
+	return build(unit, SourceJournal, observations, since, until, 0)
+}
+
+// Journal message IDs systemd attaches to unit lifecycle messages
+// (see sd-messages.h)
+const (
+	messageUnitStarted       = "39f53479d3a045ac8e11786248231fbf"
+	messageUnitStopped       = "9d1aaa27d60140bd96365438aad20286"
+	messageUnitFailed        = "be02cf6855d2428ba40df7e9d022f03d"
+	messageUnitSuccess       = "7ad2d189f7e94e70a38c781354912448"
+	messageUnitFailureResult = "d9b373ed55a64feb8242e02dbe79a49c"
+)
+
+// ManagerStatus maps a systemd (PID 1) message about a unit to the status
+// it enters. Starting/Stopping/Reloading messages do not change it.
+func ManagerStatus(entry *models.LogEntry) (models.ServiceStatus, bool) {
+	// 1. Structured: the message ID, plus JOB_RESULT for start jobs
+	switch entry.Fields["MESSAGE_ID"] {
+	case messageUnitStarted:
+		if result := entry.Fields["JOB_RESULT"]; result != "" && result != "done" {
+			return models.StatusFailed, true
+		}
+		return models.StatusRunning, true
+	case messageUnitStopped, messageUnitSuccess:
+		return models.StatusStopped, true
+	case messageUnitFailed, messageUnitFailureResult:
+		return models.StatusFailed, true
+	}
+
+	// 2. Message text, for journals without IDs
+	message := entry.Message
+	switch {
+	case strings.HasPrefix(message, "Started "):
+		return models.StatusRunning, true
+	case strings.HasPrefix(message, "Failed to start "),
+		strings.Contains(message, ": Failed with result "):
+		return models.StatusFailed, true
+	case strings.HasPrefix(message, "Stopped "),
+		strings.HasSuffix(message, ": Deactivated successfully."),
+		strings.HasPrefix(message, "Finished "):
+		return models.StatusStopped, true
+	}
+	return "", false
+}
+
+// build turns observations into spans covering [since, until)
+func build(unit, source string, observations []Observation, since, until time.Time, maxGap time.Duration) *Timeline {
+	sort.SliceStable(observations, func(i, j int) bool {
+		return observations[i].Time.Before(observations[j].Time)
+	})
+
+	timeline := &Timeline{Unit: unit, Source: source, Since: since, Until: until}
+	add := func(start, end time.Time, status models.ServiceStatus) {
+		if start.Before(since) {
+			start = since
+		}
+		if end.After(until) {
+			end = until
+		}
+		if !end.After(start) {
+			return
+		}
+
+		// Merge with the previous span when the status did not change
+		if n := len(timeline.Spans); n > 0 {
+			last := &timeline.Spans[n-1]
+			if last.Status == status && last.End.Equal(start) {
+				last.End = end
+				return
+			}
+		}
+		timeline.Spans = append(timeline.Spans, Span{Start: start, End: end, Status: status})
+	}
+
+	cursor := since
+	for i, observation := range observations {
+		// Observations at or after the end of the window do not count
+		if !observation.Time.Before(until) {
+			break
+		}
+
+		end := until
+		if i+1 < len(observations) {
+			end = observations[i+1].Time
+		}
+		if maxGap > 0 && end.Sub(observation.Time) > maxGap {
+			end = observation.Time.Add(maxGap)
+		}
+
+		add(cursor, observation.Time, models.StatusUnknown)
+		add(observation.Time, end, observation.Status)
+		if end.After(cursor) {
+			cursor = end
+		}
+	}
+	add(cursor, until, models.StatusUnknown)
+
+	return timeline
+}
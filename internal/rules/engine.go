@@ -0,0 +1,166 @@
+package rules
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// ruleState tracks one rule for one unit
+type ruleState struct {
+	firing bool
+	since  time.Time // when the pending change (fire or clear) started holding; zero = none
+}
+
+// restartSample is a unit's restart counter at one check
+type restartSample struct {
+	at       time.Time
+	restarts int
+}
+
+// Engine evaluates rules against successive snapshots of units. A rule
+// fires once its condition held for the rule's duration and clears once
+// the value stayed past the clear level for as long.
+type Engine struct {
+	states   map[string]*ruleState      // unit, severity and expression of a rule
+	restarts map[string][]restartSample // recent restart counters per unit
+}
+
+// NewEngine creates an engine without any history
+func NewEngine() *Engine {
+	return &Engine{
+		states:   make(map[string]*ruleState),
+		restarts: make(map[string][]restartSample),
+	}
+}
+
+// Evaluate checks a snapshot against rules and returns a threshold event
+// for every rule that fired and a cleared event for every rule that
+// recovered. Rules that do not apply to the unit are skipped.
+func (e *Engine) Evaluate(rules []*Rule, service *models.ServiceInfo) []*models.Event {
+	now := service.CheckedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// 1. Restart history, kept as long as the longest window needs it
+	var window time.Duration
+	for _, rule := range rules {
+		if rule.Op == opIncreased && rule.Window > window && rule.Applies(service.Name) {
+			window = rule.Window
+		}
+	}
+	if window > 0 {
+		e.recordRestarts(service.Key(), service.Restarts, now, window)
+	}
+
+	// 2. Rules
+	var events []*models.Event
+	for _, rule := range rules {
+		if !rule.Applies(service.Name) {
+			continue
+		}
+
+		value, ok := rule.current(service)
+		if rule.Op == opIncreased {
+			value = e.restartIncrease(service.Key(), rule.Window, now)
+		}
+
+		key := service.Key() + "\x00" + string(rule.Severity) + "\x00" + rule.Expr
+		state, exists := e.states[key]
+		if !exists {
+			state = &ruleState{}
+			e.states[key] = state
+		}
+
+		// Unknown values neither fire nor clear a rule
+		if !ok {
+			state.since = time.Time{}
+			continue
+		}
+
+		changing := rule.exceeded(value)
+		if state.firing {
+			changing = rule.cleared(value)
+		}
+		if !changing {
+			state.since = time.Time{}
+			continue
+		}
+		if state.since.IsZero() {
+			state.since = now
+		}
+		if now.Sub(state.since) < rule.For {
+			continue
+		}
+
+		state.firing = !state.firing
+		state.since = time.Time{}
+		events = append(events, newEvent(rule, service, value, state.firing))
+	}
+
+	return events
+}
+
+// recordRestarts appends a restart sample and drops the ones no longer
+// needed: the newest sample at least window old stays as the baseline. A
+// counter that went down was reset, so the history starts over.
+func (e *Engine) recordRestarts(unit string, restarts int, now time.Time, window time.Duration) {
+	samples := e.restarts[unit]
+	if n := len(samples); n > 0 && restarts < samples[n-1].restarts {
+		samples = nil
+	}
+	samples = append(samples, restartSample{at: now, restarts: restarts})
+
+	cutoff := now.Add(-window)
+	start := 0
+	for start+1 < len(samples) && !samples[start+1].at.After(cutoff) {
+		start++
+	}
+	e.restarts[unit] = samples[start:]
+}
+
+// restartIncrease returns how much the restart counter of unit grew
+// within window, measured from the newest sample at least window old (or
+// the oldest one while the history is shorter)
+func (e *Engine) restartIncrease(unit string, window time.Duration, now time.Time) float64 {
+	samples := e.restarts[unit]
+	if len(samples) == 0 {
+		return 0
+	}
+
+	cutoff := now.Add(-window)
+	baseline := samples[0]
+	for _, sample := range samples[1:] {
+		if sample.at.After(cutoff) {
+			break
+		}
+		baseline = sample
+	}
+	return float64(samples[len(samples)-1].restarts - baseline.restarts)
+}
+
+// newEvent describes a rule firing or clearing for a unit
+func newEvent(rule *Rule, service *models.ServiceInfo, value float64, firing bool) *models.Event {
+	eventType := models.EventThreshold
+	if !firing {
+		eventType = models.EventThresholdCleared
+	}
+
+	event := models.NewEvent(eventType, service)
+	event.Threshold = rule.Expr
+	event.Severity = string(rule.Severity)
+
+	current := fmt.Sprintf("%s %s", rule.Metric, rule.FormatValue(value))
+	if rule.Op == opIncreased {
+		current = fmt.Sprintf("%s restarts in %s", rule.FormatValue(value), rule.Window)
+	}
+
+	if firing {
+		event.Message = fmt.Sprintf("Service %s breached %s rule %q: %s", service.Name, rule.Severity, rule.Expr, current)
+	} else {
+		event.Message = fmt.Sprintf("Service %s is back within rule %q: %s", service.Name, rule.Expr, current)
+	}
+	return event
+}
@@ -0,0 +1,214 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// fakeClock stamps snapshots with a time the test moves forward; the
+// engine takes its clock from CheckedAt
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)}
+}
+
+// at returns a snapshot of unit checked after advancing the clock by d
+func (c *fakeClock) at(d time.Duration, unit string) *models.ServiceInfo {
+	c.now = c.now.Add(d)
+	return &models.ServiceInfo{Name: unit, Status: models.StatusRunning, CheckedAt: c.now}
+}
+
+func mustParse(t *testing.T, expr string) *Rule {
+	t.Helper()
+	rule, err := Parse(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rule
+}
+
+// step is one check: after advancing the clock, the unit reports value
+// and the engine should return the event type, or none
+type step struct {
+	after time.Duration
+	value int64
+	want  models.EventType // "" = no event
+}
+
+func runSteps(t *testing.T, engine *Engine, rules []*Rule, clock *fakeClock, steps []step, set func(*models.ServiceInfo, int64)) {
+	t.Helper()
+	for i, s := range steps {
+		service := clock.at(s.after, "app.service")
+		set(service, s.value)
+		events := engine.Evaluate(rules, service)
+
+		var got models.EventType
+		if len(events) > 1 {
+			t.Fatalf("step %d: got %d events, want at most 1", i, len(events))
+		}
+		if len(events) == 1 {
+			got = events[0].Type
+		}
+		if got != s.want {
+			t.Errorf("step %d (+%s, value %d): got event %q, want %q", i, s.after, s.value, got, s.want)
+		}
+	}
+}
+
+func setMemory(service *models.ServiceInfo, value int64) { service.MemoryBytes = value }
+
+func TestEngineFiresAfterFor(t *testing.T) {
+	rule := mustParse(t, "memory > 500MB for 5m") // clears at 450MB
+	engine := NewEngine()
+
+	runSteps(t, engine, []*Rule{rule}, newFakeClock(), []step{
+		{after: 0, value: 600e6},
+		{after: 2 * time.Minute, value: 600e6},
+		// Back under the threshold restarts the wait
+		{after: 2 * time.Minute, value: 480e6},
+		{after: time.Minute, value: 600e6},
+		{after: 4 * time.Minute, value: 600e6},
+		{after: time.Minute, value: 600e6, want: models.EventThreshold},
+		// Firing once, not at every check
+		{after: time.Minute, value: 700e6},
+	}, setMemory)
+}
+
+func TestEngineClearsWithHysteresis(t *testing.T) {
+	rule := mustParse(t, "memory > 500MB for 5m")
+	engine := NewEngine()
+
+	runSteps(t, engine, []*Rule{rule}, newFakeClock(), []step{
+		{after: 0, value: 600e6},
+		{after: 5 * time.Minute, value: 600e6, want: models.EventThreshold},
+		// Under the threshold but above the clear level: still firing
+		{after: time.Minute, value: 480e6},
+		{after: 10 * time.Minute, value: 460e6},
+		// Past the clear level, which must also hold for 5m
+		{after: time.Minute, value: 400e6},
+		{after: 3 * time.Minute, value: 400e6},
+		// Bouncing back restarts the clear wait
+		{after: time.Minute, value: 470e6},
+		{after: time.Minute, value: 450e6},
+		{after: 4 * time.Minute, value: 300e6},
+		{after: time.Minute, value: 300e6, want: models.EventThresholdCleared},
+		// And the rule can fire again
+		{after: time.Minute, value: 600e6},
+		{after: 5 * time.Minute, value: 600e6, want: models.EventThreshold},
+	}, setMemory)
+}
+
+func TestEngineWithoutFor(t *testing.T) {
+	rule := mustParse(t, "tasks >= 100") // clears at 90
+	engine := NewEngine()
+
+	runSteps(t, engine, []*Rule{rule}, newFakeClock(), []step{
+		{after: 0, value: 99},
+		{after: time.Second, value: 100, want: models.EventThreshold},
+		{after: time.Second, value: 91},
+		{after: time.Second, value: 90, want: models.EventThresholdCleared},
+	}, func(service *models.ServiceInfo, value int64) { service.Tasks = int(value) })
+}
+
+func TestEngineBelowThreshold(t *testing.T) {
+	rule := mustParse(t, "memory < 100MB for 1m") // clears at 110MB
+	engine := NewEngine()
+
+	runSteps(t, engine, []*Rule{rule}, newFakeClock(), []step{
+		{after: 0, value: 50e6},
+		{after: time.Minute, value: 50e6, want: models.EventThreshold},
+		{after: time.Minute, value: 105e6},
+		{after: time.Minute, value: 105e6},
+		{after: time.Minute, value: 120e6},
+		{after: time.Minute, value: 120e6, want: models.EventThresholdCleared},
+	}, setMemory)
+}
+
+func TestEngineUnknownValue(t *testing.T) {
+	rule := mustParse(t, "memory > 500MB for 2m")
+	engine := NewEngine()
+
+	// No memory reading neither fires nor clears, and restarts the wait
+	runSteps(t, engine, []*Rule{rule}, newFakeClock(), []step{
+		{after: 0, value: 600e6},
+		{after: time.Minute, value: 0},
+		{after: time.Minute, value: 600e6},
+		{after: time.Minute, value: 600e6},
+		{after: time.Minute, value: 600e6, want: models.EventThreshold},
+		{after: time.Minute, value: 0},
+		{after: time.Hour, value: 0},
+		{after: time.Minute, value: 600e6},
+	}, setMemory)
+}
+
+func TestEngineRestartIncrease(t *testing.T) {
+	rule := mustParse(t, "restarts increased by 3 in 10m")
+	engine := NewEngine()
+	setRestarts := func(service *models.ServiceInfo, value int64) { service.Restarts = int(value) }
+
+	runSteps(t, engine, []*Rule{rule}, newFakeClock(), []step{
+		{after: 0, value: 5},
+		{after: 4 * time.Minute, value: 6},
+		{after: 4 * time.Minute, value: 7},
+		// 5 -> 8 within 10m
+		{after: time.Minute, value: 8, want: models.EventThreshold},
+		{after: 2 * time.Minute, value: 8},
+		// 7 -> 8: one restart is still in the window
+		{after: 7 * time.Minute, value: 8},
+		// None left
+		{after: time.Minute, value: 8, want: models.EventThresholdCleared},
+		// A counter that went down (systemd reset it) starts over
+		{after: time.Minute, value: 0},
+		{after: time.Minute, value: 2},
+		{after: time.Minute, value: 3, want: models.EventThreshold},
+	}, setRestarts)
+}
+
+func TestEngineTracksUnitsAndSeverities(t *testing.T) {
+	warning := mustParse(t, "memory > 500MB")
+	critical := mustParse(t, "memory > 500MB")
+	critical.Severity = SeverityCritical
+	other := mustParse(t, "memory > 1MB")
+	other.Match = "db.service"
+
+	engine := NewEngine()
+	clock := newFakeClock()
+	rules := []*Rule{warning, critical, other}
+
+	service := clock.at(0, "app.service")
+	service.MemoryBytes = 600e6
+	events := engine.Evaluate(rules, service)
+	if len(events) != 2 || events[0].Severity != "warning" || events[1].Severity != "critical" {
+		t.Fatalf("app.service: got %v, want a warning and a critical event", events)
+	}
+	event := events[0]
+	if event.Unit != "app.service" || event.Threshold != "memory > 500MB" || !event.Timestamp.Equal(clock.now) {
+		t.Errorf("event = %+v", event)
+	}
+	if want := `Service app.service breached warning rule "memory > 500MB": memory 572.2 MiB`; event.Message != want {
+		t.Errorf("message = %q, want %q", event.Message, want)
+	}
+
+	// The same unit on another host is tracked on its own
+	remote := clock.at(time.Minute, "app.service")
+	remote.Host = "web-01"
+	remote.MemoryBytes = 600e6
+	if events := engine.Evaluate(rules, remote); len(events) != 2 {
+		t.Errorf("web-01/app.service: got %d events, want 2", len(events))
+	}
+
+	service = clock.at(time.Minute, "app.service")
+	service.MemoryBytes = 100e6
+	events = engine.Evaluate(rules, service)
+	if len(events) != 2 || events[0].Type != models.EventThresholdCleared {
+		t.Errorf("app.service: got %v, want both rules cleared", events)
+	}
+	if want := `Service app.service is back within rule "memory > 500MB": memory 95.4 MiB`; len(events) > 0 && events[0].Message != want {
+		t.Errorf("message = %q, want %q", events[0].Message, want)
+	}
+}
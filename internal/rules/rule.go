@@ -0,0 +1,314 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// Metric is the unit property a rule watches
+type Metric string
+
+const (
+	MetricMemory   Metric = "memory"   // current memory, bytes
+	MetricSwap     Metric = "swap"     // current swap, bytes
+	MetricCPU      Metric = "cpu"      // CPU rate, percent of one CPU
+	MetricTasks    Metric = "tasks"    // number of tasks (processes and threads)
+	MetricRestarts Metric = "restarts" // systemd's restart counter (NRestarts)
+)
+
+// Severity of the events a rule produces
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// ParseSeverity converts "warning" or "critical" into a Severity
+func ParseSeverity(s string) (Severity, error) {
+	switch Severity(strings.ToLower(s)) {
+	case SeverityWarning:
+		return SeverityWarning, nil
+	case SeverityCritical:
+		return SeverityCritical, nil
+	}
+	return "", fmt.Errorf("severity must be warning or critical, got %q", s)
+}
+
+// opIncreased compares how much a counter grew within Window
+const opIncreased = "increased"
+
+// Rule is a condition on a unit's resource usage, e.g.
+// "memory > 512MiB for 5m" or "restarts increased by 3 in 10m"
+type Rule struct {
+	Expr     string // the condition as written; names the rule in events
+	Match    string // glob of unit names the rule applies to; "" = any
+	Severity Severity
+
+	Metric Metric
+	Op     string        // >, >=, <, <= or "increased"
+	Value  float64       // threshold in the metric's unit
+	Window time.Duration // "increased by" window
+	For    time.Duration // the condition must hold this long (0 = at once)
+
+	// Clear is the level the metric must get back past before the rule
+	// clears, so a value hovering at the threshold does not flap
+	Clear float64
+}
+
+// Parse parses a condition:
+//
+//	<metric> <op> <value> [for <duration>]
+//	restarts increased by <n> in <duration> [for <duration>]
+//
+// Metrics are memory and swap (bytes, e.g. 512MiB or 2G), cpu (percent of
+// one CPU, e.g. 90%), tasks and restarts. The clear level defaults to 10%
+// short of the threshold; restart increases clear once no restart is left
+// in the window.
+func Parse(expr string) (*Rule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("invalid rule %q: expected \"<metric> <op> <value> [for <duration>]\"", expr)
+	}
+
+	rule := &Rule{Expr: strings.Join(fields, " "), Severity: SeverityWarning}
+
+	// 1. Metric
+	rule.Metric = Metric(strings.ToLower(fields[0]))
+	switch rule.Metric {
+	case MetricMemory, MetricSwap, MetricCPU, MetricTasks, MetricRestarts:
+	default:
+		return nil, fmt.Errorf("invalid rule %q: unknown metric %q (memory, swap, cpu, tasks or restarts)", expr, fields[0])
+	}
+
+	// 2. Comparison; restarts can also be compared by their increase
+	rest := fields[1:]
+	if strings.ToLower(rest[0]) == opIncreased {
+		if rule.Metric != MetricRestarts {
+			return nil, fmt.Errorf("invalid rule %q: only restarts can be compared with \"increased by\"", expr)
+		}
+		if len(rest) < 5 || strings.ToLower(rest[1]) != "by" || strings.ToLower(rest[3]) != "in" {
+			return nil, fmt.Errorf("invalid rule %q: expected \"restarts increased by <n> in <duration>\"", expr)
+		}
+
+		rule.Op = opIncreased
+		value, err := ParseValue(rule.Metric, rest[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", expr, err)
+		}
+		if value < 1 {
+			return nil, fmt.Errorf("invalid rule %q: the increase must be at least 1", expr)
+		}
+		rule.Value = value
+
+		window, err := time.ParseDuration(rest[4])
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid rule %q: expected a duration like 10m, got %q", expr, rest[4])
+		}
+		rule.Window = window
+		rest = rest[5:]
+	} else {
+		switch rest[0] {
+		case ">", ">=", "<", "<=":
+			rule.Op = rest[0]
+		default:
+			return nil, fmt.Errorf("invalid rule %q: operator must be >, >=, < or <=, got %q", expr, rest[0])
+		}
+
+		value, err := ParseValue(rule.Metric, rest[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", expr, err)
+		}
+		rule.Value = value
+		rest = rest[2:]
+	}
+
+	// 3. Optional duration
+	if len(rest) > 0 {
+		if len(rest) != 2 || strings.ToLower(rest[0]) != "for" {
+			return nil, fmt.Errorf("invalid rule %q: unexpected %q", expr, strings.Join(rest, " "))
+		}
+		duration, err := time.ParseDuration(rest[1])
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("invalid rule %q: expected a duration like 5m, got %q", expr, rest[1])
+		}
+		rule.For = duration
+	}
+
+	rule.Clear = rule.defaultClear()
+	return rule, nil
+}
+
+// ParseValue parses a threshold in the unit of metric: bytes with an
+// optional K/M/G/T suffix (KiB = K = 1024, KB = 1000) for memory and swap,
+// a percentage for cpu and a count otherwise
+func ParseValue(metric Metric, s string) (float64, error) {
+	switch metric {
+	case MetricMemory, MetricSwap:
+		return parseBytes(s)
+	case MetricCPU:
+		value, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("expected a percentage like 90%%, got %q", s)
+		}
+		return value, nil
+	default:
+		value, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("expected a count, got %q", s)
+		}
+		return float64(value), nil
+	}
+}
+
+// parseBytes parses sizes such as 512MiB, 2G, 1.5GB or 1048576
+func parseBytes(s string) (float64, error) {
+	units := []struct {
+		suffix string
+		factor float64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+		{"B", 1},
+	}
+
+	number, factor := s, 1.0
+	for _, unit := range units {
+		if trimmed, ok := strings.CutSuffix(strings.ToUpper(s), strings.ToUpper(unit.suffix)); ok {
+			number, factor = s[:len(trimmed)], unit.factor
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("expected a size like 512MiB, got %q", s)
+	}
+	return math.Round(value * factor), nil
+}
+
+// defaultClear returns the clear level: 10% short of the threshold,
+// rounded down to whole counts for tasks and restarts, and no restart
+// left in the window for increases
+func (r *Rule) defaultClear() float64 {
+	if r.Op == opIncreased {
+		return 0
+	}
+
+	margin := r.Value / 10
+	if r.Metric == MetricTasks || r.Metric == MetricRestarts {
+		margin = math.Floor(margin)
+	}
+	if r.upper() {
+		return r.Value - margin
+	}
+	return r.Value + margin
+}
+
+// Applies reports whether the rule covers a unit
+func (r *Rule) Applies(unit string) bool {
+	if r.Match == "" {
+		return true
+	}
+	matched, _ := path.Match(r.Match, unit)
+	return matched
+}
+
+// String describes the rule for listings, e.g.
+// "critical: memory > 512MiB for 5m (clears at 460.8 MiB)"
+func (r *Rule) String() string {
+	s := fmt.Sprintf("%s: %s", r.Severity, r.Expr)
+	switch {
+	case r.Op == opIncreased && r.Clear == 0:
+		s += fmt.Sprintf(" (clears after %s without restarts)", r.Window)
+	case r.Op == opIncreased:
+		s += fmt.Sprintf(" (clears at %s restarts in %s)", r.FormatValue(r.Clear), r.Window)
+	default:
+		s += fmt.Sprintf(" (clears at %s)", r.FormatValue(r.Clear))
+	}
+	if r.Match != "" {
+		s += " for units matching " + r.Match
+	}
+	return s
+}
+
+// FormatValue renders a value in the rule's metric unit
+func (r *Rule) FormatValue(value float64) string {
+	switch r.Metric {
+	case MetricMemory, MetricSwap:
+		return formatBytes(value)
+	case MetricCPU:
+		return fmt.Sprintf("%.1f%%", value)
+	default:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+}
+
+// upper reports whether the rule fires above its threshold (>, >= and
+// increases) rather than below it
+func (r *Rule) upper() bool {
+	return r.Op != "<" && r.Op != "<="
+}
+
+// exceeded reports whether value breaks the rule
+func (r *Rule) exceeded(value float64) bool {
+	switch r.Op {
+	case ">":
+		return value > r.Value
+	case "<":
+		return value < r.Value
+	case "<=":
+		return value <= r.Value
+	default: // >= and increased
+		return value >= r.Value
+	}
+}
+
+// cleared reports whether value is back past the clear level
+func (r *Rule) cleared(value float64) bool {
+	if r.exceeded(value) {
+		return false
+	}
+	if r.upper() {
+		return value <= r.Clear
+	}
+	return value >= r.Clear
+}
+
+// current returns the value the rule compares for a snapshot; false when
+// the unit does not report it (accounting off, no CPU rate yet, ...)
+func (r *Rule) current(service *models.ServiceInfo) (float64, bool) {
+	switch r.Metric {
+	case MetricMemory:
+		return float64(service.MemoryBytes), service.MemoryBytes > 0
+	case MetricSwap:
+		return float64(service.SwapBytes), service.HasResources()
+	case MetricCPU:
+		return service.CPUPercent, service.CPUSampled
+	case MetricTasks:
+		return float64(service.Tasks), service.Tasks > 0
+	default:
+		return float64(service.Restarts), true
+	}
+}
+
+// formatBytes renders a size in binary units, e.g. "512.0 MiB"
+func formatBytes(value float64) string {
+	const unit = 1024
+	if value < unit {
+		return fmt.Sprintf("%.0f B", value)
+	}
+	exp := 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exp-1])
+}
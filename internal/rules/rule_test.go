@@ -0,0 +1,148 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		want Rule
+	}{
+		{
+			expr: "memory > 500MB for 5m",
+			want: Rule{Expr: "memory > 500MB for 5m", Metric: MetricMemory, Op: ">", Value: 500e6, For: 5 * time.Minute, Clear: 450e6},
+		},
+		{
+			expr: "  CPU  >=  90%  ",
+			want: Rule{Expr: "CPU >= 90%", Metric: MetricCPU, Op: ">=", Value: 90, Clear: 81},
+		},
+		{
+			expr: "tasks > 15",
+			want: Rule{Expr: "tasks > 15", Metric: MetricTasks, Op: ">", Value: 15, Clear: 14},
+		},
+		{
+			// Below-threshold rules clear above it
+			expr: "memory < 1GB for 30s",
+			want: Rule{Expr: "memory < 1GB for 30s", Metric: MetricMemory, Op: "<", Value: 1e9, For: 30 * time.Second, Clear: 1.1e9},
+		},
+		{
+			expr: "swap >= 1.5GB",
+			want: Rule{Expr: "swap >= 1.5GB", Metric: MetricSwap, Op: ">=", Value: 1.5e9, Clear: 1.35e9},
+		},
+		{
+			expr: "restarts increased by 3 in 10m",
+			want: Rule{Expr: "restarts increased by 3 in 10m", Metric: MetricRestarts, Op: opIncreased, Value: 3, Window: 10 * time.Minute},
+		},
+		{
+			expr: "restarts INCREASED BY 1 IN 1h FOR 2m",
+			want: Rule{Expr: "restarts INCREASED BY 1 IN 1h FOR 2m", Metric: MetricRestarts, Op: opIncreased, Value: 1, Window: time.Hour, For: 2 * time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		rule, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		tt.want.Severity = SeverityWarning
+		if *rule != tt.want {
+			t.Errorf("Parse(%q) =\n%+v\nwant\n%+v", tt.expr, *rule, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: "memory >", want: `expected "<metric> <op> <value> [for <duration>]"`},
+		{expr: "load > 3", want: `unknown metric "load"`},
+		{expr: "memory = 1G", want: `operator must be >, >=, < or <=, got "="`},
+		{expr: "memory > lots", want: `expected a size like 512MiB, got "lots"`},
+		{expr: "memory > -1G", want: `expected a size like 512MiB, got "-1G"`},
+		{expr: "cpu > ninety%", want: `expected a percentage like 90%, got "ninety%"`},
+		{expr: "tasks > 1.5", want: `expected a count, got "1.5"`},
+		{expr: "memory > 1G for", want: `unexpected "for"`},
+		{expr: "memory > 1G during 5m", want: `unexpected "during 5m"`},
+		{expr: "memory > 1G for soon", want: `expected a duration like 5m, got "soon"`},
+		{expr: "memory > 1G for -5m", want: `expected a duration like 5m, got "-5m"`},
+		{expr: "cpu increased by 3 in 10m", want: `only restarts can be compared with "increased by"`},
+		{expr: "restarts increased 3 in 10m", want: `expected "restarts increased by <n> in <duration>"`},
+		{expr: "restarts increased by 0 in 10m", want: "the increase must be at least 1"},
+		{expr: "restarts increased by 3 in 0s", want: `expected a duration like 10m, got "0s"`},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, want %q", tt.expr, tt.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), tt.expr) {
+			t.Errorf("Parse(%q) = %q, want it to name the rule and say %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestParseValueBytes(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{s: "1048576", want: 1 << 20},
+		{s: "100B", want: 100},
+		{s: "4K", want: 4096},
+		{s: "4KiB", want: 4096},
+		{s: "4KB", want: 4000},
+		{s: "4kb", want: 4000},
+		{s: "512MiB", want: 512 << 20},
+		{s: "2G", want: 2 << 30},
+		{s: "1.5GB", want: 1.5e9},
+		{s: "1T", want: 1 << 40},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseValue(MetricMemory, tt.s)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseValue(memory, %q) = %v, %v; want %v", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestRuleString(t *testing.T) {
+	tests := []struct {
+		expr  string
+		match string
+		want  string
+	}{
+		{expr: "memory > 512MiB for 5m", want: "warning: memory > 512MiB for 5m (clears at 460.8 MiB)"},
+		{expr: "cpu > 90%", match: "docker-*", want: "warning: cpu > 90% (clears at 81.0%) for units matching docker-*"},
+		{expr: "restarts increased by 3 in 10m", want: "warning: restarts increased by 3 in 10m (clears after 10m0s without restarts)"},
+	}
+
+	for _, tt := range tests {
+		rule, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule.Match = tt.match
+		if got := rule.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestApplies(t *testing.T) {
+	rule := &Rule{Match: "docker-*.service"}
+	if !rule.Applies("docker-web.service") || rule.Applies("nginx.service") {
+		t.Error("docker-*.service should match docker-web.service only")
+	}
+	if !(&Rule{}).Applies("nginx.service") {
+		t.Error("a rule without a match should apply to every unit")
+	}
+}
@@ -1,122 +1,321 @@
 package systemd
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os/exec"
+	"io"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/andinianst93/systemd-monitoring/internal/cgroup"
+	"github.com/andinianst93/systemd-monitoring/internal/dbus"
 	"github.com/andinianst93/systemd-monitoring/internal/models"
 )
 
+// Backend selects how Client queries unit state
+type Backend string
+
+const (
+	// BackendAuto uses D-Bus when the system bus is reachable and falls
+	// back to systemctl otherwise (or when a D-Bus call fails)
+	BackendAuto Backend = "auto"
+	// BackendDBus talks to org.freedesktop.systemd1 only
+	BackendDBus Backend = "dbus"
+	// BackendExec shells out to systemctl only
+	BackendExec Backend = "exec"
+)
+
 type Client struct {
-	useSudo bool
+	useSudo  bool
+	executor Executor
+	bus      *dbus.Conn       // nil when using the exec backend
+	fallback bool             // retry failed D-Bus queries with systemctl
+	now      func() time.Time // clock used for uptime calculations
+	cgroups  *cgroup.Reader   // nil = resource data from properties only
+	cpu      cpuMeter         // CPU rates between samples
+	host     string           // remote host label; "" = this machine
+	scope    Scope            // manager the client talks to
+
+	uidMu sync.Mutex
+	uid   string // UID of scope.User, looked up on first use
 }
 
 func NewClient(useSudo bool) *Client {
 	return &Client{
-		useSudo: useSudo,
+		useSudo:  useSudo,
+		executor: NewExecExecutor(),
+		now:      time.Now,
+		cgroups:  cgroup.NewReader(cgroup.DefaultRoot),
+	}
+}
+
+// SetExecutor replaces the executor used for systemctl/journalctl calls,
+// e.g. with a FixtureExecutor in tests
+func (c *Client) SetExecutor(executor Executor) {
+	c.executor = executor
+}
+
+// SetClock replaces the clock used to compute uptimes, so replayed
+// fixtures give stable results
+func (c *Client) SetClock(now func() time.Time) {
+	c.now = now
+}
+
+// NewClientWithBackend creates a client using the given backend.
+// The bus address is taken from DBUS_SYSTEM_BUS_ADDRESS when set.
+func NewClientWithBackend(useSudo bool, backend Backend) (*Client, error) {
+	client := NewClient(useSudo)
+
+	switch backend {
+	case BackendExec, "":
+		return client, nil
+
+	case BackendDBus, BackendAuto:
+		bus, err := dbus.SystemBus()
+		if err != nil {
+			if backend == BackendAuto {
+				return client, nil
+			}
+			return nil, fmt.Errorf("failed to connect to system bus: %w", err)
+		}
+		client.bus = bus
+		client.fallback = backend == BackendAuto
+		return client, nil
+	}
+
+	return nil, fmt.Errorf("unknown backend: %s", backend)
+}
+
+// Backend reports which backend the client is currently using
+func (c *Client) Backend() Backend {
+	if c.bus != nil {
+		return BackendDBus
+	}
+	return BackendExec
+}
+
+// Close releases the D-Bus connection, if any
+func (c *Client) Close() error {
+	if c.bus != nil {
+		return c.bus.Close()
 	}
+	return nil
 }
 
-func (c *Client) ListServices() (*models.ServiceList, error) {
-	// 1. Build command: systemctl list-units --type=service --all --no-pager
-	cmd := c.buildCommand("systemctl", "list-units", "--type=service", "--all", "--no-pager")
+// ListServices lists all service units
+func (c *Client) ListServices(ctx context.Context) (*models.ServiceList, error) {
+	return c.ListUnits(ctx, models.UnitService)
+}
 
-	// 2. Execute command using exec.Command()
-	output, err := cmd.CombinedOutput()
+// ListUnits lists all loaded units of the given type (or models.UnitAll)
+func (c *Client) ListUnits(ctx context.Context, unitType models.UnitType) (*models.ServiceList, error) {
+	if c.bus != nil {
+		serviceList, err := c.listUnitsDBus(ctx, unitType)
+		if err == nil || !c.fallback {
+			if serviceList != nil {
+				c.stamp(serviceList.Services...)
+			}
+			return serviceList, err
+		}
+	}
+
+	// 1. Build command: systemctl list-units --type=<type> --all --plain --no-legend --no-pager
+	args := []string{"list-units"}
+	if unitType != models.UnitAll {
+		args = append(args, "--type="+string(unitType))
+	}
+	args = append(args, "--all", "--plain", "--no-legend", "--no-pager")
+
+	// 2. Execute command through the executor
+	output, err := c.run(ctx, "systemctl", args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute systemctl: %w", err)
 	}
 
 	// 3. Parse output line by line
+	units := parseListUnitsOutput(string(output), unitType)
+
+	// 4. list-units has no type-specific columns; fetch them in one call
+	if err := c.fillDetails(ctx, units); err != nil {
+		return nil, err
+	}
+
+	// 5. Return ServiceList
+	c.stamp(units...)
 	serviceList := models.NewServiceList()
-	lines := strings.Split(string(output), "\n")
+	for _, serviceInfo := range units {
+		serviceList.AddService(serviceInfo)
+	}
 
-	// 4. For each service line:
-	//    - Extract: name, load-state, active-state, sub-state
-	//    - Create ServiceInfo
-	//    - Add to ServiceList
+	return serviceList, nil
+}
 
-	for _, line := range lines {
-		// Skip empty lines
-		line = strings.TrimSpace(line)
-		if line == "" {
+// fillDetails populates Details for units whose type has extra properties
+// using a single "systemctl show -p ... unit1 unit2 ..." call
+func (c *Client) fillDetails(ctx context.Context, units []*models.ServiceInfo) error {
+	var names []string
+	propSet := map[string]bool{"Id": true}
+	for _, unit := range units {
+		details := unitDetails[unit.UnitType]
+		if len(details) == 0 {
 			continue
 		}
+		names = append(names, unit.Name)
+		for _, d := range details {
+			propSet[d.property] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
 
-		// Skip header lines
-		if strings.Contains(line, "UNIT") || strings.Contains(line, "●") {
-			continue
+	props := make([]string, 0, len(propSet))
+	for prop := range propSet {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+
+	args := append([]string{"show", "-p", strings.Join(props, ","), "--no-pager"}, names...)
+	output, err := c.run(ctx, "systemctl", args...)
+	if err != nil {
+		return fmt.Errorf("failed to get unit details: %w", err)
+	}
+
+	// Each unit's properties form a block separated by an empty line
+	byName := make(map[string]map[string]string)
+	for _, block := range strings.Split(string(output), "\n\n") {
+		unitProps := parseShowOutput(block)
+		if id := unitProps["Id"]; id != "" {
+			byName[id] = unitProps
 		}
+	}
 
-		// Skip summary line
-		if strings.Contains(line, "loaded units") {
-			break
+	for _, unit := range units {
+		if unitProps, ok := byName[unit.Name]; ok {
+			unit.Details = extractDetails(unit.UnitType, unitProps)
 		}
+	}
+
+	return nil
+}
 
-		// Parse setiap field dari line
-		// Format output systemctl:
-		// UNIT                    LOAD   ACTIVE SUB     DESCRIPTION
-		// ssh.service             loaded active running OpenSSH server
-		fields := strings.Fields(line)
+// statusProperties are the properties newServiceInfoFromProperties reads
+var statusProperties = append([]string{
+	"Id", "ActiveState", "SubState", "UnitFileState", "MainPID",
+	"NRestarts", "MemoryCurrent", "ActiveEnterTimestamp",
+}, resourceProperties...)
+
+// ListUnitStatuses returns the full status of every unit of unitType whose
+// name passes include (nil = all units). The result matches calling
+// GetServiceStatus for each unit, but the exec backend needs only one
+// systemctl show call for all of them.
+func (c *Client) ListUnitStatuses(ctx context.Context, unitType models.UnitType, include func(name string) bool) ([]*models.ServiceInfo, error) {
+	// 1. Find the unit names
+	serviceList, err := c.ListUnits(ctx, unitType)
+	if err != nil {
+		return nil, err
+	}
 
-		// There must 4 fields: name, load, active, sub
-		if len(fields) < 4 {
+	var names []string
+	propSet := make(map[string]bool)
+	for _, prop := range statusProperties {
+		propSet[prop] = true
+	}
+	for _, unit := range serviceList.Services {
+		if include != nil && !include(unit.Name) {
 			continue
 		}
+		names = append(names, unit.Name)
+		for _, d := range unitDetails[unit.UnitType] {
+			propSet[d.property] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
 
-		// Extract fields
-		name := fields[0]
-		// loadState := fields[1]
-		activeState := fields[2]
-		subState := fields[3]
+	// 2. D-Bus: one GetAll per unit
+	if c.bus != nil {
+		statuses := make([]*models.ServiceInfo, 0, len(names))
+		for _, name := range names {
+			serviceInfo, err := c.getServiceStatusDBus(ctx, name)
+			if err != nil {
+				if !c.fallback {
+					return nil, err
+				}
+				statuses = nil
+				break
+			}
+			statuses = append(statuses, serviceInfo)
+		}
+		if statuses != nil {
+			c.stamp(statuses...)
+			return statuses, nil
+		}
+	}
 
-		// Create serviceinfo for each services
-		serviceInfo := models.NewServiceInfo(name)
-		serviceInfo.ActiveState = activeState
-		serviceInfo.SubState = subState
-		serviceInfo.Status = c.parseStatus(activeState, subState)
+	// 3. Exec: a single batched show, one block per unit
+	props := make([]string, 0, len(propSet))
+	for prop := range propSet {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
 
-		serviceList.AddService(serviceInfo)
+	args := append([]string{"show", "-p", strings.Join(props, ","), "--no-pager"}, names...)
+	output, err := c.run(ctx, "systemctl", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unit status: %w", err)
+	}
 
+	now := c.now()
+	statuses := make([]*models.ServiceInfo, 0, len(names))
+	for _, block := range strings.Split(string(output), "\n\n") {
+		unitProps := parseShowOutput(block)
+		if id := unitProps["Id"]; id != "" {
+			serviceInfo := newServiceInfoFromProperties(id, unitProps, now)
+			c.addResources(serviceInfo)
+			statuses = append(statuses, serviceInfo)
+		}
 	}
-	// 5. Return ServiceList
+	c.stamp(statuses...)
 
-	return serviceList, nil
+	return statuses, nil
 }
 
-func (c *Client) GetServiceStatus(serviceName string) (*models.ServiceInfo, error) {
+func (c *Client) GetServiceStatus(ctx context.Context, serviceName string) (*models.ServiceInfo, error) {
 	// PSEUDOCODE:
-	// 1. Add .service suffix if not present
-	if !strings.HasSuffix(serviceName, ".service") {
-		serviceName = serviceName + ".service"
+	// 1. Add .service suffix unless the name has an explicit unit suffix
+	serviceName = models.NormalizeUnitName(serviceName, models.UnitService)
+
+	if c.bus != nil {
+		serviceInfo, err := c.getServiceStatusDBus(ctx, serviceName)
+		if err == nil || !c.fallback {
+			c.stamp(serviceInfo)
+			return serviceInfo, err
+		}
 	}
 
 	// 2.  Build and execute command
-	cmd := c.buildCommand("systemctl", "show", serviceName, "--no-pager")
-	output, err := cmd.CombinedOutput()
+	output, err := c.run(ctx, "systemctl", "show", serviceName, "--no-pager")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get service status for %s: %w", serviceName, err)
 	}
 
-	// Create ServiceInfo
-	serviceInfo := models.NewServiceInfo(serviceName)
+	serviceInfo := newServiceInfoFromProperties(serviceName, parseShowOutput(string(output)), c.now())
+	c.addResources(serviceInfo)
+	c.stamp(serviceInfo)
 
-	// Parse key=value output line by line
-	lines := strings.Split(string(output), "\n")
+	return serviceInfo, nil
+}
 
-	// Variables to store parsed data
-	var (
-		activeState     string
-		subState        string
-		activeEnterTime string
-	)
+// parseShowOutput parses "systemctl show" key=value output into a map
+func parseShowOutput(output string) map[string]string {
+	props := make(map[string]string)
 
-	// STEP 5: Loop dan extract values
-	for _, line := range lines {
+	for _, line := range strings.Split(output, "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
@@ -128,69 +327,110 @@ func (c *Client) GetServiceStatus(serviceName string) (*models.ServiceInfo, erro
 			continue
 		}
 
-		key := parts[0]
-		value := parts[1]
+		props[parts[0]] = parts[1]
+	}
 
-		// STEP 6: Extract fields yang kita butuhkan
-		switch key {
-		case "ActiveState":
-			activeState = value
-			serviceInfo.ActiveState = value
+	return props
+}
 
-		case "SubState":
-			subState = value
-			serviceInfo.SubState = value
+// newServiceInfoFromProperties builds a ServiceInfo from unit properties,
+// no matter whether they came from systemctl show or from D-Bus
+func newServiceInfoFromProperties(serviceName string, props map[string]string, now time.Time) *models.ServiceInfo {
+	serviceInfo := models.NewServiceInfo(serviceName)
 
-		case "MainPID":
-			// Convert string to int
-			if pid, err := strconv.Atoi(value); err == nil {
-				serviceInfo.PID = pid
-			}
+	serviceInfo.ActiveState = props["ActiveState"]
+	serviceInfo.SubState = props["SubState"]
+	serviceInfo.UnitFileState = props["UnitFileState"]
 
-		case "MemoryCurrent":
-			// Convert bytes to human readable (MB)
-			if memBytes, err := strconv.ParseInt(value, 10, 64); err == nil {
-				serviceInfo.MemoryUsage = formatMemory(memBytes)
-			} else {
-				serviceInfo.MemoryUsage = value
-			}
+	if pid, err := strconv.Atoi(props["MainPID"]); err == nil {
+		serviceInfo.PID = pid
+	}
+
+	if restarts, err := strconv.Atoi(props["NRestarts"]); err == nil {
+		serviceInfo.Restarts = restarts
+	}
 
-		case "ActiveEnterTimestamp":
-			activeEnterTime = value
-			// We'll calculate uptime later
+	if value, ok := props["MemoryCurrent"]; ok {
+		// Convert bytes to human readable (MB)
+		if memBytes, err := strconv.ParseInt(value, 10, 64); err == nil {
+			serviceInfo.MemoryUsage = formatMemory(memBytes)
+			serviceInfo.MemoryBytes = memBytes
+		} else {
+			serviceInfo.MemoryUsage = value
 		}
 	}
 
-	serviceInfo.Status = c.parseStatus(activeState, subState)
+	parseResourceProperties(serviceInfo, props)
 
+	serviceInfo.Status = parseStatus(serviceInfo.UnitType, serviceInfo.ActiveState, serviceInfo.SubState)
+	serviceInfo.Details = extractDetails(serviceInfo.UnitType, props)
+
+	activeEnterTime := props["ActiveEnterTimestamp"]
 	if activeEnterTime != "" && activeEnterTime != "0" {
-		uptime, err := calculateUptime(activeEnterTime)
+		uptime, err := calculateUptime(activeEnterTime, now)
 		if err == nil {
 			serviceInfo.Uptime = uptime
 		}
 	}
 
-	serviceInfo.CheckedAt = time.Now()
+	serviceInfo.CheckedAt = now
 
-	return serviceInfo, nil
+	return serviceInfo
 }
 
-func (c *Client) buildCommand(name string, args ...string) *exec.Cmd {
-	// If useSudo, prepend "sudo"
-	// Return exec.Command()
+// command returns the argv to execute, prefixed with sudo if requested.
+// systemctl calls get the options selecting the client's manager.
+func (c *Client) command(name string, args ...string) (string, []string) {
+	if flags := c.scope.systemctlFlags(); name == "systemctl" && flags != nil {
+		args = append(append([]string(nil), flags...), args...)
+	}
 	if c.useSudo {
-		allArgs := append([]string{name}, args...)
-		return exec.Command("sudo", allArgs...)
+		return "sudo", append([]string{name}, args...)
 	}
-	return exec.Command(name, args...)
+	return name, args
 }
 
-func (c *Client) parseStatus(activeState, subState string) models.ServiceStatus {
+// run executes a command to completion through the executor
+func (c *Client) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	name, args = c.command(name, args...)
+	return c.executor.Output(ctx, name, args...)
+}
+
+// runStdout executes a command to completion and returns its stdout
+// only, for output that is parsed line by line such as "journalctl -o
+// json", where a hint on stderr would read as a bad line
+func (c *Client) runStdout(ctx context.Context, name string, args ...string) ([]byte, error) {
+	stdout, wait, err := c.stream(ctx, name, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	output, readErr := io.ReadAll(stdout)
+	stdout.Close()
+	if err := wait(); err != nil {
+		return output, err
+	}
+	return output, readErr
+}
+
+// stream starts a long-running command through the executor
+func (c *Client) stream(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+	name, args = c.command(name, args...)
+	return c.executor.Stream(ctx, name, args...)
+}
+
+func parseStatus(unitType models.UnitType, activeState, subState string) models.ServiceStatus {
 	// Convert systemd states to our ServiceStatus
 	if activeState == "active" && subState == "running" {
 		return models.StatusRunning
 	}
 
+	// Only services have a "running" sub state; timers are "waiting",
+	// sockets "listening", mounts "mounted" and so on
+	if unitType != models.UnitService && activeState == "active" {
+		return models.StatusRunning
+	}
+
 	if activeState == "failed" {
 		return models.StatusFailed
 	}
@@ -219,251 +459,40 @@ func formatMemory(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGT"[exp])
 }
 
-// calculateUptime calculates uptime from timestamp string
-func calculateUptime(timestamp string) (time.Duration, error) {
-	// ActiveEnterTimestamp format: "Mon 2024-01-15 10:30:45 WIB"
-	// Try multiple time formats systemd might use
-	formats := []string{
-		"Mon 2006-01-02 15:04:05 MST",
-		"Mon 2006-01-02 15:04:05 -0700",
-		time.RFC3339,
-		time.RFC1123,
-	}
-
-	var startTime time.Time
-	var parseErr error
-
-	for _, format := range formats {
-		startTime, parseErr = time.Parse(format, timestamp)
-		if parseErr == nil {
-			break
-		}
-	}
-
-	// If all formats failed, try Unix timestamp (microseconds)
-	if parseErr != nil {
-		if usec, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
-			startTime = time.Unix(usec/1000000, 0)
-			parseErr = nil
-		}
-	}
-
-	if parseErr != nil {
-		return 0, fmt.Errorf("failed to parse timestamp %s: %w", timestamp, parseErr)
+// calculateUptime calculates uptime from timestamp string relative to now
+func calculateUptime(timestamp string, now time.Time) (time.Duration, error) {
+	startTime, err := parseTimestamp(timestamp)
+	if err != nil {
+		return 0, err
 	}
 
 	// Calculate duration from start time until now
-	uptime := time.Since(startTime)
+	uptime := now.Sub(startTime)
 
 	return uptime, nil
 }
 
-// GetServiceLogs retrieves logs from systemd journal
-func (c *Client) GetServiceLogs(serviceName string, opts *models.LogOptions) ([]*models.LogEntry, error) {
-	// Add .service suffix if not present
-	if !strings.HasSuffix(serviceName, ".service") {
-		serviceName = serviceName + ".service"
-	}
-
-	// Build journalctl command
-	args := []string{"journalctl", "-u", serviceName, "--no-pager"}
-
-	// Add options
-	if opts != nil {
-		if opts.Lines > 0 {
-			args = append(args, "-n", fmt.Sprintf("%d", opts.Lines))
-		}
-		if opts.Since != "" {
-			args = append(args, "--since", opts.Since)
-		}
-		if opts.Until != "" {
-			args = append(args, "--until", opts.Until)
-		}
-		if opts.Priority != "" {
-			args = append(args, "-p", opts.Priority)
-		}
-	}
-
-	// Build command
-	cmd := c.buildCommand(args[0], args[1:]...)
-
-	// Execute
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get logs for %s: %w", serviceName, err)
-	}
-
-	// Parse output
-	entries := parseJournalOutput(string(output), serviceName)
-
-	// Apply grep filter if specified
-	if opts != nil && opts.Grep != "" {
-		entries = filterLogs(entries, opts.Grep)
-	}
-
-	return entries, nil
-}
-
-// GetServiceLogsStream returns a channel for following logs in real-time
-func (c *Client) GetServiceLogsStream(serviceName string, opts *models.LogOptions) (<-chan *models.LogEntry, <-chan error, error) {
-	// Add .service suffix if not present
-	if !strings.HasSuffix(serviceName, ".service") {
-		serviceName = serviceName + ".service"
-	}
-
-	// Build journalctl command with -f (follow)
-	args := []string{"journalctl", "-u", serviceName, "-f", "--no-pager"}
-
-	// Add options
-	if opts != nil {
-		if opts.Lines > 0 {
-			args = append(args, "-n", fmt.Sprintf("%d", opts.Lines))
-		}
-		if opts.Since != "" {
-			args = append(args, "--since", opts.Since)
-		}
-		if opts.Priority != "" {
-			args = append(args, "-p", opts.Priority)
-		}
-	}
-
-	// Build command
-	cmd := c.buildCommand(args[0], args[1:]...)
-
-	// Get stdout pipe
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get stdout pipe: %w", err)
-	}
-
-	// Start command
-	if err := cmd.Start(); err != nil {
-		return nil, nil, fmt.Errorf("failed to start journalctl: %w", err)
-	}
-
-	// Create channels
-	logChan := make(chan *models.LogEntry, 100)
-	errChan := make(chan error, 1)
-
-	// Start goroutine to read logs
-	go func() {
-		defer close(logChan)
-		defer close(errChan)
-		defer cmd.Wait()
-
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			line := scanner.Text()
-			entry := parseJournalLine(line, serviceName)
-
-			// Apply grep filter if specified
-			if opts != nil && opts.Grep != "" {
-				if !strings.Contains(strings.ToLower(entry.Message), strings.ToLower(opts.Grep)) {
-					continue
-				}
-			}
-
-			logChan <- entry
-		}
-
-		if err := scanner.Err(); err != nil {
-			errChan <- err
-		}
-	}()
-
-	return logChan, errChan, nil
-}
-
-// parseJournalOutput parses journalctl output into LogEntry structs
-func parseJournalOutput(output string, serviceName string) []*models.LogEntry {
-	var entries []*models.LogEntry
-	lines := strings.Split(output, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		entry := parseJournalLine(line, serviceName)
-		entries = append(entries, entry)
-	}
-
-	return entries
-}
-
-// parseJournalLine parses a single journalctl line
-func parseJournalLine(line string, serviceName string) *models.LogEntry {
-	// Journal format examples:
-	// Dec 22 19:32:42 msi clash[1397]: time="2025-12-22T19:32:42.832776698+08:00" level=info msg="Start initial configuration"
-	// Dec 22 19:32:42 msi systemd[1]: Started clash.service - Clash daemon.
-
-	entry := models.NewLogEntry(serviceName, line)
-
-	// Try to extract timestamp from beginning (Dec 22 19:32:42)
-	parts := strings.Fields(line)
-	if len(parts) >= 3 {
-		// Try to parse first 3 fields as date
-		dateStr := strings.Join(parts[0:3], " ")
-		// Add current year
-		year := time.Now().Year()
-		fullDateStr := fmt.Sprintf("%s %d", dateStr, year)
-
-		if t, err := time.Parse("Jan 2 15:04:05 2006", fullDateStr); err == nil {
-			entry.Timestamp = t
-		}
-	}
-
-	// Try to find the actual message after the host/process info
-	// Format: Dec 22 19:32:42 msi clash[1397]: MESSAGE_HERE
-	colonIndex := strings.Index(line, "]: ")
-	if colonIndex > 0 && colonIndex+3 < len(line) {
-		entry.Message = line[colonIndex+3:]
-	} else {
-		// Try finding just ": " separator
-		colonIndex = strings.Index(line, ": ")
-		if colonIndex > 0 && colonIndex+2 < len(line) {
-			entry.Message = line[colonIndex+2:]
-		}
-	}
-
-	// Extract log level from message if present
-	// Format 1: level=info msg="..."
-	if strings.Contains(entry.Message, "level=") {
-		levelStart := strings.Index(entry.Message, "level=")
-		if levelStart != -1 {
-			remaining := entry.Message[levelStart+6:]
-			spaceIdx := strings.IndexAny(remaining, " \"")
-			if spaceIdx > 0 {
-				level := remaining[:spaceIdx]
-				entry.Level = strings.ToUpper(level)
-			}
-		}
+// parseTimestamp parses a timestamp property as systemctl show prints it,
+// e.g. "Mon 2024-01-15 10:30:45 WIB", or as microseconds since the epoch
+func parseTimestamp(timestamp string) (time.Time, error) {
+	formats := []string{
+		"Mon 2006-01-02 15:04:05 MST",
+		"Mon 2006-01-02 15:04:05 -0700",
+		time.RFC3339,
+		time.RFC1123,
 	}
 
-	// Format 2: [INFO] message or INFO: message
-	msgUpper := strings.ToUpper(entry.Message)
-	levels := []string{"ERROR", "WARN", "WARNING", "INFO", "DEBUG", "CRITICAL", "FATAL"}
-	for _, level := range levels {
-		if strings.HasPrefix(msgUpper, "["+level+"]") || strings.HasPrefix(msgUpper, level+":") {
-			entry.Level = level
-			break
+	var parseErr error
+	for _, format := range formats {
+		t, err := time.Parse(format, timestamp)
+		if err == nil {
+			return t, nil
 		}
+		parseErr = err
 	}
 
-	return entry
-}
-
-// filterLogs filters log entries by pattern
-func filterLogs(entries []*models.LogEntry, pattern string) []*models.LogEntry {
-	var filtered []*models.LogEntry
-	pattern = strings.ToLower(pattern)
-
-	for _, entry := range entries {
-		if strings.Contains(strings.ToLower(entry.Message), pattern) {
-			filtered = append(filtered, entry)
-		}
+	if usec, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+		return time.Unix(usec/1000000, 0), nil
 	}
-
-	return filtered
+	return time.Time{}, fmt.Errorf("failed to parse timestamp %s: %w", timestamp, parseErr)
 }
@@ -0,0 +1,195 @@
+package systemd
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// fixtureNow is the clock of the synthetic fixtures: two hours after
+// nginx.service became active
+var fixtureNow = time.Date(2024, 1, 15, 12, 30, 45, 0, time.UTC)
+
+// newFixtureClient replays the fixtures in testdata/<name>
+func newFixtureClient(t *testing.T, name string) *Client {
+	t.Helper()
+	client := NewClient(false)
+	client.SetExecutor(NewFixtureExecutor("testdata/" + name))
+	client.SetClock(func() time.Time { return fixtureNow })
+	client.SetCgroupReader(nil)
+	return client
+}
+
+func TestListServicesFixture(t *testing.T) {
+	client := newFixtureClient(t, "synthetic")
+
+	list, err := client.ListServices(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 8 || list.Running != 4 || list.Failed != 1 || list.Stopped != 2 {
+		t.Errorf("total %d, running %d, failed %d, stopped %d; want 8, 4, 1, 2",
+			list.Total, list.Running, list.Failed, list.Stopped)
+	}
+
+	tests := []struct {
+		name             string
+		activeState, sub string
+		status           models.ServiceStatus
+	}{
+		{name: "cron.service", activeState: "active", sub: "running", status: models.StatusRunning},
+		{name: "db.service", activeState: "failed", sub: "failed", status: models.StatusFailed},
+		{name: "getty@tty1.service", activeState: "active", sub: "running", status: models.StatusRunning},
+		{name: "networking.service", activeState: "active", sub: "exited", status: models.StatusUnknown},
+		{name: "systemd-fsck-root.service", activeState: "inactive", sub: "dead", status: models.StatusStopped},
+		{name: "ufw.service", activeState: "inactive", sub: "dead", status: models.StatusStopped},
+	}
+
+	byName := make(map[string]*models.ServiceInfo)
+	for _, service := range list.Services {
+		byName[service.Name] = service
+	}
+	for _, tt := range tests {
+		service, ok := byName[tt.name]
+		if !ok {
+			t.Errorf("%s missing from the list", tt.name)
+			continue
+		}
+		if service.ActiveState != tt.activeState || service.SubState != tt.sub || service.Status != tt.status {
+			t.Errorf("%s = %s/%s (%s), want %s/%s (%s)", tt.name,
+				service.ActiveState, service.SubState, service.Status, tt.activeState, tt.sub, tt.status)
+		}
+	}
+}
+
+func TestListUnitsTimerDetailsFixture(t *testing.T) {
+	client := newFixtureClient(t, "synthetic")
+
+	list, err := client.ListUnits(context.Background(), models.UnitTimer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]map[string]string{
+		"apt-daily.timer": {
+			"Triggers":    "apt-daily.service",
+			"NextElapse":  "Tue 2024-01-16 06:12:31 UTC",
+			"LastTrigger": "Mon 2024-01-15 06:40:02 UTC",
+		},
+		// n/a and empty values are left out
+		"backup.timer":    {"Triggers": "backup.service", "NextElapse": "Tue 2024-01-16 02:00:00 UTC"},
+		"logrotate.timer": {"Triggers": "logrotate.service", "LastTrigger": "Mon 2024-01-15 00:00:01 UTC"},
+	}
+
+	if len(list.Services) != len(want) {
+		t.Fatalf("got %d timers, want %d", len(list.Services), len(want))
+	}
+	for _, timer := range list.Services {
+		if !reflect.DeepEqual(timer.Details, want[timer.Name]) {
+			t.Errorf("%s details = %v, want %v", timer.Name, timer.Details, want[timer.Name])
+		}
+	}
+	if list.Running != 2 || list.Failed != 1 {
+		t.Errorf("running %d, failed %d; want 2, 1", list.Running, list.Failed)
+	}
+}
+
+func TestGetServiceStatusFixture(t *testing.T) {
+	client := newFixtureClient(t, "synthetic")
+
+	tests := []struct {
+		unit        string
+		status      models.ServiceStatus
+		pid         int
+		restarts    int
+		memoryBytes int64
+		memory      string
+		cpu         time.Duration
+		uptime      time.Duration
+	}{
+		{
+			unit:        "nginx",
+			status:      models.StatusRunning,
+			pid:         1397,
+			restarts:    3,
+			memoryBytes: 15728640,
+			memory:      "15.0 MB",
+			cpu:         2500 * time.Millisecond,
+			uptime:      2 * time.Hour,
+		},
+		{
+			// [not set] memory and an empty ActiveEnterTimestamp
+			unit:   "db.service",
+			status: models.StatusFailed,
+			memory: "[not set]",
+		},
+	}
+
+	for _, tt := range tests {
+		service, err := client.GetServiceStatus(context.Background(), tt.unit)
+		if err != nil {
+			t.Errorf("%s: %v", tt.unit, err)
+			continue
+		}
+		if service.Status != tt.status || service.PID != tt.pid || service.Restarts != tt.restarts {
+			t.Errorf("%s: status %s, PID %d, restarts %d; want %s, %d, %d", tt.unit,
+				service.Status, service.PID, service.Restarts, tt.status, tt.pid, tt.restarts)
+		}
+		if service.MemoryBytes != tt.memoryBytes || service.MemoryUsage != tt.memory {
+			t.Errorf("%s: memory %d (%q), want %d (%q)", tt.unit,
+				service.MemoryBytes, service.MemoryUsage, tt.memoryBytes, tt.memory)
+		}
+		if service.CPUUsage != tt.cpu || service.Uptime != tt.uptime {
+			t.Errorf("%s: CPU %s, uptime %s; want %s, %s", tt.unit, service.CPUUsage, service.Uptime, tt.cpu, tt.uptime)
+		}
+		if !service.CheckedAt.Equal(fixtureNow) {
+			t.Errorf("%s: checked at %s, want the fixture clock", tt.unit, service.CheckedAt)
+		}
+	}
+
+	// No fixture, no guessing
+	if _, err := client.GetServiceStatus(context.Background(), "postfix"); err == nil {
+		t.Error("postfix: got a status without a fixture")
+	}
+}
+
+func TestCalculateUptime(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		timestamp string
+		want      time.Duration
+		wantErr   bool
+	}{
+		{timestamp: "Mon 2024-01-15 10:30:45 UTC", want: 2 * time.Hour},
+		{timestamp: "Mon 2024-01-15 17:30:45 +0700", want: 2 * time.Hour},
+		{timestamp: "2024-01-15T12:00:45Z", want: 30 * time.Minute},
+		{timestamp: "Mon, 15 Jan 2024 12:30:00 UTC", want: 45 * time.Second},
+		// Microseconds since the epoch, as D-Bus reports timestamps
+		{timestamp: "1705318245000000", want: time.Hour},
+		{timestamp: "1705318245999999", want: time.Hour},
+		{timestamp: "", wantErr: true},
+		{timestamp: "n/a", wantErr: true},
+		{timestamp: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := calculateUptime(tt.timestamp, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("calculateUptime(%q) = %s, want an error", tt.timestamp, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("calculateUptime(%q): %v", tt.timestamp, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("calculateUptime(%q) = %s, want %s", tt.timestamp, got, tt.want)
+		}
+	}
+}
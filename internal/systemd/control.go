@@ -0,0 +1,210 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// ControlAction is a systemctl verb that changes the state of a unit
+type ControlAction string
+
+const (
+	ActionStart   ControlAction = "start"
+	ActionStop    ControlAction = "stop"
+	ActionRestart ControlAction = "restart"
+	ActionReload  ControlAction = "reload"
+	ActionEnable  ControlAction = "enable"
+	ActionDisable ControlAction = "disable"
+	ActionMask    ControlAction = "mask"
+	ActionUnmask  ControlAction = "unmask"
+)
+
+// ControlActions lists every supported action
+var ControlActions = []ControlAction{
+	ActionStart, ActionStop, ActionRestart, ActionReload,
+	ActionEnable, ActionDisable, ActionMask, ActionUnmask,
+}
+
+// ParseControlAction converts a command name into a ControlAction
+func ParseControlAction(s string) (ControlAction, error) {
+	for _, action := range ControlActions {
+		if s == string(action) {
+			return action, nil
+		}
+	}
+	return "", fmt.Errorf("unknown action: %s", s)
+}
+
+// queuesJob reports whether the action queues a job that has to finish
+// (enable/disable/mask/unmask only touch unit files)
+func (a ControlAction) queuesJob() bool {
+	switch a {
+	case ActionStart, ActionStop, ActionRestart, ActionReload:
+		return true
+	}
+	return false
+}
+
+// jobPollInterval is how often pending jobs are checked while waiting
+const jobPollInterval = 200 * time.Millisecond
+
+// StartUnit starts a unit and waits for the job to finish
+func (c *Client) StartUnit(ctx context.Context, unitName string, timeout time.Duration) (*models.ServiceInfo, error) {
+	return c.ControlUnit(ctx, ActionStart, unitName, timeout)
+}
+
+// StopUnit stops a unit and waits for the job to finish
+func (c *Client) StopUnit(ctx context.Context, unitName string, timeout time.Duration) (*models.ServiceInfo, error) {
+	return c.ControlUnit(ctx, ActionStop, unitName, timeout)
+}
+
+// RestartUnit restarts a unit and waits for the job to finish
+func (c *Client) RestartUnit(ctx context.Context, unitName string, timeout time.Duration) (*models.ServiceInfo, error) {
+	return c.ControlUnit(ctx, ActionRestart, unitName, timeout)
+}
+
+// ReloadUnit reloads a unit's configuration and waits for the job to finish
+func (c *Client) ReloadUnit(ctx context.Context, unitName string, timeout time.Duration) (*models.ServiceInfo, error) {
+	return c.ControlUnit(ctx, ActionReload, unitName, timeout)
+}
+
+// EnableUnit enables a unit
+func (c *Client) EnableUnit(ctx context.Context, unitName string, timeout time.Duration) (*models.ServiceInfo, error) {
+	return c.ControlUnit(ctx, ActionEnable, unitName, timeout)
+}
+
+// DisableUnit disables a unit
+func (c *Client) DisableUnit(ctx context.Context, unitName string, timeout time.Duration) (*models.ServiceInfo, error) {
+	return c.ControlUnit(ctx, ActionDisable, unitName, timeout)
+}
+
+// MaskUnit masks a unit so it cannot be started
+func (c *Client) MaskUnit(ctx context.Context, unitName string, timeout time.Duration) (*models.ServiceInfo, error) {
+	return c.ControlUnit(ctx, ActionMask, unitName, timeout)
+}
+
+// UnmaskUnit unmasks a unit
+func (c *Client) UnmaskUnit(ctx context.Context, unitName string, timeout time.Duration) (*models.ServiceInfo, error) {
+	return c.ControlUnit(ctx, ActionUnmask, unitName, timeout)
+}
+
+// ResetFailed clears a unit's failed state and its start rate limit
+// counter, so a following start is not refused
+func (c *Client) ResetFailed(ctx context.Context, unitName string) error {
+	unitName = models.NormalizeUnitName(unitName, models.UnitService)
+
+	output, err := c.run(ctx, "systemctl", "reset-failed", unitName)
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("failed to reset-failed %s: %w (%s)", unitName, err, msg)
+		}
+		return fmt.Errorf("failed to reset-failed %s: %w", unitName, err)
+	}
+	return nil
+}
+
+// ControlUnit runs a systemctl action on a unit, waits up to timeout for
+// the queued job to complete and returns the resulting unit status.
+// Actions always go through systemctl so --sudo applies to them.
+func (c *Client) ControlUnit(ctx context.Context, action ControlAction, unitName string, timeout time.Duration) (*models.ServiceInfo, error) {
+	unitName = models.NormalizeUnitName(unitName, models.UnitService)
+	if strings.HasPrefix(unitName, "-") {
+		// systemctl would take it as an option
+		return nil, fmt.Errorf("invalid unit name: %s", unitName)
+	}
+
+	// 1. Queue the job without blocking so we control how long we wait
+	args := []string{string(action)}
+	if action.queuesJob() {
+		args = append(args, "--no-block")
+	}
+	args = append(args, unitName)
+
+	output, err := c.run(ctx, "systemctl", args...)
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return nil, fmt.Errorf("failed to %s %s: %w (%s)", action, unitName, err, msg)
+		}
+		return nil, fmt.Errorf("failed to %s %s: %w", action, unitName, err)
+	}
+
+	// 2. Wait for the job to leave the queue
+	if action.queuesJob() {
+		if err := c.waitForJobs(ctx, unitName, timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. Read back the resulting state
+	serviceInfo, err := c.GetServiceStatus(ctx, unitName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Check the unit ended up where the action should have put it
+	if err := checkActionResult(action, serviceInfo); err != nil {
+		return serviceInfo, err
+	}
+
+	return serviceInfo, nil
+}
+
+// waitForJobs polls "systemctl list-jobs" until no job for the unit remains
+func (c *Client) waitForJobs(ctx context.Context, unitName string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		output, err := c.run(ctx, "systemctl", "list-jobs", "--plain", "--no-legend", "--no-pager", unitName)
+		if err != nil {
+			return fmt.Errorf("failed to list jobs for %s: %w", unitName, err)
+		}
+		if !hasPendingJob(string(output), unitName) {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for job on %s", timeout, unitName)
+		}
+
+		select {
+		case <-time.After(jobPollInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for job on %s: %w", unitName, ctx.Err())
+		}
+	}
+}
+
+// hasPendingJob reports whether list-jobs output still contains a job
+// for the unit ("JOB UNIT TYPE STATE" columns)
+func hasPendingJob(output string, unitName string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[1] == unitName {
+			return true
+		}
+	}
+	return false
+}
+
+// checkActionResult verifies the state a unit reached after an action
+func checkActionResult(action ControlAction, serviceInfo *models.ServiceInfo) error {
+	switch action {
+	case ActionStart, ActionRestart, ActionReload:
+		if serviceInfo.ActiveState != "active" {
+			return fmt.Errorf("%s %s: unit is %s (%s) instead of active", action, serviceInfo.Name, serviceInfo.ActiveState, serviceInfo.SubState)
+		}
+	case ActionStop:
+		if serviceInfo.ActiveState != "inactive" && serviceInfo.ActiveState != "failed" {
+			return fmt.Errorf("%s %s: unit is still %s", action, serviceInfo.Name, serviceInfo.ActiveState)
+		}
+	case ActionMask:
+		if serviceInfo.UnitFileState != "" && !strings.HasPrefix(serviceInfo.UnitFileState, "masked") {
+			return fmt.Errorf("%s %s: unit file is %s", action, serviceInfo.Name, serviceInfo.UnitFileState)
+		}
+	}
+	return nil
+}
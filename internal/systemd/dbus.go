@@ -0,0 +1,179 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/andinianst93/systemd-monitoring/internal/dbus"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// D-Bus names of the systemd manager
+const (
+	systemdDest       = "org.freedesktop.systemd1"
+	systemdPath       = dbus.ObjectPath("/org/freedesktop/systemd1")
+	managerInterface  = "org.freedesktop.systemd1.Manager"
+	unitInterface     = "org.freedesktop.systemd1.Unit"
+	propertiesIface   = "org.freedesktop.DBus.Properties"
+	errNoSuchUnitName = "org.freedesktop.systemd1.NoSuchUnit"
+)
+
+// listUnitsDBus lists units of one type via Manager.ListUnits
+func (c *Client) listUnitsDBus(ctx context.Context, unitType models.UnitType) (*models.ServiceList, error) {
+	reply, err := c.bus.CallContext(ctx, systemdDest, systemdPath, managerInterface, "ListUnits")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list units: %w", err)
+	}
+	if len(reply) == 0 {
+		return nil, fmt.Errorf("failed to list units: empty reply")
+	}
+
+	units, ok := reply[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to list units: unexpected reply %T", reply[0])
+	}
+
+	serviceList := models.NewServiceList()
+
+	// Each unit is a struct (ssssssouso):
+	// name, description, load state, active state, sub state, following,
+	// unit path, job id, job type, job path
+	for _, u := range units {
+		fields, ok := u.([]interface{})
+		if !ok || len(fields) < 5 {
+			continue
+		}
+
+		name, _ := fields[0].(string)
+		if unitType != models.UnitAll && models.UnitTypeOf(name) != unitType {
+			continue
+		}
+		activeState, _ := fields[3].(string)
+		subState, _ := fields[4].(string)
+
+		serviceInfo := models.NewServiceInfo(name)
+		serviceInfo.ActiveState = activeState
+		serviceInfo.SubState = subState
+		serviceInfo.Status = parseStatus(serviceInfo.UnitType, activeState, subState)
+
+		// ListUnits has no type-specific columns; read them per unit
+		if len(unitDetails[serviceInfo.UnitType]) > 0 {
+			props, err := c.unitPropertiesDBus(ctx, name, dbusInterface(serviceInfo.UnitType))
+			if err == nil {
+				serviceInfo.Details = extractDetails(serviceInfo.UnitType, props)
+			}
+		}
+
+		serviceList.AddService(serviceInfo)
+	}
+
+	return serviceList, nil
+}
+
+// getServiceStatusDBus reads the generic and type-specific properties of
+// one unit
+func (c *Client) getServiceStatusDBus(ctx context.Context, serviceName string) (*models.ServiceInfo, error) {
+	unitType := models.UnitTypeOf(serviceName)
+	props, err := c.unitPropertiesDBus(ctx, serviceName, unitInterface, dbusInterface(unitType))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service status for %s: %w", serviceName, err)
+	}
+
+	serviceInfo := newServiceInfoFromProperties(serviceName, props, c.now())
+	c.addResources(serviceInfo)
+
+	return serviceInfo, nil
+}
+
+// unitPropertiesDBus fetches all properties of the given interfaces on a
+// unit, formatted the same way systemctl show prints them
+func (c *Client) unitPropertiesDBus(ctx context.Context, unitName string, interfaces ...string) (map[string]string, error) {
+	path, err := c.unitPath(ctx, unitName)
+	if err != nil {
+		return nil, err
+	}
+
+	props := make(map[string]string)
+	for _, iface := range interfaces {
+		reply, err := c.bus.CallContext(ctx, systemdDest, path, propertiesIface, "GetAll", iface)
+		if err != nil {
+			return nil, err
+		}
+		if len(reply) == 0 {
+			continue
+		}
+
+		values, ok := reply[0].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range values {
+			if v, ok := value.(dbus.Variant); ok {
+				props[key] = formatProperty(v.Value)
+			}
+		}
+	}
+
+	return props, nil
+}
+
+// unitPath resolves a unit name to its object path, loading the unit if
+// it is not currently loaded (systemctl show behaves the same way)
+func (c *Client) unitPath(ctx context.Context, unitName string) (dbus.ObjectPath, error) {
+	reply, err := c.bus.CallContext(ctx, systemdDest, systemdPath, managerInterface, "GetUnit", unitName)
+
+	var dbusErr *dbus.Error
+	if errors.As(err, &dbusErr) && dbusErr.Name == errNoSuchUnitName {
+		reply, err = c.bus.CallContext(ctx, systemdDest, systemdPath, managerInterface, "LoadUnit", unitName)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(reply) == 0 {
+		return "", fmt.Errorf("empty reply for unit %s", unitName)
+	}
+
+	path, ok := reply[0].(dbus.ObjectPath)
+	if !ok {
+		return "", fmt.Errorf("unexpected unit path %T", reply[0])
+	}
+	return path, nil
+}
+
+// formatProperty renders a D-Bus property value like systemctl show does
+func formatProperty(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case dbus.ObjectPath:
+		return string(v)
+	case bool:
+		if v {
+			return "yes"
+		}
+		return "no"
+	case uint64:
+		// systemd uses UINT64_MAX for "not set" counters
+		if v == math.MaxUint64 {
+			return "[not set]"
+		}
+		return strconv.FormatUint(v, 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, formatProperty(item))
+		}
+		return strings.Join(parts, " ")
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,174 @@
+package systemd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andinianst93/systemd-monitoring/internal/dbus"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// busConfig configures a private bus with the allow-all policy of the
+// stock session bus, so the test can own systemd's name
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startPrivateBus runs a dbus-daemon for the test and points
+// DBUS_SYSTEM_BUS_ADDRESS at it
+func startPrivateBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(config, []byte(fmt.Sprintf(busConfig, filepath.Join(dir, "bus"))), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+config, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("dbus-daemon did not start: %v", err)
+	}
+	address = strings.TrimSpace(address)
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", address)
+	return address
+}
+
+// stubUnit is a unit served by the stub manager
+type stubUnit struct {
+	name, activeState, subState string
+	mainPID                     uint32
+}
+
+// startStubManager claims systemd's bus name and serves ListUnits,
+// GetUnit, LoadUnit and the unit properties of units
+func startStubManager(t *testing.T, address string, units []stubUnit) {
+	t.Helper()
+	conn, err := dbus.Dial(address)
+	if err != nil {
+		t.Fatalf("stub manager: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	paths := make(map[string]dbus.ObjectPath)
+	for _, unit := range units {
+		path := dbus.ObjectPath("/org/freedesktop/systemd1/unit/" + strings.ReplaceAll(unit.name, ".", "_2e"))
+		paths[unit.name] = path
+
+		unit := unit
+		conn.Export(path, propertiesIface, func(method string, args []interface{}) (dbus.Signature, []interface{}, error) {
+			props := map[string]dbus.Variant{}
+			switch args[0] {
+			case unitInterface:
+				props["Id"] = dbus.MakeVariant(unit.name)
+				props["LoadState"] = dbus.MakeVariant("loaded")
+				props["ActiveState"] = dbus.MakeVariant(unit.activeState)
+				props["SubState"] = dbus.MakeVariant(unit.subState)
+			case "org.freedesktop.systemd1.Service":
+				props["MainPID"] = dbus.MakeVariant(unit.mainPID)
+				props["NRestarts"] = dbus.MakeVariant(uint32(2))
+			}
+			return "a{sv}", []interface{}{props}, nil
+		})
+	}
+
+	conn.Export(systemdPath, managerInterface, func(method string, args []interface{}) (dbus.Signature, []interface{}, error) {
+		switch method {
+		case "ListUnits":
+			var list []interface{}
+			for _, unit := range units {
+				list = append(list, []interface{}{
+					unit.name, "", "loaded", unit.activeState, unit.subState, "",
+					paths[unit.name], uint32(0), "", dbus.ObjectPath("/"),
+				})
+			}
+			return "a(ssssssouso)", []interface{}{list}, nil
+		case "GetUnit", "LoadUnit":
+			if path, ok := paths[args[0].(string)]; ok {
+				return "o", []interface{}{path}, nil
+			}
+			return "", nil, &dbus.Error{Name: errNoSuchUnitName, Message: "Unit " + args[0].(string) + " not loaded."}
+		}
+		return "", nil, &dbus.Error{Name: "org.freedesktop.DBus.Error.UnknownMethod", Message: method}
+	})
+
+	if err := conn.RequestName(systemdDest); err != nil {
+		t.Fatalf("stub manager: %v", err)
+	}
+}
+
+func TestDBusBackend(t *testing.T) {
+	address := startPrivateBus(t)
+	startStubManager(t, address, []stubUnit{
+		{name: "nginx.service", activeState: "active", subState: "running", mainPID: 1234},
+		{name: "db.service", activeState: "failed", subState: "failed"},
+		{name: "backup.timer", activeState: "active", subState: "waiting"},
+	})
+
+	client, err := NewClientWithBackend(false, BackendDBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if client.Backend() != BackendDBus {
+		t.Fatalf("Backend() = %s, want dbus", client.Backend())
+	}
+	ctx := context.Background()
+
+	// ListUnits keeps the services only
+	list, err := client.ListServices(ctx)
+	if err != nil {
+		t.Fatalf("ListServices: %v", err)
+	}
+	if list.Total != 2 || list.Running != 1 || list.Failed != 1 {
+		t.Errorf("ListServices: total %d, running %d, failed %d; want 2, 1, 1", list.Total, list.Running, list.Failed)
+	}
+
+	// Properties of one unit
+	service, err := client.GetServiceStatus(ctx, "nginx")
+	if err != nil {
+		t.Fatalf("GetServiceStatus: %v", err)
+	}
+	if service.Status != models.StatusRunning || service.PID != 1234 || service.Restarts != 2 {
+		t.Errorf("GetServiceStatus(nginx) = status %s, PID %d, restarts %d; want running, 1234, 2",
+			service.Status, service.PID, service.Restarts)
+	}
+
+	// Unknown units are an error, not a crash
+	if _, err := client.GetServiceStatus(ctx, "missing"); err == nil {
+		t.Error("GetServiceStatus(missing) succeeded, want an error")
+	}
+}
@@ -0,0 +1,156 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// DependencyOptions selects the part of a dependency graph to build
+type DependencyOptions struct {
+	Kinds   []models.DependencyKind // nil = every kind
+	Reverse bool                    // follow the units depending on the root instead
+	Depth   int                     // levels followed from the root; 0 = all
+}
+
+// dependencyStateProperties are the properties read for every node
+var dependencyStateProperties = []string{"Id", "LoadState", "ActiveState", "SubState", "UnitFileState"}
+
+// GetDependencyGraph builds the dependency graph of a unit from its
+// Requires=, Wants=, ... properties, or with opts.Reverse from RequiredBy=,
+// WantedBy=, ... The exec backend reads each level of the graph with a
+// single systemctl show call.
+func (c *Client) GetDependencyGraph(ctx context.Context, unitName string, opts DependencyOptions) (*models.DependencyGraph, error) {
+	unitName = models.NormalizeUnitName(unitName, models.UnitService)
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = models.DependencyKinds
+	}
+
+	// The property read for each kind, e.g. WantedBy for Wants in reverse
+	properties := make([]string, len(kinds))
+	for i, kind := range kinds {
+		properties[i] = string(kind)
+		if opts.Reverse {
+			properties[i] = kind.Reverse()
+		}
+	}
+	fields := append(append([]string(nil), dependencyStateProperties...), properties...)
+
+	graph := &models.DependencyGraph{
+		Root:    unitName,
+		Host:    c.host,
+		User:    c.scope.User,
+		Reverse: opts.Reverse,
+		Nodes:   []*models.DependencyNode{},
+		Edges:   []*models.DependencyEdge{},
+	}
+
+	// Walk the graph breadth first; every level is fetched at once
+	seen := map[string]bool{unitName: true}
+	edges := make(map[models.DependencyEdge]bool)
+	level := []string{unitName}
+	for depth := 0; len(level) > 0; depth++ {
+		byName, err := c.unitProperties(ctx, level, fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dependencies of %s: %w", unitName, err)
+		}
+
+		var next []string
+		for _, name := range level {
+			props := byName[name]
+			node := newDependencyNode(name, props, depth)
+			graph.Nodes = append(graph.Nodes, node)
+
+			for i, kind := range kinds {
+				others := strings.Fields(props[properties[i]])
+				if opts.Depth > 0 && depth >= opts.Depth {
+					node.Truncated = node.Truncated || len(others) > 0
+					continue
+				}
+
+				for _, other := range others {
+					edge := models.DependencyEdge{From: name, To: other, Kind: kind}
+					if opts.Reverse {
+						edge.From, edge.To = other, name
+					}
+					if !edges[edge] {
+						edges[edge] = true
+						graph.Edges = append(graph.Edges, &edge)
+					}
+					if !seen[other] {
+						seen[other] = true
+						next = append(next, other)
+					}
+				}
+			}
+		}
+		level = next
+	}
+
+	graph.MarkPropagation()
+	return graph, nil
+}
+
+// newDependencyNode builds a graph node from a unit's properties
+func newDependencyNode(name string, props map[string]string, depth int) *models.DependencyNode {
+	unitType := models.UnitTypeOf(name)
+	status := models.StatusUnknown
+	if props["ActiveState"] != "" {
+		status = parseStatus(unitType, props["ActiveState"], props["SubState"])
+	}
+
+	return &models.DependencyNode{
+		Name:          name,
+		Status:        status,
+		ActiveState:   props["ActiveState"],
+		SubState:      props["SubState"],
+		LoadState:     props["LoadState"],
+		UnitFileState: props["UnitFileState"],
+		Depth:         depth,
+		Failed:        status == models.StatusFailed,
+	}
+}
+
+// unitProperties reads the given properties of several units, by unit
+// name. Units systemd does not know are returned with LoadState=not-found
+// like systemctl show does.
+func (c *Client) unitProperties(ctx context.Context, names []string, properties []string) (map[string]map[string]string, error) {
+	byName := make(map[string]map[string]string, len(names))
+
+	if c.bus != nil {
+		var err error
+		for _, name := range names {
+			var props map[string]string
+			props, err = c.unitPropertiesDBus(ctx, name, unitInterface)
+			if err != nil {
+				break
+			}
+			byName[name] = props
+		}
+		if err == nil || !c.fallback {
+			return byName, err
+		}
+	}
+
+	// One block per unit, in argument order; Id may name the unit an
+	// alias points to, so blocks are matched by position
+	args := append([]string{"show", "-p", strings.Join(properties, ","), "--no-pager"}, names...)
+	output, err := c.run(ctx, "systemctl", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := strings.Split(strings.TrimSpace(string(output)), "\n\n")
+	for i, block := range blocks {
+		props := parseShowOutput(block)
+		if len(blocks) == len(names) {
+			byName[names[i]] = props
+		} else if id := props["Id"]; id != "" {
+			byName[id] = props
+		}
+	}
+	return byName, nil
+}
@@ -0,0 +1,88 @@
+package systemd
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Executor runs the external commands (systemctl, journalctl) Client needs.
+// Swapping it out lets Client be driven by recorded fixtures instead of a
+// real systemd. Commands are stopped when their context is done.
+type Executor interface {
+	// Output runs the command to completion and returns its combined output
+	Output(ctx context.Context, name string, args ...string) ([]byte, error)
+
+	// Stream starts the command and returns its stdout. Closing stdout
+	// stops the command. The returned wait function must be called once the
+	// caller is done reading.
+	Stream(ctx context.Context, name string, args ...string) (stdout io.ReadCloser, wait func() error, err error)
+}
+
+// killDelay is how long a cancelled command gets to exit after SIGTERM
+// before it is killed
+const killDelay = 2 * time.Second
+
+// ExecExecutor runs commands on the local machine via os/exec
+type ExecExecutor struct{}
+
+// NewExecExecutor creates the default executor
+func NewExecExecutor() *ExecExecutor {
+	return &ExecExecutor{}
+}
+
+// Output runs the command and returns its combined stdout and stderr
+func (e *ExecExecutor) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	output, err := command(ctx, name, args...).CombinedOutput()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return output, ctxErr
+	}
+	return output, err
+}
+
+// Stream starts the command with its stdout connected to a pipe
+func (e *ExecExecutor) Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+	cmd := command(ctx, name, args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	return &processReader{ReadCloser: stdout, process: cmd.Process}, cmd.Wait, nil
+}
+
+// command builds a command that is sent SIGTERM when ctx is done, which
+// sudo relays to systemctl (a SIGKILL would leave it running), and is
+// killed if it has not exited killDelay later
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = killDelay
+	return cmd
+}
+
+// processReader is a command's stdout whose Close also stops the command,
+// so followers such as "journalctl -f" do not outlive their reader
+type processReader struct {
+	io.ReadCloser
+	process *os.Process
+}
+
+func (r *processReader) Close() error {
+	// SIGTERM is relayed by sudo; fall back to a kill where signals are
+	// not supported
+	if err := r.process.Signal(syscall.SIGTERM); err != nil {
+		r.process.Kill()
+	}
+	return r.ReadCloser.Close()
+}
@@ -0,0 +1,101 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// failureProperties are the properties GetFailureDetails reads
+var failureProperties = []string{
+	"Id", "Result", "ExecMainCode", "ExecMainStatus", "ExecMainExitTimestamp",
+	"StateChangeTimestamp", "NRestarts", "ConditionResult", "ConditionTimestamp",
+	"AssertResult", "AssertTimestamp", "Conditions", "Asserts",
+}
+
+// execMainCodes names the ExecMainCode values (the CLD_* codes of
+// waitid(2)) systemctl show prints as numbers
+var execMainCodes = map[string]string{"1": "exited", "2": "killed", "3": "dumped"}
+
+// GetFailureDetails reads what systemd recorded about the last run of a
+// unit: its result, how the main process ended and which conditions
+// failed. It always uses systemctl, which flattens the condition lists
+// into one line per check.
+func (c *Client) GetFailureDetails(ctx context.Context, unitName string) (*models.FailureDetails, error) {
+	unitName = models.NormalizeUnitName(unitName, models.UnitService)
+
+	output, err := c.run(ctx, "systemctl", "show", "-p", strings.Join(failureProperties, ","), "--no-pager", unitName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failure details for %s: %w", unitName, err)
+	}
+	props := parseShowOutput(string(output))
+
+	details := &models.FailureDetails{
+		Result:       props["Result"],
+		ExecMainCode: execMainCodes[props["ExecMainCode"]],
+		// The timestamps are empty until the condition was first checked
+		ConditionFailed: props["ConditionResult"] == "no" && props["ConditionTimestamp"] != "",
+		AssertFailed:    props["AssertResult"] == "no" && props["AssertTimestamp"] != "",
+	}
+	if status, err := strconv.Atoi(props["ExecMainStatus"]); err == nil {
+		details.ExecMainStatus = status
+	}
+	if restarts, err := strconv.Atoi(props["NRestarts"]); err == nil {
+		details.Restarts = restarts
+	}
+	if t, err := parseTimestamp(props["ExecMainExitTimestamp"]); err == nil {
+		details.ExitedAt = t
+	}
+	if t, err := parseTimestamp(props["StateChangeTimestamp"]); err == nil {
+		details.ChangedAt = t
+	}
+
+	// Conditions= and Asserts= come as one line per check, e.g.
+	// "Conditions=ConditionPathExists=|!/etc/app.conf -1"
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		if key != "Conditions" && key != "Asserts" {
+			continue
+		}
+		if condition, ok := parseCondition(value); ok {
+			details.Conditions = append(details.Conditions, condition)
+		}
+	}
+
+	return details, nil
+}
+
+// parseCondition parses one check of the Conditions= or Asserts= property:
+// "<type>=[|][!]<parameter> <state>", where the state is positive when the
+// check passed, negative when it failed and 0 when it was not run
+func parseCondition(value string) (models.Condition, bool) {
+	conditionType, rest, ok := strings.Cut(value, "=")
+	space := strings.LastIndex(rest, " ")
+	if !ok || space < 0 {
+		return models.Condition{}, false
+	}
+	state, err := strconv.Atoi(rest[space+1:])
+	if err != nil {
+		return models.Condition{}, false
+	}
+
+	condition := models.Condition{Type: conditionType, Parameter: rest[:space], Result: "untested"}
+	if strings.HasPrefix(condition.Parameter, "|") {
+		condition.Trigger = true
+		condition.Parameter = condition.Parameter[1:]
+	}
+	if strings.HasPrefix(condition.Parameter, "!") {
+		condition.Negate = true
+		condition.Parameter = condition.Parameter[1:]
+	}
+	switch {
+	case state > 0:
+		condition.Result = "passed"
+	case state < 0:
+		condition.Result = "failed"
+	}
+	return condition, true
+}
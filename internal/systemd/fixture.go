@@ -0,0 +1,185 @@
+package systemd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Fixture files are named after the command line that produced them:
+//
+//	systemctl list-units --type=service --all --no-pager
+//	-> <dir>/systemctl_list-units_--type=service_--all_--no-pager.out
+//
+// A command that failed additionally gets a ".err" file holding the error
+// message, so failures replay as failures. Names that would exceed the file
+// name limit, e.g. a batched systemctl show of many units, keep their first
+// maxFixtureName bytes followed by a hash of the whole command line.
+const (
+	fixtureOutputExt = ".out"
+	fixtureErrorExt  = ".err"
+	maxFixtureName   = 200
+)
+
+// FixtureName returns the base file name (without extension) used to store
+// the output of a command. A leading "sudo" is ignored so fixtures recorded
+// with --sudo replay without it and vice versa.
+func FixtureName(name string, args ...string) string {
+	argv := append([]string{name}, args...)
+	if len(argv) > 1 && argv[0] == "sudo" {
+		argv = argv[1:]
+	}
+
+	parts := make([]string, 0, len(argv))
+	for _, arg := range argv {
+		parts = append(parts, sanitizeFixturePart(arg))
+	}
+
+	base := strings.Join(parts, "_")
+	if len(base) > maxFixtureName {
+		sum := sha256.Sum256([]byte(strings.Join(argv, "\x00")))
+		base = base[:maxFixtureName] + "_" + hex.EncodeToString(sum[:8])
+	}
+	return base
+}
+
+func sanitizeFixturePart(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '-', r == '.', r == '=', r == '@':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// FixtureExecutor replays command outputs from a directory instead of
+// running anything
+type FixtureExecutor struct {
+	dir string
+}
+
+// NewFixtureExecutor creates an executor serving fixtures from dir
+func NewFixtureExecutor(dir string) *FixtureExecutor {
+	return &FixtureExecutor{dir: dir}
+}
+
+// Output returns the recorded output of the command
+func (f *FixtureExecutor) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	base := filepath.Join(f.dir, FixtureName(name, args...))
+
+	output, err := os.ReadFile(base + fixtureOutputExt)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no fixture for %q in %s", strings.Join(append([]string{name}, args...), " "), f.dir)
+		}
+		return nil, err
+	}
+
+	// Replay recorded failures
+	if msg, err := os.ReadFile(base + fixtureErrorExt); err == nil {
+		return output, errors.New(strings.TrimSpace(string(msg)))
+	}
+
+	return output, nil
+}
+
+// Stream serves the recorded output as if the command had printed it and
+// exited
+func (f *FixtureExecutor) Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+	output, err := f.Output(ctx, name, args...)
+	if output == nil && err != nil {
+		return nil, nil, err
+	}
+
+	wait := func() error { return err }
+	return io.NopCloser(bytes.NewReader(output)), wait, nil
+}
+
+// RecordingExecutor runs commands through another executor and saves
+// every output into a fixture directory
+type RecordingExecutor struct {
+	inner Executor
+	dir   string
+}
+
+// NewRecordingExecutor records the outputs of inner into dir
+func NewRecordingExecutor(inner Executor, dir string) (*RecordingExecutor, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	return &RecordingExecutor{inner: inner, dir: dir}, nil
+}
+
+// Output runs the command and records what it returned
+func (r *RecordingExecutor) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	output, runErr := r.inner.Output(ctx, name, args...)
+
+	// A cancelled command did not produce a meaningful result
+	if ctx.Err() != nil {
+		return output, runErr
+	}
+
+	if err := r.save(FixtureName(name, args...), output, runErr); err != nil {
+		return output, fmt.Errorf("failed to record fixture: %w", err)
+	}
+
+	return output, runErr
+}
+
+// Stream runs the command and records everything read from it
+func (r *RecordingExecutor) Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+	stdout, wait, err := r.inner.Stream(ctx, name, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	base := FixtureName(name, args...)
+	var captured bytes.Buffer
+
+	recordingWait := func() error {
+		runErr := wait()
+		if err := r.save(base, captured.Bytes(), runErr); err != nil {
+			return fmt.Errorf("failed to record fixture: %w", err)
+		}
+		return runErr
+	}
+
+	return &teeReadCloser{Reader: io.TeeReader(stdout, &captured), Closer: stdout}, recordingWait, nil
+}
+
+func (r *RecordingExecutor) save(base string, output []byte, runErr error) error {
+	path := filepath.Join(r.dir, base)
+
+	if err := os.WriteFile(path+fixtureOutputExt, output, 0o644); err != nil {
+		return err
+	}
+
+	if runErr != nil {
+		return os.WriteFile(path+fixtureErrorExt, []byte(runErr.Error()+"\n"), 0o644)
+	}
+
+	// A successful re-recording replaces an earlier failure
+	if err := os.Remove(path + fixtureErrorExt); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
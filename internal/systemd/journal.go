@@ -0,0 +1,352 @@
+package systemd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// GetServiceLogs retrieves logs from systemd journal
+func (c *Client) GetServiceLogs(ctx context.Context, serviceName string, opts *models.LogOptions) ([]*models.LogEntry, error) {
+	// Add .service suffix unless the name has an explicit unit suffix
+	serviceName = models.NormalizeUnitName(serviceName, models.UnitService)
+
+	// Build journalctl command
+	matches, err := c.unitMatches(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	args := journalArgs(matches, opts, false)
+
+	// Execute
+	output, err := c.runStdout(ctx, args[0], args[1:]...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs for %s: %w", serviceName, err)
+	}
+
+	// Parse output
+	entries, err := parseJournalOutput(string(output), serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse logs for %s: %w", serviceName, err)
+	}
+	c.stampLogs(entries...)
+
+	// Apply grep filter if specified
+	if opts != nil && opts.Grep != "" {
+		entries = filterLogs(entries, opts.Grep)
+	}
+
+	return entries, nil
+}
+
+// GetManagerLogs retrieves the messages systemd itself (PID 1, or the
+// user's manager for user units) logged about a unit: Starting, Started,
+// Stopped, Failed with result, ... These carry the unit in the UNIT (or
+// USER_UNIT) field rather than _SYSTEMD_UNIT.
+func (c *Client) GetManagerLogs(ctx context.Context, unitName string, opts *models.LogOptions) ([]*models.LogEntry, error) {
+	unitName = models.NormalizeUnitName(unitName, models.UnitService)
+
+	matches, err := c.managerMatches(ctx, unitName)
+	if err != nil {
+		return nil, err
+	}
+	args := journalArgs(matches, opts, false)
+
+	output, err := c.runStdout(ctx, args[0], args[1:]...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systemd messages for %s: %w", unitName, err)
+	}
+
+	entries, err := parseJournalOutput(string(output), unitName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse systemd messages for %s: %w", unitName, err)
+	}
+	c.stampLogs(entries...)
+
+	return entries, nil
+}
+
+// GetServiceLogsStream returns a channel for following logs in real-time.
+// Following stops and both channels close when ctx is done.
+func (c *Client) GetServiceLogsStream(ctx context.Context, serviceName string, opts *models.LogOptions) (<-chan *models.LogEntry, <-chan error, error) {
+	// Add .service suffix unless the name has an explicit unit suffix
+	serviceName = models.NormalizeUnitName(serviceName, models.UnitService)
+
+	// Build journalctl command with -f (follow)
+	matches, err := c.unitMatches(ctx, serviceName)
+	if err != nil {
+		return nil, nil, err
+	}
+	args := journalArgs(matches, opts, true)
+
+	// Start command
+	stdout, wait, err := c.stream(ctx, args[0], args[1:]...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start journalctl: %w", err)
+	}
+
+	// Create channels
+	logChan := make(chan *models.LogEntry, 100)
+	errChan := make(chan error, 1)
+
+	// Stop journalctl when the caller is gone; closing stdout ends the scan
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			stdout.Close()
+		case <-finished:
+		}
+	}()
+
+	// Start goroutine to read logs. On the way out journalctl is stopped
+	// before waiting for it, and logChan is closed before errChan so
+	// consumers can drain the entries read before an error.
+	go func() {
+		var failed, eof bool
+		fail := func(err error) {
+			failed = true
+			errChan <- err
+		}
+
+		defer close(errChan)
+		defer close(logChan)
+		defer func() {
+			// Stopping a journalctl that already exited would make it
+			// look killed
+			if !eof {
+				stdout.Close()
+			}
+			// One that exited on its own may have failed
+			if err := wait(); err != nil && !failed && ctx.Err() == nil {
+				fail(fmt.Errorf("journalctl failed: %w", err))
+			}
+		}()
+		defer close(finished)
+
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), maxJournalLine)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+
+			// Lines that are not entries are skipped, like in
+			// parseJournalOutput
+			entry, err := parseJournalJSON([]byte(line), serviceName)
+			if err != nil {
+				continue
+			}
+			c.stampLogs(entry)
+
+			// Apply grep filter if specified
+			if opts != nil && opts.Grep != "" {
+				if !strings.Contains(strings.ToLower(entry.Message), strings.ToLower(opts.Grep)) {
+					continue
+				}
+			}
+
+			select {
+			case logChan <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			if ctx.Err() == nil {
+				fail(err)
+			}
+			return
+		}
+		eof = true
+	}()
+
+	return logChan, errChan, nil
+}
+
+// maxJournalLine bounds a single JSON entry; journalctl truncates fields
+// larger than 64K to null by default
+const maxJournalLine = 4 * 1024 * 1024
+
+// journalArgs builds the journalctl command line for the given matches,
+// e.g. "-u nginx.service" or "--user-unit syncthing.service"
+func journalArgs(matches []string, opts *models.LogOptions, follow bool) []string {
+	args := append([]string{"journalctl"}, matches...)
+	if follow {
+		args = append(args, "-f")
+	}
+	// -q leaves out hints such as "No journal files were found"
+	args = append(args, "-o", "json", "--no-pager", "-q")
+
+	if opts != nil {
+		if opts.Lines > 0 {
+			args = append(args, "-n", fmt.Sprintf("%d", opts.Lines))
+		}
+		if opts.Since != "" {
+			args = append(args, "--since", opts.Since)
+		}
+		if opts.Until != "" && !follow {
+			args = append(args, "--until", opts.Until)
+		}
+		if opts.Priority != "" {
+			args = append(args, "-p", opts.Priority)
+		}
+	}
+
+	return args
+}
+
+// parseJournalOutput parses "journalctl -o json" output (one JSON object
+// per line) into LogEntry structs. Lines that are not JSON are skipped;
+// the output is only an error when none of its lines was an entry.
+func parseJournalOutput(output string, serviceName string) ([]*models.LogEntry, error) {
+	var entries []*models.LogEntry
+	var firstErr error
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		entry, err := parseJournalJSON([]byte(line), serviceName)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return entries, nil
+}
+
+// parseJournalJSON converts one journal entry in journalctl's JSON format:
+//
+//	{"__CURSOR":"s=...","__REALTIME_TIMESTAMP":"1734867162832776",
+//	 "PRIORITY":"6","_PID":"1397","_HOSTNAME":"msi",
+//	 "SYSLOG_IDENTIFIER":"clash","MESSAGE":"level=info msg=\"Start\"",...}
+func parseJournalJSON(data []byte, serviceName string) (*models.LogEntry, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid journal entry: %w", err)
+	}
+
+	entry := models.NewLogEntry(serviceName, "")
+	entry.Fields = make(map[string]string)
+
+	for key, value := range raw {
+		field := journalFieldValue(value)
+
+		switch key {
+		case "MESSAGE":
+			entry.Message = field
+		case "__REALTIME_TIMESTAMP":
+			if usec, err := strconv.ParseInt(field, 10, 64); err == nil {
+				entry.Timestamp = time.UnixMicro(usec)
+			}
+		case "PRIORITY":
+			if priority, err := strconv.Atoi(field); err == nil {
+				entry.SetPriority(priority)
+			}
+		case "_PID":
+			entry.PID, _ = strconv.Atoi(field)
+		case "_HOSTNAME":
+			entry.Hostname = field
+		case "SYSLOG_IDENTIFIER":
+			entry.Identifier = field
+		case "_BOOT_ID":
+			entry.BootID = field
+		case "__CURSOR":
+			entry.Cursor = field
+		default:
+			entry.Fields[key] = field
+		}
+	}
+
+	// Secondary enrichment: a level written inside the message text
+	entry.MessageLevel = detectMessageLevel(entry.Message)
+
+	return entry, nil
+}
+
+// journalFieldValue decodes a JSON field value. Journal fields are strings,
+// byte arrays for non-UTF-8 data, arrays of either when a field repeats, or
+// null when the value was too large to output.
+func journalFieldValue(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+
+	var bytes []byte
+	var numbers []int
+	if err := json.Unmarshal(value, &numbers); err == nil {
+		bytes = make([]byte, len(numbers))
+		for i, n := range numbers {
+			bytes[i] = byte(n)
+		}
+		return string(bytes)
+	}
+
+	var values []json.RawMessage
+	if err := json.Unmarshal(value, &values); err == nil {
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = journalFieldValue(v)
+		}
+		return strings.Join(parts, "\n")
+	}
+
+	return ""
+}
+
+// detectMessageLevel finds a log level written by the application itself
+func detectMessageLevel(message string) string {
+	// Format 1: level=info msg="..."
+	if levelStart := strings.Index(message, "level="); levelStart != -1 {
+		remaining := strings.TrimPrefix(message[levelStart+6:], "\"")
+		if end := strings.IndexAny(remaining, " \""); end > 0 {
+			return strings.ToUpper(remaining[:end])
+		} else if end == -1 && remaining != "" {
+			return strings.ToUpper(remaining)
+		}
+	}
+
+	// Format 2: [INFO] message or INFO: message
+	msgUpper := strings.ToUpper(message)
+	levels := []string{"ERROR", "WARN", "WARNING", "INFO", "DEBUG", "CRITICAL", "FATAL"}
+	for _, level := range levels {
+		if strings.HasPrefix(msgUpper, "["+level+"]") || strings.HasPrefix(msgUpper, level+":") {
+			return level
+		}
+	}
+
+	return ""
+}
+
+// filterLogs filters log entries by pattern
+func filterLogs(entries []*models.LogEntry, pattern string) []*models.LogEntry {
+	var filtered []*models.LogEntry
+	pattern = strings.ToLower(pattern)
+
+	for _, entry := range entries {
+		if strings.Contains(strings.ToLower(entry.Message), pattern) {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,201 @@
+package systemd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+func TestGetServiceLogsFixture(t *testing.T) {
+	client := newFixtureClient(t, "synthetic")
+
+	entries, err := client.GetServiceLogs(context.Background(), "nginx", &models.LogOptions{Lines: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		message      string
+		level        string
+		messageLevel string
+		pid          int
+		identifier   string
+	}{
+		{message: "Starting nginx.service - A high performance web server and a reverse proxy server...", level: "info", pid: 1, identifier: "systemd"},
+		{message: `nginx: [warn] the "ssl" directive is deprecated, use the "listen ... ssl" directive instead`, level: "warning", pid: 1397, identifier: "nginx"},
+		// A byte array, as journalctl prints non-UTF-8 messages
+		{message: "level=error upstream \xff timed out", level: "err", messageLevel: "ERROR", pid: 1397, identifier: "nginx"},
+		{message: "[INFO] worker process 1402 started", level: "info", messageLevel: "INFO", pid: 1397, identifier: "nginx"},
+		{message: "Started nginx.service - A high performance web server and a reverse proxy server.", level: "info", pid: 1, identifier: "systemd"},
+	}
+
+	if len(entries) != len(tests) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(tests))
+	}
+	for i, tt := range tests {
+		entry := entries[i]
+		if entry.Message != tt.message {
+			t.Errorf("entry %d: message %q, want %q", i, entry.Message, tt.message)
+		}
+		if entry.Level != tt.level || entry.MessageLevel != tt.messageLevel {
+			t.Errorf("entry %d: level %q/%q, want %q/%q", i, entry.Level, entry.MessageLevel, tt.level, tt.messageLevel)
+		}
+		if entry.PID != tt.pid || entry.Identifier != tt.identifier || entry.Hostname != "web-01" {
+			t.Errorf("entry %d: PID %d, identifier %q, host %q", i, entry.PID, entry.Identifier, entry.Hostname)
+		}
+		if entry.ServiceName != "nginx.service" || entry.BootID == "" || entry.Cursor == "" {
+			t.Errorf("entry %d: service %q, boot %q, cursor %q", i, entry.ServiceName, entry.BootID, entry.Cursor)
+		}
+	}
+
+	if want := time.UnixMicro(1705314645123456); !entries[0].Timestamp.Equal(want) {
+		t.Errorf("timestamp %s, want %s", entries[0].Timestamp, want)
+	}
+
+	// Repeated fields are joined, null fields are empty
+	if got := entries[3].Fields["CODE_FILE"]; got != "src/core/ngx_cycle.c\nsrc/core/nginx.c" {
+		t.Errorf("CODE_FILE = %q", got)
+	}
+	if got, ok := entries[3].Fields["CORE_DUMP"]; !ok || got != "" {
+		t.Errorf("CORE_DUMP = %q, %v; want an empty field", got, ok)
+	}
+	if got := entries[0].Fields["UNIT"]; got != "nginx.service" {
+		t.Errorf("UNIT = %q", got)
+	}
+}
+
+func TestGetServiceLogsFixtureGrep(t *testing.T) {
+	client := newFixtureClient(t, "synthetic")
+
+	entries, err := client.GetServiceLogs(context.Background(), "nginx", &models.LogOptions{Lines: 5, Grep: "WORKER"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !strings.Contains(entries[0].Message, "worker process") {
+		t.Errorf("grep WORKER matched %d entries", len(entries))
+	}
+}
+
+func TestGetServiceLogsFixtureErrors(t *testing.T) {
+	client := newFixtureClient(t, "synthetic")
+	opts := &models.LogOptions{Lines: 5}
+
+	// A recorded journalctl failure replays as a failure
+	_, err := client.GetServiceLogs(context.Background(), "db", opts)
+	if err == nil || !strings.Contains(err.Error(), "exit status 1") {
+		t.Errorf("db: err = %v, want the recorded failure", err)
+	}
+
+	// Lines that are not JSON are skipped...
+	entries, err := client.GetServiceLogs(context.Background(), "broken", opts)
+	if err != nil || len(entries) != 1 || entries[0].Message != "first line" {
+		t.Errorf("broken: %d entries, err = %v; want the first line only", len(entries), err)
+	}
+
+	// ...unless no line is an entry
+	_, err = client.GetServiceLogs(context.Background(), "garbage", opts)
+	if err == nil || !strings.Contains(err.Error(), "invalid journal entry") {
+		t.Errorf("garbage: err = %v, want a parse error", err)
+	}
+}
+
+func TestGetServiceLogsStreamFixture(t *testing.T) {
+	client := newFixtureClient(t, "synthetic")
+	opts := &models.LogOptions{Lines: 5}
+
+	tests := []struct {
+		unit    string
+		entries int
+		wantErr string
+	}{
+		{unit: "nginx", entries: 5},
+		{unit: "db", wantErr: "exit status 1"},
+		// The line that is not JSON is skipped
+		{unit: "broken", entries: 1},
+	}
+
+	for _, tt := range tests {
+		logChan, errChan, err := client.GetServiceLogsStream(context.Background(), tt.unit, opts)
+		if err != nil {
+			if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: %v", tt.unit, err)
+			}
+			continue
+		}
+
+		count := 0
+		for range logChan {
+			count++
+		}
+		err = <-errChan
+		if count != tt.entries {
+			t.Errorf("%s: got %d entries, want %d", tt.unit, count, tt.entries)
+		}
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: %v", tt.unit, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: err = %v, want %q", tt.unit, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseJournalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		message string
+		level   string
+		wantErr bool
+	}{
+		{name: "string", line: `{"MESSAGE":"hello","PRIORITY":"5"}`, message: "hello", level: "notice"},
+		{name: "byte array", line: `{"MESSAGE":[104,105,0,33]}`, message: "hi\x00!", level: "info"},
+		{name: "repeated", line: `{"MESSAGE":["a",[98]]}`, message: "a\nb", level: "info"},
+		{name: "null", line: `{"MESSAGE":null,"PRIORITY":"2"}`, message: "", level: "crit"},
+		{name: "bad priority", line: `{"MESSAGE":"x","PRIORITY":"high"}`, message: "x", level: "info"},
+		{name: "not json", line: `-- No entries --`, wantErr: true},
+		{name: "truncated", line: `{"MESSAGE":"x"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		entry, err := parseJournalJSON([]byte(tt.line), "nginx.service")
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: parsed %q, want an error", tt.name, tt.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if entry.Message != tt.message || entry.Level != tt.level {
+			t.Errorf("%s: message %q (%s), want %q (%s)", tt.name, entry.Message, entry.Level, tt.message, tt.level)
+		}
+	}
+}
+
+func TestDetectMessageLevel(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{message: `level=info msg="Start"`, want: "INFO"},
+		{message: `time=1 level="warn" msg=x`, want: "WARN"},
+		{message: `level=debug`, want: "DEBUG"},
+		{message: "[ERROR] disk full", want: "ERROR"},
+		{message: "WARNING: low memory", want: "WARNING"},
+		{message: "fatal: not a level prefix", want: "FATAL"},
+		{message: "an error happened", want: ""},
+		{message: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := detectMessageLevel(tt.message); got != tt.want {
+			t.Errorf("detectMessageLevel(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
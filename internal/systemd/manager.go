@@ -0,0 +1,132 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Scope selects the systemd manager a Client talks to: the system manager
+// or a user's service manager (user@.service)
+type Scope struct {
+	User string // owner of the user manager; "" = the system manager
+	// Machine reaches User's manager from outside the user's session
+	// (systemctl --user --machine User@.host, usually as root) instead of
+	// as User itself (systemctl --user)
+	Machine bool
+}
+
+// ParseMachine parses a --machine value naming a user manager on this
+// host: "alice@.host" or "alice@"
+func ParseMachine(s string) (Scope, error) {
+	user, host, ok := strings.Cut(s, "@")
+	if !ok || (host != "" && host != ".host") {
+		return Scope{}, fmt.Errorf("invalid machine %q: use <user>@.host; containers are not supported", s)
+	}
+	if user == "" || strings.HasPrefix(user, "-") || strings.ContainsAny(user, " \t\n/@") {
+		return Scope{}, fmt.Errorf("invalid machine %q: bad user name", s)
+	}
+	return Scope{User: user, Machine: true}, nil
+}
+
+// String describes the manager, e.g. "system manager" or "user manager of
+// alice"
+func (s Scope) String() string {
+	if s.User == "" {
+		return "system manager"
+	}
+	return "user manager of " + s.User
+}
+
+// systemctlFlags returns the systemctl options selecting the manager
+func (s Scope) systemctlFlags() []string {
+	switch {
+	case s.User == "":
+		return nil
+	case s.Machine:
+		return []string{"--user", "--machine", s.User + "@.host"}
+	default:
+		return []string{"--user"}
+	}
+}
+
+// SetScope makes the client talk to scope's manager. User managers are
+// always queried with systemctl, since the client only connects to the
+// system bus.
+func (c *Client) SetScope(scope Scope) {
+	c.scope = scope
+	if scope.User != "" && c.bus != nil {
+		c.bus.Close()
+		c.bus = nil
+	}
+}
+
+// Scope returns the manager the client talks to
+func (c *Client) Scope() Scope {
+	return c.scope
+}
+
+// unitMatches returns the journalctl arguments selecting the messages of
+// a unit, including those its manager logged about it
+func (c *Client) unitMatches(ctx context.Context, unitName string) ([]string, error) {
+	switch {
+	case c.scope.User == "":
+		return []string{"-u", unitName}, nil
+	case !c.scope.Machine:
+		return []string{"--user-unit", unitName}, nil
+	}
+
+	// journalctl --user-unit matches the caller's UID, which is root's
+	// here; spell out its matches for the user instead
+	uid, err := c.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		"_SYSTEMD_USER_UNIT=" + unitName, "_UID=" + uid, "+",
+		"USER_UNIT=" + unitName, "_UID=" + uid,
+	}, nil
+}
+
+// managerMatches returns the journalctl arguments selecting the messages
+// a unit's manager logged about it: PID 1 for system units, the user's
+// systemd --user for user units
+func (c *Client) managerMatches(ctx context.Context, unitName string) ([]string, error) {
+	if c.scope.User == "" {
+		return []string{"_PID=1", "UNIT=" + unitName}, nil
+	}
+
+	uid, err := c.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []string{"USER_UNIT=" + unitName, "_UID=" + uid}, nil
+}
+
+// userID returns the UID of the scope's user on the client's host, looked
+// up once with id(1) so it also works over ssh
+func (c *Client) userID(ctx context.Context) (string, error) {
+	c.uidMu.Lock()
+	defer c.uidMu.Unlock()
+
+	if c.uid != "" {
+		return c.uid, nil
+	}
+
+	args := []string{"-u"}
+	if c.scope.Machine {
+		args = append(args, c.scope.User)
+	}
+	output, err := c.run(ctx, "id", args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the UID of %s: %w", c.scope.User, err)
+	}
+
+	uid := strings.TrimSpace(string(output))
+	if _, err := strconv.Atoi(uid); err != nil {
+		return "", fmt.Errorf("failed to look up the UID of %s: unexpected output %q", c.scope.User, uid)
+	}
+	c.uid = uid
+	return uid, nil
+}
@@ -0,0 +1,125 @@
+package systemd
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/cgroup"
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// resourceProperties are the unit properties holding resource usage;
+// systemd reports "[not set]" when the accounting is off
+var resourceProperties = []string{
+	"ControlGroup", "CPUUsageNSec", "MemoryPeak", "MemorySwapCurrent",
+	"IOReadBytes", "IOWriteBytes", "TasksCurrent",
+}
+
+// SetCgroupReader replaces the reader used for cgroup resource data, e.g.
+// with one rooted at a fake tree; nil reads systemd properties only
+func (c *Client) SetCgroupReader(reader *cgroup.Reader) {
+	c.cgroups = reader
+}
+
+// parseResourceProperties copies the resource properties into serviceInfo
+func parseResourceProperties(serviceInfo *models.ServiceInfo, props map[string]string) {
+	serviceInfo.ControlGroup = props["ControlGroup"]
+
+	if nsec, ok := propertyInt(props, "CPUUsageNSec"); ok {
+		serviceInfo.CPUUsage = time.Duration(nsec)
+	}
+	if peak, ok := propertyInt(props, "MemoryPeak"); ok {
+		serviceInfo.MemoryPeak = peak
+	}
+	if swap, ok := propertyInt(props, "MemorySwapCurrent"); ok {
+		serviceInfo.SwapBytes = swap
+	}
+	if read, ok := propertyInt(props, "IOReadBytes"); ok {
+		serviceInfo.IOReadBytes = read
+	}
+	if written, ok := propertyInt(props, "IOWriteBytes"); ok {
+		serviceInfo.IOWriteBytes = written
+	}
+	if tasks, ok := propertyInt(props, "TasksCurrent"); ok {
+		serviceInfo.Tasks = int(tasks)
+	}
+}
+
+// propertyInt parses a numeric property; "[not set]" and missing
+// properties are not ok
+func propertyInt(props map[string]string, name string) (int64, bool) {
+	value, err := strconv.ParseInt(props[name], 10, 64)
+	return value, err == nil
+}
+
+// addResources completes serviceInfo with the unit's cgroup files, which
+// also cover controllers systemd does not account for (and PSI), then
+// computes the CPU rate against the previous sample of the unit
+func (c *Client) addResources(serviceInfo *models.ServiceInfo) {
+	if c.cgroups != nil && serviceInfo.ControlGroup != "" {
+		if stats, err := c.cgroups.Read(serviceInfo.ControlGroup); err == nil {
+			if stats.CPUUsage > 0 {
+				serviceInfo.CPUUsage = stats.CPUUsage
+			}
+			if stats.MemoryCurrent > 0 && serviceInfo.MemoryBytes == 0 {
+				serviceInfo.MemoryBytes = stats.MemoryCurrent
+				serviceInfo.MemoryUsage = formatMemory(stats.MemoryCurrent)
+			}
+			if stats.MemoryPeak > 0 {
+				serviceInfo.MemoryPeak = stats.MemoryPeak
+			}
+			if stats.SwapCurrent > 0 {
+				serviceInfo.SwapBytes = stats.SwapCurrent
+			}
+			if stats.IOReadBytes > 0 || stats.IOWriteBytes > 0 {
+				serviceInfo.IOReadBytes = stats.IOReadBytes
+				serviceInfo.IOWriteBytes = stats.IOWriteBytes
+			}
+			if stats.Tasks > 0 {
+				serviceInfo.Tasks = stats.Tasks
+			}
+			serviceInfo.Pressure = stats.Pressure
+		}
+	}
+
+	c.cpu.observe(serviceInfo)
+}
+
+// cpuSample is the CPU time of a unit at one point in time
+type cpuSample struct {
+	usage time.Duration
+	at    time.Time
+}
+
+// cpuMeter turns CPU time counters into rates between samples
+type cpuMeter struct {
+	mu   sync.Mutex
+	last map[string]cpuSample
+}
+
+// observe sets CPUPercent from the unit's previous sample and remembers
+// this one. A counter that went down means the unit restarted; the rate
+// is unknown until the next sample.
+func (m *cpuMeter) observe(serviceInfo *models.ServiceInfo) {
+	if serviceInfo.CPUUsage <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.last == nil {
+		m.last = make(map[string]cpuSample)
+	}
+
+	current := cpuSample{usage: serviceInfo.CPUUsage, at: serviceInfo.CheckedAt}
+	if previous, ok := m.last[serviceInfo.Name]; ok {
+		elapsed := current.at.Sub(previous.at)
+		if elapsed > 0 && current.usage >= previous.usage {
+			serviceInfo.CPUPercent = 100 * float64(current.usage-previous.usage) / float64(elapsed)
+			serviceInfo.CPUSampled = true
+		}
+	}
+	m.last[serviceInfo.Name] = current
+}
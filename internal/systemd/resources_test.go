@@ -0,0 +1,65 @@
+package systemd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/cgroup"
+)
+
+func TestAddResourcesFromCgroup(t *testing.T) {
+	v2 := t.TempDir()
+	dir := filepath.Join(v2, "system.slice", "nginx.service")
+	files := map[string]string{
+		"cpu.stat":            "usage_usec 4000000\n",
+		"memory.current":      "31457280\n",
+		"memory.swap.current": "8192\n",
+		"pids.current":        "5\n",
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// cgroup v1 is not read: nothing at the unit's ControlGroup path
+	v1 := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(v1, "memory", "system.slice", "nginx.service"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		root        string
+		cpu         time.Duration
+		memoryBytes int64
+		swap        int64
+		tasks       int
+	}{
+		// The cgroup's CPU time wins; MemoryCurrent from systemd is kept
+		{name: "v2", root: v2, cpu: 4 * time.Second, memoryBytes: 15728640, swap: 8192, tasks: 5},
+		// v1 is unsupported; only the properties of the fixture
+		{name: "v1 unsupported", root: v1, cpu: 2500 * time.Millisecond, memoryBytes: 15728640, tasks: 3},
+	}
+
+	for _, tt := range tests {
+		client := newFixtureClient(t, "synthetic")
+		client.SetCgroupReader(cgroup.NewReader(tt.root))
+
+		service, err := client.GetServiceStatus(context.Background(), "nginx")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if service.CPUUsage != tt.cpu || service.MemoryBytes != tt.memoryBytes || service.SwapBytes != tt.swap || service.Tasks != tt.tasks {
+			t.Errorf("%s: CPU %s, memory %d, swap %d, tasks %d; want %s, %d, %d, %d", tt.name,
+				service.CPUUsage, service.MemoryBytes, service.SwapBytes, service.Tasks,
+				tt.cpu, tt.memoryBytes, tt.swap, tt.tasks)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// sshConnectTimeout bounds connecting and authenticating to a host, so an
+// unreachable host fails its check instead of hanging until the check
+// timeout
+const sshConnectTimeout = 10 * time.Second
+
+// SSHTarget is a remote host reached with the system's OpenSSH client.
+// Anything left empty falls back to ~/.ssh/config and the ssh defaults.
+type SSHTarget struct {
+	Name         string // shown in output and alerts; "" = Host
+	Host         string // address or ssh_config alias
+	User         string
+	Port         int    // 0 = 22 or ssh_config
+	IdentityFile string // private key; only this key is offered
+	KnownHosts   string // known_hosts file; the host key must be listed in it
+	Jump         string // jump host(s), as for ssh -J: [user@]host[:port][,...]
+}
+
+// ParseSSHTarget parses "[user@]host[:port]"; IPv6 addresses with a port
+// are written in brackets, e.g. "admin@[2001:db8::1]:2222"
+func ParseSSHTarget(s string) (SSHTarget, error) {
+	var target SSHTarget
+
+	hostPort := s
+	if at := strings.LastIndex(s, "@"); at >= 0 {
+		target.User, hostPort = s[:at], s[at+1:]
+		if target.User == "" {
+			return target, fmt.Errorf("invalid host %q: empty user", s)
+		}
+	}
+
+	target.Host = hostPort
+	if host, port, err := net.SplitHostPort(hostPort); err == nil {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return target, fmt.Errorf("invalid host %q: bad port %q", s, port)
+		}
+		target.Host, target.Port = host, n
+	} else if strings.HasPrefix(hostPort, "[") && strings.HasSuffix(hostPort, "]") {
+		target.Host = hostPort[1 : len(hostPort)-1]
+	}
+
+	if err := target.Validate(); err != nil {
+		return target, err
+	}
+	return target, nil
+}
+
+// Validate rejects targets ssh would misread, such as a host starting
+// with "-" being taken for an option
+func (t SSHTarget) Validate() error {
+	switch {
+	case t.Host == "":
+		return errors.New("host must not be empty")
+	case strings.HasPrefix(t.Host, "-") || strings.HasPrefix(t.User, "-") || strings.HasPrefix(t.Jump, "-"):
+		return fmt.Errorf("invalid host %q", t.String())
+	case strings.ContainsAny(t.Host+t.User, " \t\n/@"):
+		return fmt.Errorf("invalid host %q", t.String())
+	case t.Port < 0 || t.Port > 65535:
+		return fmt.Errorf("invalid port %d for %s", t.Port, t.Host)
+	}
+	return nil
+}
+
+// Label returns the name the target is shown under
+func (t SSHTarget) Label() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Host
+}
+
+// String renders the target as "user@host:port"
+func (t SSHTarget) String() string {
+	s := t.Host
+	if t.Port != 0 {
+		s = net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	}
+	if t.User != "" {
+		s = t.User + "@" + s
+	}
+	return s
+}
+
+// SSHExecutor runs commands on a remote host through ssh. When a runtime
+// directory is available, connections to a host are shared (ControlMaster),
+// so a check pays the handshake once a minute instead of once per command.
+type SSHExecutor struct {
+	target     SSHTarget
+	controlDir string // where control sockets live; "" = no sharing
+}
+
+// NewSSHExecutor creates an executor for target. Control sockets go to
+// $RUNTIME_DIRECTORY (set by systemd's RuntimeDirectory=) or
+// $XDG_RUNTIME_DIR, both private to the user.
+func NewSSHExecutor(target SSHTarget) *SSHExecutor {
+	controlDir := os.Getenv("RUNTIME_DIRECTORY")
+	if controlDir == "" {
+		controlDir = os.Getenv("XDG_RUNTIME_DIR")
+	}
+	// RUNTIME_DIRECTORY may list several directories
+	controlDir, _, _ = strings.Cut(controlDir, ":")
+
+	return &SSHExecutor{target: target, controlDir: controlDir}
+}
+
+// Output runs the command on the remote host and returns its combined
+// output. ssh's own failures (exit status 255) are reported with its
+// message, e.g. "Permission denied (publickey)".
+func (e *SSHExecutor) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	output, err := command(ctx, "ssh", e.args(name, args)...).CombinedOutput()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return output, ctxErr
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 255 {
+		return nil, fmt.Errorf("ssh %s: %s", e.target.String(), lastLine(output, exitErr.Error()))
+	}
+	return output, err
+}
+
+// Stream starts the command on the remote host with its stdout connected
+// to a pipe; closing it ends the ssh session and with it the command
+func (e *SSHExecutor) Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, func() error, error) {
+	cmd := command(ctx, "ssh", e.args(name, args)...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	return &processReader{ReadCloser: stdout, process: cmd.Process}, cmd.Wait, nil
+}
+
+// args builds the ssh command line running name and args remotely
+func (e *SSHExecutor) args(name string, args []string) []string {
+	t := e.target
+	sshArgs := []string{
+		"-o", "BatchMode=yes", // never prompt for a password or host key
+		"-o", "LogLevel=ERROR", // keep warnings out of the parsed output
+		"-o", fmt.Sprintf("ConnectTimeout=%d", int(sshConnectTimeout/time.Second)),
+		"-o", "ServerAliveInterval=15",
+	}
+	if e.controlDir != "" {
+		sshArgs = append(sshArgs,
+			"-o", "ControlMaster=auto",
+			"-o", "ControlPath="+filepath.Join(e.controlDir, "ssh-%C"),
+			"-o", "ControlPersist=60")
+	}
+	if t.IdentityFile != "" {
+		sshArgs = append(sshArgs, "-i", t.IdentityFile, "-o", "IdentitiesOnly=yes")
+	}
+	if t.KnownHosts != "" {
+		sshArgs = append(sshArgs, "-o", "UserKnownHostsFile="+t.KnownHosts, "-o", "StrictHostKeyChecking=yes")
+	}
+	if t.Jump != "" {
+		sshArgs = append(sshArgs, "-J", t.Jump)
+	}
+	if t.Port != 0 {
+		sshArgs = append(sshArgs, "-p", strconv.Itoa(t.Port))
+	}
+	if t.User != "" {
+		sshArgs = append(sshArgs, "-l", t.User)
+	}
+
+	// The remote side runs the command through its login shell, so every
+	// word is quoted
+	remote := make([]string, 0, len(args)+1)
+	for _, word := range append([]string{name}, args...) {
+		remote = append(remote, shellQuote(word))
+	}
+	return append(sshArgs, t.Host, strings.Join(remote, " "))
+}
+
+// shellQuote quotes a word for a POSIX shell
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.,:=/@+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// lastLine returns the last non-empty line of output, or fallback
+func lastLine(output []byte, fallback string) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return last
+	}
+	return fallback
+}
+
+// NewRemoteClient creates a client running systemctl and journalctl on
+// target over ssh. Remote clients always use the exec backend and read
+// resource usage from unit properties only, since the cgroup tree is not
+// local. Results carry the target's label in their Host field.
+func NewRemoteClient(target SSHTarget, useSudo bool) *Client {
+	return &Client{
+		useSudo:  useSudo,
+		executor: NewSSHExecutor(target),
+		now:      time.Now,
+		host:     target.Label(),
+	}
+}
+
+// Host returns the label of the remote host the client talks to; "" for
+// the local machine
+func (c *Client) Host() string {
+	return c.host
+}
+
+// stamp marks snapshots with the client's host and user manager
+func (c *Client) stamp(services ...*models.ServiceInfo) {
+	if c.host == "" && c.scope.User == "" {
+		return
+	}
+	for _, service := range services {
+		if service != nil {
+			service.Host = c.host
+			service.User = c.scope.User
+		}
+	}
+}
+
+// stampLogs marks journal entries with the client's host and user manager
+func (c *Client) stampLogs(entries ...*models.LogEntry) {
+	if c.host == "" && c.scope.User == "" {
+		return
+	}
+	for _, entry := range entries {
+		entry.Host = c.host
+		entry.User = c.scope.User
+	}
+}
@@ -0,0 +1,357 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{word: "nginx.service", want: "nginx.service"},
+		{word: "--since=2024-01-15", want: "--since=2024-01-15"},
+		{word: "user@1000.service", want: "user@1000.service"},
+		{word: "", want: "''"},
+		{word: "a b", want: "'a b'"},
+		{word: "it's", want: `'it'\''s'`},
+		{word: "$HOME", want: "'$HOME'"},
+		{word: "`id`", want: "'`id`'"},
+		{word: "$(reboot)", want: "'$(reboot)'"},
+		{word: "a;b", want: "'a;b'"},
+		{word: "*", want: "'*'"},
+		{word: `back\slash`, want: `'back\slash'`},
+		{word: "line\nbreak", want: "'line\nbreak'"},
+		{word: `"quoted"`, want: `'"quoted"'`},
+	}
+
+	for _, tt := range tests {
+		if got := shellQuote(tt.word); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuoteRoundTrip(t *testing.T) {
+	words := []string{"", "plain", "a b", "it's", "'", "''", "$HOME", "`id`", "$(id)", "a;b|c&d", "*", "~", `\`, "x\ny", `"`, "-n"}
+
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = shellQuote(word)
+	}
+
+	// Each word comes back as one NUL-terminated argument
+	output, err := exec.Command("sh", "-c", `printf '%s\0' `+strings.Join(quoted, " ")).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	if !reflect.DeepEqual(got, words) {
+		t.Errorf("the shell saw %q, want %q", got, words)
+	}
+}
+
+func TestParseSSHTarget(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    SSHTarget
+		wantErr bool
+	}{
+		{in: "web-01", want: SSHTarget{Host: "web-01"}},
+		{in: "admin@web-01", want: SSHTarget{Host: "web-01", User: "admin"}},
+		{in: "admin@10.0.0.5:2222", want: SSHTarget{Host: "10.0.0.5", User: "admin", Port: 2222}},
+		{in: "2001:db8::1", want: SSHTarget{Host: "2001:db8::1"}},
+		{in: "[2001:db8::1]", want: SSHTarget{Host: "2001:db8::1"}},
+		{in: "admin@[2001:db8::1]:2222", want: SSHTarget{Host: "2001:db8::1", User: "admin", Port: 2222}},
+
+		// Would be read as ssh options
+		{in: "-oProxyCommand=touch /tmp/pwned", wantErr: true},
+		{in: "-oProxyCommand=id", wantErr: true},
+		{in: "admin@-oProxyCommand=id", wantErr: true},
+		{in: "-ladmin@web-01", wantErr: true},
+		{in: "[-oProxyCommand=id]:22", wantErr: true},
+
+		{in: "", wantErr: true},
+		{in: "@web-01", wantErr: true},
+		{in: "admin@", wantErr: true},
+		{in: "web-01:0", wantErr: true},
+		{in: "web-01:65536", wantErr: true},
+		{in: "web-01:ssh", wantErr: true},
+		{in: "web 01", wantErr: true},
+		{in: "web-01/x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSSHTarget(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseSSHTarget(%q) = %+v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSSHTarget(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSSHTarget(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSSHTargetValidate(t *testing.T) {
+	tests := []struct {
+		target  SSHTarget
+		wantErr bool
+	}{
+		{target: SSHTarget{Host: "web-01", User: "admin", Port: 22, Jump: "bastion"}},
+		{target: SSHTarget{Host: "web-01", Jump: "-oProxyCommand=id"}, wantErr: true},
+		{target: SSHTarget{Host: "web-01", User: "-oProxyCommand=id"}, wantErr: true},
+		{target: SSHTarget{Host: "web-01", User: "a b"}, wantErr: true},
+		{target: SSHTarget{Host: "web-01", Port: -1}, wantErr: true},
+		{target: SSHTarget{Host: "web-01", Port: 70000}, wantErr: true},
+		{target: SSHTarget{User: "admin"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.target.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, want an error: %v", tt.target, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSSHExecutorArgs(t *testing.T) {
+	e := &SSHExecutor{target: SSHTarget{
+		Host:         "web-01",
+		User:         "admin",
+		Port:         2222,
+		IdentityFile: "/etc/monitor/id_ed25519",
+		KnownHosts:   "/etc/monitor/known_hosts",
+		Jump:         "bastion",
+	}}
+
+	args := e.args("journalctl", []string{"-u", "nginx.service", "--grep", "can't connect", "--since", "1 hour ago"})
+
+	// The host and the quoted remote command come last
+	n := len(args)
+	if args[n-2] != "web-01" {
+		t.Errorf("host argument %q, want web-01", args[n-2])
+	}
+	if want := `journalctl -u nginx.service --grep 'can'\''t connect' --since '1 hour ago'`; args[n-1] != want {
+		t.Errorf("remote command %s, want %s", args[n-1], want)
+	}
+
+	options := strings.Join(args[:n-2], " ")
+	for _, want := range []string{
+		"-o BatchMode=yes",
+		"-i /etc/monitor/id_ed25519 -o IdentitiesOnly=yes",
+		"-o UserKnownHostsFile=/etc/monitor/known_hosts -o StrictHostKeyChecking=yes",
+		"-J bastion",
+		"-p 2222",
+		"-l admin",
+	} {
+		if !strings.Contains(options, want) {
+			t.Errorf("options %q lack %q", options, want)
+		}
+	}
+	if strings.Contains(options, "ControlMaster") {
+		t.Error("connection sharing without a control directory")
+	}
+}
+
+// fakeSSH puts an ssh on PATH that runs the remote command with sh, the
+// way sshd hands it to the login shell
+func fakeSSH(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestSSHExecutorOutput(t *testing.T) {
+	fakeSSH(t, `for last; do :; done; exec sh -c "$last"`)
+
+	marker := filepath.Join(t.TempDir(), "injected")
+	e := &SSHExecutor{target: SSHTarget{Host: "web-01"}}
+	words := []string{"a b", "it's", "$HOME", "$(touch " + marker + ")", "; touch " + marker}
+
+	output, err := e.Output(context.Background(), "printf", append([]string{`%s\n`}, words...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n"); !reflect.DeepEqual(got, words) {
+		t.Errorf("remote side saw %q, want %q", got, words)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("an argument was run by the remote shell")
+	}
+}
+
+func TestSSHExecutorError(t *testing.T) {
+	fakeSSH(t, `echo "Warning: Permanently added 'web-01' to the list of known hosts." >&2
+echo "admin@web-01: Permission denied (publickey)." >&2
+exit 255`)
+
+	e := &SSHExecutor{target: SSHTarget{Host: "web-01", User: "admin"}}
+	_, err := e.Output(context.Background(), "systemctl", "is-active", "nginx.service")
+	if err == nil || err.Error() != "ssh admin@web-01: admin@web-01: Permission denied (publickey)." {
+		t.Errorf("err = %v, want ssh's last message", err)
+	}
+}
+
+// fakeRemote is a host behind a fake ssh: the remote command runs with sh
+// and a fake systemctl whose behaviour the test sets through files in dir
+type fakeRemote struct {
+	dir string
+}
+
+// newFakeRemote puts an ssh on PATH that logs its arguments, emulates a
+// ControlMaster by creating the control socket on first use and fails
+// like an unreachable host while dir/down exists
+func newFakeRemote(t *testing.T) *fakeRemote {
+	t.Helper()
+	r := &fakeRemote{dir: t.TempDir()}
+
+	bin := filepath.Join(r.dir, "remote-bin")
+	if err := os.Mkdir(bin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	systemctl := `#!/bin/sh
+state=` + r.dir + `
+case "$1" in
+start|stop|restart|reload)
+	if [ -s "$state/action-error" ]; then cat "$state/action-error" >&2; exit 5; fi ;;
+list-jobs) ;;
+show)
+	echo "Id=$2"
+	cat "$state/show" ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(bin, "systemctl"), []byte(systemctl), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	fakeSSH(t, `state=`+r.dir+`
+printf '%s\n' "$*" >> "$state/calls"
+if [ -e "$state/down" ]; then
+	echo "ssh: connect to host 10.0.0.5 port 22: Connection refused" >&2
+	exit 255
+fi
+for arg; do
+	case "$arg" in ControlPath=*) socket=${arg#ControlPath=} ;; esac
+	last=$arg
+done
+if [ -n "$socket" ]; then
+	if [ -e "$socket" ]; then echo reused >> "$state/connections"; else : > "$socket"; echo master >> "$state/connections"; fi
+fi
+PATH=`+bin+`:$PATH exec sh -c "$last"`)
+
+	r.set(t, "show", "ActiveState=active\nSubState=running\nMainPID=1397\n")
+	return r
+}
+
+// set writes one of the files steering the fake host; "" removes it
+func (r *fakeRemote) set(t *testing.T, name, content string) {
+	t.Helper()
+	path := filepath.Join(r.dir, name)
+	if content == "" {
+		os.Remove(path)
+		return
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (r *fakeRemote) lines(name string) []string {
+	data, _ := os.ReadFile(filepath.Join(r.dir, name))
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestRemoteControlUnit(t *testing.T) {
+	remote := newFakeRemote(t)
+	runtimeDir := t.TempDir()
+	t.Setenv("RUNTIME_DIRECTORY", "")
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	client := NewRemoteClient(SSHTarget{Name: "web-01", Host: "10.0.0.5", User: "admin"}, false)
+	ctx := context.Background()
+
+	// 1. The action goes through; the unit is read back from the host
+	service, err := client.RestartUnit(ctx, "nginx", time.Second)
+	if err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if service.Host != "web-01" || service.PID != 1397 || service.ActiveState != "active" {
+		t.Errorf("restart: host %q, PID %d, state %s; want web-01, 1397, active", service.Host, service.PID, service.ActiveState)
+	}
+
+	// 2. The state the unit reached is checked
+	remote.set(t, "show", "ActiveState=failed\nSubState=failed\n")
+	service, err = client.RestartUnit(ctx, "nginx", time.Second)
+	if err == nil || err.Error() != "restart nginx.service: unit is failed (failed) instead of active" {
+		t.Errorf("restart into failed: err = %v", err)
+	}
+	if service == nil || service.Host != "web-01" {
+		t.Errorf("restart into failed: got %+v, want the unit's state", service)
+	}
+	if _, err := client.StopUnit(ctx, "nginx", time.Second); err != nil {
+		t.Errorf("stop into failed: %v", err)
+	}
+
+	// 3. systemctl's exit status and message come back from the host
+	remote.set(t, "action-error", "Failed to restart nope.service: Unit nope.service not found.\n")
+	_, err = client.RestartUnit(ctx, "nope", time.Second)
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 5 {
+		t.Fatalf("missing unit: err = %v, want exit status 5", err)
+	}
+	if want := "failed to restart nope.service: exit status 5 (Failed to restart nope.service: Unit nope.service not found.)"; err.Error() != want {
+		t.Errorf("missing unit: err = %q, want %q", err, want)
+	}
+	remote.set(t, "action-error", "")
+
+	// 4. A host that cannot be reached is reported as such, not as a
+	// remote failure
+	remote.set(t, "down", "yes")
+	_, err = client.RestartUnit(ctx, "nginx", time.Second)
+	if want := "failed to restart nginx.service: ssh admin@10.0.0.5: ssh: connect to host 10.0.0.5 port 22: Connection refused"; err == nil || err.Error() != want {
+		t.Errorf("host down: err = %v, want %q", err, want)
+	}
+	if errors.As(err, &exitErr) {
+		t.Errorf("host down: err carries exit status %d", exitErr.ExitCode())
+	}
+	remote.set(t, "down", "")
+
+	// 5. Every call shared one connection through the same control socket
+	calls := remote.lines("calls")
+	connections := remote.lines("connections")
+	if len(connections) < 2 || connections[0] != "master" {
+		t.Fatalf("connections = %q, want a master then reuses", connections)
+	}
+	for _, c := range connections[1:] {
+		if c != "reused" {
+			t.Errorf("connections = %q, want one master", connections)
+			break
+		}
+	}
+	controlPath := "ControlPath=" + filepath.Join(runtimeDir, "ssh-%C")
+	for _, call := range calls {
+		if !strings.Contains(call, "-o ControlMaster=auto") || !strings.Contains(call, controlPath) {
+			t.Errorf("ssh %s: no shared connection", call)
+		}
+		if !strings.Contains(call, "-l admin 10.0.0.5 systemctl ") {
+			t.Errorf("ssh %s: want systemctl run as admin on 10.0.0.5", call)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+	"github.com/andinianst93/systemd-monitoring/internal/pool"
+)
+
+// CheckOptions bounds concurrent status checks
+type CheckOptions struct {
+	Workers int           // checks running at once; <= 0 = pool.DefaultWorkers
+	Timeout time.Duration // per check; 0 = no limit
+}
+
+// StatusResult is the outcome of checking one unit
+type StatusResult struct {
+	Name    string
+	Service *models.ServiceInfo
+	Err     error
+}
+
+// GetServiceStatuses checks the units concurrently, one GetServiceStatus
+// per unit, and returns the results in the order of names. A check that
+// exceeds opts.Timeout is cancelled, killing its systemctl, and reported
+// as an error without holding up the others.
+func (c *Client) GetServiceStatuses(ctx context.Context, names []string, opts CheckOptions) []StatusResult {
+	results := make([]StatusResult, len(names))
+
+	pool.Run(ctx, len(names), opts.Workers, opts.Timeout, func(ctx context.Context, i int) {
+		name := models.NormalizeUnitName(names[i], models.UnitService)
+		results[i].Name = name
+
+		service, err := c.GetServiceStatus(ctx, name)
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("checking %s timed out after %s", name, opts.Timeout)
+		}
+		results[i].Service, results[i].Err = service, err
+	})
+
+	return results
+}
@@ -0,0 +1,132 @@
+package systemd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+// unitDetail maps a systemd property to the label shown in output
+type unitDetail struct {
+	property  string
+	label     string
+	timestamp bool // property holds a timestamp (text or microseconds)
+}
+
+// unitDetails lists the type-specific properties surfaced per unit type
+var unitDetails = map[models.UnitType][]unitDetail{
+	models.UnitTimer: {
+		{property: "NextElapseUSecRealtime", label: "NextElapse", timestamp: true},
+		{property: "LastTriggerUSec", label: "LastTrigger", timestamp: true},
+		{property: "Unit", label: "Triggers"},
+	},
+	models.UnitSocket: {
+		{property: "Listen", label: "Listen"},
+		{property: "NConnections", label: "Connections"},
+	},
+	models.UnitMount: {
+		{property: "Where", label: "Where"},
+		{property: "What", label: "What"},
+	},
+	models.UnitAutomount: {
+		{property: "Where", label: "Where"},
+	},
+	models.UnitPath: {
+		{property: "Paths", label: "Paths"},
+		{property: "Unit", label: "Triggers"},
+	},
+	models.UnitSwap: {
+		{property: "What", label: "What"},
+	},
+	models.UnitScope: {
+		{property: "Slice", label: "Slice"},
+	},
+	models.UnitDevice: {
+		{property: "SysFSPath", label: "SysFSPath"},
+	},
+}
+
+// dbusInterface returns the type-specific D-Bus interface of a unit type
+func dbusInterface(unitType models.UnitType) string {
+	if unitType == "" {
+		unitType = models.UnitService
+	}
+	name := string(unitType)
+	return "org.freedesktop.systemd1." + strings.ToUpper(name[:1]) + name[1:]
+}
+
+// extractDetails picks the type-specific properties out of a property map
+func extractDetails(unitType models.UnitType, props map[string]string) map[string]string {
+	details := make(map[string]string)
+
+	for _, d := range unitDetails[unitType] {
+		value := strings.TrimSpace(props[d.property])
+		if value == "" || value == "n/a" || value == "0" || value == "[not set]" {
+			continue
+		}
+		if d.timestamp {
+			value = formatTimestamp(value)
+		}
+		details[d.label] = value
+	}
+
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// formatTimestamp renders a systemd timestamp (text or microseconds since
+// the epoch, as D-Bus reports it) as "2006-01-02 15:04:05"
+func formatTimestamp(value string) string {
+	if usec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMicro(usec).Format("2006-01-02 15:04:05")
+	}
+	return value
+}
+
+// parseListUnitsOutput parses "systemctl list-units --plain --no-legend"
+// output. Older header/legend lines and the "●" failure marker are
+// tolerated so fixtures recorded without --plain still parse.
+func parseListUnitsOutput(output string, unitType models.UnitType) []*models.ServiceInfo {
+	var units []*models.ServiceInfo
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "●"))
+		if line == "" {
+			continue
+		}
+
+		// Summary line ends the table
+		if strings.Contains(line, "loaded units") {
+			break
+		}
+
+		// Format output systemctl:
+		// UNIT                    LOAD   ACTIVE SUB     DESCRIPTION
+		// ssh.service             loaded active running OpenSSH server
+		fields := strings.Fields(line)
+
+		// There must 4 fields: name, load, active, sub. Legend lines
+		// read "LOAD   = Reflects whether ..."
+		if len(fields) < 4 || fields[0] == "UNIT" || fields[1] == "=" {
+			continue
+		}
+
+		name := fields[0]
+		if unitType != models.UnitAll && models.UnitTypeOf(name) != unitType {
+			continue
+		}
+
+		serviceInfo := models.NewServiceInfo(name)
+		serviceInfo.ActiveState = fields[2]
+		serviceInfo.SubState = fields[3]
+		serviceInfo.Status = parseStatus(serviceInfo.UnitType, fields[2], fields[3])
+
+		units = append(units, serviceInfo)
+	}
+
+	return units
+}
@@ -0,0 +1,39 @@
+package systemd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/andinianst93/systemd-monitoring/internal/models"
+)
+
+func TestParseListUnitsOutput(t *testing.T) {
+	// Recorded without --plain: header, ● markers and the legend
+	output := `  UNIT                LOAD   ACTIVE SUB     DESCRIPTION
+  cron.service        loaded active running Regular background program processing daemon
+● db.service          loaded failed failed  PostgreSQL database
+  backup.timer        loaded active waiting Nightly backup
+
+LOAD   = Reflects whether the unit definition was properly loaded.
+3 loaded units listed.
+`
+
+	tests := []struct {
+		unitType models.UnitType
+		want     []string
+	}{
+		{unitType: models.UnitService, want: []string{"cron.service", "db.service"}},
+		{unitType: models.UnitTimer, want: []string{"backup.timer"}},
+		{unitType: models.UnitAll, want: []string{"cron.service", "db.service", "backup.timer"}},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, unit := range parseListUnitsOutput(output, tt.unitType) {
+			got = append(got, unit.Name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.unitType, got, tt.want)
+		}
+	}
+}
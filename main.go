@@ -1,15 +1,38 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/andinianst93/systemd-monitoring/internal/api"
+	"github.com/andinianst93/systemd-monitoring/internal/cgroup"
+	"github.com/andinianst93/systemd-monitoring/internal/config"
+	"github.com/andinianst93/systemd-monitoring/internal/daemon"
+	"github.com/andinianst93/systemd-monitoring/internal/dashboard"
+	"github.com/andinianst93/systemd-monitoring/internal/diagnose"
+	"github.com/andinianst93/systemd-monitoring/internal/fleet"
+	"github.com/andinianst93/systemd-monitoring/internal/history"
 	"github.com/andinianst93/systemd-monitoring/internal/logger"
+	"github.com/andinianst93/systemd-monitoring/internal/metrics"
 	"github.com/andinianst93/systemd-monitoring/internal/models"
+	"github.com/andinianst93/systemd-monitoring/internal/monitor"
+	"github.com/andinianst93/systemd-monitoring/internal/notify"
 	"github.com/andinianst93/systemd-monitoring/internal/output"
+	"github.com/andinianst93/systemd-monitoring/internal/pool"
+	"github.com/andinianst93/systemd-monitoring/internal/remediate"
+	"github.com/andinianst93/systemd-monitoring/internal/report"
 	"github.com/andinianst93/systemd-monitoring/internal/systemd"
 )
 
@@ -28,12 +51,34 @@ func main() {
 		handleList()
 	case "check":
 		handleCheck()
-	case "monitor":
-		handleMonitor()
+	case "deps":
+		handleDeps()
+	case "explain":
+		handleExplain()
+	case "monitor", "agent":
+		handleMonitor(command)
+	case "collector":
+		handleCollector()
+	case "dashboard":
+		handleDashboard()
 	case "logs":
 		handleLogs()
 	case "write-log":
 		handleWriteLog()
+	case "config":
+		handleConfig()
+	case "history":
+		handleHistory()
+	case "report":
+		handleReport()
+	case "serve-metrics":
+		handleServeMetrics()
+	case "serve":
+		handleServe()
+	case "install-unit":
+		handleInstallUnit()
+	case "start", "stop", "restart", "reload", "enable", "disable", "mask", "unmask":
+		handleControl(command)
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		printUsage()
@@ -48,17 +93,86 @@ func handleList() {
 	statusFilter := listCmd.String("status", "all", "Filter by status (running/failed/stopped/all)")
 	outputFormat := listCmd.String("output", "table", "Output format (table/json)")
 	useSudo := listCmd.Bool("sudo", false, "Use sudo for systemctl")
+	backend := listCmd.String("backend", "auto", "Backend (auto/dbus/exec)")
+	unitTypeFlag := listCmd.String("type", "service", "Unit type (service/timer/socket/mount/path/target/scope/.../all)")
+	resources := listCmd.Bool("resources", false, "Include CPU, memory and task usage of every unit")
+	hostOpts := addHostFlags(listCmd)
+	scopeOpts := addScopeFlags(listCmd)
+	managers := listCmd.String("scope", "", "Managers to list: system, user or all (default: the one --user/--machine selects)")
+	collectorURL := listCmd.String("collector", "", "List the units every agent reported to this collector")
+	secretFile := listCmd.String("secret-file", "", "File holding the collector's shared secret (or set SYSMON_FLEET_SECRET)")
 
 	listCmd.Parse(os.Args[2:])
 
-	// 2. Create client
-	client := systemd.NewClient(*useSudo)
+	unitType := parseUnitTypeFlag(*unitTypeFlag)
 
-	// 3. Get services
-	serviceList, err := client.ListServices()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(2)
+	serviceList := models.NewServiceList()
+	failedHosts := 0
+	var staleHosts map[string]time.Time
+	if *collectorURL != "" {
+		// 2. Read the fleet from the collector; stale hosts are listed with
+		// their last reported state
+		if hostOpts.selected() || scopeOpts.selected() || *managers != "" {
+			fmt.Fprintln(os.Stderr, "Error: --collector cannot be combined with --host/--hosts-file, --user/--machine or --scope")
+			os.Exit(2)
+		}
+		view, err := fleet.FetchView(context.Background(), *collectorURL, readSecret(*secretFile))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+
+		staleHosts = make(map[string]time.Time)
+		for _, host := range view.Hosts {
+			if host.Stale {
+				fmt.Fprintf(os.Stderr, "Warning: %s has not reported since %s\n", host.Name, host.LastReport.Local().Format("2006-01-02 15:04:05"))
+				staleHosts[host.Name] = host.LastReport
+				failedHosts++
+			}
+		}
+		for _, service := range view.Services {
+			if unitType == models.UnitAll || service.UnitType == unitType {
+				serviceList.AddService(service)
+			}
+		}
+	} else {
+		// 2. Create a client per host (just this machine without --host)
+		// and manager
+		clients := listClients(hostOpts, scopeOpts, *managers, *useSudo, *backend)
+		for _, client := range clients {
+			defer client.Close()
+		}
+
+		// 3. Get services from every host at once; resource usage needs the
+		// full status of each unit
+		lists := make([][]*models.ServiceInfo, len(clients))
+		errs := make([]error, len(clients))
+		pool.Run(context.Background(), len(clients), pool.DefaultWorkers, 0, func(ctx context.Context, i int) {
+			if *resources {
+				lists[i], errs[i] = clients[i].ListUnitStatuses(ctx, unitType, nil)
+				return
+			}
+			hostList, err := clients[i].ListUnits(ctx, unitType)
+			if err == nil {
+				lists[i] = hostList.Services
+			}
+			errs[i] = err
+		})
+
+		// Unreachable hosts are reported; the others are still listed
+		for i, err := range errs {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", clientError(clients[i], err))
+				failedHosts++
+				continue
+			}
+			for _, service := range lists[i] {
+				serviceList.AddService(service)
+			}
+		}
+		if failedHosts == len(clients) {
+			os.Exit(2)
+		}
 	}
 
 	// 4. Filter if needed
@@ -95,118 +209,1403 @@ func handleList() {
 
 	if *outputFormat == "json" {
 		output.PrintJSON(serviceList)
+	} else if staleHosts != nil {
+		output.PrintFleetTable(serviceList, staleHosts)
 	} else {
 		output.PrintTable(serviceList)
 	}
-
-	// 6. Exit with code 1 if any failures
-	// if serviceList.HasFailures():
-	//    os.Exit(1)
-
-	if serviceList.HasFailures() {
-		os.Exit(1)
+
+	// 6. Exit with code 1 if any failures
+	// if serviceList.HasFailures():
+	//    os.Exit(1)
+
+	if failedHosts > 0 {
+		os.Exit(2)
+	}
+	if serviceList.HasFailures() {
+		os.Exit(1)
+	}
+}
+
+func handleCheck() {
+	// 1. Parse flags
+	checkCmd := flag.NewFlagSet("check", flag.ExitOnError)
+	useSudo := checkCmd.Bool("sudo", false, "Use sudo")
+	backend := checkCmd.String("backend", "auto", "Backend (auto/dbus/exec)")
+	unitTypeFlag := checkCmd.String("type", "service", "Unit type for names without a suffix")
+	workers := checkCmd.Int("workers", pool.DefaultWorkers, "Number of services checked at once")
+	timeout := checkCmd.Duration("timeout", 10*time.Second, "Give up on a single service after this long (0 = never)")
+	hostOpts := addHostFlags(checkCmd)
+	scopeOpts := addScopeFlags(checkCmd)
+
+	checkCmd.Parse(os.Args[2:])
+
+	unitType := parseUnitTypeFlag(*unitTypeFlag)
+
+	// 2. Get service names from remaining args
+	serviceNames := checkCmd.Args()
+	if len(serviceNames) == 0 {
+		fmt.Println("Error: No services specified")
+		os.Exit(1)
+	}
+
+	// 3. Check the services concurrently on every host, printing by host
+	// and in argument order
+	clients := hostOpts.clients(*useSudo, *backend, scopeOpts)
+	for _, client := range clients {
+		defer client.Close()
+	}
+
+	unitNames := make([]string, 0, len(serviceNames))
+	for _, name := range serviceNames {
+		unitNames = append(unitNames, models.NormalizeUnitName(name, unitType))
+	}
+
+	hasFailures := false
+	results := make([][]systemd.StatusResult, len(clients))
+	hostWorkers, unitWorkers := pool.Split(*workers, len(clients))
+	pool.Run(context.Background(), len(clients), hostWorkers, 0, func(ctx context.Context, i int) {
+		results[i] = clients[i].GetServiceStatuses(ctx, unitNames, systemd.CheckOptions{Workers: unitWorkers, Timeout: *timeout})
+	})
+	for i, hostResults := range results {
+		for _, result := range hostResults {
+			if result.Err != nil {
+				fmt.Printf("Error: %s\n", clientError(clients[i], result.Err))
+				continue
+			}
+			if result.Service.IsFailed() {
+				hasFailures = true
+			}
+			output.PrintService(result.Service)
+		}
+	}
+
+	// 4. Exit with code 1 if any failed
+	// if hasFailures: os.Exit(1)
+	if hasFailures {
+		os.Exit(1)
+	}
+}
+
+func handleDeps() {
+	// 1. Parse flags
+	depsCmd := flag.NewFlagSet("deps", flag.ExitOnError)
+	reverse := depsCmd.Bool("reverse", false, "Show the units depending on the unit instead of its dependencies")
+	depth := depsCmd.Int("depth", 3, "Levels of dependencies to follow (0 = all)")
+	kindsFlag := depsCmd.String("kinds", "all", "Comma-separated dependency kinds (requires/bindsto/partof/wants/after/before/all)")
+	outputFormat := depsCmd.String("output", "tree", "Output format (tree/dot/json)")
+	useSudo := depsCmd.Bool("sudo", false, "Use sudo")
+	backend := depsCmd.String("backend", "auto", "Backend (auto/dbus/exec)")
+	unitTypeFlag := depsCmd.String("type", "service", "Unit type for names without a suffix")
+	scopeOpts := addScopeFlags(depsCmd)
+
+	depsCmd.Parse(os.Args[2:])
+
+	if depsCmd.NArg() != 1 {
+		fmt.Println("Error: deps needs exactly one unit name")
+		fmt.Println("\nUsage: monitor deps [options] <unit>")
+		os.Exit(1)
+	}
+	kinds, err := models.ParseDependencyKinds(*kindsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if *depth < 0 {
+		fmt.Println("Error: --depth must not be negative")
+		os.Exit(1)
+	}
+	switch *outputFormat {
+	case "tree", "dot", "json":
+	default:
+		fmt.Printf("Error: unknown output format %q (use tree, dot or json)\n", *outputFormat)
+		os.Exit(1)
+	}
+	unitName := models.NormalizeUnitName(depsCmd.Arg(0), parseUnitTypeFlag(*unitTypeFlag))
+
+	// 2. Build the graph
+	client := scopeOpts.client(*useSudo, *backend)
+	defer client.Close()
+
+	graph, err := client.GetDependencyGraph(context.Background(), unitName, systemd.DependencyOptions{
+		Kinds:   kinds,
+		Reverse: *reverse,
+		Depth:   *depth,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// 3. Print
+	switch *outputFormat {
+	case "dot":
+		output.PrintDependencyDOT(graph)
+	case "json":
+		output.PrintDependencyJSON(graph)
+	default:
+		output.PrintDependencyTree(graph)
+	}
+
+	// 4. Exit with code 1 if any unit of the graph failed
+	if graph.HasFailures() {
+		os.Exit(1)
+	}
+}
+
+// handleExplain prints why a unit failed: what systemd recorded about its
+// last run, the errors it logged and failed units it requires
+func handleExplain() {
+	// 1. Parse flags
+	explainCmd := flag.NewFlagSet("explain", flag.ExitOnError)
+	lines := explainCmd.Int("lines", 10, "Journal lines to show (0 = none)")
+	window := explainCmd.Duration("window", 5*time.Minute, "Read journal lines this long before and after the failure (0 = any time)")
+	outputFormat := explainCmd.String("output", "text", "Output format (text/json)")
+	useSudo := explainCmd.Bool("sudo", false, "Use sudo")
+	backend := explainCmd.String("backend", "auto", "Backend (auto/dbus/exec)")
+	unitTypeFlag := explainCmd.String("type", "service", "Unit type for names without a suffix")
+	scopeOpts := addScopeFlags(explainCmd)
+
+	explainCmd.Parse(os.Args[2:])
+
+	if explainCmd.NArg() != 1 {
+		fmt.Println("Error: explain needs exactly one unit name")
+		fmt.Println("\nUsage: monitor explain [options] <unit>")
+		os.Exit(1)
+	}
+	if *lines < 0 || *window < 0 {
+		fmt.Println("Error: --lines and --window must not be negative")
+		os.Exit(1)
+	}
+	if *outputFormat != "text" && *outputFormat != "json" {
+		fmt.Printf("Error: unknown output format %q (use text or json)\n", *outputFormat)
+		os.Exit(1)
+	}
+	unitName := models.NormalizeUnitName(explainCmd.Arg(0), parseUnitTypeFlag(*unitTypeFlag))
+
+	// 2. Gather the diagnosis
+	client := scopeOpts.client(*useSudo, *backend)
+	defer client.Close()
+
+	diagnosis, err := diagnose.Explain(context.Background(), client, unitName, diagnose.Options{
+		Lines:  *lines,
+		Window: *window,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// 3. Print
+	if *outputFormat == "json" {
+		output.PrintDiagnosisJSON(diagnosis)
+	} else {
+		output.PrintDiagnosis(diagnosis)
+	}
+
+	// 4. Exit with code 1 if the unit failed
+	if diagnosis.Failed() {
+		os.Exit(1)
+	}
+}
+
+// shutdownTimeout bounds how long a stopping monitor waits for alert
+// deliveries and the agent's last report
+const shutdownTimeout = 15 * time.Second
+
+// handleMonitor runs the monitor loop; as "agent" it also pushes the
+// state of this machine to a collector
+func handleMonitor(command string) {
+	// 1. Parse flags
+	monitorCmd := flag.NewFlagSet(command, flag.ExitOnError)
+	configFile := monitorCmd.String("config", "", "YAML/TOML config file (flags override its values)")
+	services := monitorCmd.String("services", "", "Comma-separated service names")
+	interval := monitorCmd.Duration("interval", 30*time.Second, "Check interval")
+	logFile := monitorCmd.String("log-file", "logs/monitor.log", "Log file path")
+	useSudo := monitorCmd.Bool("sudo", false, "Use sudo")
+	backend := monitorCmd.String("backend", "auto", "Backend (auto/dbus/exec)")
+	unitTypeFlag := monitorCmd.String("type", "service", "Unit type for names without a suffix")
+	changesOnly := monitorCmd.Bool("changes-only", false, "Suppress unchanged status lines; only log transitions")
+	useJournal := monitorCmd.Bool("journal", false, "Also write transition events to the systemd journal")
+	webhookURL := monitorCmd.String("webhook-url", "", "POST alerts as JSON to this URL")
+	smtpAddr := monitorCmd.String("smtp-addr", "", "SMTP server (host:port) for email alerts")
+	smtpFrom := monitorCmd.String("smtp-from", "monitor@localhost", "Sender address for email alerts")
+	smtpTo := monitorCmd.String("smtp-to", "", "Comma-separated recipients for email alerts")
+	smtpUser := monitorCmd.String("smtp-user", "", "SMTP username (password from SYSMON_SMTP_PASSWORD)")
+	alertCommand := monitorCmd.String("alert-command", "", "Shell command run for every alert (MONITOR_* env vars)")
+	alertRetries := monitorCmd.Int("alert-retries", 3, "Delivery attempts per notifier")
+	alertRepeat := monitorCmd.Duration("alert-repeat", 0, "Re-send alerts for still-failed services this often (0 = never)")
+	metricsAddr := monitorCmd.String("metrics-addr", "", "Serve Prometheus metrics on this address, e.g. :9558")
+	metricsInclude := monitorCmd.String("metrics-include", "", "Comma-separated unit globs to export (default all)")
+	metricsExclude := monitorCmd.String("metrics-exclude", "", "Comma-separated unit globs not to export")
+	historyFile := monitorCmd.String("history-file", "logs/history.jsonl", "Record samples and transitions here (\"\" = off)")
+	historyRetention := monitorCmd.Duration("history-retention", 30*24*time.Hour, "Drop history older than this (0 = keep forever)")
+	remediateAction := monitorCmd.String("remediate", "", "Repair failed services: restart, reset-start, command or none")
+	remediateCommand := monitorCmd.String("remediate-command", "", "Shell command for --remediate command (MONITOR_UNIT env var)")
+	workers := monitorCmd.Int("workers", pool.DefaultWorkers, "Number of status checks run at once")
+	checkTimeout := monitorCmd.Duration("check-timeout", 10*time.Second, "Give up on a single status check after this long (0 = never)")
+	hostOpts := addHostFlags(monitorCmd)
+	scopeOpts := addScopeFlags(monitorCmd)
+	var collectorURL, secretFile, agentName *string
+	if command == "agent" {
+		collectorURL = monitorCmd.String("collector", "", "Collector URL to report to, e.g. http://collector:9559 (required)")
+		secretFile = monitorCmd.String("secret-file", "", "File holding the shared secret (or set SYSMON_FLEET_SECRET)")
+		agentName = monitorCmd.String("name", "", "Name this machine reports as (default: its hostname)")
+	}
+
+	monitorCmd.Parse(os.Args[2:])
+
+	// 2. Load the config file, then let explicitly set flags override it;
+	// SIGHUP repeats this while running
+	loadConfig := func() (*config.Config, error) {
+		cfg := config.Default()
+		if *configFile != "" {
+			loaded, err := config.Load(*configFile)
+			if err != nil {
+				return nil, fmt.Errorf("invalid config:\n%w", err)
+			}
+			cfg = loaded
+		}
+
+		monitorCmd.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "services":
+				cfg.Services = nil
+				for _, name := range splitList(*services) {
+					cfg.Services = append(cfg.Services, config.ServiceConfig{Name: name})
+				}
+			case "interval":
+				cfg.Interval = *interval
+			case "log-file":
+				cfg.Logging.File = *logFile
+			case "sudo":
+				cfg.Sudo = *useSudo
+			case "backend":
+				cfg.Backend = *backend
+			case "type":
+				cfg.Type = parseUnitTypeFlag(*unitTypeFlag)
+			case "changes-only":
+				cfg.Logging.ChangesOnly = *changesOnly
+			case "journal":
+				cfg.Logging.Journal = *useJournal
+			case "alert-retries":
+				cfg.Alerts.Retries = *alertRetries
+			case "alert-repeat":
+				cfg.Alerts.Repeat = *alertRepeat
+			case "metrics-addr":
+				cfg.Metrics.Addr = *metricsAddr
+			case "metrics-include":
+				cfg.Metrics.Include = splitList(*metricsInclude)
+			case "metrics-exclude":
+				cfg.Metrics.Exclude = splitList(*metricsExclude)
+			case "history-file":
+				cfg.History.File = *historyFile
+			case "history-retention":
+				cfg.History.Retention.MaxAge = *historyRetention
+			case "remediate-command":
+				cfg.Remediation.Command = *remediateCommand
+			case "workers":
+				cfg.Workers = *workers
+			case "check-timeout":
+				cfg.CheckTimeout = *checkTimeout
+			}
+		})
+
+		// --remediate replaces every service's remediation setting
+		if *remediateAction != "" {
+			var policy *remediate.Policy
+			if *remediateAction != "none" {
+				action, err := remediate.ParseAction(*remediateAction)
+				if err != nil {
+					return nil, err
+				}
+				cfg.Remediation.Action = action
+				if action == remediate.ActionCommand && cfg.Remediation.Command == "" {
+					return nil, fmt.Errorf("--remediate command requires --remediate-command")
+				}
+				policy = &cfg.Remediation
+			}
+			for i := range cfg.Services {
+				cfg.Services[i].Remediation = policy
+			}
+		}
+
+		// 3. Validate services parameter
+		if len(cfg.Services) == 0 {
+			return nil, fmt.Errorf("--services parameter or a config file with services is required")
+		}
+		if cfg.Interval <= 0 {
+			return nil, fmt.Errorf("--interval must be greater than zero")
+		}
+		if cfg.Workers < 1 {
+			return nil, fmt.Errorf("--workers must be at least 1")
+		}
+		return cfg, nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	// 4. Create logger
+	fileLogger, err := logger.NewFileLogger(cfg.Logging.File)
+	if err != nil {
+		fmt.Println("Error creating logger:", err)
+		os.Exit(1)
+	}
+	defer fileLogger.Close()
+
+	var historyStore *history.Store
+	if cfg.History.File != "" {
+		historyStore, err = history.Open(cfg.History.File, cfg.History.Retention)
+		if err != nil {
+			fmt.Println("Error opening history:", err)
+			os.Exit(1)
+		}
+		defer historyStore.Close()
+	}
+
+	// 5. Create the client, or one per remote host; hosts are only read
+	// at startup
+	var client *systemd.Client
+	var remotes []*systemd.Client
+	for _, host := range hostOpts.load(cfg.Sudo) {
+		remote := newRemoteClient(host)
+		remote.SetScope(scopeOpts.scope(host.Sudo, host.Target.User))
+		remotes = append(remotes, remote)
+	}
+	if len(remotes) == 0 {
+		client = scopeOpts.client(cfg.Sudo, cfg.Backend)
+		defer client.Close()
+	}
+
+	// The agent reports this machine's state to a collector
+	var agent *fleet.Agent
+	if command == "agent" {
+		agent = newAgent(*collectorURL, *agentName, *secretFile, len(remotes) > 0)
+		agent.OnError(func(err error) {
+			if err == nil {
+				fileLogger.Info("Reporting to the collector again")
+				fmt.Println("Reporting to the collector again")
+				return
+			}
+			fileLogger.Error(err)
+			fmt.Println("Error:", err)
+		})
+	}
+
+	// 6. Notifiers given as flags form an extra route for every service.
+	// Route names in config files cannot be empty, so "" never collides.
+	const flagRoute = ""
+	flagRetry := notify.DefaultRetryPolicy()
+	flagRetry.MaxAttempts = cfg.Alerts.Retries
+	flagDispatcher := notify.NewDispatcher(flagRetry, cfg.Alerts.Repeat)
+	if *webhookURL != "" {
+		flagDispatcher.AddNotifier(notify.NewWebhookNotifier(*webhookURL, nil))
+	}
+	if *smtpAddr != "" {
+		flagDispatcher.AddNotifier(notify.NewSMTPNotifier(*smtpAddr, *smtpFrom, splitList(*smtpTo), *smtpUser, os.Getenv("SYSMON_SMTP_PASSWORD")))
+	}
+	if *alertCommand != "" {
+		flagDispatcher.AddNotifier(notify.NewCommandNotifier(*alertCommand))
+	}
+	flagDispatcher.OnError(func(notifier string, alert *notify.Alert, err error) {
+		fileLogger.Error(fmt.Errorf("%s notifier (route flags) failed for %s: %w", notifier, alert.Key, err))
+	})
+
+	// 7. Expose what the monitor sees to Prometheus
+	var exporter *metrics.Exporter
+	if cfg.Metrics.Addr != "" {
+		exporter = metrics.NewExporter(metrics.Filter{Include: cfg.Metrics.Include, Exclude: cfg.Metrics.Exclude})
+		go serveMetrics(cfg.Metrics.Addr, exporter)
+	}
+
+	// 8. Turn a config into the watch list and monitor options
+	notifier := daemon.FromEnv()
+	var remediator *remediate.Engine
+	var dispatchers map[string]*notify.Dispatcher // of the running config, by route name
+	build := func(cfg *config.Config) ([]monitor.Target, monitor.Options) {
+		var journalLogger *logger.JournalLogger
+		if cfg.Logging.Journal && logger.IsJournalAvailable() {
+			journalLogger = logger.NewJournalLogger("monitor")
+		}
+
+		// Each route has its own dispatcher so dedup and repeat state are
+		// tracked per destination. Routes that survive a reload keep their
+		// dispatcher, so firing alerts are neither re-sent nor forgotten;
+		// the monitor retires the dispatchers of removed routes.
+		retry := notify.DefaultRetryPolicy()
+		retry.MaxAttempts = cfg.Alerts.Retries
+
+		routes := make(map[string]*notify.Dispatcher)
+		for i := range cfg.Alerts.Routes {
+			route := &cfg.Alerts.Routes[i]
+			dispatcher, ok := dispatchers[route.Name]
+			if ok {
+				dispatcher.Reconfigure(retry, cfg.Alerts.Repeat, route.Notifier())
+			} else {
+				dispatcher = notify.NewDispatcher(retry, cfg.Alerts.Repeat, route.Notifier())
+				routeName := route.Name
+				dispatcher.OnError(func(notifier string, alert *notify.Alert, err error) {
+					fileLogger.Error(fmt.Errorf("%s notifier (route %s) failed for %s: %w", notifier, routeName, alert.Key, err))
+				})
+			}
+			routes[route.Name] = dispatcher
+		}
+		dispatchers = routes
+		if flagDispatcher.HasNotifiers() {
+			routes[flagRoute] = flagDispatcher
+		}
+
+		targets := make([]monitor.Target, 0, len(cfg.Services))
+		for _, svc := range cfg.Services {
+			target := monitor.Target{
+				Interval:    svc.Interval,
+				Expected:    svc.Expected,
+				Routes:      svc.Alerts,
+				MaxRestarts: svc.Thresholds.MaxRestarts,
+				MinUptime:   svc.Thresholds.MinUptime,
+				Rules:       svc.Rules,
+				Probes:      svc.Probes,
+				Remediation: svc.Remediation,
+			}
+			if svc.Pattern != "" {
+				target.Pattern = svc.UnitPattern(cfg.Type)
+				target.Type = svc.UnitType(cfg.Type)
+			} else {
+				target.Name = svc.UnitName(cfg.Type)
+			}
+
+			// Services without their own routes use the default routes, or
+			// every configured route when no defaults are set
+			if target.Routes == nil {
+				target.Routes = cfg.Alerts.DefaultRoutes
+				if len(target.Routes) == 0 {
+					for _, route := range cfg.Alerts.Routes {
+						target.Routes = append(target.Routes, route.Name)
+					}
+				}
+			}
+			if _, ok := routes[flagRoute]; ok {
+				target.Routes = append(append([]string(nil), target.Routes...), flagRoute)
+			}
+
+			targets = append(targets, target)
+		}
+
+		// With remote hosts every service is watched on each of them
+		if len(remotes) > 0 {
+			local := targets
+			targets = make([]monitor.Target, 0, len(local)*len(remotes))
+			for _, remote := range remotes {
+				for _, target := range local {
+					target.Client = remote
+					targets = append(targets, target)
+				}
+			}
+		}
+
+		// Repair failed services; the audit trail always goes to the
+		// journal when it is available, even without --journal. The
+		// engine survives reloads so backoff state is kept.
+		for _, target := range targets {
+			if target.Remediation != nil && remediator == nil {
+				auditJournal := journalLogger
+				if auditJournal == nil && logger.IsJournalAvailable() {
+					auditJournal = logger.NewJournalLogger("monitor")
+				}
+				remediator = remediate.NewEngine(client, fileLogger, auditJournal)
+				for _, remote := range remotes {
+					remediator.AddRemote(remote)
+				}
+			}
+		}
+
+		return targets, monitor.Options{
+			Interval:      cfg.Interval,
+			ChangesOnly:   cfg.Logging.ChangesOnly,
+			FileLogger:    fileLogger,
+			JournalLogger: journalLogger,
+			History:       historyStore,
+			Routes:        routes,
+			Metrics:       exporter,
+			Remediator:    remediator,
+			Rules:         cfg.Rules,
+			Workers:       cfg.Workers,
+			CheckTimeout:  cfg.CheckTimeout,
+			Notifier:      notifier,
+			Agent:         agent,
+		}
+	}
+
+	targets, opts := build(cfg)
+	mon := monitor.New(client, targets, opts)
+
+	// The watchdog is pinged once per pass, so it must outlast a few
+	if watchdog := notifier.WatchdogInterval(); watchdog > 0 && watchdog < 2*mon.TickInterval() {
+		fmt.Printf("Warning: WatchdogSec=%s is shorter than two %s check intervals; systemd will restart the monitor\n", watchdog, mon.TickInterval())
+	}
+
+	// 9. Stop on SIGINT/SIGTERM; SIGHUP reloads the config and reopens the
+	// log file (for logrotate)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	go func() {
+		current := cfg
+		for range hup {
+			notifier.Reloading()
+
+			reloaded, err := loadConfig()
+			if err != nil {
+				fileLogger.Error(fmt.Errorf("reload failed, keeping the running config: %w", err))
+				fmt.Println("Reload failed, keeping the running config:", err)
+				notifier.Ready("Reload failed, running the previous config")
+				continue
+			}
+
+			if err := fileLogger.Reopen(reloaded.Logging.File); err != nil {
+				fmt.Println("Error reopening log file:", err)
+			}
+			for _, setting := range restartOnlyChanges(current, reloaded) {
+				fileLogger.Warn(fmt.Sprintf("%s changed; restart the monitor to apply it", setting))
+				fmt.Printf("Warning: %s changed; restart the monitor to apply it\n", setting)
+			}
+
+			targets, opts := build(reloaded)
+			mon.Reload(targets, opts)
+			current = reloaded
+
+			fileLogger.Info(fmt.Sprintf("Reloaded configuration: %d services", len(targets)))
+			fmt.Printf("Reloaded configuration: %d services\n", len(targets))
+			notifier.Ready(fmt.Sprintf("Reloaded, watching %d services", len(targets)))
+		}
+	}()
+
+	// 10. Loop until stopped; the agent sends its last report on the way out
+	agentDone := make(chan struct{})
+	if agent != nil {
+		go func() {
+			agent.Run(ctx)
+			close(agentDone)
+		}()
+		fmt.Printf("Monitoring services, reporting to %s as %s. Press Ctrl+C to stop...\n", *collectorURL, agent.Host())
+	} else {
+		close(agentDone)
+		if len(remotes) > 0 {
+			fmt.Printf("Monitoring services on %d hosts. Press Ctrl+C to stop...\n", len(remotes))
+		} else {
+			fmt.Println("Monitoring services. Press Ctrl+C to stop...")
+		}
+	}
+	notifier.Ready(fmt.Sprintf("Watching %d services", len(targets)))
+	mon.Run(ctx)
+	stop() // a second Ctrl+C kills right away
+	notifier.Stopping("Stopping, " + mon.Summary())
+
+	// 11. Let queued alerts and reports go out and leave a summary in the log
+	fmt.Println("\nStopping monitor, waiting for alert deliveries...")
+	drained := make(chan struct{})
+	go func() {
+		mon.Wait()
+		<-agentDone
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(shutdownTimeout):
+		// A hung notifier must not keep the process from exiting
+		fileLogger.Warn(fmt.Sprintf("Gave up waiting for alert deliveries after %s", shutdownTimeout))
+		fmt.Printf("Gave up waiting for alert deliveries after %s\n", shutdownTimeout)
+	}
+
+	stats := mon.Stats()
+	fileLogger.Info("Monitor stopped, " + stats.String())
+	fmt.Println("Monitor stopped,", stats)
+}
+
+// restartOnlyChanges lists the settings that differ between two configs
+// but are only read at startup
+func restartOnlyChanges(running, reloaded *config.Config) []string {
+	var changed []string
+	if running.Sudo != reloaded.Sudo {
+		changed = append(changed, "sudo")
+	}
+	if running.Backend != reloaded.Backend {
+		changed = append(changed, "backend")
+	}
+	if running.History.File != reloaded.History.File || running.History.Retention != reloaded.History.Retention {
+		changed = append(changed, "history")
+	}
+	if running.Metrics.Addr != reloaded.Metrics.Addr ||
+		strings.Join(running.Metrics.Include, ",") != strings.Join(reloaded.Metrics.Include, ",") ||
+		strings.Join(running.Metrics.Exclude, ",") != strings.Join(reloaded.Metrics.Exclude, ",") {
+		changed = append(changed, "metrics")
+	}
+	return changed
+}
+
+func handleDashboard() {
+	// 1. Parse flags
+	dashboardCmd := flag.NewFlagSet("dashboard", flag.ExitOnError)
+	configFile := dashboardCmd.String("config", "", "YAML/TOML config file; its services form the watchlist")
+	services := dashboardCmd.String("services", "", "Comma-separated watchlist")
+	interval := dashboardCmd.Duration("interval", 2*time.Second, "Refresh interval")
+	lines := dashboardCmd.Int("lines", 100, "Log lines shown for the selected unit")
+	actionTimeout := dashboardCmd.Duration("action-timeout", 30*time.Second, "Wait for restart/stop jobs")
+	useSudo := dashboardCmd.Bool("sudo", false, "Use sudo")
+	backend := dashboardCmd.String("backend", "auto", "Backend (auto/dbus/exec)")
+	unitTypeFlag := dashboardCmd.String("type", "service", "Unit type to list (service/timer/socket/.../all)")
+	scopeOpts := addScopeFlags(dashboardCmd)
+
+	dashboardCmd.Parse(os.Args[2:])
+
+	// 2. Take the watchlist and client settings from the config file,
+	// letting explicitly set flags override them
+	cfg := config.Default()
+	if *configFile != "" {
+		loaded, err := config.Load(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error in config:\n%v\n", err)
+			os.Exit(1)
+		}
+		cfg = loaded
+	}
+
+	// Pattern entries have no fixed unit to watch
+	watchlist := make([]string, 0, len(cfg.Services))
+	for _, svc := range cfg.Services {
+		if svc.Pattern == "" {
+			watchlist = append(watchlist, svc.UnitName(cfg.Type))
+		}
+	}
+
+	dashboardCmd.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "services":
+			watchlist = splitList(*services)
+		case "sudo":
+			cfg.Sudo = *useSudo
+		case "backend":
+			cfg.Backend = *backend
+		case "type":
+			cfg.Type = parseUnitTypeFlag(*unitTypeFlag)
+		}
+	})
+
+	if *interval <= 0 {
+		fmt.Println("Error: --interval must be greater than zero")
+		os.Exit(1)
+	}
+
+	// 3. Create client
+	client := scopeOpts.client(cfg.Sudo, cfg.Backend)
+	defer client.Close()
+
+	// 4. Run until the user quits
+	dash := dashboard.New(client, dashboard.Options{
+		UnitType:      cfg.Type,
+		Watchlist:     watchlist,
+		Interval:      *interval,
+		LogLines:      *lines,
+		ActionTimeout: *actionTimeout,
+	})
+	if err := dash.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleHistory() {
+	// 1. Parse flags
+	historyCmd := flag.NewFlagSet("history", flag.ExitOnError)
+	file := historyCmd.String("file", "logs/history.jsonl", "History file written by the monitor command")
+	since := historyCmd.String("since", "24h", "Start of the range (e.g. 24h, 7d, 2024-12-22, \"2024-12-22 15:04\")")
+	until := historyCmd.String("until", "now", "End of the range")
+	samples := historyCmd.Bool("samples", false, "Include status samples, not only events")
+	outputFormat := historyCmd.String("output", "table", "Output format (table/json)")
+	unitTypeFlag := historyCmd.String("type", "service", "Unit type for names without a suffix")
+	host := historyCmd.String("host", "", "Show the unit of this remote host (its name in the monitor's output)")
+	scopeOpts := addScopeFlags(historyCmd)
+
+	historyCmd.Parse(os.Args[2:])
+
+	if historyCmd.NArg() != 1 {
+		fmt.Println("Error: history needs exactly one service name")
+		os.Exit(1)
+	}
+	unit := models.NormalizeUnitName(historyCmd.Arg(0), parseUnitTypeFlag(*unitTypeFlag))
+	user := scopeOpts.scope(false, "").User
+
+	// 2. Resolve the time range
+	now := time.Now()
+	from, err := history.ParseTime(*since, now)
+	if err != nil {
+		fmt.Println("Error: --since:", err)
+		os.Exit(1)
+	}
+	to, err := history.ParseTime(*until, now)
+	if err != nil {
+		fmt.Println("Error: --until:", err)
+		os.Exit(1)
+	}
+
+	// 3. Query the store
+	query := history.Query{Unit: unit, Host: *host, User: user, Since: from, Until: to}
+	if !*samples {
+		query.Kind = history.KindEvent
+	}
+	records, err := history.Read(*file, query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// 4. Print
+	if *outputFormat == "json" {
+		output.PrintHistoryJSON(records)
+	} else {
+		output.PrintHistory(models.UnitKey(*host, models.ScopedUnit(user, unit)), from, to, records)
+	}
+}
+
+func handleReport() {
+	// 1. Parse flags
+	reportCmd := flag.NewFlagSet("report", flag.ExitOnError)
+	since := reportCmd.String("since", "30d", "Start of the window (e.g. 24h, 30d, 2024-12-01)")
+	until := reportCmd.String("until", "now", "End of the window")
+	month := reportCmd.String("month", "", "Report a calendar month, e.g. 2024-12 (overrides --since/--until)")
+	source := reportCmd.String("source", "auto", "Data source (auto/history/journal)")
+	file := reportCmd.String("file", "logs/history.jsonl", "History file written by the monitor command")
+	maxGap := reportCmd.Duration("max-gap", report.DefaultMaxGap, "Longest gap between history samples still counted as observed")
+	outputFormat := reportCmd.String("output", "table", "Output format (table/json/csv/html)")
+	useSudo := reportCmd.Bool("sudo", false, "Use sudo for journalctl")
+	backend := reportCmd.String("backend", "auto", "Backend (auto/dbus/exec)")
+	unitTypeFlag := reportCmd.String("type", "service", "Unit type for names without a suffix")
+	host := reportCmd.String("host", "", "Report the units of this remote host from the history (its name in the monitor's output)")
+	scopeOpts := addScopeFlags(reportCmd)
+
+	reportCmd.Parse(os.Args[2:])
+
+	// Only the history knows remote hosts; the journal is this machine's
+	if *host != "" && *source == "journal" {
+		fmt.Println("Error: --host needs the history source")
+		os.Exit(1)
+	}
+
+	switch *source {
+	case "auto", "history", "journal":
+	default:
+		fmt.Printf("Error: unknown source %q (use auto, history or journal)\n", *source)
+		os.Exit(1)
+	}
+	switch *outputFormat {
+	case "table", "json", "csv", "html":
+	default:
+		fmt.Printf("Error: unknown output format %q (use table, json, csv or html)\n", *outputFormat)
+		os.Exit(1)
+	}
+
+	user := scopeOpts.scope(false, "").User
+
+	// 2. Resolve the window
+	now := time.Now()
+	var from, to time.Time
+	if *month != "" {
+		start, err := time.ParseInLocation("2006-01", *month, time.Local)
+		if err != nil {
+			fmt.Printf("Error: --month must look like 2024-12, got %q\n", *month)
+			os.Exit(1)
+		}
+		from, to = start, start.AddDate(0, 1, 0)
+	} else {
+		var err error
+		if from, err = history.ParseTime(*since, now); err != nil {
+			fmt.Println("Error: --since:", err)
+			os.Exit(1)
+		}
+		if to, err = history.ParseTime(*until, now); err != nil {
+			fmt.Println("Error: --until:", err)
+			os.Exit(1)
+		}
+	}
+	if to.After(now) {
+		to = now
+	}
+	if !to.After(from) {
+		fmt.Println("Error: the report window is empty")
+		os.Exit(1)
+	}
+
+	// 3. Load the history; an observation shortly before the window tells
+	// the status at its start
+	var records []*history.Record
+	if *source != "journal" {
+		var err error
+		records, err = history.Read(*file, history.Query{Host: *host, User: user, Since: from.Add(-*maxGap), Until: to})
+		if err != nil && (*source == "history" || !os.IsNotExist(err)) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	recorded := make(map[string]bool)
+	var recordedUnits []string
+	for _, record := range records {
+		if !recorded[record.Unit] {
+			recorded[record.Unit] = true
+			recordedUnits = append(recordedUnits, record.Unit)
+		}
+	}
+
+	// 4. Units: the arguments, or everything in the history
+	unitType := parseUnitTypeFlag(*unitTypeFlag)
+	var units []string
+	for _, name := range reportCmd.Args() {
+		units = append(units, models.NormalizeUnitName(name, unitType))
+	}
+	if len(units) == 0 {
+		units = recordedUnits
+	}
+	if len(units) == 0 {
+		fmt.Println("Error: no services given and no history recorded in this window")
+		os.Exit(1)
+	}
+
+	// 5. Reconstruct each unit's timeline
+	var client *systemd.Client
+	var reports []*report.UnitReport
+	for _, unit := range units {
+		var timeline *report.Timeline
+		if *source == "history" || *host != "" || (*source == "auto" && recorded[unit]) {
+			timeline = report.FromHistory(unit, records, from, to, *maxGap)
+		} else {
+			if client == nil {
+				client = scopeOpts.client(*useSudo, *backend)
+				defer client.Close()
+			}
+			before, err := client.GetManagerLogs(context.Background(), unit, &models.LogOptions{Until: from.Format("2006-01-02 15:04:05"), Lines: 50})
+			if err == nil {
+				var entries []*models.LogEntry
+				entries, err = client.GetManagerLogs(context.Background(), unit, &models.LogOptions{
+					Since: from.Format("2006-01-02 15:04:05"),
+					Until: to.Format("2006-01-02 15:04:05"),
+				})
+				timeline = report.FromJournal(unit, before, entries, from, to)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(2)
+			}
+		}
+		reports = append(reports, report.Compute(timeline))
+	}
+
+	// 6. Print
+	switch *outputFormat {
+	case "json":
+		output.PrintReportJSON(reports)
+	case "csv":
+		output.PrintReportCSV(reports)
+	case "html":
+		output.PrintReportHTML(reports, from, to)
+	default:
+		output.PrintReportTable(reports, from, to)
+	}
+}
+
+func handleServeMetrics() {
+	// 1. Parse flags
+	metricsCmd := flag.NewFlagSet("serve-metrics", flag.ExitOnError)
+	addr := metricsCmd.String("addr", ":9558", "Listen address")
+	units := metricsCmd.String("units", "", "Comma-separated units to export (default: every unit of --type)")
+	include := metricsCmd.String("include", "", "Comma-separated unit globs to export (default all)")
+	exclude := metricsCmd.String("exclude", "", "Comma-separated unit globs not to export")
+	useSudo := metricsCmd.Bool("sudo", false, "Use sudo")
+	backend := metricsCmd.String("backend", "auto", "Backend (auto/dbus/exec)")
+	unitTypeFlag := metricsCmd.String("type", "service", "Unit type to export (service/timer/socket/.../all)")
+	scopeOpts := addScopeFlags(metricsCmd)
+
+	metricsCmd.Parse(os.Args[2:])
+
+	unitType := parseUnitTypeFlag(*unitTypeFlag)
+	var unitNames []string
+	for _, name := range splitList(*units) {
+		unitNames = append(unitNames, models.NormalizeUnitName(name, unitType))
+	}
+
+	// 2. Create client
+	client := scopeOpts.client(*useSudo, *backend)
+	defer client.Close()
+
+	// 3. Refresh on every scrape
+	filter := metrics.Filter{Include: splitList(*include), Exclude: splitList(*exclude)}
+	exporter := metrics.NewExporter(filter)
+	exporter.SetRefresh(func() ([]*models.ServiceInfo, error) {
+		if len(unitNames) == 0 {
+			services, err := client.ListUnitStatuses(context.Background(), unitType, filter.Match)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			return services, err
+		}
+
+		services := make([]*models.ServiceInfo, 0, len(unitNames))
+		for _, result := range client.GetServiceStatuses(context.Background(), unitNames, systemd.CheckOptions{}) {
+			if result.Err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", result.Err)
+				exporter.ObserveError("", client.Scope().User, result.Name)
+				continue
+			}
+			services = append(services, result.Service)
+		}
+		return services, nil
+	})
+
+	// 4. Serve until killed
+	fmt.Printf("Serving metrics on http://%s/metrics (Ctrl+C to stop)...\n", *addr)
+	serveMetrics(*addr, exporter)
+}
+
+// serveMetrics serves exporter on addr or exits
+func serveMetrics(addr string, exporter *metrics.Exporter) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `<html><body><h1>systemd-monitoring</h1><a href="/metrics">Metrics</a></body></html>`)
+	})
+
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "Error serving metrics: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+func handleServe() {
+	// 1. Parse flags
+	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := serveCmd.String("addr", "127.0.0.1:8080", "Listen address")
+	tokenFile := serveCmd.String("token-file", "", "File holding the bearer token (or set SYSMON_API_TOKEN)")
+	readOnly := serveCmd.Bool("read-only", false, "Refuse start/stop/restart/... requests (always without a token)")
+	actionTimeout := serveCmd.Duration("action-timeout", 30*time.Second, "Default wait for action jobs")
+	tlsCert := serveCmd.String("tls-cert", "", "TLS certificate file (enables HTTPS with --tls-key)")
+	tlsKey := serveCmd.String("tls-key", "", "TLS private key file")
+	useSudo := serveCmd.Bool("sudo", false, "Use sudo")
+	backend := serveCmd.String("backend", "auto", "Backend (auto/dbus/exec)")
+	scopeOpts := addScopeFlags(serveCmd)
+
+	serveCmd.Parse(os.Args[2:])
+
+	// 2. Read the token; never from a flag, which would show up in ps
+	token := os.Getenv("SYSMON_API_TOKEN")
+	if *tokenFile != "" {
+		data, err := os.ReadFile(*tokenFile)
+		if err != nil {
+			fmt.Println("Error reading token file:", err)
+			os.Exit(1)
+		}
+		token = strings.TrimSpace(string(data))
+		if token == "" {
+			fmt.Println("Error: token file is empty")
+			os.Exit(1)
+		}
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		fmt.Println("Error: --tls-cert and --tls-key must be given together")
+		os.Exit(1)
+	}
+
+	// 3. Create client and server
+	client := scopeOpts.client(*useSudo, *backend)
+	defer client.Close()
+
+	server := api.NewServer(client, api.Options{
+		Token:         token,
+		ReadOnly:      *readOnly,
+		ActionTimeout: *actionTimeout,
+	})
+
+	if token == "" {
+		fmt.Println("⚠️  No token configured: serving read-only, and anyone who can reach", *addr, "can read the API")
+	}
+	mode := "read-write"
+	if server.ReadOnly() {
+		mode = "read-only"
+	}
+	scheme := "http"
+	if *tlsCert != "" {
+		scheme = "https"
+	}
+	fmt.Printf("Serving API (%s) on %s://%s/api/v1 (Ctrl+C to stop)...\n", mode, scheme, *addr)
+
+	// 4. Serve until killed; no write timeout so log streams stay open
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	var err error
+	if *tlsCert != "" {
+		err = httpServer.ListenAndServeTLS(*tlsCert, *tlsKey)
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error serving API: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+func handleCollector() {
+	// 1. Parse flags
+	collectorCmd := flag.NewFlagSet("collector", flag.ExitOnError)
+	addr := collectorCmd.String("addr", ":9559", "Listen address")
+	secretFile := collectorCmd.String("secret-file", "", "File holding the shared secret (or set SYSMON_FLEET_SECRET)")
+	stale := collectorCmd.Duration("stale", 0, "Mark hosts stale after this long without a report (default: three report intervals)")
+	logFile := collectorCmd.String("log-file", "logs/collector.log", "Log file path")
+	historyFile := collectorCmd.String("history-file", "logs/fleet-history.jsonl", "Record every host's samples and transitions here (\"\" = off)")
+	historyRetention := collectorCmd.Duration("history-retention", 30*24*time.Hour, "Drop history older than this (0 = keep forever)")
+	tlsCert := collectorCmd.String("tls-cert", "", "TLS certificate file (enables HTTPS with --tls-key)")
+	tlsKey := collectorCmd.String("tls-key", "", "TLS private key file")
+
+	collectorCmd.Parse(os.Args[2:])
+
+	secret := readSecret(*secretFile)
+	if (*tlsCert == "") != (*tlsKey == "") {
+		fmt.Println("Error: --tls-cert and --tls-key must be given together")
+		os.Exit(1)
+	}
+
+	// 2. Open the log and history
+	fileLogger, err := logger.NewFileLogger(*logFile)
+	if err != nil {
+		fmt.Println("Error creating logger:", err)
+		os.Exit(1)
+	}
+	defer fileLogger.Close()
+
+	var historyStore *history.Store
+	if *historyFile != "" {
+		retention := history.DefaultRetention()
+		retention.MaxAge = *historyRetention
+		historyStore, err = history.Open(*historyFile, retention)
+		if err != nil {
+			fmt.Println("Error opening history:", err)
+			os.Exit(1)
+		}
+		defer historyStore.Close()
+	}
+
+	// 3. Serve until SIGINT/SIGTERM
+	collector := fleet.NewCollector(fleet.CollectorOptions{
+		Secret:     secret,
+		Stale:      *stale,
+		FileLogger: fileLogger,
+		History:    historyStore,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go collector.Run(ctx)
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           collector,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		if *tlsCert != "" {
+			serveErr <- httpServer.ListenAndServeTLS(*tlsCert, *tlsKey)
+		} else {
+			serveErr <- httpServer.ListenAndServe()
+		}
+	}()
+
+	scheme := "http"
+	if *tlsCert != "" {
+		scheme = "https"
+	}
+	fmt.Printf("Collecting reports on %s://%s%s (Ctrl+C to stop)...\n", scheme, *addr, fleet.ReportsPath)
+	fileLogger.Info("Collector started on " + *addr)
+
+	select {
+	case err := <-serveErr:
+		fmt.Fprintf(os.Stderr, "Error serving collector: %v\n", err)
+		fileLogger.Error(err)
+		return
+	case <-ctx.Done():
+	}
+
+	// 4. Let requests in flight finish
+	stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	httpServer.Shutdown(shutdownCtx)
+	fileLogger.Info("Collector stopped")
+	fmt.Println("\nCollector stopped")
+}
+
+func handleConfig() {
+	// 1. Parse subcommand
+	if len(os.Args) < 3 || os.Args[2] != "validate" {
+		fmt.Println("Usage: monitor config validate <file>")
+		os.Exit(1)
+	}
+
+	configCmd := flag.NewFlagSet("config validate", flag.ExitOnError)
+	configFile := configCmd.String("config", "", "Config file to validate")
+
+	configCmd.Parse(os.Args[3:])
+
+	if *configFile == "" && configCmd.NArg() > 0 {
+		*configFile = configCmd.Arg(0)
+	}
+	if *configFile == "" {
+		fmt.Println("Error: No config file specified")
+		fmt.Println("\nUsage: monitor config validate <file>")
+		os.Exit(1)
+	}
+
+	// 2. Load and validate
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		if errs, ok := err.(config.Errors); ok {
+			for _, e := range errs {
+				fmt.Printf("❌ %s\n", e)
+			}
+			fmt.Printf("\n%d problem(s) found\n", len(errs))
+		} else {
+			fmt.Printf("❌ %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	// 3. Summary
+	patterns := 0
+	for _, svc := range cfg.Services {
+		if svc.Pattern != "" {
+			patterns++
+		}
+	}
+	fmt.Printf("✅ %s is valid\n", *configFile)
+	fmt.Printf("   Services: %d (%d patterns)\n", len(cfg.Services), patterns)
+	fmt.Printf("   Alert routes: %d\n", len(cfg.Alerts.Routes))
+	remediated := 0
+	for _, svc := range cfg.Services {
+		if svc.Remediation != nil {
+			remediated++
+		}
+	}
+	fmt.Printf("   Remediation: %d service(s)\n", remediated)
+	probeCount := 0
+	for _, svc := range cfg.Services {
+		probeCount += len(svc.Probes)
+	}
+	if probeCount > 0 {
+		fmt.Printf("   Health probes: %d\n", probeCount)
+	}
+	ruleCount := len(cfg.Rules)
+	for _, svc := range cfg.Services {
+		ruleCount += len(svc.Rules)
+	}
+	if ruleCount > 0 {
+		fmt.Printf("   Rules: %d\n", ruleCount)
+		for _, rule := range cfg.Rules {
+			fmt.Printf("     - %s\n", rule)
+		}
+		for _, svc := range cfg.Services {
+			name := svc.Name
+			if svc.Pattern != "" {
+				name = svc.Pattern
+			}
+			for _, rule := range svc.Rules {
+				fmt.Printf("     - %s: %s\n", name, rule)
+			}
+		}
+	}
+	fmt.Printf("   Interval: %s\n", cfg.Interval)
+	fmt.Printf("   Workers: %d (check timeout %s)\n", cfg.Workers, cfg.CheckTimeout)
+	fmt.Printf("   Log file: %s\n", cfg.Logging.File)
+	if cfg.History.File != "" {
+		fmt.Printf("   History: %s (kept %s)\n", cfg.History.File, cfg.History.Retention.MaxAge)
 	}
 }
 
-func handleCheck() {
+func handleInstallUnit() {
 	// 1. Parse flags
-	checkCmd := flag.NewFlagSet("check", flag.ExitOnError)
-	useSudo := checkCmd.Bool("sudo", false, "Use sudo")
+	installCmd := flag.NewFlagSet("install-unit", flag.ExitOnError)
+	configFile := installCmd.String("config", "/etc/systemd-monitoring/config.yaml", "Config file the service runs with")
+	name := installCmd.String("name", "systemd-monitoring", "Unit name")
+	outputPath := installCmd.String("output", "", "Unit file path (default /etc/systemd/system/<name>.service, - = stdout)")
+	binary := installCmd.String("binary", "", "Monitor binary (default: this executable)")
+	force := installCmd.Bool("force", false, "Overwrite an existing unit file")
 
-	checkCmd.Parse(os.Args[2:])
+	installCmd.Parse(os.Args[2:])
 
-	// 2. Get service names from remaining args
-	serviceNames := checkCmd.Args()
-	if len(serviceNames) == 0 {
-		fmt.Println("Error: No services specified")
-		os.Exit(1)
+	*name = strings.TrimSuffix(*name, ".service")
+	if *outputPath == "" {
+		*outputPath = "/etc/systemd/system/" + *name + ".service"
 	}
 
-	// 3. Check each service
-	client := systemd.NewClient(*useSudo)
-	hasFailures := false
-	for _, name := range serviceNames {
-		service, err := client.GetServiceStatus(name)
+	// 2. Resolve absolute paths; systemd does not search PATH for us
+	if *binary == "" {
+		exe, err := os.Executable()
 		if err != nil {
-			fmt.Printf("Error: %s\n", err)
-			continue
-		}
-		if service.IsFailed() {
-			hasFailures = true
+			fmt.Fprintf(os.Stderr, "Error: cannot find the monitor binary, use --binary: %v\n", err)
+			os.Exit(1)
 		}
-		output.PrintService(service)
+		*binary = exe
 	}
-
-	// 4. Exit with code 1 if any failed
-	// if hasFailures: os.Exit(1)
-	if hasFailures {
+	binaryPath, err := filepath.Abs(*binary)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	configPath, err := filepath.Abs(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-}
 
-func handleMonitor() {
-	// 1. Parse flags
-	monitorCmd := flag.NewFlagSet("monitor", flag.ExitOnError)
-	services := monitorCmd.String("services", "", "Comma-separated service names")
-	interval := monitorCmd.Duration("interval", 30*time.Second, "Check interval")
-	logFile := monitorCmd.String("log-file", "logs/monitor.log", "Log file path")
-	useSudo := monitorCmd.Bool("sudo", false, "Use sudo")
+	// 3. The config decides the watchdog timeout and which paths outside
+	// the sandbox need to stay writable; without one the defaults apply
+	cfg := config.Default()
+	if loaded, err := config.Load(configPath); err == nil {
+		cfg = loaded
+	} else if !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Error in config:\n%v\n", err)
+		os.Exit(1)
+	} else {
+		fmt.Fprintf(os.Stderr, "Note: %s does not exist yet; using default intervals\n", configPath)
+	}
 
-	monitorCmd.Parse(os.Args[2:])
+	tick := cfg.Interval
+	for _, svc := range cfg.Services {
+		if svc.Interval > 0 && svc.Interval < tick {
+			tick = svc.Interval
+		}
+	}
 
-	// 2. Validate services parameter
-	if *services == "" {
-		fmt.Println("Error: --services parameter is required")
-		os.Exit(1)
+	var writable []string
+	for _, file := range []string{cfg.Logging.File, cfg.History.File} {
+		if filepath.IsAbs(file) {
+			// "-" = skip the path when it does not exist
+			writable = append(writable, "-"+filepath.Dir(file))
+		}
 	}
 
-	// 3. Parse service list
-	serviceList := strings.Split(*services, ",")
+	unit := daemon.Unit(daemon.UnitOptions{
+		Description:    "systemd service monitor",
+		Directory:      "systemd-monitoring",
+		Binary:         binaryPath,
+		Args:           []string{"monitor", "--config", configPath},
+		Watchdog:       daemon.WatchdogFor(tick, cfg.CheckTimeout),
+		ReadWritePaths: writable,
+	})
 
-	// 4. Create logger
-	fileLogger, err := logger.NewFileLogger(*logFile)
-	if err != nil {
-		fmt.Println("Error creating logger:", err)
+	// 4. Write it
+	if *outputPath == "-" {
+		fmt.Print(unit)
+		return
+	}
+	if _, err := os.Stat(*outputPath); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists (use --force to overwrite)\n", *outputPath)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*outputPath, []byte(unit), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	defer fileLogger.Close()
 
-	// 5. Create client
-	client := systemd.NewClient(*useSudo)
+	fmt.Printf("✅ Wrote %s\n", *outputPath)
+	fmt.Println("\nNext steps:")
+	fmt.Println("  systemctl daemon-reload")
+	fmt.Printf("  systemctl enable --now %s\n", *name)
+	fmt.Printf("  systemctl status %s      # shows the monitor's status line\n", *name)
+	fmt.Printf("  systemctl reload %s      # re-reads %s\n", *name, configPath)
+}
 
-	// 6. Create ticker
-	ticker := time.NewTicker(*interval)
-	defer ticker.Stop()
+func handleControl(command string) {
+	// 1. Parse flags
+	controlCmd := flag.NewFlagSet(command, flag.ExitOnError)
+	useSudo := controlCmd.Bool("sudo", false, "Use sudo")
+	timeout := controlCmd.Duration("timeout", 30*time.Second, "How long to wait for each job")
+	unitTypeFlag := controlCmd.String("type", "service", "Unit type for names without a suffix")
+	scopeOpts := addScopeFlags(controlCmd)
 
-	fmt.Println("Monitoring services. Press Ctrl+C to stop...")
+	controlCmd.Parse(os.Args[2:])
 
-	// 7. Loop
-	for range ticker.C {
-		fmt.Println("\n--- Checking services ---")
+	action, err := systemd.ParseControlAction(command)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	unitType := parseUnitTypeFlag(*unitTypeFlag)
 
-		// Check services
-		for _, serviceName := range serviceList {
-			serviceName = strings.TrimSpace(serviceName)
-			if serviceName == "" {
-				continue
-			}
+	// 2. Get unit names from remaining args
+	unitNames := controlCmd.Args()
+	if len(unitNames) == 0 {
+		fmt.Println("Error: No services specified")
+		fmt.Printf("\nUsage: monitor %s [options] <service> [service...]\n", command)
+		os.Exit(1)
+	}
+	// Flags after the first unit are not parsed and would reach
+	// systemctl as options
+	for _, name := range unitNames {
+		if strings.HasPrefix(name, "-") {
+			fmt.Printf("Error: %s is not a unit; options go before the units\n", name)
+			fmt.Printf("\nUsage: monitor %s [options] <service> [service...]\n", command)
+			os.Exit(1)
+		}
+	}
 
-			service, err := client.GetServiceStatus(serviceName)
-			if err != nil {
-				fileLogger.Error(err)
-				fmt.Printf("Error checking %s: %v\n", serviceName, err)
-				continue
-			}
+	// 3. Run the action on each unit; actions always use systemctl
+	client := scopeOpts.client(*useSudo, string(systemd.BackendExec))
 
-			// Log to file
-			fileLogger.WriteServiceStatus(service.Name, string(service.Status))
+	type result struct {
+		unit     string
+		err      error
+		duration time.Duration
+	}
+	results := make([]result, 0, len(unitNames))
+
+	for _, name := range unitNames {
+		unitName := models.NormalizeUnitName(name, unitType)
 
-			// Print to console
+		started := time.Now()
+		service, err := client.ControlUnit(context.Background(), action, unitName, *timeout)
+		results = append(results, result{unit: unitName, err: err, duration: time.Since(started)})
+
+		if service != nil {
 			output.PrintService(service)
 		}
+	}
+
+	// 4. Print per-unit summary
+	hasFailures := false
+	fmt.Printf("%s summary:\n", command)
+	for _, r := range results {
+		if r.err != nil {
+			hasFailures = true
+			fmt.Printf("  ❌ %s: %v\n", r.unit, r.err)
+			continue
+		}
+		fmt.Printf("  ✅ %s: %s succeeded (%s)\n", r.unit, command, r.duration.Round(time.Millisecond))
+	}
 
-		// Log summary
-		fileLogger.Info(fmt.Sprintf("Checked %d services", len(serviceList)))
+	// 5. Exit with code 1 if any action failed
+	if hasFailures {
+		os.Exit(1)
 	}
 }
 
@@ -220,6 +1619,10 @@ func handleLogs() {
 	priority := logsCmd.String("priority", "", "Filter by priority (emerg, alert, crit, err, warning, notice, info, debug)")
 	grep := logsCmd.String("grep", "", "Filter logs by pattern")
 	useSudo := logsCmd.Bool("sudo", false, "Use sudo")
+	unitTypeFlag := logsCmd.String("type", "service", "Unit type for names without a suffix")
+	outputFormat := logsCmd.String("output", "text", "Output format (text/json)")
+	hostOpts := addHostFlags(logsCmd)
+	scopeOpts := addScopeFlags(logsCmd)
 
 	logsCmd.Parse(os.Args[2:])
 
@@ -227,14 +1630,14 @@ func handleLogs() {
 	args := logsCmd.Args()
 	if len(args) == 0 {
 		fmt.Println("Error: No service specified")
-		fmt.Println("\nUsage: monitor logs <service> [options]")
+		fmt.Println("\nUsage: monitor logs [options] <service>")
 		os.Exit(1)
 	}
 
-	serviceName := args[0]
+	serviceName := models.NormalizeUnitName(args[0], parseUnitTypeFlag(*unitTypeFlag))
 
-	// Create client
-	client := systemd.NewClient(*useSudo)
+	// Create a client per host (just this machine without --host)
+	clients := hostOpts.clients(*useSudo, string(systemd.BackendExec), scopeOpts)
 
 	// Create log options
 	opts := &models.LogOptions{
@@ -250,33 +1653,70 @@ func handleLogs() {
 	if *follow {
 		fmt.Printf("Following logs for %s (Ctrl+C to stop)...\n\n", serviceName)
 
-		logChan, errChan, err := client.GetServiceLogsStream(serviceName, opts)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(2)
-		}
+		// SIGINT/SIGTERM stop journalctl and close the channels, so we
+		// return normally instead of being killed mid-line
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-		// Print logs as they come
-		for {
-			select {
-			case entry, ok := <-logChan:
-				if !ok {
-					return
+		// Entries of every host are printed as they arrive
+		entries := make(chan *models.LogEntry)
+		var wg sync.WaitGroup
+		for _, client := range clients {
+			logChan, errChan, err := client.GetServiceLogsStream(ctx, serviceName, opts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", clientError(client, err))
+				os.Exit(2)
+			}
+
+			wg.Add(1)
+			go func(client *systemd.Client) {
+				defer wg.Done()
+				// Entries read before an error still go out; logChan is
+				// closed once the stream has ended
+				for entry := range logChan {
+					entries <- entry
 				}
-				printLogEntry(entry)
-			case err, ok := <-errChan:
-				if ok && err != nil {
-					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				if err := <-errChan; err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", clientError(client, err))
 				}
-				return
-			}
+			}(client)
+		}
+		go func() {
+			wg.Wait()
+			close(entries)
+		}()
+
+		// Print logs as they come
+		printEntry := printLogEntry
+		if *outputFormat == "json" {
+			printEntry = func(entry *models.LogEntry) { output.PrintLogEntryJSON(entry) }
+		}
+		for entry := range entries {
+			printEntry(entry)
 		}
 	} else {
-		// One-time fetch
-		entries, err := client.GetServiceLogs(serviceName, opts)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(2)
+		// One-time fetch from every host, merged in time order
+		hostEntries := make([][]*models.LogEntry, len(clients))
+		errs := make([]error, len(clients))
+		pool.Run(context.Background(), len(clients), pool.DefaultWorkers, 0, func(ctx context.Context, i int) {
+			hostEntries[i], errs[i] = clients[i].GetServiceLogs(ctx, serviceName, opts)
+		})
+
+		var entries []*models.LogEntry
+		for i, err := range errs {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", clientError(clients[i], err))
+				os.Exit(2)
+			}
+			entries = append(entries, hostEntries[i]...)
+		}
+		if len(clients) > 1 {
+			sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.Before(entries[j].Timestamp) })
+		}
+
+		if *outputFormat == "json" {
+			output.PrintLogEntriesJSON(entries)
+			return
 		}
 
 		if len(entries) == 0 {
@@ -293,17 +1733,376 @@ func handleLogs() {
 	}
 }
 
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseUnitTypeFlag validates a --type flag value or exits
+func parseUnitTypeFlag(value string) models.UnitType {
+	unitType, err := models.ParseUnitType(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	return unitType
+}
+
+// newClient creates a systemd client for the requested backend or exits.
+// SYSMON_FIXTURE_DIR replays recorded command outputs instead of calling
+// systemd; SYSMON_RECORD_DIR records every command output into a directory.
+func newClient(useSudo bool, backend string) *systemd.Client {
+	fixtureDir := os.Getenv("SYSMON_FIXTURE_DIR")
+	recordDir := os.Getenv("SYSMON_RECORD_DIR")
+
+	// Fixtures and recordings only cover systemctl/journalctl calls
+	if fixtureDir != "" || recordDir != "" {
+		backend = string(systemd.BackendExec)
+	}
+
+	client, err := systemd.NewClientWithBackend(useSudo, systemd.Backend(backend))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Replayed units have no cgroup on this machine unless a fake tree
+	// is given with SYSMON_CGROUP_ROOT
+	if root := os.Getenv("SYSMON_CGROUP_ROOT"); root != "" {
+		client.SetCgroupReader(cgroup.NewReader(root))
+	} else if fixtureDir != "" {
+		client.SetCgroupReader(nil)
+	}
+
+	if fixtureDir != "" {
+		client.SetExecutor(systemd.NewFixtureExecutor(fixtureDir))
+	} else if recordDir != "" {
+		recorder, err := systemd.NewRecordingExecutor(systemd.NewExecExecutor(), recordDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		client.SetExecutor(recorder)
+	}
+
+	return client
+}
+
+// hostFlags are the options selecting remote hosts, shared by list,
+// check, monitor and logs
+type hostFlags struct {
+	hosts      *string
+	hostsFile  *string
+	key        *string
+	knownHosts *string
+	jump       *string
+}
+
+func addHostFlags(fs *flag.FlagSet) *hostFlags {
+	return &hostFlags{
+		hosts:      fs.String("host", "", "Check remote hosts over ssh instead of this machine: comma-separated [user@]host[:port]"),
+		hostsFile:  fs.String("hosts-file", "", "YAML/TOML file listing remote hosts to check over ssh"),
+		key:        fs.String("ssh-key", "", "Private key for --host hosts (default: ssh's own)"),
+		knownHosts: fs.String("ssh-known-hosts", "", "known_hosts file for --host hosts; hosts not listed are refused"),
+		jump:       fs.String("ssh-jump", "", "Jump host for --host hosts, as for ssh -J"),
+	}
+}
+
+// selected reports whether any remote host was given
+func (h *hostFlags) selected() bool {
+	return *h.hosts != "" || *h.hostsFile != ""
+}
+
+// load returns the selected remote hosts, or nil for this machine. It
+// exits on invalid hosts.
+func (h *hostFlags) load(useSudo bool) []config.Host {
+	var hosts []config.Host
+	if *h.hostsFile != "" {
+		loaded, err := config.LoadHosts(*h.hostsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid hosts file:\n%v\n", err)
+			os.Exit(2)
+		}
+		for _, host := range loaded {
+			host.Sudo = host.Sudo || useSudo
+			hosts = append(hosts, host)
+		}
+	}
+
+	for _, value := range splitList(*h.hosts) {
+		target, err := systemd.ParseSSHTarget(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --host: %v\n", err)
+			os.Exit(2)
+		}
+		target.IdentityFile = *h.key
+		target.KnownHosts = *h.knownHosts
+		target.Jump = *h.jump
+		hosts = append(hosts, config.Host{Target: target, Sudo: useSudo})
+	}
+
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		if seen[host.Target.Label()] {
+			fmt.Fprintf(os.Stderr, "Error: host %s is given twice\n", host.Target.Label())
+			os.Exit(2)
+		}
+		seen[host.Target.Label()] = true
+	}
+	return hosts
+}
+
+// clients returns a client per selected remote host, or a single local
+// client when no host is selected, each talking to the manager selected
+// by scope
+func (h *hostFlags) clients(useSudo bool, backend string, scope *scopeFlags) []*systemd.Client {
+	hosts := h.load(useSudo)
+	if len(hosts) == 0 {
+		return []*systemd.Client{scope.client(useSudo, backend)}
+	}
+
+	clients := make([]*systemd.Client, 0, len(hosts))
+	for _, host := range hosts {
+		client := newRemoteClient(host)
+		client.SetScope(scope.scope(host.Sudo, host.Target.User))
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// newRemoteClient creates a client for a remote host. Like newClient it
+// replays or records command outputs, in a subdirectory named after the
+// host.
+func newRemoteClient(host config.Host) *systemd.Client {
+	client := systemd.NewRemoteClient(host.Target, host.Sudo)
+
+	if dir := os.Getenv("SYSMON_FIXTURE_DIR"); dir != "" {
+		client.SetExecutor(systemd.NewFixtureExecutor(filepath.Join(dir, host.Target.Label())))
+	} else if dir := os.Getenv("SYSMON_RECORD_DIR"); dir != "" {
+		recorder, err := systemd.NewRecordingExecutor(systemd.NewSSHExecutor(host.Target), filepath.Join(dir, host.Target.Label()))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		client.SetExecutor(recorder)
+	}
+
+	return client
+}
+
+// scopeFlags are the options selecting a user's service manager instead
+// of the system manager
+type scopeFlags struct {
+	user    *bool
+	machine *string
+}
+
+func addScopeFlags(fs *flag.FlagSet) *scopeFlags {
+	return &scopeFlags{
+		user:    fs.Bool("user", false, "Use the user manager of the calling user (systemctl --user) instead of the system manager"),
+		machine: fs.String("machine", "", "Use the user manager of another user, as root: <user>@.host"),
+	}
+}
+
+// scope returns the selected manager or exits. The user of --user is the
+// caller on this machine and the ssh login user on remote hosts; sshUser
+// is that login user, "" when it is the local user as well.
+func (s *scopeFlags) scope(useSudo bool, sshUser string) systemd.Scope {
+	switch {
+	case *s.user && *s.machine != "":
+		fmt.Fprintln(os.Stderr, "Error: --user and --machine cannot be combined")
+		os.Exit(2)
+	case *s.machine != "":
+		scope, err := systemd.ParseMachine(*s.machine)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --machine: %v\n", err)
+			os.Exit(2)
+		}
+		return scope
+	case *s.user:
+		// sudo would reach root's user manager, not the caller's
+		if useSudo {
+			fmt.Fprintln(os.Stderr, "Error: --user cannot be combined with --sudo; use --machine <user>@.host to reach another user's manager")
+			os.Exit(2)
+		}
+		if sshUser != "" {
+			return systemd.Scope{User: sshUser}
+		}
+		current, err := user.Current()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: cannot determine the current user:", err)
+			os.Exit(2)
+		}
+		return systemd.Scope{User: current.Username}
+	}
+	return systemd.Scope{}
+}
+
+// selected reports whether a user manager was given
+func (s *scopeFlags) selected() bool {
+	return *s.user || *s.machine != ""
+}
+
+// backend returns the backend to use for the selected manager: user
+// managers are only reached with systemctl
+func (s *scopeFlags) backend(backend string) string {
+	if !s.selected() {
+		return backend
+	}
+	if backend == string(systemd.BackendDBus) {
+		fmt.Fprintln(os.Stderr, "Error: user managers are queried with systemctl; --backend dbus cannot be combined with --user/--machine")
+		os.Exit(2)
+	}
+	return string(systemd.BackendExec)
+}
+
+// client creates a local client for the selected manager or exits
+func (s *scopeFlags) client(useSudo bool, backend string) *systemd.Client {
+	scope := s.scope(useSudo, "")
+	client := newClient(useSudo, s.backend(backend))
+	client.SetScope(scope)
+	return client
+}
+
+// listClients returns the clients of the list command: per host, one for
+// the system manager and/or one for the user manager, as managers asks
+func listClients(hostOpts *hostFlags, scopeOpts *scopeFlags, managers string, useSudo bool, backend string) []*systemd.Client {
+	switch managers {
+	case "":
+		return hostOpts.clients(useSudo, backend, scopeOpts)
+	case "system":
+		if scopeOpts.selected() {
+			fmt.Fprintln(os.Stderr, "Error: --scope system cannot be combined with --user/--machine")
+			os.Exit(2)
+		}
+		return hostOpts.clients(useSudo, backend, scopeOpts)
+	case "user", "all":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid scope %q: use system, user or all\n", managers)
+		os.Exit(2)
+	}
+
+	// Without --machine the user manager is the caller's
+	userOpts := *scopeOpts
+	if *userOpts.machine == "" {
+		userOpts.user = new(bool)
+		*userOpts.user = true
+	}
+	if managers == "user" {
+		return hostOpts.clients(useSudo, backend, &userOpts)
+	}
+
+	system := hostOpts.clients(useSudo, backend, &scopeFlags{user: new(bool), machine: new(string)})
+	users := hostOpts.clients(useSudo, backend, &userOpts)
+	clients := make([]*systemd.Client, 0, len(system)+len(users))
+	for i := range system {
+		clients = append(clients, system[i], users[i])
+	}
+	return clients
+}
+
+// readSecret reads the fleet's shared secret from secretFile, or from
+// SYSMON_FLEET_SECRET without one; never from a flag, which would show up
+// in ps. It exits when the secret is missing or too short.
+func readSecret(secretFile string) []byte {
+	secret := os.Getenv("SYSMON_FLEET_SECRET")
+	if secretFile != "" {
+		data, err := os.ReadFile(secretFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading secret file:", err)
+			os.Exit(2)
+		}
+		secret = strings.TrimSpace(string(data))
+	}
+	if secret == "" {
+		fmt.Fprintln(os.Stderr, "Error: a shared secret is required: --secret-file or SYSMON_FLEET_SECRET")
+		os.Exit(2)
+	}
+	if len(secret) < 16 {
+		fmt.Fprintln(os.Stderr, "Error: the shared secret must be at least 16 characters")
+		os.Exit(2)
+	}
+	return []byte(secret)
+}
+
+// newAgent creates the agent of the agent command or exits
+func newAgent(collectorURL, name, secretFile string, remote bool) *fleet.Agent {
+	if remote {
+		fmt.Fprintln(os.Stderr, "Error: an agent reports its own machine; run one agent per host instead of --host/--hosts-file")
+		os.Exit(2)
+	}
+	if collectorURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: --collector is required")
+		os.Exit(2)
+	}
+	if name == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: cannot read the hostname, set --name:", err)
+			os.Exit(2)
+		}
+		name = hostname
+	}
+
+	agent, err := fleet.NewAgent(collectorURL, name, readSecret(secretFile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	return agent
+}
+
+// clientError prefixes an error with the remote host and the user manager
+// it came from
+func clientError(client *systemd.Client, err error) error {
+	if scope := client.Scope(); scope.User != "" {
+		err = fmt.Errorf("%s: %w", scope, err)
+	}
+	return hostError(client.Host(), err)
+}
+
+// hostError prefixes an error from a remote host with the host
+func hostError(host string, err error) error {
+	if host == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", host, err)
+}
+
 func printLogEntry(entry *models.LogEntry) {
 	color := entry.GetColorForLevel()
 	icon := entry.GetLevelIcon()
 	timestamp := entry.Timestamp.Format("2006-01-02 15:04:05")
 
-	fmt.Printf("%s[%s]%s %s %s %s\n",
+	// Process that wrote the entry, e.g. "nginx[1234]: ", after the
+	// remote host it was read from: "web1 nginx[1234]: "
+	source := ""
+	if entry.Identifier != "" {
+		source = entry.Identifier
+		if entry.PID > 0 {
+			source += fmt.Sprintf("[%d]", entry.PID)
+		}
+	}
+	if entry.Host != "" {
+		source = strings.TrimSpace(entry.Host + " " + source)
+	}
+	if source != "" {
+		source += ": "
+	}
+
+	fmt.Printf("%s[%s]%s %s %s %s%s\n",
 		color,
 		timestamp,
 		"\033[0m",
 		icon,
-		entry.Level,
+		strings.ToUpper(entry.Level),
+		source,
 		entry.Message)
 }
 
@@ -312,21 +2111,137 @@ func printUsage() {
 	fmt.Println("\nCommands:")
 	fmt.Println("  list              List all systemd services")
 	fmt.Println("  check <services>  Check specific services")
+	fmt.Println("  deps <unit>       Show a unit's dependency tree and how a failure spreads")
+	fmt.Println("  explain <unit>    Explain why a unit failed: exit status, errors, conditions, dependencies")
 	fmt.Println("  monitor           Monitor services continuously")
+	fmt.Println("  agent             Monitor this machine and report to a collector")
+	fmt.Println("  collector         Receive agent reports and serve the fleet-wide state")
+	fmt.Println("  dashboard         Full-screen, auto-refreshing view with logs and actions")
 	fmt.Println("  logs <service>    View service logs")
 	fmt.Println("  write-log         Write message to systemd journal")
+	fmt.Println("  config validate <file>  Validate a monitor config file")
+	fmt.Println("  serve-metrics     Expose unit metrics for Prometheus on /metrics")
+	fmt.Println("  serve             Run the HTTP REST API (status, logs, actions)")
+	fmt.Println("  install-unit      Write a hardened systemd unit that runs the monitor")
+	fmt.Println("  history <service> Show the recorded timeline of a service")
+	fmt.Println("  report [services] Availability, outages, MTTR and MTBF per service")
+	fmt.Println("  start|stop|restart|reload <services>  Control services and wait for the job")
+	fmt.Println("  enable|disable|mask|unmask <services> Change unit file state")
 	fmt.Println("\nList Options:")
 	fmt.Println("  --status string   Filter by status (running/failed/stopped/all)")
 	fmt.Println("  --output string   Output format (table/json)")
 	fmt.Println("  --sudo            Use sudo for systemctl")
+	fmt.Println("  --backend string  Backend (auto/dbus/exec, default auto)")
+	fmt.Println("  --type string     Unit type (service/timer/socket/mount/path/target/scope/all)")
+	fmt.Println("  --resources       Include CPU, memory and task usage")
+	fmt.Println("  --collector string  List every host reporting to this collector")
+	fmt.Println("  --secret-file string  Collector's shared secret (or SYSMON_FLEET_SECRET)")
+	fmt.Println("  --scope string    Managers to list: system, user or all (default: the one --user/--machine selects)")
 	fmt.Println("\nCheck Options:")
-	fmt.Println("  --output string   Output format (table/json)")
 	fmt.Println("  --sudo            Use sudo")
+	fmt.Println("  --backend string  Backend (auto/dbus/exec, default auto)")
+	fmt.Println("  --type string     Unit type for names without a suffix (default service)")
+	fmt.Println("  --workers int     Services checked at once (default 8)")
+	fmt.Println("  --timeout duration  Give up on a single service (default 10s)")
+	fmt.Println("\nDeps Options:")
+	fmt.Println("  --reverse         Show what depends on the unit instead")
+	fmt.Println("  --depth int       Levels to follow (default 3, 0 = all)")
+	fmt.Println("  --kinds string    Dependency kinds: requires,bindsto,partof,wants,after,before (default all)")
+	fmt.Println("  --output string   Output format (tree/dot/json)")
+	fmt.Println("  --sudo            Use sudo")
+	fmt.Println("  --backend string  Backend (auto/dbus/exec, default auto)")
+	fmt.Println("\nExplain Options:")
+	fmt.Println("  --lines int       Journal lines to show (default 10, 0 = none)")
+	fmt.Println("  --window duration Read journal lines this long around the failure (default 5m, 0 = any time)")
+	fmt.Println("  --output string   Output format (text/json)")
+	fmt.Println("  --sudo            Use sudo")
+	fmt.Println("  --backend string  Backend (auto/dbus/exec, default auto)")
 	fmt.Println("\nMonitor Options:")
+	fmt.Println("  --config string   YAML/TOML config file; flags override its values")
 	fmt.Println("  --services string Comma-separated service names")
 	fmt.Println("  --interval duration Check interval (default 30s)")
 	fmt.Println("  --log-file string   Log file path")
 	fmt.Println("  --sudo            Use sudo")
+	fmt.Println("  --backend string  Backend (auto/dbus/exec, default auto)")
+	fmt.Println("  --type string     Unit type for names without a suffix (default service)")
+	fmt.Println("  --changes-only    Only log transitions, not unchanged status lines")
+	fmt.Println("  --journal         Also write transition events to the journal")
+	fmt.Println("  --webhook-url string  POST alerts as JSON to this URL")
+	fmt.Println("  --smtp-addr string    SMTP server (host:port) for email alerts")
+	fmt.Println("  --smtp-from string    Sender address for email alerts")
+	fmt.Println("  --smtp-to string      Comma-separated email recipients")
+	fmt.Println("  --smtp-user string    SMTP username (password from SYSMON_SMTP_PASSWORD)")
+	fmt.Println("  --alert-command string  Shell command run for every alert")
+	fmt.Println("  --alert-retries int   Delivery attempts per notifier (default 3)")
+	fmt.Println("  --alert-repeat duration  Re-send alerts for still-failed services (default never)")
+	fmt.Println("  --metrics-addr string    Also serve Prometheus metrics, e.g. :9558")
+	fmt.Println("  --metrics-include string Comma-separated unit globs to export")
+	fmt.Println("  --metrics-exclude string Comma-separated unit globs not to export")
+	fmt.Println("  --history-file string    Record samples and transitions (default logs/history.jsonl, \"\" = off)")
+	fmt.Println("  --history-retention duration  Drop history older than this (default 720h)")
+	fmt.Println("  --remediate string    Repair failed services: restart, reset-start, command or none")
+	fmt.Println("  --remediate-command string  Shell command for --remediate command")
+	fmt.Println("  --workers int     Status checks run at once (default 8)")
+	fmt.Println("  --check-timeout duration  Give up on a single status check (default 10s)")
+	fmt.Println("\nAgent Options (besides the monitor options):")
+	fmt.Println("  --collector string  Collector URL to report to (required)")
+	fmt.Println("  --secret-file string  Shared secret file (or SYSMON_FLEET_SECRET)")
+	fmt.Println("  --name string     Name this machine reports as (default: its hostname)")
+	fmt.Println("\nCollector Options:")
+	fmt.Println("  --addr string     Listen address (default :9559)")
+	fmt.Println("  --secret-file string  Shared secret file (or SYSMON_FLEET_SECRET)")
+	fmt.Println("  --stale duration  Mark hosts stale after this long without a report (default three report intervals)")
+	fmt.Println("  --log-file string Log file path (default logs/collector.log)")
+	fmt.Println("  --history-file string  Record every host's history (default logs/fleet-history.jsonl, \"\" = off)")
+	fmt.Println("  --history-retention duration  Drop history older than this (default 720h)")
+	fmt.Println("  --tls-cert string --tls-key string  Serve HTTPS")
+	fmt.Println("  Signals: SIGINT/SIGTERM stop cleanly, SIGHUP reloads the config and reopens the log")
+	fmt.Println("\nInstall-Unit Options:")
+	fmt.Println("  --config string   Config file the service runs with (default /etc/systemd-monitoring/config.yaml)")
+	fmt.Println("  --name string     Unit name (default systemd-monitoring)")
+	fmt.Println("  --output string   Unit file path (default /etc/systemd/system/<name>.service, - = stdout)")
+	fmt.Println("  --binary string   Monitor binary (default: this executable)")
+	fmt.Println("  --force           Overwrite an existing unit file")
+	fmt.Println("\nDashboard Options:")
+	fmt.Println("  --services string Comma-separated watchlist (or --config with services)")
+	fmt.Println("  --interval duration Refresh interval (default 2s)")
+	fmt.Println("  --lines int       Log lines shown for the selected unit (default 100)")
+	fmt.Println("  --action-timeout duration  Wait for restart/stop jobs (default 30s)")
+	fmt.Println("  --type string     Unit type to list (default service)")
+	fmt.Println("  --sudo            Use sudo")
+	fmt.Println("  --backend string  Backend (auto/dbus/exec, default auto)")
+	fmt.Println("\nHistory Options:")
+	fmt.Println("  --since string    Start of the range: 24h, 7d, 2024-12-22, \"2024-12-22 15:04\" (default 24h)")
+	fmt.Println("  --until string    End of the range (default now)")
+	fmt.Println("  --samples         Include status samples, not only events")
+	fmt.Println("  --output string   Output format (table/json)")
+	fmt.Println("  --file string     History file (default logs/history.jsonl)")
+	fmt.Println("  --host string     Remote host the service ran on (default this machine)")
+	fmt.Println("\nReport Options:")
+	fmt.Println("  --since string    Start of the window (default 30d)")
+	fmt.Println("  --until string    End of the window (default now)")
+	fmt.Println("  --month string    A calendar month, e.g. 2024-12")
+	fmt.Println("  --source string   Data source (auto/history/journal)")
+	fmt.Println("  --output string   Output format (table/json/csv/html)")
+	fmt.Println("  --file string     History file (default logs/history.jsonl)")
+	fmt.Println("  --max-gap duration  Longest gap between samples still counted (default 10m)")
+	fmt.Println("  --host string     Report on a remote host's services (history source only)")
+	fmt.Println("\nServe Options:")
+	fmt.Println("  --addr string     Listen address (default 127.0.0.1:8080)")
+	fmt.Println("  --token-file string  Bearer token file (or SYSMON_API_TOKEN)")
+	fmt.Println("  --read-only       Refuse start/stop/restart/... requests")
+	fmt.Println("  --action-timeout duration  Default wait for action jobs (default 30s)")
+	fmt.Println("  --tls-cert string --tls-key string  Serve HTTPS")
+	fmt.Println("  --sudo            Use sudo")
+	fmt.Println("  --backend string  Backend (auto/dbus/exec, default auto)")
+	fmt.Println("\nServe-Metrics Options:")
+	fmt.Println("  --addr string     Listen address (default :9558)")
+	fmt.Println("  --units string    Comma-separated units (default: every unit of --type)")
+	fmt.Println("  --include string  Comma-separated unit globs to export")
+	fmt.Println("  --exclude string  Comma-separated unit globs not to export")
+	fmt.Println("  --type string     Unit type to export (default service)")
+	fmt.Println("  --sudo            Use sudo")
+	fmt.Println("  --backend string  Backend (auto/dbus/exec, default auto)")
 	fmt.Println("\nLogs Options:")
 	fmt.Println("  --lines int       Number of lines to show (default 50)")
 	fmt.Println("  --follow          Follow log output in real-time")
@@ -334,6 +2249,21 @@ func printUsage() {
 	fmt.Println("  --until string    Show logs until")
 	fmt.Println("  --priority string Filter by priority (info, warning, error, etc)")
 	fmt.Println("  --grep string     Filter logs by pattern")
+	fmt.Println("  --output string   Output format (text/json)")
+	fmt.Println("  --sudo            Use sudo")
+	fmt.Println("  --type string     Unit type for names without a suffix (default service)")
+	fmt.Println("\nHost Options (list/check/monitor/logs):")
+	fmt.Println("  --host string     Comma-separated [user@]host[:port] to check over ssh instead of this machine")
+	fmt.Println("  --hosts-file string  YAML/TOML file listing the hosts")
+	fmt.Println("  --ssh-key string  Private key for --host hosts")
+	fmt.Println("  --ssh-known-hosts string  known_hosts file for --host hosts")
+	fmt.Println("  --ssh-jump string Jump host for --host hosts, as for ssh -J")
+	fmt.Println("\nUser Manager Options (every command but config/install-unit/collector/write-log):")
+	fmt.Println("  --user            Use your own user manager (systemctl --user) instead of the system manager")
+	fmt.Println("  --machine string  Use another user's manager as root: <user>@.host")
+	fmt.Println("\nControl Options (start/stop/restart/reload/enable/disable/mask/unmask):")
+	fmt.Println("  --timeout duration  How long to wait for each job (default 30s)")
+	fmt.Println("  --type string     Unit type for names without a suffix (default service)")
 	fmt.Println("  --sudo            Use sudo")
 	fmt.Println("\nWrite-Log Options:")
 	fmt.Println("  --message string  Message to write (required)")
@@ -343,11 +2273,32 @@ func printUsage() {
 	fmt.Println("  monitor list")
 	fmt.Println("  monitor list --status running --output json")
 	fmt.Println("  monitor check nginx mysql redis")
+	fmt.Println("  monitor list --type timer")
+	fmt.Println("  monitor deps --kinds requires,wants multi-user.target")
+	fmt.Println("  monitor deps --reverse --output dot postgresql | dot -Tsvg > deps.svg")
+	fmt.Println("  monitor explain nginx")
+	fmt.Println("  monitor explain --output json --lines 50 postgresql")
+	fmt.Println("  monitor check --type timer apt-daily logrotate.timer")
 	fmt.Println("  monitor monitor --services nginx,mysql --interval 1m")
+	fmt.Println("  monitor monitor --config /etc/systemd-monitoring/config.yaml")
+	fmt.Println("  monitor monitor --services nginx --remediate restart")
+	fmt.Println("  monitor check --hosts-file /etc/systemd-monitoring/hosts.yaml nginx")
+	fmt.Println("  monitor agent --config /etc/systemd-monitoring/config.yaml --collector http://collector:9559")
+	fmt.Println("  monitor list --collector http://collector:9559 --status failed")
+	fmt.Println("  monitor list --scope all")
+	fmt.Println("  monitor logs --machine alice@.host --sudo syncthing")
+	fmt.Println("  monitor history --since 7d redis")
+	fmt.Println("  monitor report --month 2024-12 --output html > report.html")
+	fmt.Println("  monitor config validate /etc/systemd-monitoring/config.yaml")
+	fmt.Println("  monitor serve --addr :8080 --token-file /etc/systemd-monitoring/token --read-only")
+	fmt.Println("  monitor serve-metrics --addr :9558 --exclude 'user@*,systemd-*'")
+	fmt.Println("  monitor install-unit --config /etc/systemd-monitoring/config.yaml")
 	fmt.Println("  monitor logs clash")
-	fmt.Println("  monitor logs clash --follow")
-	fmt.Println("  monitor logs clash --lines 100 --since '1 hour ago'")
-	fmt.Println("  monitor logs nginx --grep error --priority err")
+	fmt.Println("  monitor logs --follow clash")
+	fmt.Println("  monitor logs --lines 100 --since '1 hour ago' clash")
+	fmt.Println("  monitor logs --grep error --priority err nginx")
+	fmt.Println("  monitor restart --sudo nginx redis")
+	fmt.Println("  monitor enable --type timer backup")
 	fmt.Println("  monitor write-log --message 'Service started' --priority info")
 	fmt.Println("  monitor write-log --message 'Critical error' --priority crit")
 }